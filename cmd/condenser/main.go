@@ -3,6 +3,7 @@ package main
 import (
 	httpapi "condenser/internal/api/http"
 	"condenser/internal/core/cert"
+	"condenser/internal/core/image"
 	"condenser/internal/core/pod"
 	"condenser/internal/core/service"
 	"condenser/internal/dns"
@@ -108,12 +109,24 @@ func main() {
 		pod.NewPodController().Start()
 	}()
 
+	// probe controller
+	go func() {
+		log.Printf("[*] probe controller start")
+		pod.NewProbeController().Start()
+	}()
+
 	// service controller
 	go func() {
 		log.Printf("[*] service controller start")
 		service.NewServiceController().Start()
 	}()
 
+	// image gc controller
+	go func() {
+		log.Printf("[*] image gc controller start")
+		image.NewImageGcController().Start()
+	}()
+
 	// forwarder
 	go func() {
 		log.Printf("[*] dns proxy listening")
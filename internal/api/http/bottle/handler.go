@@ -1,6 +1,7 @@
 package bottle
 
 import (
+	trustHandler "condenser/internal/api/http/trust"
 	apimodel "condenser/internal/api/http/utils"
 	"condenser/internal/core/bottle"
 	"condenser/internal/core/container"
@@ -82,6 +83,9 @@ func (h *RequestHandler) RegisterBottle(w http.ResponseWriter, r *http.Request)
 	if _, err := h.serviceHandler.Create(bottleId); err != nil {
 		_ = h.bsmHandler.RemoveBottle(bottleId)
 		h.rollbackPolicies(extractPolicyIds(policies))
+		if trustHandler.RespondVerificationError(w, r, err, "create containers failed: ", nil) {
+			return
+		}
 		apimodel.RespondFail(w, http.StatusInternalServerError, "create containers failed: "+err.Error(), nil)
 		return
 	}
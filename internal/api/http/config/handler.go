@@ -0,0 +1,136 @@
+package config
+
+import (
+	"net/http"
+
+	apimodel "condenser/internal/api/http/utils"
+	"condenser/internal/core/config"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func NewRequestHandler() *RequestHandler {
+	return &RequestHandler{
+		serviceHandler: config.NewConfigService(),
+	}
+}
+
+type RequestHandler struct {
+	serviceHandler config.ConfigServiceHandler
+}
+
+// GetConfigMapList godoc
+// @Summary list configmaps
+// @Description list configmaps with their data
+// @Tags configmaps
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/configmaps [get]
+func (h *RequestHandler) GetConfigMapList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.serviceHandler.GetConfigMapList()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "configmap list", list)
+}
+
+// GetConfigMapById godoc
+// @Summary get configmap detail
+// @Description get configmap detail
+// @Tags configmaps
+// @Param configMapId path string true "ConfigMap ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/configmaps/{configMapId} [get]
+func (h *RequestHandler) GetConfigMapById(w http.ResponseWriter, r *http.Request) {
+	configMapId := chi.URLParam(r, "configMapId")
+	if configMapId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing configMapId", nil)
+		return
+	}
+	info, err := h.serviceHandler.GetConfigMapById(configMapId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusNotFound, "get failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "configmap detail", info)
+}
+
+// RemoveConfigMap godoc
+// @Summary remove configmap
+// @Description remove configmap, pods that mount it keep their files
+// @Tags configmaps
+// @Param configMapId path string true "ConfigMap ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/configmaps/{configMapId} [delete]
+func (h *RequestHandler) RemoveConfigMap(w http.ResponseWriter, r *http.Request) {
+	configMapId := chi.URLParam(r, "configMapId")
+	if configMapId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing configMapId", RemoveConfigMapResponse{})
+		return
+	}
+	result, err := h.serviceHandler.RemoveConfigMap(configMapId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), RemoveConfigMapResponse{ConfigMapId: configMapId})
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "configmap removed", RemoveConfigMapResponse{ConfigMapId: result})
+}
+
+// GetSecretList godoc
+// @Summary list secrets
+// @Description list secrets, only the key names are returned
+// @Tags secrets
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/secrets [get]
+func (h *RequestHandler) GetSecretList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.serviceHandler.GetSecretList()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "secret list", list)
+}
+
+// GetSecretById godoc
+// @Summary get secret detail
+// @Description get secret detail, only the key names are returned
+// @Tags secrets
+// @Param secretId path string true "Secret ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/secrets/{secretId} [get]
+func (h *RequestHandler) GetSecretById(w http.ResponseWriter, r *http.Request) {
+	secretId := chi.URLParam(r, "secretId")
+	if secretId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing secretId", nil)
+		return
+	}
+	info, err := h.serviceHandler.GetSecretById(secretId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusNotFound, "get failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "secret detail", info)
+}
+
+// RemoveSecret godoc
+// @Summary remove secret
+// @Description remove secret, pods that mount it keep their files
+// @Tags secrets
+// @Param secretId path string true "Secret ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/secrets/{secretId} [delete]
+func (h *RequestHandler) RemoveSecret(w http.ResponseWriter, r *http.Request) {
+	secretId := chi.URLParam(r, "secretId")
+	if secretId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing secretId", RemoveSecretResponse{})
+		return
+	}
+	result, err := h.serviceHandler.RemoveSecret(secretId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), RemoveSecretResponse{SecretId: secretId})
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "secret removed", RemoveSecretResponse{SecretId: result})
+}
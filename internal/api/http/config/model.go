@@ -0,0 +1,9 @@
+package config
+
+type RemoveConfigMapResponse struct {
+	ConfigMapId string `json:"configMapId"`
+}
+
+type RemoveSecretResponse struct {
+	SecretId string `json:"secretId"`
+}
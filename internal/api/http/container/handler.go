@@ -2,14 +2,17 @@ package container
 
 import (
 	"condenser/internal/core/container"
+	"condenser/internal/core/image"
 	"condenser/internal/store/csm"
 	"condenser/internal/utils"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 
 	"condenser/internal/api/http/logger"
+	trustHandler "condenser/internal/api/http/trust"
 	apimodel "condenser/internal/api/http/utils"
 )
 
@@ -42,6 +45,16 @@ func (h *RequestHandler) CreateContainer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	var diskQuota int64
+	if req.DiskQuota != "" {
+		size, err := utils.ParseByteSize(req.DiskQuota)
+		if err != nil || size <= 0 {
+			apimodel.RespondFail(w, http.StatusBadRequest, "invalid diskQuota: "+req.DiskQuota, CreateContainerResponse{Id: ""})
+			return
+		}
+		diskQuota = size
+	}
+
 	// set log: target
 	logger.SetTarget(r.Context(), logger.Target{
 		ContainerName: req.Name,
@@ -56,18 +69,29 @@ func (h *RequestHandler) CreateContainer(w http.ResponseWriter, r *http.Request)
 	// service: create
 	result, err := h.serviceHandler.Create(
 		container.ServiceCreateModel{
-			Image:   req.Image,
-			Command: req.Command,
-			Port:    req.Port,
-			Mount:   req.Mount,
-			Env:     req.Env,
-			Network: req.Network,
-			Tty:     req.Tty,
-			Name:    req.Name,
-			PodId:   req.PodId,
+			Image:    req.Image,
+			Platform: req.Platform,
+			Command:  req.Command,
+			Port:     req.Port,
+			Mount:    req.Mount,
+			Env:      req.Env,
+			Network:  req.Network,
+			Tty:      req.Tty,
+			Name:     req.Name,
+			PodId:    req.PodId,
+
+			DiskQuota: diskQuota,
 		},
 	)
 	if err != nil {
+		if trustHandler.RespondVerificationError(w, r, err, "image verification failed: ", CreateContainerResponse{Id: ""}) {
+			return
+		}
+		if errors.Is(err, image.ErrDigestRequired) {
+			logger.SetReason(r.Context(), err.Error())
+			apimodel.RespondFail(w, http.StatusForbidden, "image policy: "+err.Error(), CreateContainerResponse{Id: ""})
+			return
+		}
 		apimodel.RespondFail(w, http.StatusInternalServerError, "service failed: "+err.Error(), CreateContainerResponse{Id: ""})
 		return
 	}
@@ -243,11 +267,19 @@ func (h *RequestHandler) DeleteContainer(w http.ResponseWriter, r *http.Request)
 // @Summary get container list
 // @Description get all container list
 // @Tags containers
+// @Param labelSelector query string false "label selector (app=web,env in (a,b),!canary)"
+// @Param fieldSelector query string false "field selector (metadata.name, status.phase, spec.podId, spec.nodeName)"
 // @Success 200 {object} apimodel.ApiResponse
 // @Router /v1/containers [get]
 func (h *RequestHandler) GetContainerList(w http.ResponseWriter, r *http.Request) {
+	opts, err := apimodel.ParseListOptions(r, container.ContainerSelectableFields)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
 	// service: get container list
-	containerList, err := h.serviceHandler.GetContainerList()
+	containerList, err := h.serviceHandler.GetContainerList(opts)
 	if err != nil {
 		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve container list failed: "+err.Error(), nil)
 		return
@@ -2,15 +2,18 @@ package container
 
 // == create ==
 type CreateContainerRequest struct {
-	Image   string   `json:"image" example:"alpine:latest"`
-	Command []string `json:"command,omitempty" example:"/bin/sh,-c,echo hello; sleep 60"`
-	Port    []string `json:"port" example:"8080:80,4443:443"`
-	Mount   []string `json:"mount" example:"/host/dir:/container/dir,/src:/dst"`
-	Env     []string `json:"env" exampe:"key=value"`
-	Network string   `json:"network" example:"raind0"`
-	Tty     bool     `json:"tty" example:"false"`
-	Name    string   `json:"name"  example:"my-container"`
-	PodId   string   `json:"podId" example:"pod-1234"`
+	Image    string   `json:"image" example:"alpine:latest"`
+	Platform string   `json:"platform,omitempty" example:"linux/arm64"` // os/arch[/variant], the host platform by default
+	Command  []string `json:"command,omitempty" example:"/bin/sh,-c,echo hello; sleep 60"`
+	Port     []string `json:"port" example:"8080:80,4443:443"`
+	Mount    []string `json:"mount" example:"/host/dir:/container/dir,/src:/dst"`
+	Env      []string `json:"env" exampe:"key=value"`
+	Network  string   `json:"network" example:"raind0"`
+	Tty      bool     `json:"tty" example:"false"`
+	Name     string   `json:"name"  example:"my-container"`
+	PodId    string   `json:"podId" example:"pod-1234"`
+	// upper dir limit, needs project quota on the filesystem of /etc/raind
+	DiskQuota string `json:"diskQuota,omitempty" example:"10G"`
 }
 
 type CreateContainerResponse struct {
@@ -1,11 +1,17 @@
 package image
 
 import (
+	"condenser/internal/api/http/logger"
 	"condenser/internal/core/image"
+	"condenser/internal/utils"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	apimodel "condenser/internal/api/http/utils"
 )
@@ -22,11 +28,12 @@ type RequestHandler struct {
 
 // PullImage godoc
 // @Summary pull image
-// @Description pull image from registry
+// @Description pull image from registry, with stream=true per-layer progress is streamed as NDJSON
 // @Tags image
 // @Accept json
 // @Produce json
 // @Param request body PullImageRequest true "Target Image"
+// @Param stream query bool false "stream progress events"
 // @Success 201 {object} apimodel.ApiResponse
 // @Router /v1/images [post]
 func (h *RequestHandler) PullImage(w http.ResponseWriter, r *http.Request) {
@@ -34,24 +41,100 @@ func (h *RequestHandler) PullImage(w http.ResponseWriter, r *http.Request) {
 	var req PullImageRequest
 	if err := apimodel.DecodeRequestBody(r, &req); err != nil {
 		apimodel.RespondFail(w, http.StatusBadRequest, "invalid json: "+err.Error(), nil)
+		return
+	}
+	if req.MaxConcurrentDownloads < 0 {
+		apimodel.RespondFail(w, http.StatusBadRequest, "maxConcurrentDownloads must not be negative", nil)
+		return
+	}
+
+	// set log: target
+	logger.SetTarget(r.Context(), logger.Target{
+		ImageRef: req.Image,
+	})
+
+	pullParameter := image.ServicePullModel{
+		Image:                  req.Image,
+		Platform:               req.Platform,
+		Os:                     req.Os,
+		Arch:                   req.Arch,
+		MaxConcurrentDownloads: req.MaxConcurrentDownloads,
+	}
+	stream := newEventStream(w)
+	if r.URL.Query().Get("stream") == "true" {
+		pullParameter.Progress = func(ev image.PullProgress) {
+			stream.writeEvent(PullProgressEvent{
+				Id:      ev.Id,
+				Status:  ev.Status,
+				Current: ev.Current,
+				Total:   ev.Total,
+				Message: ev.Message,
+			})
+		}
 	}
 
 	// service
-	if err := h.serviceHandler.Pull(
-		image.ServicePullModel{
-			Image: req.Image,
-			Os:    req.Os,
-			Arch:  req.Arch,
-		},
-	); err != nil {
+	if err := h.serviceHandler.Pull(pullParameter); err != nil {
+		logger.SetReason(r.Context(), err.Error())
+		if stream.started() {
+			stream.writeEvent(PullProgressEvent{Status: "error", Error: "pull failed: " + err.Error()})
+			return
+		}
+		if errors.Is(err, image.ErrDigestRequired) {
+			apimodel.RespondFail(w, http.StatusForbidden, "pull failed: "+err.Error(), nil)
+			return
+		}
 		apimodel.RespondFail(w, http.StatusInternalServerError, "pull failed: "+err.Error(), nil)
 		return
 	}
 
+	if stream.started() {
+		stream.writeEvent(PullProgressEvent{Status: "success", Message: "pull completed"})
+		return
+	}
+
 	// encode response
 	apimodel.RespondSuccess(w, http.StatusOK, "pull completed", req)
 }
 
+// eventStream writes progress events as NDJSON. The header is only sent with
+// the first event, so errors raised before any progress still get a regular
+// status code.
+type eventStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	rc      *http.ResponseController
+	written bool
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	return &eventStream{
+		w:   w,
+		enc: json.NewEncoder(w),
+		rc:  http.NewResponseController(w),
+	}
+}
+
+func (s *eventStream) writeEvent(ev any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.written {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.w.WriteHeader(http.StatusOK)
+		s.written = true
+	}
+	// a client that went away must not stop the operation
+	_ = s.enc.Encode(ev)
+	_ = s.rc.Flush()
+}
+
+func (s *eventStream) started() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.written
+}
+
 // RemoveImage godoc
 // @Summary remove image
 // @Description remove image from local
@@ -74,6 +157,12 @@ func (h *RequestHandler) RemoveImage(w http.ResponseWriter, r *http.Request) {
 			Image: req.Image,
 		},
 	); err != nil {
+		var inUse *image.ImageInUseError
+		if errors.As(err, &inUse) {
+			logger.SetReason(r.Context(), err.Error())
+			apimodel.RespondFail(w, http.StatusConflict, "remove failed: "+err.Error(), nil)
+			return
+		}
 		apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), nil)
 		return
 	}
@@ -82,15 +171,241 @@ func (h *RequestHandler) RemoveImage(w http.ResponseWriter, r *http.Request) {
 	apimodel.RespondSuccess(w, http.StatusOK, "remove completed", req)
 }
 
+// PruneImage godoc
+// @Summary prune images
+// @Description remove dangling images, images unused by containers/pod templates/bottles, or unused images older than a given age
+// @Tags image
+// @Accept json
+// @Produce json
+// @Param request body PruneImageRequest false "Prune Mode"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/prune [post]
+func (h *RequestHandler) PruneImage(w http.ResponseWriter, r *http.Request) {
+	// decode request
+	var req PruneImageRequest
+	if r.ContentLength != 0 {
+		if err := apimodel.DecodeRequestBody(r, &req); err != nil {
+			apimodel.RespondFail(w, http.StatusBadRequest, "invalid json: "+err.Error(), nil)
+			return
+		}
+	}
+
+	var olderThan time.Duration
+	if req.OlderThan != "" {
+		d, err := time.ParseDuration(req.OlderThan)
+		if err != nil {
+			apimodel.RespondFail(w, http.StatusBadRequest, "invalid olderThan: "+err.Error(), nil)
+			return
+		}
+		olderThan = d
+	}
+	logger.PutExtra(r.Context(), "mode", req.Mode)
+
+	// service
+	result, err := h.serviceHandler.Prune(
+		image.ServicePruneModel{
+			Mode:      req.Mode,
+			OlderThan: olderThan,
+		},
+	)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "prune failed: "+err.Error(), nil)
+		return
+	}
+	logger.PutExtra(r.Context(), "removed", len(result.Removed))
+	logger.PutExtra(r.Context(), "reclaimed_bytes", result.ReclaimedBytes)
+
+	// encode response
+	resp := PruneImageResponse{
+		Removed:        make([]PrunedImage, 0, len(result.Removed)),
+		Skipped:        make([]SkippedImage, 0, len(result.Skipped)),
+		ReclaimedBytes: result.ReclaimedBytes,
+	}
+	for _, p := range result.Removed {
+		resp.Removed = append(resp.Removed, PrunedImage{
+			Image:          p.Image,
+			BundlePath:     p.BundlePath,
+			ReclaimedBytes: p.ReclaimedBytes,
+		})
+	}
+	for _, sk := range result.Skipped {
+		resp.Skipped = append(resp.Skipped, SkippedImage{
+			Image:  sk.Image,
+			Reason: sk.Reason,
+		})
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "prune completed", resp)
+}
+
+// GetImageGcPolicy godoc
+// @Summary get image gc policy
+// @Description get disk high-watermark image garbage collection policy
+// @Tags image
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/gc [get]
+func (h *RequestHandler) GetImageGcPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.serviceHandler.GetGcPolicy()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve gc policy failed: "+err.Error(), nil)
+		return
+	}
+
+	apimodel.RespondSuccess(w, http.StatusOK, "retrieve gc policy success", ImageGcPolicyResponse{
+		Enabled:       policy.Enabled,
+		HighWatermark: policy.HighWatermark,
+		LowWatermark:  policy.LowWatermark,
+	})
+}
+
+// SetImageGcPolicy godoc
+// @Summary set image gc policy
+// @Description enable/disable background image gc and set disk usage watermarks (percent)
+// @Tags image
+// @Accept json
+// @Produce json
+// @Param request body ImageGcPolicyRequest true "GC Policy"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/gc [post]
+func (h *RequestHandler) SetImageGcPolicy(w http.ResponseWriter, r *http.Request) {
+	// decode request
+	var req ImageGcPolicyRequest
+	if err := apimodel.DecodeRequestBody(r, &req); err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "invalid json: "+err.Error(), nil)
+		return
+	}
+
+	// service
+	policy, err := h.serviceHandler.SetGcPolicy(
+		image.ServiceGcPolicyModel{
+			Enabled:       req.Enabled,
+			HighWatermark: req.HighWatermark,
+			LowWatermark:  req.LowWatermark,
+		},
+	)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "set gc policy failed: "+err.Error(), nil)
+		return
+	}
+
+	// encode response
+	apimodel.RespondSuccess(w, http.StatusOK, "set gc policy completed", ImageGcPolicyResponse{
+		Enabled:       policy.Enabled,
+		HighWatermark: policy.HighWatermark,
+		LowWatermark:  policy.LowWatermark,
+	})
+}
+
+// GetImageReferencePolicy godoc
+// @Summary get image reference policy
+// @Description get whether tag-only image references are rejected
+// @Tags image
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/policy [get]
+func (h *RequestHandler) GetImageReferencePolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.serviceHandler.GetReferencePolicy()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve reference policy failed: "+err.Error(), nil)
+		return
+	}
+
+	apimodel.RespondSuccess(w, http.StatusOK, "retrieve reference policy success", ImageReferencePolicyResponse{
+		RequireDigest: policy.RequireDigest,
+	})
+}
+
+// SetImageReferencePolicy godoc
+// @Summary set image reference policy
+// @Description when requireDigest is set, pull/create/pod/bottle reject images not pinned by @sha256 digest
+// @Tags image
+// @Accept json
+// @Produce json
+// @Param request body ImageReferencePolicyRequest true "Reference Policy"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/policy [post]
+func (h *RequestHandler) SetImageReferencePolicy(w http.ResponseWriter, r *http.Request) {
+	// decode request
+	var req ImageReferencePolicyRequest
+	if err := apimodel.DecodeRequestBody(r, &req); err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "invalid json: "+err.Error(), nil)
+		return
+	}
+	logger.PutExtra(r.Context(), "require_digest", req.RequireDigest)
+
+	// service
+	policy, err := h.serviceHandler.SetReferencePolicy(
+		image.ServiceReferencePolicyModel{
+			RequireDigest: req.RequireDigest,
+		},
+	)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "set reference policy failed: "+err.Error(), nil)
+		return
+	}
+
+	// encode response
+	apimodel.RespondSuccess(w, http.StatusOK, "set reference policy completed", ImageReferencePolicyResponse{
+		RequireDigest: policy.RequireDigest,
+	})
+}
+
+// TagImage godoc
+// @Summary tag image
+// @Description alias an existing image under a new repository:tag
+// @Tags image
+// @Accept json
+// @Produce json
+// @Param request body TagImageRequest true "Source and Target Image"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/tag [post]
+func (h *RequestHandler) TagImage(w http.ResponseWriter, r *http.Request) {
+	// decode request
+	var req TagImageRequest
+	if err := apimodel.DecodeRequestBody(r, &req); err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "invalid json: "+err.Error(), nil)
+		return
+	}
+	if req.Source == "" || req.Target == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "source and target are required", nil)
+		return
+	}
+
+	// service
+	result, err := h.serviceHandler.Tag(
+		image.ServiceTagModel{
+			SourceImage: req.Source,
+			TargetImage: req.Target,
+		},
+	)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "tag failed: "+err.Error(), nil)
+		return
+	}
+
+	// encode response
+	apimodel.RespondSuccess(w, http.StatusOK, "tag completed", TagImageResponse{Image: result})
+}
+
 // BuildImage godoc
 // @Summary build image
-// @Description build image from Dripfile-like archive (tar)
+// @Description build image from a tar of the build context, paths matched by .dripignore are left out.
+// @Description with stream=true step headers and RUN output are streamed as NDJSON.
+// @Description secrets for RUN --mount=type=secret are passed as X-Build-Secret headers, "id=<base64 value>"
 // @Tags image
 // @Accept application/x-tar
 // @Produce json
 // @Param tag query string true "Target image tag (e.g. myapp:latest)"
 // @Param dripfile query string false "Dripfile path in context (default: Dripfile)"
 // @Param network query string false "Bridge interface (default: raind0)"
+// @Param target query string false "Stage to build (default: last stage)"
+// @Param platform query string false "Platform base images are used in (os/arch[/variant]), FROM --platform overrides it"
+// @Param buildArg query []string false "Build argument KEY=VALUE, repeatable" collectionFormat(multi)
+// @Param X-Build-Secret header string false "Build secret id=<base64 value>, repeatable"
+// @Param noCache query bool false "Run every step without using the build cache"
+// @Param hermetic query bool false "Run RUN steps without network and normalize file timestamps"
+// @Param sourceDateEpoch query int false "Unix time stamped on files and config of a hermetic build (default: 0)"
+// @Param stream query bool false "stream build output events"
 // @Success 200 {object} apimodel.ApiResponse
 // @Router /v1/images/build [post]
 func (h *RequestHandler) BuildImage(w http.ResponseWriter, r *http.Request) {
@@ -103,42 +418,194 @@ func (h *RequestHandler) BuildImage(w http.ResponseWriter, r *http.Request) {
 	if dripfile == "" {
 		dripfile = r.URL.Query().Get("dockerfile")
 	}
-	if dripfile == "" {
-		dripfile = "Dripfile"
-	}
 	network := r.URL.Query().Get("network")
+	target := r.URL.Query().Get("target")
+	platform := r.URL.Query().Get("platform")
+	if platform != "" {
+		if _, err := utils.ParsePlatform(platform); err != nil {
+			apimodel.RespondFail(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+	noCache := r.URL.Query().Get("noCache") == "true"
+	hermetic := r.URL.Query().Get("hermetic") == "true"
+	var sourceDateEpoch int64
+	if v := r.URL.Query().Get("sourceDateEpoch"); v != "" {
+		epoch, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || epoch < 0 {
+			apimodel.RespondFail(w, http.StatusBadRequest, "invalid sourceDateEpoch: "+v, nil)
+			return
+		}
+		sourceDateEpoch = epoch
+	}
+	buildArgs := map[string]string{}
+	for _, kv := range r.URL.Query()["buildArg"] {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			apimodel.RespondFail(w, http.StatusBadRequest, "invalid buildArg, expected KEY=VALUE: "+kv, nil)
+			return
+		}
+		buildArgs[k] = v
+	}
+	// secrets come in headers, query strings end up in logs
+	secrets := map[string][]byte{}
+	for _, kv := range r.Header.Values("X-Build-Secret") {
+		id, encoded, ok := strings.Cut(kv, "=")
+		if !ok || id == "" {
+			apimodel.RespondFail(w, http.StatusBadRequest, "invalid X-Build-Secret, expected id=<base64 value>", nil)
+			return
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			apimodel.RespondFail(w, http.StatusBadRequest, "invalid X-Build-Secret value for "+id+": not base64", nil)
+			return
+		}
+		secrets[id] = value
+	}
+
+	// set log: target
+	logger.SetTarget(r.Context(), logger.Target{
+		ImageRef: tag,
+	})
+
+	buildParameter := image.ServiceBuildModel{
+		Image:     tag,
+		Context:   r.Body,
+		Dripfile:  dripfile,
+		Network:   network,
+		Target:    target,
+		Platform:  platform,
+		BuildArgs: buildArgs,
+		NoCache:   noCache,
+		Secrets:   secrets,
+
+		Hermetic:        hermetic,
+		SourceDateEpoch: sourceDateEpoch,
+	}
+	stream := newEventStream(w)
+	if r.URL.Query().Get("stream") == "true" {
+		buildParameter.Progress = func(ev image.BuildProgress) {
+			stream.writeEvent(BuildProgressEvent{
+				Type:    ev.Type,
+				Step:    ev.Step,
+				Total:   ev.Total,
+				Message: ev.Message,
+			})
+		}
+	}
 
-	tmpDir, err := os.MkdirTemp("", "raind-build-context-")
+	// service
+	result, err := h.serviceHandler.Build(buildParameter)
 	if err != nil {
-		apimodel.RespondFail(w, http.StatusInternalServerError, "create temp dir failed: "+err.Error(), nil)
+		logger.SetReason(r.Context(), err.Error())
+		if stream.started() {
+			stream.writeEvent(BuildProgressEvent{Type: "error", Error: "build failed: " + err.Error()})
+			return
+		}
+		if errors.Is(err, image.ErrInvalidBuildContext) {
+			apimodel.RespondFail(w, http.StatusBadRequest, "build failed: "+err.Error(), nil)
+			return
+		}
+		apimodel.RespondFail(w, http.StatusInternalServerError, "build failed: "+err.Error(), nil)
 		return
 	}
-	defer os.RemoveAll(tmpDir)
 
-	if err := image.ExtractTarToDir(r.Body, tmpDir); err != nil {
-		apimodel.RespondFail(w, http.StatusBadRequest, "invalid build context: "+err.Error(), nil)
+	if stream.started() {
+		stream.writeEvent(BuildProgressEvent{Type: "success", Message: "build completed", Image: result})
 		return
 	}
 
-	dfPath := filepath.Join(tmpDir, filepath.Clean(dripfile))
-	rel, err := filepath.Rel(tmpDir, dfPath)
-	if err != nil || strings.HasPrefix(rel, "..") {
-		apimodel.RespondFail(w, http.StatusBadRequest, "invalid dripfile path", nil)
+	apimodel.RespondSuccess(w, http.StatusOK, "build completed", BuildImageResponse{Image: result})
+}
+
+// GetBuildCacheList godoc
+// @Summary get build cache list
+// @Description list the cached snapshots of build steps, most recently used first
+// @Tags image
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/build/cache [get]
+func (h *RequestHandler) GetBuildCacheList(w http.ResponseWriter, r *http.Request) {
+	// service
+	list, err := h.serviceHandler.GetBuildCacheList()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve build cache failed: "+err.Error(), nil)
 		return
 	}
 
-	result, err := h.serviceHandler.Build(image.ServiceBuildModel{
-		Image:        tag,
-		ContextDir:   tmpDir,
-		DripfilePath: dfPath,
-		Network:      network,
-	})
+	// encode response
+	resp := make([]BuildCacheEntry, 0, len(list))
+	for _, e := range list {
+		resp = append(resp, BuildCacheEntry{
+			Key:        e.Key,
+			Parent:     e.Parent,
+			CreatedBy:  e.CreatedBy,
+			SizeBytes:  e.SizeBytes,
+			UsageCount: e.UsageCount,
+			CreatedAt:  e.CreatedAt,
+			LastUsedAt: e.LastUsedAt,
+		})
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "retrieve build cache success", resp)
+}
+
+// PruneBuildCache godoc
+// @Summary prune build cache
+// @Description remove cached build snapshots unused for a given age (default 168h), or all of them
+// @Tags image
+// @Accept json
+// @Produce json
+// @Param request body PruneBuildCacheRequest false "Prune Filter"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/build/cache/prune [post]
+func (h *RequestHandler) PruneBuildCache(w http.ResponseWriter, r *http.Request) {
+	// decode request
+	var req PruneBuildCacheRequest
+	if r.ContentLength != 0 {
+		if err := apimodel.DecodeRequestBody(r, &req); err != nil {
+			apimodel.RespondFail(w, http.StatusBadRequest, "invalid json: "+err.Error(), nil)
+			return
+		}
+	}
+
+	var olderThan time.Duration
+	if req.OlderThan != "" {
+		d, err := time.ParseDuration(req.OlderThan)
+		if err != nil {
+			apimodel.RespondFail(w, http.StatusBadRequest, "invalid olderThan: "+err.Error(), nil)
+			return
+		}
+		olderThan = d
+	}
+	logger.PutExtra(r.Context(), "all", req.All)
+
+	// service
+	result, err := h.serviceHandler.PruneBuildCache(
+		image.ServiceBuildCachePruneModel{
+			All:       req.All,
+			OlderThan: olderThan,
+		},
+	)
 	if err != nil {
-		apimodel.RespondFail(w, http.StatusInternalServerError, "build failed: "+err.Error(), nil)
+		apimodel.RespondFail(w, http.StatusInternalServerError, "prune build cache failed: "+err.Error(), nil)
 		return
 	}
+	logger.PutExtra(r.Context(), "removed", len(result.Removed))
+	logger.PutExtra(r.Context(), "reclaimed_bytes", result.ReclaimedBytes)
 
-	apimodel.RespondSuccess(w, http.StatusOK, "build completed", BuildImageResponse{Image: result})
+	// encode response
+	resp := PruneBuildCacheResponse{
+		Removed:        make([]PrunedBuildCache, 0, len(result.Removed)),
+		ReclaimedBytes: result.ReclaimedBytes,
+	}
+	for _, p := range result.Removed {
+		resp.Removed = append(resp.Removed, PrunedBuildCache{
+			Key:            p.Key,
+			CreatedBy:      p.CreatedBy,
+			ReclaimedBytes: p.ReclaimedBytes,
+		})
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "prune build cache completed", resp)
 }
 
 // GetImageList godoc
@@ -218,3 +685,184 @@ func (h *RequestHandler) GetImageFsInfo(w http.ResponseWriter, r *http.Request)
 		UsedBytes: info.UsedBytes,
 	})
 }
+
+// InspectImage godoc
+// @Summary inspect image
+// @Description get image manifest, config and layer details
+// @Tags image
+// @Produce json
+// @Param image query string true "Target Image"
+// @Param platform query string false "Platform to inspect (os/arch[/variant]), the first stored one by default"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/inspect [get]
+func (h *RequestHandler) InspectImage(w http.ResponseWriter, r *http.Request) {
+	imageStr := r.URL.Query().Get("image")
+	if imageStr == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing image query", nil)
+		return
+	}
+
+	info, err := h.serviceHandler.InspectImage(image.ServiceInspectModel{
+		Image:    imageStr,
+		Platform: r.URL.Query().Get("platform"),
+	})
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "inspect image failed: "+err.Error(), nil)
+		return
+	}
+
+	layers := make([]ImageLayer, 0, len(info.Layers))
+	for _, l := range info.Layers {
+		layers = append(layers, ImageLayer{
+			MediaType: l.MediaType,
+			Digest:    l.Digest,
+			SizeBytes: l.SizeBytes,
+		})
+	}
+
+	apimodel.RespondSuccess(w, http.StatusOK, "inspect image success", ImageInspectResponse{
+		Repository:     info.Repository,
+		Reference:      info.Reference,
+		ManifestDigest: info.ManifestDigest,
+		ConfigDigest:   info.ConfigDigest,
+		Layers:         layers,
+		Platform: ImagePlatform{
+			Os:           info.Platform.Os,
+			Architecture: info.Platform.Architecture,
+			Variant:      info.Platform.Variant,
+		},
+		Platforms:    info.Platforms,
+		Env:          info.Env,
+		Entrypoint:   info.Entrypoint,
+		Cmd:          info.Cmd,
+		WorkingDir:   info.WorkingDir,
+		User:         info.User,
+		Labels:       info.Labels,
+		ExposedPorts: info.ExposedPorts,
+		Volumes:      info.Volumes,
+		Healthcheck:  healthcheckResponse(info.Healthcheck),
+		Shell:        info.Shell,
+		StopSignal:   info.StopSignal,
+		SizeBytes:    info.SizeBytes,
+		CreatedAt:    info.CreatedAt,
+	})
+}
+
+func healthcheckResponse(hc *image.ImageHealthConfig) *ImageHealthcheck {
+	if hc == nil {
+		return nil
+	}
+	res := &ImageHealthcheck{Test: hc.Test, Retries: hc.Retries}
+	if hc.Interval > 0 {
+		res.Interval = hc.Interval.String()
+	}
+	if hc.Timeout > 0 {
+		res.Timeout = hc.Timeout.String()
+	}
+	if hc.StartPeriod > 0 {
+		res.StartPeriod = hc.StartPeriod.String()
+	}
+	if hc.StartInterval > 0 {
+		res.StartInterval = hc.StartInterval.String()
+	}
+	return res
+}
+
+// GetImageHistory godoc
+// @Summary get image history
+// @Description get image history built from the image config
+// @Tags image
+// @Produce json
+// @Param image query string true "Target Image"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/history [get]
+func (h *RequestHandler) GetImageHistory(w http.ResponseWriter, r *http.Request) {
+	imageStr := r.URL.Query().Get("image")
+	if imageStr == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing image query", nil)
+		return
+	}
+
+	history, err := h.serviceHandler.GetImageHistory(imageStr)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve image history failed: "+err.Error(), nil)
+		return
+	}
+
+	apimodel.RespondSuccess(w, http.StatusOK, "retrieve image history success", history)
+}
+
+// GetImageSbom godoc
+// @Summary get image sbom
+// @Description list the packages installed in an image as an SPDX or CycloneDX document
+// @Tags image
+// @Produce json
+// @Param image query string true "Target Image"
+// @Param format query string false "spdx (default) | cyclonedx"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/sbom [get]
+func (h *RequestHandler) GetImageSbom(w http.ResponseWriter, r *http.Request) {
+	imageStr := r.URL.Query().Get("image")
+	if imageStr == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing image query", nil)
+		return
+	}
+	format := r.URL.Query().Get("format")
+
+	// set log: target
+	logger.SetTarget(r.Context(), logger.Target{
+		ImageRef: imageStr,
+	})
+
+	doc, err := h.serviceHandler.GenerateSbom(
+		image.ServiceSbomModel{
+			Image:  imageStr,
+			Format: format,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, image.ErrUnsupportedSbomFormat) {
+			apimodel.RespondFail(w, http.StatusBadRequest, "generate sbom failed: "+err.Error(), nil)
+			return
+		}
+		logger.SetReason(r.Context(), err.Error())
+		apimodel.RespondFail(w, http.StatusInternalServerError, "generate sbom failed: "+err.Error(), nil)
+		return
+	}
+
+	apimodel.RespondSuccess(w, http.StatusOK, "generate sbom success", json.RawMessage(doc))
+}
+
+// GetImageProvenance godoc
+// @Summary get image provenance
+// @Description get the SLSA provenance recorded when the image was built
+// @Tags image
+// @Produce json
+// @Param image query string true "Target Image"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/images/provenance [get]
+func (h *RequestHandler) GetImageProvenance(w http.ResponseWriter, r *http.Request) {
+	imageStr := r.URL.Query().Get("image")
+	if imageStr == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing image query", nil)
+		return
+	}
+
+	// set log: target
+	logger.SetTarget(r.Context(), logger.Target{
+		ImageRef: imageStr,
+	})
+
+	doc, err := h.serviceHandler.GetImageProvenance(imageStr)
+	if err != nil {
+		if errors.Is(err, image.ErrProvenanceNotFound) {
+			apimodel.RespondFail(w, http.StatusNotFound, "get provenance failed: "+err.Error(), nil)
+			return
+		}
+		logger.SetReason(r.Context(), err.Error())
+		apimodel.RespondFail(w, http.StatusInternalServerError, "get provenance failed: "+err.Error(), nil)
+		return
+	}
+
+	apimodel.RespondSuccess(w, http.StatusOK, "get provenance success", json.RawMessage(doc))
+}
@@ -3,15 +3,118 @@ package image
 import "time"
 
 type PullImageRequest struct {
-	Image string `json:"image" example:"alpine:latest"`
-	Os    string `json:"os" example:"linux"`
-	Arch  string `json:"arch" example:"arm64"`
+	Image                  string `json:"image" example:"alpine:latest"`            // also accepts alpine@sha256:...
+	Platform               string `json:"platform,omitempty" example:"linux/arm64"` // os/arch[/variant], takes precedence over os and arch
+	Os                     string `json:"os" example:"linux"`
+	Arch                   string `json:"arch" example:"arm64"`
+	MaxConcurrentDownloads int    `json:"maxConcurrentDownloads,omitempty" example:"3"`
+}
+
+// PullProgressEvent is one NDJSON line of a streamed pull.
+type PullProgressEvent struct {
+	Id      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BuildProgressEvent is one NDJSON line of a streamed build.
+type BuildProgressEvent struct {
+	Type    string `json:"type"` // step | cached | output | success | error
+	Step    int    `json:"step,omitempty"`
+	Total   int    `json:"total,omitempty"`
+	Message string `json:"message,omitempty"`
+	Image   string `json:"image,omitempty"`
+	Error   string `json:"error,omitempty"`
 }
 
 type RemoveImageRequest struct {
 	Image string `json:"image" example:"alpine:latest"`
 }
 
+// == tag ==
+type TagImageRequest struct {
+	Source string `json:"source" example:"alpine:latest"`
+	Target string `json:"target" example:"myalpine:v1"`
+}
+
+type TagImageResponse struct {
+	Image string `json:"image"`
+}
+
+// == prune ==
+type PruneImageRequest struct {
+	Mode      string `json:"mode" example:"dangling"` // dangling | unused | age
+	OlderThan string `json:"olderThan" example:"168h"`
+}
+
+type PruneImageResponse struct {
+	Removed        []PrunedImage  `json:"removed"`
+	Skipped        []SkippedImage `json:"skipped"`
+	ReclaimedBytes int64          `json:"reclaimedBytes"`
+}
+
+type PruneBuildCacheRequest struct {
+	All       bool   `json:"all"`
+	OlderThan string `json:"olderThan" example:"168h"`
+}
+
+type PruneBuildCacheResponse struct {
+	Removed        []PrunedBuildCache `json:"removed"`
+	ReclaimedBytes int64              `json:"reclaimedBytes"`
+}
+
+type PrunedBuildCache struct {
+	Key            string `json:"key"`
+	CreatedBy      string `json:"createdBy,omitempty"`
+	ReclaimedBytes int64  `json:"reclaimedBytes"`
+}
+
+type BuildCacheEntry struct {
+	Key        string    `json:"key"`
+	Parent     string    `json:"parent"`
+	CreatedBy  string    `json:"createdBy"`
+	SizeBytes  int64     `json:"sizeBytes"`
+	UsageCount int       `json:"usageCount"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+type PrunedImage struct {
+	Image          string `json:"image"`
+	BundlePath     string `json:"bundlePath"`
+	ReclaimedBytes int64  `json:"reclaimedBytes"`
+}
+
+type SkippedImage struct {
+	Image  string `json:"image"`
+	Reason string `json:"reason"`
+}
+
+// == gc ==
+type ImageGcPolicyRequest struct {
+	Enabled       bool `json:"enabled" example:"true"`
+	HighWatermark int  `json:"highWatermark" example:"85"`
+	LowWatermark  int  `json:"lowWatermark" example:"75"`
+}
+
+type ImageGcPolicyResponse struct {
+	Enabled       bool `json:"enabled"`
+	HighWatermark int  `json:"highWatermark"`
+	LowWatermark  int  `json:"lowWatermark"`
+}
+
+// == reference policy ==
+type ImageReferencePolicyRequest struct {
+	RequireDigest bool `json:"requireDigest" example:"true"`
+}
+
+type ImageReferencePolicyResponse struct {
+	RequireDigest bool `json:"requireDigest"`
+}
+
 // == build ==
 type BuildImageResponse struct {
 	Image string `json:"image"`
@@ -34,3 +137,49 @@ type ImageFsInfoResponse struct {
 	Image     string `json:"image"`
 	UsedBytes int64  `json:"usedBytes"`
 }
+
+// == inspect ==
+type ImageInspectResponse struct {
+	Repository     string            `json:"repository"`
+	Reference      string            `json:"reference"`
+	ManifestDigest string            `json:"manifestDigest"`
+	ConfigDigest   string            `json:"configDigest"`
+	Layers         []ImageLayer      `json:"layers"`
+	Platform       ImagePlatform     `json:"platform"`
+	Platforms      []string          `json:"platforms"`
+	Env            []string          `json:"env"`
+	Entrypoint     []string          `json:"entrypoint"`
+	Cmd            []string          `json:"cmd"`
+	WorkingDir     string            `json:"workingDir"`
+	User           string            `json:"user"`
+	Labels         map[string]string `json:"labels"`
+	ExposedPorts   []string          `json:"exposedPorts"`
+	Volumes        []string          `json:"volumes"`
+	Healthcheck    *ImageHealthcheck `json:"healthcheck,omitempty"`
+	Shell          []string          `json:"shell,omitempty"`
+	StopSignal     string            `json:"stopSignal,omitempty"`
+	SizeBytes      int64             `json:"sizeBytes"`
+	CreatedAt      time.Time         `json:"createdAt"`
+}
+
+// ImageHealthcheck durations are Go duration strings such as "30s".
+type ImageHealthcheck struct {
+	Test          []string `json:"test"`
+	Interval      string   `json:"interval,omitempty"`
+	Timeout       string   `json:"timeout,omitempty"`
+	StartPeriod   string   `json:"startPeriod,omitempty"`
+	StartInterval string   `json:"startInterval,omitempty"`
+	Retries       int      `json:"retries,omitempty"`
+}
+
+type ImageLayer struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	SizeBytes int64  `json:"sizeBytes"`
+}
+
+type ImagePlatform struct {
+	Os           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
@@ -121,7 +121,19 @@ var rules = []Rule{
 	{"GET", "/v1/images", "image.list", SEV_INFO},
 	{"POST", "/v1/images", "image.pull", SEV_MEDIUM},
 	{"POST", "/v1/images/build", "image.build", SEV_HIGH},
+	{"GET", "/v1/images/build/cache", "image.build.cache.list", SEV_INFO},
+	{"POST", "/v1/images/build/cache/prune", "image.build.cache.prune", SEV_HIGH},
 	{"DELETE", "/v1/images", "image.remove", SEV_HIGH},
+	{"POST", "/v1/images/tag", "image.tag", SEV_MEDIUM},
+	{"GET", "/v1/images/inspect", "image.inspect", SEV_INFO},
+	{"GET", "/v1/images/history", "image.history", SEV_INFO},
+	{"GET", "/v1/images/sbom", "image.sbom", SEV_INFO},
+	{"GET", "/v1/images/provenance", "image.provenance", SEV_INFO},
+	{"POST", "/v1/images/prune", "image.prune", SEV_HIGH},
+	{"GET", "/v1/images/gc", "image.gc.get", SEV_INFO},
+	{"POST", "/v1/images/gc", "image.gc.set", SEV_MEDIUM},
+	{"GET", "/v1/images/policy", "image.policy.get", SEV_INFO},
+	{"POST", "/v1/images/policy", "image.policy.set", SEV_HIGH},
 
 	// policy
 	{"GET", "/v1/policies/{chain}", "policy.list", SEV_INFO},
@@ -133,6 +145,17 @@ var rules = []Rule{
 
 	// pki
 	{"POST", "/v1/pki/sign", "pki.sign", SEV_HIGH},
+
+	// trust
+	{"GET", "/v1/trust/keys", "trust.key.list", SEV_INFO},
+	{"POST", "/v1/trust/keys", "trust.key.add", SEV_HIGH},
+	{"DELETE", "/v1/trust/keys/{keyId}", "trust.key.remove", SEV_HIGH},
+	{"GET", "/v1/trust/policies", "trust.policy.list", SEV_INFO},
+	{"POST", "/v1/trust/policies", "trust.policy.add", SEV_HIGH},
+	{"DELETE", "/v1/trust/policies/{policyId}", "trust.policy.remove", SEV_CRITICAL},
+
+	// system
+	{"GET", "/v1/system/df", "system.df", SEV_INFO},
 }
 
 var actionSeverity = map[string]int{
@@ -141,4 +164,5 @@ var actionSeverity = map[string]int{
 	"hook.poststart":       SEV_MEDIUM,
 	"hook.stopContainer":   SEV_MEDIUM,
 	"hook.poststop":        SEV_MEDIUM,
+	"trust.verify.deny":    SEV_HIGH,
 }
@@ -0,0 +1,220 @@
+package namespace
+
+import (
+	"net/http"
+
+	apimodel "condenser/internal/api/http/utils"
+	"condenser/internal/core/namespace"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func NewRequestHandler() *RequestHandler {
+	return &RequestHandler{
+		serviceHandler: namespace.NewNamespaceService(),
+	}
+}
+
+type RequestHandler struct {
+	serviceHandler namespace.NamespaceServiceHandler
+}
+
+// CreateNamespace godoc
+// @Summary create namespace
+// @Description create a namespace or replace its labels
+// @Tags namespaces
+// @Accept json
+// @Produce json
+// @Param request body CreateNamespaceRequest true "Namespace"
+// @Success 201 {object} apimodel.ApiResponse
+// @Router /v1/namespaces [post]
+func (h *RequestHandler) CreateNamespace(w http.ResponseWriter, r *http.Request) {
+	var req CreateNamespaceRequest
+	if err := apimodel.DecodeRequestBody(r, &req); err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "invalid json: "+err.Error(), CreateNamespaceResponse{})
+		return
+	}
+	result, err := h.serviceHandler.ApplyNamespace(namespace.ServiceNamespaceModel{
+		Name:   req.Name,
+		Labels: req.Labels,
+	}, false)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "create namespace failed: "+err.Error(), CreateNamespaceResponse{Name: req.Name})
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusCreated, "namespace "+result, CreateNamespaceResponse{Name: req.Name, Result: result})
+}
+
+// GetNamespaceList godoc
+// @Summary list namespaces
+// @Description list namespaces with the number of resources in them
+// @Tags namespaces
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/namespaces [get]
+func (h *RequestHandler) GetNamespaceList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.serviceHandler.GetNamespaceList()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "namespace list", list)
+}
+
+// GetNamespace godoc
+// @Summary get namespace detail
+// @Description get namespace detail with its resource quotas and limit ranges
+// @Tags namespaces
+// @Param namespace path string true "Namespace"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/namespaces/{namespace} [get]
+func (h *RequestHandler) GetNamespace(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "namespace")
+	if name == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing namespace", nil)
+		return
+	}
+	info, err := h.serviceHandler.GetNamespace(name)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusNotFound, "get failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "namespace detail", info)
+}
+
+// RemoveNamespace godoc
+// @Summary remove namespace
+// @Description remove namespace with its deployments, replicasets, pods, services, configmaps, secrets, quotas and limit ranges
+// @Tags namespaces
+// @Param namespace path string true "Namespace"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/namespaces/{namespace} [delete]
+func (h *RequestHandler) RemoveNamespace(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "namespace")
+	if name == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing namespace", RemoveNamespaceResponse{})
+		return
+	}
+	result, err := h.serviceHandler.RemoveNamespace(name)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), RemoveNamespaceResponse{Name: name})
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "namespace removed", RemoveNamespaceResponse{Name: result})
+}
+
+// GetResourceQuotaList godoc
+// @Summary list resource quotas
+// @Description list resource quotas with their usage
+// @Tags resourcequotas
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/resourcequotas [get]
+func (h *RequestHandler) GetResourceQuotaList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.serviceHandler.GetResourceQuotaList()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "resourcequota list", list)
+}
+
+// GetResourceQuotaById godoc
+// @Summary get resource quota detail
+// @Description get resource quota detail with its usage
+// @Tags resourcequotas
+// @Param resourceQuotaId path string true "ResourceQuota ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/resourcequotas/{resourceQuotaId} [get]
+func (h *RequestHandler) GetResourceQuotaById(w http.ResponseWriter, r *http.Request) {
+	resourceQuotaId := chi.URLParam(r, "resourceQuotaId")
+	if resourceQuotaId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing resourceQuotaId", nil)
+		return
+	}
+	info, err := h.serviceHandler.GetResourceQuotaById(resourceQuotaId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusNotFound, "get failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "resourcequota detail", info)
+}
+
+// RemoveResourceQuota godoc
+// @Summary remove resource quota
+// @Description remove resource quota, running pods are not affected
+// @Tags resourcequotas
+// @Param resourceQuotaId path string true "ResourceQuota ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/resourcequotas/{resourceQuotaId} [delete]
+func (h *RequestHandler) RemoveResourceQuota(w http.ResponseWriter, r *http.Request) {
+	resourceQuotaId := chi.URLParam(r, "resourceQuotaId")
+	if resourceQuotaId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing resourceQuotaId", RemoveResourceQuotaResponse{})
+		return
+	}
+	result, err := h.serviceHandler.RemoveResourceQuota(resourceQuotaId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), RemoveResourceQuotaResponse{ResourceQuotaId: resourceQuotaId})
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "resourcequota removed", RemoveResourceQuotaResponse{ResourceQuotaId: result})
+}
+
+// GetLimitRangeList godoc
+// @Summary list limit ranges
+// @Description list limit ranges
+// @Tags limitranges
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/limitranges [get]
+func (h *RequestHandler) GetLimitRangeList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.serviceHandler.GetLimitRangeList()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "limitrange list", list)
+}
+
+// GetLimitRangeById godoc
+// @Summary get limit range detail
+// @Description get limit range detail
+// @Tags limitranges
+// @Param limitRangeId path string true "LimitRange ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/limitranges/{limitRangeId} [get]
+func (h *RequestHandler) GetLimitRangeById(w http.ResponseWriter, r *http.Request) {
+	limitRangeId := chi.URLParam(r, "limitRangeId")
+	if limitRangeId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing limitRangeId", nil)
+		return
+	}
+	info, err := h.serviceHandler.GetLimitRangeById(limitRangeId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusNotFound, "get failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "limitrange detail", info)
+}
+
+// RemoveLimitRange godoc
+// @Summary remove limit range
+// @Description remove limit range, pods keep the resources it filled in
+// @Tags limitranges
+// @Param limitRangeId path string true "LimitRange ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/limitranges/{limitRangeId} [delete]
+func (h *RequestHandler) RemoveLimitRange(w http.ResponseWriter, r *http.Request) {
+	limitRangeId := chi.URLParam(r, "limitRangeId")
+	if limitRangeId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing limitRangeId", RemoveLimitRangeResponse{})
+		return
+	}
+	result, err := h.serviceHandler.RemoveLimitRange(limitRangeId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), RemoveLimitRangeResponse{LimitRangeId: limitRangeId})
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "limitrange removed", RemoveLimitRangeResponse{LimitRangeId: result})
+}
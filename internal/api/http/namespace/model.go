@@ -0,0 +1,23 @@
+package namespace
+
+type CreateNamespaceRequest struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+}
+
+type CreateNamespaceResponse struct {
+	Name   string `json:"name"`
+	Result string `json:"result"` // created, configured or unchanged
+}
+
+type RemoveNamespaceResponse struct {
+	Name string `json:"name"`
+}
+
+type RemoveResourceQuotaResponse struct {
+	ResourceQuotaId string `json:"resourceQuotaId"`
+}
+
+type RemoveLimitRangeResponse struct {
+	LimitRangeId string `json:"limitRangeId"`
+}
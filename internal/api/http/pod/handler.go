@@ -2,13 +2,17 @@ package pod
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
+	"condenser/internal/api/http/logger"
+	trustHandler "condenser/internal/api/http/trust"
 	apimodel "condenser/internal/api/http/utils"
-	"condenser/internal/core/container"
+	"condenser/internal/core/config"
+	"condenser/internal/core/namespace"
 	"condenser/internal/core/pod"
 	coreService "condenser/internal/core/service"
 	"condenser/internal/store/psm"
@@ -21,18 +25,22 @@ import (
 
 func NewRequestHandler() *RequestHandler {
 	return &RequestHandler{
-		serviceHandler:   pod.NewPodService(),
-		containerHandler: container.NewContaierService(),
-		psmHandler:       psm.NewPsmManager(psm.NewPsmStore(utils.PsmStorePath)),
-		ssmHandler:       ssm.NewSsmManager(ssm.NewSsmStore(utils.SsmStorePath)),
+		serviceHandler: pod.NewPodService(),
+		psmHandler:     psm.NewPsmManager(psm.NewPsmStore(utils.PsmStorePath)),
+		ssmHandler:     ssm.NewSsmManager(ssm.NewSsmStore(utils.SsmStorePath)),
+		configHandler:  config.NewConfigService(),
+
+		namespaceHandler: namespace.NewNamespaceService(),
 	}
 }
 
 type RequestHandler struct {
-	serviceHandler   pod.PodServiceHandler
-	containerHandler container.ContainerServiceHandler
-	psmHandler       psm.PsmHandler
-	ssmHandler       ssm.SsmHandler
+	serviceHandler pod.PodServiceHandler
+	psmHandler     psm.PsmHandler
+	ssmHandler     ssm.SsmHandler
+	configHandler  config.ConfigServiceHandler
+
+	namespaceHandler namespace.NamespaceServiceHandler
 }
 
 // CreatePod godoc
@@ -52,34 +60,18 @@ func (h *RequestHandler) CreatePod(w http.ResponseWriter, r *http.Request) {
 	}
 
 	podId, err := h.serviceHandler.Create(pod.ServiceCreateModel{
-		Name:        req.Name,
-		Namespace:   req.Namespace,
-		UID:         req.UID,
-		NetworkNS:   req.NetworkNS,
-		IPCNS:       req.IPCNS,
-		UTSNS:       req.UTSNS,
-		UserNS:      req.UserNS,
-		Labels:      req.Labels,
-		Annotations: req.Annotations,
-		Containers: func() []psm.ContainerTemplateSpec {
-			if len(req.Containers) == 0 {
-				return nil
-			}
-			specs := make([]psm.ContainerTemplateSpec, 0, len(req.Containers))
-			for _, c := range req.Containers {
-				specs = append(specs, psm.ContainerTemplateSpec{
-					Name:    c.Name,
-					Image:   c.Image,
-					Command: c.Command,
-					Port:    c.Port,
-					Mount:   c.Mount,
-					Env:     c.Env,
-					Network: c.Network,
-					Tty:     c.Tty,
-				})
-			}
-			return specs
-		}(),
+		Name:           req.Name,
+		Namespace:      req.Namespace,
+		UID:            req.UID,
+		NetworkNS:      req.NetworkNS,
+		IPCNS:          req.IPCNS,
+		UTSNS:          req.UTSNS,
+		UserNS:         req.UserNS,
+		Labels:         req.Labels,
+		Annotations:    req.Annotations,
+		Containers:     toContainerTemplateSpecs(req.Containers),
+		InitContainers: toContainerTemplateSpecs(req.InitContainers),
+		RestartPolicy:  req.RestartPolicy,
 	})
 	if err != nil {
 		apimodel.RespondFail(w, http.StatusInternalServerError, "create pod failed: "+err.Error(), CreatePodResponse{PodId: ""})
@@ -89,12 +81,36 @@ func (h *RequestHandler) CreatePod(w http.ResponseWriter, r *http.Request) {
 	apimodel.RespondSuccess(w, http.StatusOK, "pod created", CreatePodResponse{PodId: podId})
 }
 
+func toContainerTemplateSpecs(containers []CreatePodContainerRequest) []psm.ContainerTemplateSpec {
+	if len(containers) == 0 {
+		return nil
+	}
+	specs := make([]psm.ContainerTemplateSpec, 0, len(containers))
+	for _, c := range containers {
+		specs = append(specs, psm.ContainerTemplateSpec{
+			Name:           c.Name,
+			Image:          c.Image,
+			Command:        c.Command,
+			Port:           c.Port,
+			Mount:          c.Mount,
+			Env:            c.Env,
+			Network:        c.Network,
+			Tty:            c.Tty,
+			LivenessProbe:  c.LivenessProbe,
+			ReadinessProbe: c.ReadinessProbe,
+			StartupProbe:   c.StartupProbe,
+		})
+	}
+	return specs
+}
+
 // ApplyPodYaml godoc
 // @Summary apply pod/replicaset manifest
-// @Description apply kubectl-compatible yaml manifest
+// @Description apply kubectl-compatible yaml manifest, existing resources are updated in place
 // @Tags pods
 // @Accept text/plain
 // @Produce json
+// @Param dryRun query bool false "report what would change without storing it"
 // @Success 201 {object} apimodel.ApiResponse
 // @Router /v1/resource/apply [post]
 func (h *RequestHandler) ApplyPodYaml(w http.ResponseWriter, r *http.Request) {
@@ -103,9 +119,19 @@ func (h *RequestHandler) ApplyPodYaml(w http.ResponseWriter, r *http.Request) {
 		apimodel.RespondFail(w, http.StatusBadRequest, "invalid body: "+err.Error(), nil)
 		return
 	}
+	dryRun, err := parseDryRun(r.URL.Query().Get("dryRun"))
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
 
 	var results []ApplyPodResult
 	var serviceResults []ApplyServiceResult
+	var configMapResults []ApplyConfigMapResult
+	var secretResults []ApplySecretResult
+	var namespaceResults []ApplyNamespaceResult
+	var quotaResults []ApplyResourceQuotaResult
+	var limitRangeResults []ApplyLimitRangeResult
 
 	dec := yaml.NewDecoder(bytes.NewReader(body))
 	for {
@@ -132,6 +158,55 @@ func (h *RequestHandler) ApplyPodYaml(w http.ResponseWriter, r *http.Request) {
 		}
 
 		switch kind {
+		case "Namespace":
+			manifest, err := namespace.DecodeK8sNamespaceManifest(rawBytes)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			result, err := h.namespaceHandler.ApplyNamespace(manifest, dryRun)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "namespace apply failed: "+err.Error(), nil)
+				return
+			}
+			namespaceResults = append(namespaceResults, ApplyNamespaceResult{
+				Name:   manifest.Name,
+				Result: result,
+			})
+		case "ResourceQuota":
+			manifest, err := namespace.DecodeK8sResourceQuotaManifest(rawBytes)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			resourceQuotaId, result, err := h.namespaceHandler.ApplyResourceQuota(manifest, dryRun)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "resourcequota apply failed: "+err.Error(), nil)
+				return
+			}
+			quotaResults = append(quotaResults, ApplyResourceQuotaResult{
+				ResourceQuotaId: resourceQuotaId,
+				Name:            manifest.Name,
+				Namespace:       manifest.Namespace,
+				Result:          result,
+			})
+		case "LimitRange":
+			manifest, err := namespace.DecodeK8sLimitRangeManifest(rawBytes)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			limitRangeId, result, err := h.namespaceHandler.ApplyLimitRange(manifest, dryRun)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "limitrange apply failed: "+err.Error(), nil)
+				return
+			}
+			limitRangeResults = append(limitRangeResults, ApplyLimitRangeResult{
+				LimitRangeId: limitRangeId,
+				Name:         manifest.Name,
+				Namespace:    manifest.Namespace,
+				Result:       result,
+			})
 		case "Service":
 			manifest, err := coreService.DecodeK8sServiceManifest(rawBytes)
 			if err != nil {
@@ -142,17 +217,12 @@ func (h *RequestHandler) ApplyPodYaml(w http.ResponseWriter, r *http.Request) {
 				apimodel.RespondFail(w, http.StatusBadRequest, "name and namespace are required", nil)
 				return
 			}
-			if h.ssmHandler.IsNameAlreadyUsed(manifest.Name, manifest.Namespace) {
-				apimodel.RespondFail(w, http.StatusBadRequest, "name already used by other service", nil)
+			if err := h.namespaceHandler.CheckNamespaceActive(manifest.Namespace); err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "service apply failed: "+err.Error(), nil)
 				return
 			}
-			serviceId := utils.NewUlid()
-			if err := h.ssmHandler.StoreService(serviceId, ssm.ServiceInfo{
-				Name:      manifest.Name,
-				Namespace: manifest.Namespace,
-				Selector:  manifest.Selector,
-				Ports:     manifest.Ports,
-			}); err != nil {
+			serviceId, result, err := h.applyService(manifest, dryRun)
+			if err != nil {
 				apimodel.RespondFail(w, http.StatusInternalServerError, "service store failed: "+err.Error(), nil)
 				return
 			}
@@ -160,6 +230,78 @@ func (h *RequestHandler) ApplyPodYaml(w http.ResponseWriter, r *http.Request) {
 				ServiceId: serviceId,
 				Name:      manifest.Name,
 				Namespace: manifest.Namespace,
+				Result:    result,
+			})
+		case "ConfigMap":
+			manifest, err := config.DecodeK8sConfigMapManifest(rawBytes)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			configMapId, result, err := h.configHandler.ApplyConfigMap(manifest, dryRun)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "configmap apply failed: "+err.Error(), nil)
+				return
+			}
+			configMapResults = append(configMapResults, ApplyConfigMapResult{
+				ConfigMapId: configMapId,
+				Name:        manifest.Name,
+				Namespace:   manifest.Namespace,
+				Result:      result,
+			})
+		case "Secret":
+			manifest, err := config.DecodeK8sSecretManifest(rawBytes)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			secretId, result, err := h.configHandler.ApplySecret(manifest, dryRun)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "secret apply failed: "+err.Error(), nil)
+				return
+			}
+			secretResults = append(secretResults, ApplySecretResult{
+				SecretId:  secretId,
+				Name:      manifest.Name,
+				Namespace: manifest.Namespace,
+				Result:    result,
+			})
+		case "Deployment":
+			manifests, err := pod.DecodeK8sManifests(rawBytes)
+			if err != nil || len(manifests) == 0 {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			m := manifests[0]
+			deploymentId, result, err := h.serviceHandler.ApplyDeployment(pod.ServiceDeploymentModel{
+				Name:      m.Name,
+				Namespace: m.Namespace,
+				Replicas:  m.Replicas,
+				Selector:  m.Selector,
+				Template: psm.PodTemplateSpec{
+					Labels:         m.Labels,
+					Annotations:    m.Annotations,
+					Containers:     m.Containers,
+					InitContainers: m.InitContainers,
+					RestartPolicy:  m.RestartPolicy,
+					Volumes:        m.Volumes,
+					Overhead:       m.Overhead,
+				},
+				Strategy:             m.Strategy,
+				RevisionHistoryLimit: m.RevisionHistoryLimit,
+
+				MatchExpressions: m.MatchExpressions,
+			}, dryRun)
+			if err != nil {
+				logger.SetReason(r.Context(), err.Error())
+				apimodel.RespondFail(w, http.StatusBadRequest, "deployment apply failed: "+err.Error(), nil)
+				return
+			}
+			results = append(results, ApplyPodResult{
+				DeploymentId: deploymentId,
+				Namespace:    m.Namespace,
+				Name:         m.Name,
+				Result:       result,
 			})
 		case "Pod", "ReplicaSet":
 			manifests, err := pod.DecodeK8sManifests(rawBytes)
@@ -168,78 +310,69 @@ func (h *RequestHandler) ApplyPodYaml(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			m := manifests[0]
+			images := append(append([]psm.ContainerTemplateSpec{}, m.InitContainers...), m.Containers...)
+			if err := h.serviceHandler.CheckImagePolicy(images); err != nil {
+				logger.SetReason(r.Context(), err.Error())
+				apimodel.RespondFail(w, http.StatusForbidden, "image policy: "+err.Error(), nil)
+				return
+			}
 			if m.Kind == "ReplicaSet" {
-				templateId := utils.NewUlid()
-				if err := h.psmHandler.StorePodTemplate(templateId, psm.PodTemplateSpec{
-					Name:        m.Name,
-					Namespace:   m.Namespace,
-					Labels:      m.Labels,
-					Annotations: m.Annotations,
-					Containers:  m.Containers,
-				}); err != nil {
-					apimodel.RespondFail(w, http.StatusInternalServerError, "template store failed: "+err.Error(), nil)
-					return
-				}
-				replicaSetId := utils.NewUlid()
-				if err := h.psmHandler.StoreReplicaSet(replicaSetId, psm.ReplicaSetSpec{
-					Name:       m.Name,
-					Namespace:  m.Namespace,
-					Replicas:   m.Replicas,
-					TemplateId: templateId,
-					Selector:   m.Selector,
-				}); err != nil {
-					apimodel.RespondFail(w, http.StatusInternalServerError, "replicaset store failed: "+err.Error(), nil)
+				replicaSetId, result, err := h.serviceHandler.ApplyReplicaSet(pod.ServiceReplicaSetModel{
+					Name:      m.Name,
+					Namespace: m.Namespace,
+					Replicas:  m.Replicas,
+					Selector:  m.Selector,
+					Template: psm.PodTemplateSpec{
+						Labels:         m.Labels,
+						Annotations:    m.Annotations,
+						Containers:     m.Containers,
+						InitContainers: m.InitContainers,
+						RestartPolicy:  m.RestartPolicy,
+						Volumes:        m.Volumes,
+						Overhead:       m.Overhead,
+					},
+
+					MatchExpressions: m.MatchExpressions,
+				}, dryRun)
+				if err != nil {
+					logger.SetReason(r.Context(), err.Error())
+					apimodel.RespondFail(w, http.StatusBadRequest, "replicaset apply failed: "+err.Error(), nil)
 					return
 				}
 				results = append(results, ApplyPodResult{
 					ReplicaSetId: replicaSetId,
 					Namespace:    m.Namespace,
 					Name:         m.Name,
+					Result:       result,
 				})
 				continue
 			}
 
-			podId, err := h.serviceHandler.Create(pod.ServiceCreateModel{
-				Name:        m.Name,
-				Namespace:   m.Namespace,
-				Labels:      m.Labels,
-				Annotations: m.Annotations,
-				Containers:  m.Containers,
-			})
+			applied, err := h.serviceHandler.ApplyPod(pod.ServiceCreateModel{
+				Name:           m.Name,
+				Namespace:      m.Namespace,
+				Labels:         m.Labels,
+				Annotations:    m.Annotations,
+				Containers:     m.Containers,
+				InitContainers: m.InitContainers,
+				RestartPolicy:  m.RestartPolicy,
+				Volumes:        m.Volumes,
+				Overhead:       m.Overhead,
+			}, dryRun)
 			if err != nil {
-				apimodel.RespondFail(w, http.StatusInternalServerError, "pod create failed: "+err.Error(), nil)
-				return
-			}
-
-			var containerIds []string
-			for _, c := range m.Containers {
-				if c.Image == "" {
-					continue
-				}
-				containerId, err := h.containerHandler.Create(container.ServiceCreateModel{
-					Image:   c.Image,
-					Command: c.Command,
-					Port:    c.Port,
-					Mount:   c.Mount,
-					Env:     c.Env,
-					Network: c.Network,
-					Tty:     c.Tty,
-					Name:    c.Name,
-					PodId:   podId,
-				})
-				if err != nil {
-					_, _ = h.serviceHandler.Remove(podId)
-					apimodel.RespondFail(w, http.StatusInternalServerError, "container create failed: "+err.Error(), nil)
+				if trustHandler.RespondVerificationError(w, r, err, "pod apply failed: ", nil) {
 					return
 				}
-				containerIds = append(containerIds, containerId)
+				apimodel.RespondFail(w, http.StatusInternalServerError, "pod apply failed: "+err.Error(), nil)
+				return
 			}
 
 			results = append(results, ApplyPodResult{
-				PodId:        podId,
+				PodId:        applied.PodId,
 				Namespace:    m.Namespace,
 				Name:         m.Name,
-				ContainerIds: containerIds,
+				ContainerIds: applied.ContainerIds,
+				Result:       applied.Result,
 			})
 		default:
 			apimodel.RespondFail(w, http.StatusBadRequest, "unsupported kind: "+kind, nil)
@@ -247,7 +380,77 @@ func (h *RequestHandler) ApplyPodYaml(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	apimodel.RespondSuccess(w, http.StatusCreated, "resources applied", ApplyPodResponse{Pods: results, Services: serviceResults})
+	status, message := http.StatusCreated, "resources applied"
+	if dryRun {
+		status, message = http.StatusOK, "resources applied (dry run)"
+	}
+	apimodel.RespondSuccess(w, status, message, ApplyPodResponse{
+		Pods:       results,
+		Services:   serviceResults,
+		ConfigMaps: configMapResults,
+		Secrets:    secretResults,
+		DryRun:     dryRun,
+
+		Namespaces:     namespaceResults,
+		ResourceQuotas: quotaResults,
+		LimitRanges:    limitRangeResults,
+	})
+}
+
+// parseDryRun accepts the boolean forms and "All" as kubectl sends it.
+func parseDryRun(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	if v == "All" {
+		return true, nil
+	}
+	dryRun, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.New("invalid dryRun: " + v)
+	}
+	return dryRun, nil
+}
+
+// applyService stores a new service or patches the labels, selector and
+// ports of the one with the same name and namespace.
+func (h *RequestHandler) applyService(manifest coreService.ServiceManifest, dryRun bool) (string, string, error) {
+	list, err := h.ssmHandler.GetServiceList()
+	if err != nil {
+		return "", "", err
+	}
+	for _, svc := range list {
+		if svc.Name != manifest.Name || svc.Namespace != manifest.Namespace {
+			continue
+		}
+		applied := ssm.ServiceInfo{Selector: manifest.Selector, Ports: manifest.Ports, MatchExpressions: manifest.MatchExpressions, Labels: manifest.Labels}
+		if !utils.SpecChanged(ssm.ServiceInfo{Selector: svc.Selector, Ports: svc.Ports, MatchExpressions: svc.MatchExpressions, Labels: svc.Labels}, applied) {
+			return svc.ServiceId, utils.ApplyResultUnchanged, nil
+		}
+		if dryRun {
+			return svc.ServiceId, utils.ApplyResultConfigured, nil
+		}
+		if err := h.ssmHandler.UpdateService(svc.ServiceId, applied); err != nil {
+			return "", "", err
+		}
+		return svc.ServiceId, utils.ApplyResultConfigured, nil
+	}
+	if dryRun {
+		return "", utils.ApplyResultCreated, nil
+	}
+	serviceId := utils.NewUlid()
+	if err := h.ssmHandler.StoreService(serviceId, ssm.ServiceInfo{
+		Name:      manifest.Name,
+		Namespace: manifest.Namespace,
+		Selector:  manifest.Selector,
+		Ports:     manifest.Ports,
+
+		MatchExpressions: manifest.MatchExpressions,
+		Labels:           manifest.Labels,
+	}); err != nil {
+		return "", "", err
+	}
+	return serviceId, utils.ApplyResultCreated, nil
 }
 
 // DeleteResourceYaml godoc
@@ -267,7 +470,13 @@ func (h *RequestHandler) DeleteResourceYaml(w http.ResponseWriter, r *http.Reque
 
 	var podResults []DeletePodResult
 	var rsResults []DeleteReplicaSetResult
+	var deployResults []DeleteDeploymentResult
 	var svcResults []DeleteServiceResult
+	var configMapResults []DeleteConfigMapResult
+	var secretResults []DeleteSecretResult
+	var namespaceResults []DeleteNamespaceResult
+	var quotaResults []DeleteResourceQuotaResult
+	var limitRangeResults []DeleteLimitRangeResult
 
 	dec := yaml.NewDecoder(bytes.NewReader(body))
 	for {
@@ -325,6 +534,175 @@ func (h *RequestHandler) DeleteResourceYaml(w http.ResponseWriter, r *http.Reque
 				apimodel.RespondFail(w, http.StatusNotFound, "service not found", nil)
 				return
 			}
+		case "ConfigMap":
+			manifest, err := config.DecodeK8sConfigMapManifest(rawBytes)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			list, err := h.configHandler.GetConfigMapList()
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
+				return
+			}
+			var removed bool
+			for _, c := range list {
+				if c.Name != manifest.Name || c.Namespace != manifest.Namespace {
+					continue
+				}
+				if _, err := h.configHandler.RemoveConfigMap(c.ConfigMapId); err != nil {
+					apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), nil)
+					return
+				}
+				configMapResults = append(configMapResults, DeleteConfigMapResult{
+					ConfigMapId: c.ConfigMapId,
+					Name:        c.Name,
+					Namespace:   c.Namespace,
+				})
+				removed = true
+			}
+			if !removed {
+				apimodel.RespondFail(w, http.StatusNotFound, "configmap not found", nil)
+				return
+			}
+		case "Secret":
+			manifest, err := config.DecodeK8sSecretManifest(rawBytes)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			list, err := h.configHandler.GetSecretList()
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
+				return
+			}
+			var removed bool
+			for _, sec := range list {
+				if sec.Name != manifest.Name || sec.Namespace != manifest.Namespace {
+					continue
+				}
+				if _, err := h.configHandler.RemoveSecret(sec.SecretId); err != nil {
+					apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), nil)
+					return
+				}
+				secretResults = append(secretResults, DeleteSecretResult{
+					SecretId:  sec.SecretId,
+					Name:      sec.Name,
+					Namespace: sec.Namespace,
+				})
+				removed = true
+			}
+			if !removed {
+				apimodel.RespondFail(w, http.StatusNotFound, "secret not found", nil)
+				return
+			}
+		case "Namespace":
+			manifest, err := namespace.DecodeK8sNamespaceManifest(rawBytes)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			if _, err := h.namespaceHandler.RemoveNamespace(manifest.Name); err != nil {
+				apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), nil)
+				return
+			}
+			namespaceResults = append(namespaceResults, DeleteNamespaceResult{
+				Name: manifest.Name,
+			})
+		case "ResourceQuota":
+			manifest, err := namespace.DecodeK8sResourceQuotaManifest(rawBytes)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			list, err := h.namespaceHandler.GetResourceQuotaList()
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
+				return
+			}
+			var removed bool
+			for _, q := range list {
+				if q.Name != manifest.Name || q.Namespace != manifest.Namespace {
+					continue
+				}
+				if _, err := h.namespaceHandler.RemoveResourceQuota(q.ResourceQuotaId); err != nil {
+					apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), nil)
+					return
+				}
+				quotaResults = append(quotaResults, DeleteResourceQuotaResult{
+					ResourceQuotaId: q.ResourceQuotaId,
+					Name:            q.Name,
+					Namespace:       q.Namespace,
+				})
+				removed = true
+			}
+			if !removed {
+				apimodel.RespondFail(w, http.StatusNotFound, "resourcequota not found", nil)
+				return
+			}
+		case "LimitRange":
+			manifest, err := namespace.DecodeK8sLimitRangeManifest(rawBytes)
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			list, err := h.namespaceHandler.GetLimitRangeList()
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
+				return
+			}
+			var removed bool
+			for _, lr := range list {
+				if lr.Name != manifest.Name || lr.Namespace != manifest.Namespace {
+					continue
+				}
+				if _, err := h.namespaceHandler.RemoveLimitRange(lr.LimitRangeId); err != nil {
+					apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), nil)
+					return
+				}
+				limitRangeResults = append(limitRangeResults, DeleteLimitRangeResult{
+					LimitRangeId: lr.LimitRangeId,
+					Name:         lr.Name,
+					Namespace:    lr.Namespace,
+				})
+				removed = true
+			}
+			if !removed {
+				apimodel.RespondFail(w, http.StatusNotFound, "limitrange not found", nil)
+				return
+			}
+		case "Deployment":
+			manifests, err := pod.DecodeK8sManifests(rawBytes)
+			if err != nil || len(manifests) == 0 {
+				apimodel.RespondFail(w, http.StatusBadRequest, "invalid yaml: "+err.Error(), nil)
+				return
+			}
+			m := manifests[0]
+			list, err := h.psmHandler.GetDeploymentList()
+			if err != nil {
+				apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
+				return
+			}
+			var removed bool
+			for _, d := range list {
+				if d.Spec.Name != m.Name || d.Spec.Namespace != m.Namespace {
+					continue
+				}
+				if _, err := h.serviceHandler.RemoveDeployment(d.DeploymentId); err != nil {
+					apimodel.RespondFail(w, http.StatusInternalServerError, "remove failed: "+err.Error(), nil)
+					return
+				}
+				deployResults = append(deployResults, DeleteDeploymentResult{
+					DeploymentId: d.DeploymentId,
+					Name:         d.Spec.Name,
+					Namespace:    d.Spec.Namespace,
+				})
+				removed = true
+			}
+			if !removed {
+				apimodel.RespondFail(w, http.StatusNotFound, "deployment not found", nil)
+				return
+			}
 		case "ReplicaSet":
 			manifests, err := pod.DecodeK8sManifests(rawBytes)
 			if err != nil || len(manifests) == 0 {
@@ -398,7 +776,14 @@ func (h *RequestHandler) DeleteResourceYaml(w http.ResponseWriter, r *http.Reque
 	apimodel.RespondSuccess(w, http.StatusOK, "resources deleted", DeleteResourcesResponse{
 		Pods:        podResults,
 		ReplicaSets: rsResults,
+		Deployments: deployResults,
 		Services:    svcResults,
+		ConfigMaps:  configMapResults,
+		Secrets:     secretResults,
+
+		Namespaces:     namespaceResults,
+		ResourceQuotas: quotaResults,
+		LimitRanges:    limitRangeResults,
 	})
 }
 
@@ -410,11 +795,13 @@ func (h *RequestHandler) removeReplicaSetById(replicaSetId string) error {
 	if err := h.psmHandler.RemoveReplicaSet(replicaSetId); err != nil {
 		return err
 	}
-	// delete pods and template (best-effort)
+	// delete pods and template (best-effort). Only the pods of its template
+	// belong to the replica set, a NotIn or DoesNotExist selector matches
+	// pods it never created.
 	pods, err := h.psmHandler.GetPodList()
 	if err == nil {
 		for _, p := range pods {
-			if p.TemplateId == rs.Spec.TemplateId || labelsMatch(rs.Spec.Selector, p.Labels, rs.Spec.Namespace, p.Namespace) {
+			if p.TemplateId == rs.Spec.TemplateId {
 				_, _ = h.serviceHandler.Remove(p.PodId)
 			}
 		}
@@ -426,53 +813,11 @@ func (h *RequestHandler) removeReplicaSetById(replicaSetId string) error {
 	return nil
 }
 
-func labelsMatch(selector, labels map[string]string, selectorNamespace, podNamespace string) bool {
-	if selectorNamespace != "" && selectorNamespace != podNamespace {
-		return false
-	}
-	if len(selector) == 0 {
+func labelsMatch(rs psm.ReplicaSetSpec, p psm.PodInfo) bool {
+	if rs.Namespace != "" && rs.Namespace != p.Namespace {
 		return false
 	}
-	for k, v := range selector {
-		if labels[k] != v {
-			return false
-		}
-	}
-	return true
-}
-
-func (h *RequestHandler) getTemplateContainerCount(templateId string) int {
-	if templateId == "" {
-		return 0
-	}
-	tmpl, err := h.psmHandler.GetPodTemplate(templateId)
-	if err != nil {
-		return 0
-	}
-	return len(tmpl.Spec.Containers)
-}
-
-func (h *RequestHandler) getPodContainerCounts(podId string, desiredFallback int) (int, int, error) {
-	containers, err := h.containerHandler.GetContainersByPodId(podId)
-	if err != nil {
-		return desiredFallback, 0, err
-	}
-	running := 0
-	nonInfra := 0
-	for _, c := range containers {
-		if strings.HasPrefix(c.Name, utils.PodInfraContainerNamePrefix) {
-			continue
-		}
-		nonInfra++
-		if c.State == "running" {
-			running++
-		}
-	}
-	desired := desiredFallback
-	if desired == 0 {
-		desired = nonInfra
-	}
-	return desired, running, nil
+	return utils.MatchWorkloadSelector(rs.Selector, rs.MatchExpressions, p.Labels)
 }
 
 // ScaleReplicaSet godoc
@@ -515,10 +860,17 @@ func (h *RequestHandler) ScaleReplicaSet(w http.ResponseWriter, r *http.Request)
 // @Description list replica sets
 // @Tags replicasets
 // @Produce json
+// @Param labelSelector query string false "label selector on the pod template labels"
+// @Param fieldSelector query string false "field selector (metadata.name, metadata.namespace)"
 // @Success 200 {object} apimodel.ApiResponse
 // @Router /v1/replicasets [get]
 func (h *RequestHandler) GetReplicaSetList(w http.ResponseWriter, r *http.Request) {
-	list, err := h.psmHandler.GetReplicaSetList()
+	opts, err := apimodel.ParseListOptions(r, pod.ReplicaSetSelectableFields)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	list, err := h.serviceHandler.GetReplicaSetList(opts)
 	if err != nil {
 		apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
 		return
@@ -530,19 +882,15 @@ func (h *RequestHandler) GetReplicaSetList(w http.ResponseWriter, r *http.Reques
 	}
 	res := make([]ReplicaSetSummary, 0, len(list))
 	for _, rs := range list {
-		templateCount := h.getTemplateContainerCount(rs.Spec.TemplateId)
 		current := 0
 		ready := 0
 		for _, p := range pods {
-			if !labelsMatch(rs.Spec.Selector, p.Labels, rs.Spec.Namespace, p.Namespace) && p.TemplateId != rs.Spec.TemplateId {
+			if !labelsMatch(rs.Spec, p) && p.TemplateId != rs.Spec.TemplateId {
 				continue
 			}
 			current++
-			desired, running, err := h.getPodContainerCounts(p.PodId, templateCount)
-			if err != nil {
-				continue
-			}
-			if desired > 0 && running == desired {
+			// readiness comes from the probe controller
+			if p.Ready {
 				ready++
 			}
 		}
@@ -557,6 +905,8 @@ func (h *RequestHandler) GetReplicaSetList(w http.ResponseWriter, r *http.Reques
 			TemplateId:   rs.Spec.TemplateId,
 			Selector:     rs.Spec.Selector,
 			CreatedAt:    rs.CreatedAt.Format(time.RFC3339),
+
+			MatchExpressions: rs.Spec.MatchExpressions,
 		})
 	}
 	apimodel.RespondSuccess(w, http.StatusOK, "replicaset list", res)
@@ -592,17 +942,12 @@ func (h *RequestHandler) GetReplicaSetById(w http.ResponseWriter, r *http.Reques
 	}
 	current := 0
 	ready := 0
-	templateCount := len(template.Spec.Containers)
 	for _, p := range pods {
-		if !labelsMatch(rs.Spec.Selector, p.Labels, rs.Spec.Namespace, p.Namespace) && p.TemplateId != rs.Spec.TemplateId {
+		if !labelsMatch(rs.Spec, p) && p.TemplateId != rs.Spec.TemplateId {
 			continue
 		}
 		current++
-		desired, running, err := h.getPodContainerCounts(p.PodId, templateCount)
-		if err != nil {
-			continue
-		}
-		if desired > 0 && running == desired {
+		if p.Ready {
 			ready++
 		}
 	}
@@ -617,6 +962,8 @@ func (h *RequestHandler) GetReplicaSetById(w http.ResponseWriter, r *http.Reques
 		Selector:     rs.Spec.Selector,
 		Template:     template.Spec,
 		CreatedAt:    rs.CreatedAt.Format(time.RFC3339),
+
+		MatchExpressions: rs.Spec.MatchExpressions,
 	})
 }
 
@@ -659,6 +1006,117 @@ func (h *RequestHandler) RemoveReplicaSet(w http.ResponseWriter, r *http.Request
 	apimodel.RespondSuccess(w, http.StatusOK, "replicaset removed", map[string]string{"replicaSetId": replicaSetId})
 }
 
+// GetDeploymentList godoc
+// @Summary list deployments
+// @Description list deployments with their rollout status
+// @Tags deployments
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/deployments [get]
+func (h *RequestHandler) GetDeploymentList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.serviceHandler.GetDeploymentList()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve deployment list failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "retrieve deployment list success", list)
+}
+
+// GetDeploymentById godoc
+// @Summary get deployment detail
+// @Description get deployment detail with its revision history
+// @Tags deployments
+// @Param deploymentId path string true "Deployment ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/deployments/{deploymentId} [get]
+func (h *RequestHandler) GetDeploymentById(w http.ResponseWriter, r *http.Request) {
+	deploymentId := chi.URLParam(r, "deploymentId")
+	if deploymentId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing deploymentId", nil)
+		return
+	}
+	d, err := h.serviceHandler.GetDeploymentById(deploymentId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusNotFound, "deployment not found: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "deployment detail", d)
+}
+
+// GetDeploymentRollout godoc
+// @Summary get deployment rollout status
+// @Description get the rollout status of the current revision
+// @Tags deployments
+// @Param deploymentId path string true "Deployment ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/deployments/{deploymentId}/rollout [get]
+func (h *RequestHandler) GetDeploymentRollout(w http.ResponseWriter, r *http.Request) {
+	deploymentId := chi.URLParam(r, "deploymentId")
+	if deploymentId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing deploymentId", nil)
+		return
+	}
+	d, err := h.serviceHandler.GetDeploymentById(deploymentId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusNotFound, "deployment not found: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, d.Rollout.Message, d.Rollout)
+}
+
+// RollbackDeployment godoc
+// @Summary rollback deployment
+// @Description roll the deployment back to an earlier revision, the previous one when revision is omitted
+// @Tags deployments
+// @Param deploymentId path string true "Deployment ID"
+// @Param revision query int false "Revision"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/deployments/{deploymentId}/actions/rollback [post]
+func (h *RequestHandler) RollbackDeployment(w http.ResponseWriter, r *http.Request) {
+	deploymentId := chi.URLParam(r, "deploymentId")
+	if deploymentId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing deploymentId", RollbackDeploymentResponse{})
+		return
+	}
+	revision := 0
+	if v := r.URL.Query().Get("revision"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			apimodel.RespondFail(w, http.StatusBadRequest, "invalid revision: "+v, RollbackDeploymentResponse{DeploymentId: deploymentId})
+			return
+		}
+		revision = n
+	}
+
+	newRevision, err := h.serviceHandler.RollbackDeployment(deploymentId, revision)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "rollback failed: "+err.Error(), RollbackDeploymentResponse{DeploymentId: deploymentId})
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "deployment rolled back", RollbackDeploymentResponse{DeploymentId: deploymentId, Revision: newRevision})
+}
+
+// RemoveDeployment godoc
+// @Summary remove deployment
+// @Description remove a deployment with its replica sets and pods
+// @Tags deployments
+// @Param deploymentId path string true "Deployment ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/deployments/{deploymentId} [delete]
+func (h *RequestHandler) RemoveDeployment(w http.ResponseWriter, r *http.Request) {
+	deploymentId := chi.URLParam(r, "deploymentId")
+	if deploymentId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing deploymentId", RemoveDeploymentResponse{})
+		return
+	}
+	result, err := h.serviceHandler.RemoveDeployment(deploymentId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "remove deployment failed: "+err.Error(), RemoveDeploymentResponse{DeploymentId: deploymentId})
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "deployment removed", RemoveDeploymentResponse{DeploymentId: result})
+}
+
 // StartPod godoc
 // @Summary start pod sandbox
 // @Description start a pod sandbox
@@ -675,6 +1133,9 @@ func (h *RequestHandler) StartPod(w http.ResponseWriter, r *http.Request) {
 
 	result, err := h.serviceHandler.Start(podId)
 	if err != nil {
+		if trustHandler.RespondVerificationError(w, r, err, "start pod failed: ", StartPodResponse{PodId: podId}) {
+			return
+		}
 		apimodel.RespondFail(w, http.StatusInternalServerError, "start pod failed: "+err.Error(), StartPodResponse{PodId: podId})
 		return
 	}
@@ -732,10 +1193,17 @@ func (h *RequestHandler) RemovePod(w http.ResponseWriter, r *http.Request) {
 // @Summary list pods
 // @Description list pod sandbox
 // @Tags pods
+// @Param labelSelector query string false "label selector (app=web,env in (a,b),!canary)"
+// @Param fieldSelector query string false "field selector (metadata.name, metadata.namespace, status.phase, spec.nodeName)"
 // @Success 200 {object} apimodel.ApiResponse
 // @Router /v1/pods [get]
 func (h *RequestHandler) GetPodList(w http.ResponseWriter, r *http.Request) {
-	podList, err := h.serviceHandler.GetPodList()
+	opts, err := apimodel.ParseListOptions(r, pod.PodSelectableFields)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	podList, err := h.serviceHandler.GetPodList(opts)
 	if err != nil {
 		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve pod list failed: "+err.Error(), nil)
 		return
@@ -765,3 +1233,42 @@ func (h *RequestHandler) GetPodById(w http.ResponseWriter, r *http.Request) {
 
 	apimodel.RespondSuccess(w, http.StatusOK, "retrieve pod success", podInfo)
 }
+
+// GetPodStats godoc
+// @Summary get pod stats
+// @Description get pod cgroup totals and member container stats
+// @Tags pods
+// @Param podId path string true "Pod ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/pods/{podId}/stats [get]
+func (h *RequestHandler) GetPodStats(w http.ResponseWriter, r *http.Request) {
+	podId := chi.URLParam(r, "podId")
+	if podId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing podId", nil)
+		return
+	}
+
+	stats, err := h.serviceHandler.GetPodStats(podId)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve pod stats failed: "+err.Error(), nil)
+		return
+	}
+
+	apimodel.RespondSuccess(w, http.StatusOK, "retrieve pod stats success", stats)
+}
+
+// ListPodStats godoc
+// @Summary list pod stats
+// @Description list pod cgroup totals and member container stats
+// @Tags pods
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/pods/stats [get]
+func (h *RequestHandler) ListPodStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.serviceHandler.ListPodStats()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve pod stats failed: "+err.Error(), nil)
+		return
+	}
+
+	apimodel.RespondSuccess(w, http.StatusOK, "retrieve pod stats success", stats)
+}
@@ -1,6 +1,9 @@
 package pod
 
-import "condenser/internal/store/psm"
+import (
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+)
 
 type CreatePodRequest struct {
 	Name        string                      `json:"name"`
@@ -13,6 +16,9 @@ type CreatePodRequest struct {
 	Labels      map[string]string           `json:"labels"`
 	Annotations map[string]string           `json:"annotations"`
 	Containers  []CreatePodContainerRequest `json:"containers"`
+
+	InitContainers []CreatePodContainerRequest `json:"initContainers"`
+	RestartPolicy  string                      `json:"restartPolicy"`
 }
 
 type CreatePodContainerRequest struct {
@@ -24,6 +30,10 @@ type CreatePodContainerRequest struct {
 	Env     []string `json:"env"`
 	Network string   `json:"network"`
 	Tty     bool     `json:"tty"`
+
+	LivenessProbe  *psm.ProbeSpec `json:"livenessProbe"`
+	ReadinessProbe *psm.ProbeSpec `json:"readinessProbe"`
+	StartupProbe   *psm.ProbeSpec `json:"startupProbe"`
 }
 
 type CreatePodResponse struct {
@@ -33,26 +43,80 @@ type CreatePodResponse struct {
 type ApplyPodResponse struct {
 	Pods     []ApplyPodResult     `json:"pods"`
 	Services []ApplyServiceResult `json:"services"`
+
+	ConfigMaps []ApplyConfigMapResult `json:"configmaps,omitempty"`
+	Secrets    []ApplySecretResult    `json:"secrets,omitempty"`
+
+	DryRun bool `json:"dryRun,omitempty"` // nothing was stored
+
+	Namespaces     []ApplyNamespaceResult     `json:"namespaces,omitempty"`
+	ResourceQuotas []ApplyResourceQuotaResult `json:"resourcequotas,omitempty"`
+	LimitRanges    []ApplyLimitRangeResult    `json:"limitranges,omitempty"`
 }
 
 type ApplyPodResult struct {
 	PodId        string   `json:"podId"`
 	ReplicaSetId string   `json:"replicaSetId,omitempty"`
+	DeploymentId string   `json:"deploymentId,omitempty"`
 	Name         string   `json:"name"`
 	Namespace    string   `json:"namespace"`
 	ContainerIds []string `json:"containerIds"`
+
+	Result string `json:"result"` // created, configured or unchanged
 }
 
 type ApplyServiceResult struct {
 	ServiceId string `json:"serviceId"`
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
+	Result    string `json:"result"`
+}
+
+type ApplyConfigMapResult struct {
+	ConfigMapId string `json:"configMapId"`
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	Result      string `json:"result"`
+}
+
+type ApplySecretResult struct {
+	SecretId  string `json:"secretId"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Result    string `json:"result"`
+}
+
+type ApplyNamespaceResult struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+}
+
+type ApplyResourceQuotaResult struct {
+	ResourceQuotaId string `json:"resourceQuotaId"`
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	Result          string `json:"result"`
+}
+
+type ApplyLimitRangeResult struct {
+	LimitRangeId string `json:"limitRangeId"`
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	Result       string `json:"result"`
 }
 
 type DeleteResourcesResponse struct {
 	Pods        []DeletePodResult        `json:"pods"`
 	ReplicaSets []DeleteReplicaSetResult `json:"replicasets"`
+	Deployments []DeleteDeploymentResult `json:"deployments"`
 	Services    []DeleteServiceResult    `json:"services"`
+
+	ConfigMaps []DeleteConfigMapResult `json:"configmaps,omitempty"`
+	Secrets    []DeleteSecretResult    `json:"secrets,omitempty"`
+
+	Namespaces     []DeleteNamespaceResult     `json:"namespaces,omitempty"`
+	ResourceQuotas []DeleteResourceQuotaResult `json:"resourcequotas,omitempty"`
+	LimitRanges    []DeleteLimitRangeResult    `json:"limitranges,omitempty"`
 }
 
 type DeletePodResult struct {
@@ -67,12 +131,46 @@ type DeleteReplicaSetResult struct {
 	Namespace    string `json:"namespace"`
 }
 
+type DeleteDeploymentResult struct {
+	DeploymentId string `json:"deploymentId"`
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+}
+
+type DeleteConfigMapResult struct {
+	ConfigMapId string `json:"configMapId"`
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+}
+
+type DeleteSecretResult struct {
+	SecretId  string `json:"secretId"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
 type DeleteServiceResult struct {
 	ServiceId string `json:"serviceId"`
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
 }
 
+type DeleteNamespaceResult struct {
+	Name string `json:"name"`
+}
+
+type DeleteResourceQuotaResult struct {
+	ResourceQuotaId string `json:"resourceQuotaId"`
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+}
+
+type DeleteLimitRangeResult struct {
+	LimitRangeId string `json:"limitRangeId"`
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+}
+
 type ScaleReplicaSetRequest struct {
 	Replicas int `json:"replicas"`
 }
@@ -93,6 +191,8 @@ type ReplicaSetSummary struct {
 	TemplateId   string            `json:"templateId"`
 	Selector     map[string]string `json:"selector,omitempty"`
 	CreatedAt    string            `json:"createdAt"`
+
+	MatchExpressions []utils.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
 }
 
 type ReplicaSetDetail struct {
@@ -106,6 +206,8 @@ type ReplicaSetDetail struct {
 	Selector     map[string]string   `json:"selector,omitempty"`
 	Template     psm.PodTemplateSpec `json:"template"`
 	CreatedAt    string              `json:"createdAt"`
+
+	MatchExpressions []utils.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
 }
 
 type StartPodResponse struct {
@@ -119,3 +221,12 @@ type StopPodResponse struct {
 type RemovePodResponse struct {
 	PodId string `json:"podId"`
 }
+
+type RollbackDeploymentResponse struct {
+	DeploymentId string `json:"deploymentId"`
+	Revision     int    `json:"revision"`
+}
+
+type RemoveDeploymentResponse struct {
+	DeploymentId string `json:"deploymentId"`
+}
@@ -9,15 +9,19 @@ import (
 
 	bottleHandler "condenser/internal/api/http/bottle"
 	certHandler "condenser/internal/api/http/cert"
+	configHandler "condenser/internal/api/http/config"
 	containerHandler "condenser/internal/api/http/container"
 	hookHandler "condenser/internal/api/http/hook"
 	imageHandler "condenser/internal/api/http/image"
 	"condenser/internal/api/http/logger"
 	logHandler "condenser/internal/api/http/logs"
+	namespaceHandler "condenser/internal/api/http/namespace"
 	networkHandler "condenser/internal/api/http/network"
 	podHandler "condenser/internal/api/http/pod"
 	policyHandler "condenser/internal/api/http/policy"
 	serviceHandler "condenser/internal/api/http/service"
+	systemHandler "condenser/internal/api/http/system"
+	trustHandler "condenser/internal/api/http/trust"
 	websocketHandler "condenser/internal/api/http/websocket"
 	"condenser/internal/utils"
 
@@ -57,7 +61,11 @@ func NewApiRouter() *chi.Mux {
 	policyHandler := policyHandler.NewRequestHandler()
 	logHandler := logHandler.NewRequestHandler()
 	podHandler := podHandler.NewRequestHandler()
+	trustHandler := trustHandler.NewRequestHandler()
 	serviceHandler := serviceHandler.NewRequestHandler()
+	systemHandler := systemHandler.NewRequestHandler()
+	configHandler := configHandler.NewRequestHandler()
+	namespaceHandler := namespaceHandler.NewRequestHandler()
 
 	// middleware
 	r.Use(middleware.RequestID)
@@ -100,7 +108,9 @@ func NewApiRouter() *chi.Mux {
 	// == pods ==
 	r.Get("/v1/pods", podHandler.GetPodList)                      // list pods
 	r.Post("/v1/pods", podHandler.CreatePod)                      // create pod sandbox
+	r.Get("/v1/pods/stats", podHandler.ListPodStats)              // list pod stats
 	r.Get("/v1/pods/{podId}", podHandler.GetPodById)              // get pod sandbox detail
+	r.Get("/v1/pods/{podId}/stats", podHandler.GetPodStats)       // get pod stats
 	r.Post("/v1/pods/{podId}/actions/start", podHandler.StartPod) // start pod sandbox
 	r.Post("/v1/pods/{podId}/actions/stop", podHandler.StopPod)   // stop pod sandbox
 	r.Delete("/v1/pods/{podId}", podHandler.RemovePod)            // remove pod sandbox
@@ -111,13 +121,48 @@ func NewApiRouter() *chi.Mux {
 	r.Post("/v1/replicasets/{replicaSetId}/actions/scale", podHandler.ScaleReplicaSet) // scale replicaset
 	r.Delete("/v1/replicasets/{replicaSetId}", podHandler.RemoveReplicaSet)            // remove replicaset
 
+	// == deployments ==
+	r.Get("/v1/deployments", podHandler.GetDeploymentList)                                   // list deployment
+	r.Get("/v1/deployments/{deploymentId}", podHandler.GetDeploymentById)                    // get deployment detail
+	r.Get("/v1/deployments/{deploymentId}/rollout", podHandler.GetDeploymentRollout)         // get rollout status
+	r.Post("/v1/deployments/{deploymentId}/actions/rollback", podHandler.RollbackDeployment) // rollback deployment
+	r.Delete("/v1/deployments/{deploymentId}", podHandler.RemoveDeployment)                  // remove deployment
+
 	// == images ==
-	r.Get("/v1/images", imageHandler.GetImageList)      // get image list
-	r.Post("/v1/images", imageHandler.PullImage)        // pull image
-	r.Post("/v1/images/build", imageHandler.BuildImage) // build image
-	r.Delete("/v1/images", imageHandler.RemoveImage)    // remove image
+	r.Get("/v1/images", imageHandler.GetImageList)                       // get image list
+	r.Post("/v1/images", imageHandler.PullImage)                         // pull image
+	r.Post("/v1/images/build", imageHandler.BuildImage)                  // build image
+	r.Get("/v1/images/build/cache", imageHandler.GetBuildCacheList)      // build cache list
+	r.Post("/v1/images/build/cache/prune", imageHandler.PruneBuildCache) // prune build cache
+	r.Delete("/v1/images", imageHandler.RemoveImage)                     // remove image
 	r.Get("/v1/images/status", imageHandler.GetImageStatus)
 	r.Get("/v1/images/fs", imageHandler.GetImageFsInfo)
+	r.Post("/v1/images/tag", imageHandler.TagImage)                   // tag image
+	r.Get("/v1/images/inspect", imageHandler.InspectImage)            // inspect image
+	r.Get("/v1/images/history", imageHandler.GetImageHistory)         // image history
+	r.Get("/v1/images/sbom", imageHandler.GetImageSbom)               // image sbom
+	r.Get("/v1/images/provenance", imageHandler.GetImageProvenance)   // image provenance
+	r.Post("/v1/images/prune", imageHandler.PruneImage)               // prune images
+	r.Get("/v1/images/gc", imageHandler.GetImageGcPolicy)             // get gc policy
+	r.Post("/v1/images/gc", imageHandler.SetImageGcPolicy)            // set gc policy
+	r.Get("/v1/images/policy", imageHandler.GetImageReferencePolicy)  // get reference policy
+	r.Post("/v1/images/policy", imageHandler.SetImageReferencePolicy) // set reference policy
+
+	// == namespaces ==
+	r.Get("/v1/namespaces", namespaceHandler.GetNamespaceList)               // list namespaces
+	r.Post("/v1/namespaces", namespaceHandler.CreateNamespace)               // create namespace
+	r.Get("/v1/namespaces/{namespace}", namespaceHandler.GetNamespace)       // get namespace detail
+	r.Delete("/v1/namespaces/{namespace}", namespaceHandler.RemoveNamespace) // remove namespace and its resources
+
+	// == resourcequotas ==
+	r.Get("/v1/resourcequotas", namespaceHandler.GetResourceQuotaList)                     // list resourcequotas with usage
+	r.Get("/v1/resourcequotas/{resourceQuotaId}", namespaceHandler.GetResourceQuotaById)   // get resourcequota detail
+	r.Delete("/v1/resourcequotas/{resourceQuotaId}", namespaceHandler.RemoveResourceQuota) // remove resourcequota
+
+	// == limitranges ==
+	r.Get("/v1/limitranges", namespaceHandler.GetLimitRangeList)                  // list limitranges
+	r.Get("/v1/limitranges/{limitRangeId}", namespaceHandler.GetLimitRangeById)   // get limitrange detail
+	r.Delete("/v1/limitranges/{limitRangeId}", namespaceHandler.RemoveLimitRange) // remove limitrange
 
 	// == services ==
 	r.Get("/v1/services", serviceHandler.GetServiceList)               // list services
@@ -125,6 +170,16 @@ func NewApiRouter() *chi.Mux {
 	r.Get("/v1/services/{serviceId}", serviceHandler.GetServiceById)   // get service detail
 	r.Delete("/v1/services/{serviceId}", serviceHandler.RemoveService) // remove service
 
+	// == configmaps ==
+	r.Get("/v1/configmaps", configHandler.GetConfigMapList)                 // list configmaps
+	r.Get("/v1/configmaps/{configMapId}", configHandler.GetConfigMapById)   // get configmap detail
+	r.Delete("/v1/configmaps/{configMapId}", configHandler.RemoveConfigMap) // remove configmap
+
+	// == secrets ==
+	r.Get("/v1/secrets", configHandler.GetSecretList)              // list secrets (key names only)
+	r.Get("/v1/secrets/{secretId}", configHandler.GetSecretById)   // get secret detail (key names only)
+	r.Delete("/v1/secrets/{secretId}", configHandler.RemoveSecret) // remove secret
+
 	// == network ==
 	r.Get("/v1/networks", networkHandler.GetNetworkList)                          // list network
 	r.Post("/v1/networks", networkHandler.CreateBridge)                           // create network
@@ -142,9 +197,20 @@ func NewApiRouter() *chi.Mux {
 	r.Post("/v1/policies/ns/mode", policyHandler.ChangeNSMode)      // change NS mode
 	r.Delete("/v1/policies/{policyId}", policyHandler.RemovePolicy) // remove policy
 
+	// == trust ==
+	r.Get("/v1/trust/keys", trustHandler.GetKeyList)                     // list public keys
+	r.Post("/v1/trust/keys", trustHandler.AddKey)                        // register public key
+	r.Delete("/v1/trust/keys/{keyId}", trustHandler.RemoveKey)           // remove public key
+	r.Get("/v1/trust/policies", trustHandler.GetPolicyList)              // list trust policies
+	r.Post("/v1/trust/policies", trustHandler.AddPolicy)                 // add trust policy
+	r.Delete("/v1/trust/policies/{policyId}", trustHandler.RemovePolicy) // remove trust policy
+
 	// == logs ==
 	r.Get("/v1/logs/netflow", logHandler.GetNetflowLog) // get netflow log
 
+	// == system ==
+	r.Get("/v1/system/df", systemHandler.GetDiskUsage) // disk usage
+
 	return r
 }
 
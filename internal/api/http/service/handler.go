@@ -7,6 +7,7 @@ import (
 
 	apimodel "condenser/internal/api/http/utils"
 	coreService "condenser/internal/core/service"
+	"condenser/internal/store/nsm"
 	"condenser/internal/store/ssm"
 	"condenser/internal/utils"
 
@@ -16,11 +17,17 @@ import (
 func NewRequestHandler() *RequestHandler {
 	return &RequestHandler{
 		ssmHandler: ssm.NewSsmManager(ssm.NewSsmStore(utils.SsmStorePath)),
+		nsmHandler: nsm.NewNsmManager(nsm.NewNsmStore(utils.NsmStorePath)),
+
+		serviceHandler: coreService.NewServiceService(),
 	}
 }
 
 type RequestHandler struct {
 	ssmHandler ssm.SsmHandler
+	nsmHandler nsm.NsmHandler
+
+	serviceHandler coreService.ServiceServiceHandler
 }
 
 // CreateService godoc
@@ -47,6 +54,10 @@ func (h *RequestHandler) CreateService(w http.ResponseWriter, r *http.Request) {
 		apimodel.RespondFail(w, http.StatusBadRequest, "name and namespace are required", CreateServiceResponse{ServiceId: ""})
 		return
 	}
+	if err := h.nsmHandler.CheckNamespaceActive(manifest.Namespace); err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, err.Error(), CreateServiceResponse{ServiceId: ""})
+		return
+	}
 	if h.ssmHandler.IsNameAlreadyUsed(manifest.Name, manifest.Namespace) {
 		apimodel.RespondFail(w, http.StatusBadRequest, "name already used by other service", CreateServiceResponse{ServiceId: ""})
 		return
@@ -58,6 +69,9 @@ func (h *RequestHandler) CreateService(w http.ResponseWriter, r *http.Request) {
 		Namespace: manifest.Namespace,
 		Selector:  manifest.Selector,
 		Ports:     manifest.Ports,
+
+		MatchExpressions: manifest.MatchExpressions,
+		Labels:           manifest.Labels,
 	}); err != nil {
 		apimodel.RespondFail(w, http.StatusInternalServerError, "store failed: "+err.Error(), CreateServiceResponse{ServiceId: ""})
 		return
@@ -71,10 +85,17 @@ func (h *RequestHandler) CreateService(w http.ResponseWriter, r *http.Request) {
 // @Description list services
 // @Tags services
 // @Produce json
+// @Param labelSelector query string false "label selector (app=web,env in (a,b),!canary)"
+// @Param fieldSelector query string false "field selector (metadata.name, metadata.namespace)"
 // @Success 200 {object} apimodel.ApiResponse
 // @Router /v1/services [get]
 func (h *RequestHandler) GetServiceList(w http.ResponseWriter, r *http.Request) {
-	list, err := h.ssmHandler.GetServiceList()
+	opts, err := apimodel.ParseListOptions(r, coreService.ServiceSelectableFields)
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	list, err := h.serviceHandler.GetServiceList(opts)
 	if err != nil {
 		apimodel.RespondFail(w, http.StatusInternalServerError, "list failed: "+err.Error(), nil)
 		return
@@ -95,6 +116,8 @@ func (h *RequestHandler) GetServiceList(w http.ResponseWriter, r *http.Request)
 			Namespace: s.Namespace,
 			Ports:     ports,
 			CreatedAt: s.CreatedAt.Format(time.RFC3339),
+
+			Labels: s.Labels,
 		})
 	}
 	apimodel.RespondSuccess(w, http.StatusOK, "service list", res)
@@ -23,4 +23,6 @@ type ServiceSummary struct {
 	Namespace string        `json:"namespace"`
 	Ports     []ServicePort `json:"ports"`
 	CreatedAt string        `json:"createdAt"`
+
+	Labels map[string]string `json:"labels,omitempty"`
 }
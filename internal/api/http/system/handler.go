@@ -0,0 +1,34 @@
+package system
+
+import (
+	apimodel "condenser/internal/api/http/utils"
+	"condenser/internal/core/system"
+	"net/http"
+)
+
+func NewRequestHandler() *RequestHandler {
+	return &RequestHandler{
+		serviceHandler: system.NewSystemService(),
+	}
+}
+
+type RequestHandler struct {
+	serviceHandler system.SystemServiceHandler
+}
+
+// GetDiskUsage godoc
+// @Summary disk usage
+// @Description disk usage of images, build cache, containers, volumes, logs and store files, with what can be reclaimed
+// @Tags system
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/system/df [get]
+func (h *RequestHandler) GetDiskUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.serviceHandler.GetDiskUsage()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve disk usage failed: "+err.Error(), nil)
+		return
+	}
+
+	apimodel.RespondSuccess(w, http.StatusOK, "retrieve disk usage success", usage)
+}
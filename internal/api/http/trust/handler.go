@@ -0,0 +1,201 @@
+package trust
+
+import (
+	"condenser/internal/api/http/logger"
+	apimodel "condenser/internal/api/http/utils"
+	"condenser/internal/core/trust"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func NewRequestHandler() *RequestHandler {
+	return &RequestHandler{
+		serviceHandler: trust.NewTrustService(),
+	}
+}
+
+type RequestHandler struct {
+	serviceHandler trust.TrustServiceHandler
+}
+
+// AddKey godoc
+// @Summary register public key
+// @Description register a cosign-compatible public key (PEM, PKIX) used to verify image signatures
+// @Tags trust
+// @Accept json
+// @Produce json
+// @Param request body AddKeyRequest true "Public Key"
+// @Success 201 {object} apimodel.ApiResponse
+// @Router /v1/trust/keys [post]
+func (h *RequestHandler) AddKey(w http.ResponseWriter, r *http.Request) {
+	// decode request
+	var req AddKeyRequest
+	if err := apimodel.DecodeRequestBody(r, &req); err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "invalid json: "+err.Error(), nil)
+		return
+	}
+	logger.PutExtra(r.Context(), "key_name", req.Name)
+
+	// service
+	keyId, err := h.serviceHandler.AddKey(trust.ServiceAddKeyModel{
+		Name: req.Name,
+		Pem:  req.Pem,
+	})
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "add key failed: "+err.Error(), nil)
+		return
+	}
+	logger.PutExtra(r.Context(), "key_id", keyId)
+
+	// encode response
+	apimodel.RespondSuccess(w, http.StatusCreated, "key registered", AddKeyResponse{KeyId: keyId})
+}
+
+// GetKeyList godoc
+// @Summary list public keys
+// @Description list registered public keys
+// @Tags trust
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/trust/keys [get]
+func (h *RequestHandler) GetKeyList(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.serviceHandler.GetKeyList()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve key list failed: "+err.Error(), nil)
+		return
+	}
+
+	resp := make([]KeyResponse, 0, len(keys))
+	for _, k := range keys {
+		resp = append(resp, KeyResponse{
+			KeyId:       k.KeyId,
+			Name:        k.Name,
+			Algorithm:   k.Algorithm,
+			Fingerprint: k.Fingerprint,
+			Pem:         k.Pem,
+			CreatedAt:   k.CreatedAt,
+		})
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "retrieve key list success", resp)
+}
+
+// RemoveKey godoc
+// @Summary remove public key
+// @Description remove a registered public key (refused while a trust policy references it)
+// @Tags trust
+// @Param keyId path string true "Key ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/trust/keys/{keyId} [delete]
+func (h *RequestHandler) RemoveKey(w http.ResponseWriter, r *http.Request) {
+	keyId := chi.URLParam(r, "keyId")
+	if keyId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing keyId", nil)
+		return
+	}
+	logger.PutExtra(r.Context(), "key_id", keyId)
+
+	if err := h.serviceHandler.RemoveKey(keyId); err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "remove key failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "key removed", nil)
+}
+
+// AddPolicy godoc
+// @Summary add trust policy
+// @Description require signed images for a registry (docker.io), repository (docker.io/library/nginx) or prefix (ghcr.io/org/*)
+// @Tags trust
+// @Accept json
+// @Produce json
+// @Param request body AddPolicyRequest true "Trust Policy"
+// @Success 201 {object} apimodel.ApiResponse
+// @Router /v1/trust/policies [post]
+func (h *RequestHandler) AddPolicy(w http.ResponseWriter, r *http.Request) {
+	// decode request
+	var req AddPolicyRequest
+	if err := apimodel.DecodeRequestBody(r, &req); err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "invalid json: "+err.Error(), nil)
+		return
+	}
+	logger.PutExtra(r.Context(), "scope", req.Scope)
+
+	// service
+	policyId, err := h.serviceHandler.AddPolicy(trust.ServiceAddPolicyModel{
+		Scope:  req.Scope,
+		KeyIds: req.KeyIds,
+	})
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "add trust policy failed: "+err.Error(), nil)
+		return
+	}
+	logger.SetTarget(r.Context(), logger.Target{PolicyId: policyId})
+
+	// encode response
+	apimodel.RespondSuccess(w, http.StatusCreated, "trust policy added", AddPolicyResponse{PolicyId: policyId})
+}
+
+// GetPolicyList godoc
+// @Summary list trust policies
+// @Description list trust policies
+// @Tags trust
+// @Produce json
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/trust/policies [get]
+func (h *RequestHandler) GetPolicyList(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.serviceHandler.GetPolicyList()
+	if err != nil {
+		apimodel.RespondFail(w, http.StatusInternalServerError, "retrieve trust policy list failed: "+err.Error(), nil)
+		return
+	}
+
+	resp := make([]PolicyResponse, 0, len(policies))
+	for _, p := range policies {
+		resp = append(resp, PolicyResponse{
+			PolicyId:  p.PolicyId,
+			Scope:     p.Scope,
+			KeyIds:    p.KeyIds,
+			CreatedAt: p.CreatedAt,
+		})
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "retrieve trust policy list success", resp)
+}
+
+// RemovePolicy godoc
+// @Summary remove trust policy
+// @Description remove a trust policy
+// @Tags trust
+// @Param policyId path string true "Policy ID"
+// @Success 200 {object} apimodel.ApiResponse
+// @Router /v1/trust/policies/{policyId} [delete]
+func (h *RequestHandler) RemovePolicy(w http.ResponseWriter, r *http.Request) {
+	policyId := chi.URLParam(r, "policyId")
+	if policyId == "" {
+		apimodel.RespondFail(w, http.StatusBadRequest, "missing policyId", nil)
+		return
+	}
+	logger.SetTarget(r.Context(), logger.Target{PolicyId: policyId})
+
+	if err := h.serviceHandler.RemovePolicy(policyId); err != nil {
+		apimodel.RespondFail(w, http.StatusBadRequest, "remove trust policy failed: "+err.Error(), nil)
+		return
+	}
+	apimodel.RespondSuccess(w, http.StatusOK, "trust policy removed", nil)
+}
+
+// RespondVerificationError answers 403 when err is a failed signature
+// verification and records it in the audit log as trust.verify.deny.
+// It reports whether it responded, any other error is left to the caller.
+func RespondVerificationError(w http.ResponseWriter, r *http.Request, err error, message string, data any) bool {
+	var verifyErr *trust.VerificationError
+	if !errors.As(err, &verifyErr) {
+		return false
+	}
+	logger.SetAction(r.Context(), "trust.verify.deny")
+	logger.SetTarget(r.Context(), logger.Target{ImageRef: verifyErr.Image})
+	logger.SetReason(r.Context(), verifyErr.Reason)
+	logger.PutExtra(r.Context(), "trust_policy_id", verifyErr.PolicyId)
+	apimodel.RespondFail(w, http.StatusForbidden, message+err.Error(), data)
+	return true
+}
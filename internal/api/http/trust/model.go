@@ -0,0 +1,39 @@
+package trust
+
+import "time"
+
+// == keys ==
+type AddKeyRequest struct {
+	Name string `json:"name" example:"release-key"`
+	Pem  string `json:"pem" example:"-----BEGIN PUBLIC KEY-----\n...\n-----END PUBLIC KEY-----"`
+}
+
+type AddKeyResponse struct {
+	KeyId string `json:"keyId"`
+}
+
+type KeyResponse struct {
+	KeyId       string    `json:"keyId"`
+	Name        string    `json:"name"`
+	Algorithm   string    `json:"algorithm"`
+	Fingerprint string    `json:"fingerprint"`
+	Pem         string    `json:"pem"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// == policies ==
+type AddPolicyRequest struct {
+	Scope  string   `json:"scope" example:"docker.io/library/nginx"`
+	KeyIds []string `json:"keyIds"`
+}
+
+type AddPolicyResponse struct {
+	PolicyId string `json:"policyId"`
+}
+
+type PolicyResponse struct {
+	PolicyId  string    `json:"policyId"`
+	Scope     string    `json:"scope"`
+	KeyIds    []string  `json:"keyIds"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"encoding/json"
 	"net/http"
+
+	"condenser/internal/utils"
 )
 
 type ApiResponse struct {
@@ -41,3 +43,10 @@ func RespondFail(w http.ResponseWriter, statusCode int, message string, data any
 		Data:    data,
 	})
 }
+
+// ParseListOptions reads the labelSelector and fieldSelector query
+// parameters of a list request.
+func ParseListOptions(r *http.Request, fields []string) (utils.ListOptions, error) {
+	q := r.URL.Query()
+	return utils.ParseListOptions(q.Get("labelSelector"), q.Get("fieldSelector"), fields)
+}
@@ -2,6 +2,7 @@ package bottle
 
 import (
 	"condenser/internal/core/container"
+	"condenser/internal/core/image"
 	"condenser/internal/core/network"
 	"condenser/internal/core/policy"
 	"condenser/internal/store/bsm"
@@ -17,6 +18,7 @@ import (
 func NewBottleService() *BottleService {
 	return &BottleService{
 		containerService: container.NewContaierService(),
+		imageService:     image.NewImageService(),
 		bsmHandler:       bsm.NewBsmManager(bsm.NewBsmStore(utils.BsmStorePath)),
 		csmHandler:       csm.NewCsmManager(csm.NewCsmStore(utils.CsmStorePath)),
 		ipamHandler:      ipam.NewIpamManager(ipam.NewIpamStore(utils.IpamStorePath)),
@@ -27,6 +29,7 @@ func NewBottleService() *BottleService {
 
 type BottleService struct {
 	containerService container.ContainerServiceHandler
+	imageService     image.ImageServiceHandler
 	bsmHandler       bsm.BsmHandler
 	csmHandler       csm.CsmHandler
 	ipamHandler      ipam.IpamHandler
@@ -107,6 +110,17 @@ func (s *BottleService) Create(bottleIdOrName string) (string, error) {
 		return "", fmt.Errorf("start order is empty")
 	}
 
+	// reject the whole bottle up front rather than leaving it half created
+	for _, serviceName := range info.StartOrder {
+		spec, ok := info.Services[serviceName]
+		if !ok || spec.Image == "" {
+			continue
+		}
+		if err := s.imageService.CheckReferencePolicy(spec.Image); err != nil {
+			return "", fmt.Errorf("service %q: %w", serviceName, err)
+		}
+	}
+
 	containers := make(map[string]string, len(info.Containers))
 	for k, v := range info.Containers {
 		containers[k] = v
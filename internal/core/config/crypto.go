@@ -0,0 +1,112 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"condenser/internal/utils"
+)
+
+// secret data is sealed with AES-256-GCM under a key kept next to the
+// daemon certificates. The key is generated on first use.
+const secretKeySize = 32
+
+func loadSecretKey() ([]byte, error) {
+	key, err := os.ReadFile(utils.SecretKeyPath)
+	if err == nil {
+		if len(key) != secretKeySize {
+			return nil, fmt.Errorf("secret key %s: unexpected size %d", utils.SecretKeyPath, len(key))
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(utils.SecretKeyPath), 0o700); err != nil {
+		return nil, err
+	}
+	key = make([]byte, secretKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(utils.SecretKeyPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		// created by a concurrent request in the meantime
+		if errors.Is(err, os.ErrExist) {
+			return loadSecretKey()
+		}
+		return nil, err
+	}
+	if _, err := f.Write(key); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return key, f.Close()
+}
+
+func newSecretAEAD() (cipher.AEAD, error) {
+	key, err := loadSecretKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealSecretData encrypts the data, the nonce is prepended to the result.
+// The secret id is bound as additional data so sealed blobs cannot be
+// swapped between secrets.
+func sealSecretData(secretId string, data map[string][]byte) (string, error) {
+	aead, err := newSecretAEAD()
+	if err != nil {
+		return "", err
+	}
+	plain, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(secretId))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func openSecretData(secretId string, encrypted string) (map[string][]byte, error) {
+	aead, err := newSecretAEAD()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", secretId, err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("secret %s: sealed data too short", secretId)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(secretId))
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: decrypt failed: %w", secretId, err)
+	}
+	var data map[string][]byte
+	if err := json.Unmarshal(plain, &data); err != nil {
+		return nil, fmt.Errorf("secret %s: %w", secretId, err)
+	}
+	return data, nil
+}
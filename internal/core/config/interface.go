@@ -0,0 +1,17 @@
+package config
+
+import "condenser/internal/store/psm"
+
+type ConfigServiceHandler interface {
+	ApplyConfigMap(configMapParameter ServiceConfigMapModel, dryRun bool) (string, string, error)
+	GetConfigMapList() ([]ConfigMapState, error)
+	GetConfigMapById(configMapId string) (ConfigMapState, error)
+	RemoveConfigMap(configMapId string) (string, error)
+	ApplySecret(secretParameter ServiceSecretModel, dryRun bool) (string, string, error)
+	GetSecretList() ([]SecretState, error)
+	GetSecretById(secretId string) (SecretState, error)
+	RemoveSecret(secretId string) (string, error)
+	ProjectVolumes(namespace string, volumes []psm.VolumeSpec) error
+	RemoveProjection(kind, namespace, name string) error
+	ResolveEnv(namespace string, spec psm.ContainerTemplateSpec) ([]string, error)
+}
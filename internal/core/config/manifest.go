@@ -0,0 +1,95 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+type manifestMeta struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+type configMapManifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   manifestMeta      `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+	BinaryData map[string]string `yaml:"binaryData"` // base64
+}
+
+type secretManifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   manifestMeta      `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"` // base64
+	StringData map[string]string `yaml:"stringData"`
+}
+
+// DecodeK8sConfigMapManifest reads a single ConfigMap document.
+func DecodeK8sConfigMapManifest(body []byte) (ServiceConfigMapModel, error) {
+	var cm configMapManifest
+	if err := yaml.Unmarshal(body, &cm); err != nil {
+		return ServiceConfigMapModel{}, err
+	}
+	if cm.Kind != "ConfigMap" {
+		return ServiceConfigMapModel{}, fmt.Errorf("unsupported kind: %s", cm.Kind)
+	}
+	if cm.Metadata.Namespace == "" {
+		cm.Metadata.Namespace = "default"
+	}
+	result := ServiceConfigMapModel{
+		Name:      cm.Metadata.Name,
+		Namespace: cm.Metadata.Namespace,
+		Labels:    cm.Metadata.Labels,
+		Data:      cm.Data,
+	}
+	for k, v := range cm.BinaryData {
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return ServiceConfigMapModel{}, fmt.Errorf("binaryData %q: %w", k, err)
+		}
+		if result.BinaryData == nil {
+			result.BinaryData = map[string][]byte{}
+		}
+		result.BinaryData[k] = b
+	}
+	return result, nil
+}
+
+// DecodeK8sSecretManifest reads a single Secret document, stringData
+// overrides data as in kubernetes.
+func DecodeK8sSecretManifest(body []byte) (ServiceSecretModel, error) {
+	var sec secretManifest
+	if err := yaml.Unmarshal(body, &sec); err != nil {
+		return ServiceSecretModel{}, err
+	}
+	if sec.Kind != "Secret" {
+		return ServiceSecretModel{}, fmt.Errorf("unsupported kind: %s", sec.Kind)
+	}
+	if sec.Metadata.Namespace == "" {
+		sec.Metadata.Namespace = "default"
+	}
+	result := ServiceSecretModel{
+		Name:      sec.Metadata.Name,
+		Namespace: sec.Metadata.Namespace,
+		Type:      sec.Type,
+		Labels:    sec.Metadata.Labels,
+		Data:      map[string][]byte{},
+	}
+	for k, v := range sec.Data {
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return ServiceSecretModel{}, fmt.Errorf("data %q: %w", k, err)
+		}
+		result.Data[k] = b
+	}
+	for k, v := range sec.StringData {
+		result.Data[k] = []byte(v)
+	}
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import "time"
+
+type ServiceConfigMapModel struct {
+	Name       string
+	Namespace  string
+	Labels     map[string]string
+	Data       map[string]string
+	BinaryData map[string][]byte
+}
+
+type ServiceSecretModel struct {
+	Name      string
+	Namespace string
+	Type      string // Opaque when empty
+	Labels    map[string]string
+	Data      map[string][]byte
+}
+
+type ConfigMapState struct {
+	ConfigMapId string            `json:"configMapId"`
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+	BinaryData  map[string][]byte `json:"binaryData,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+// SecretState never carries the values.
+type SecretState struct {
+	SecretId  string            `json:"secretId"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Type      string            `json:"type"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Keys      []string          `json:"keys"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"condenser/internal/store/nsm"
+	"condenser/internal/store/rsm"
+	"condenser/internal/utils"
+)
+
+// keys become file names in volumes, as in kubernetes
+var dataKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// kubernetes keeps config maps and secrets below 1MiB as well
+const maxDataBytes = 1 << 20
+
+func NewConfigService() *ConfigService {
+	return &ConfigService{
+		rsmHandler: rsm.NewRsmManager(rsm.NewRsmStore(utils.RsmStorePath)),
+		nsmHandler: nsm.NewNsmManager(nsm.NewNsmStore(utils.NsmStorePath)),
+	}
+}
+
+type ConfigService struct {
+	rsmHandler rsm.RsmHandler
+	nsmHandler nsm.NsmHandler
+}
+
+func validateDataKeys(keys []string, size int) error {
+	for _, k := range keys {
+		if !dataKeyPattern.MatchString(k) || k == "." || k == ".." || len(k) > 253 {
+			return fmt.Errorf("invalid key %q: alphanumerics, '-', '_' or '.' only", k)
+		}
+		// ..data and friends are taken by the volume projection
+		if len(k) >= 2 && k[:2] == ".." {
+			return fmt.Errorf("invalid key %q: must not start with '..'", k)
+		}
+	}
+	if size > maxDataBytes {
+		return fmt.Errorf("data too large: %d bytes (max %d)", size, maxDataBytes)
+	}
+	return nil
+}
+
+// == service: create or update config map ==
+// ApplyConfigMap stores the config map under its name and namespace. The
+// volumes of running pods that mount it are refreshed, an unchanged config
+// map is left alone.
+func (s *ConfigService) ApplyConfigMap(configMapParameter ServiceConfigMapModel, dryRun bool) (string, string, error) {
+	if configMapParameter.Name == "" {
+		return "", "", fmt.Errorf("configmap name is required")
+	}
+	if err := utils.ValidateDNSSubdomain(configMapParameter.Name); err != nil {
+		return "", "", fmt.Errorf("configmap: %w", err)
+	}
+	if configMapParameter.Namespace == "" {
+		configMapParameter.Namespace = "default"
+	}
+	if err := s.nsmHandler.CheckNamespaceActive(configMapParameter.Namespace); err != nil {
+		return "", "", err
+	}
+	var (
+		keys []string
+		size int
+	)
+	for k, v := range configMapParameter.Data {
+		keys = append(keys, k)
+		size += len(v)
+	}
+	for k, v := range configMapParameter.BinaryData {
+		if _, dup := configMapParameter.Data[k]; dup {
+			return "", "", fmt.Errorf("key %q is in both data and binaryData", k)
+		}
+		keys = append(keys, k)
+		size += len(v)
+	}
+	if err := validateDataKeys(keys, size); err != nil {
+		return "", "", err
+	}
+
+	info := rsm.ConfigMapInfo{
+		Name:       configMapParameter.Name,
+		Namespace:  configMapParameter.Namespace,
+		Labels:     configMapParameter.Labels,
+		Data:       configMapParameter.Data,
+		BinaryData: configMapParameter.BinaryData,
+	}
+	configMapId := utils.NewUlid()
+	result := utils.ApplyResultCreated
+	if existing, err := s.rsmHandler.GetConfigMapByName(configMapParameter.Name, configMapParameter.Namespace); err == nil {
+		configMapId = existing.ConfigMapId
+		result = utils.ApplyResultConfigured
+		if !utils.SpecChanged(configMapContent(existing), configMapContent(info)) {
+			return configMapId, utils.ApplyResultUnchanged, nil
+		}
+	}
+	if dryRun {
+		if result == utils.ApplyResultCreated {
+			configMapId = ""
+		}
+		return configMapId, result, nil
+	}
+	if err := s.rsmHandler.StoreConfigMap(configMapId, info); err != nil {
+		return "", "", err
+	}
+	if result == utils.ApplyResultConfigured {
+		if err := s.refreshProjection(ProjectionKindConfigMap, info.Namespace, info.Name, configMapFiles(info)); err != nil {
+			return configMapId, result, fmt.Errorf("configmap stored, volume refresh failed: %w", err)
+		}
+	}
+	return configMapId, result, nil
+}
+
+// configMapContent is the part of a config map an apply compares.
+func configMapContent(info rsm.ConfigMapInfo) rsm.ConfigMapInfo {
+	return rsm.ConfigMapInfo{Labels: info.Labels, Data: info.Data, BinaryData: info.BinaryData}
+}
+
+func configMapFiles(info rsm.ConfigMapInfo) map[string][]byte {
+	files := make(map[string][]byte, len(info.Data)+len(info.BinaryData))
+	for k, v := range info.Data {
+		files[k] = []byte(v)
+	}
+	for k, v := range info.BinaryData {
+		files[k] = v
+	}
+	return files
+}
+
+// == service: list config maps ==
+func (s *ConfigService) GetConfigMapList() ([]ConfigMapState, error) {
+	list, err := s.rsmHandler.GetConfigMapList()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]ConfigMapState, 0, len(list))
+	for _, c := range list {
+		result = append(result, toConfigMapState(c))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// == service: get config map ==
+func (s *ConfigService) GetConfigMapById(configMapId string) (ConfigMapState, error) {
+	info, err := s.rsmHandler.GetConfigMap(configMapId)
+	if err != nil {
+		return ConfigMapState{}, err
+	}
+	return toConfigMapState(info), nil
+}
+
+func toConfigMapState(info rsm.ConfigMapInfo) ConfigMapState {
+	return ConfigMapState{
+		ConfigMapId: info.ConfigMapId,
+		Name:        info.Name,
+		Namespace:   info.Namespace,
+		Labels:      info.Labels,
+		Data:        info.Data,
+		BinaryData:  info.BinaryData,
+		CreatedAt:   info.CreatedAt,
+		UpdatedAt:   info.UpdatedAt,
+	}
+}
+
+// == service: remove config map ==
+// The projected files go with it, pods that still mount it see an empty
+// directory.
+func (s *ConfigService) RemoveConfigMap(configMapId string) (string, error) {
+	info, err := s.rsmHandler.GetConfigMap(configMapId)
+	if err != nil {
+		return "", err
+	}
+	if err := s.rsmHandler.RemoveConfigMap(configMapId); err != nil {
+		return "", err
+	}
+	if err := s.RemoveProjection(ProjectionKindConfigMap, info.Namespace, info.Name); err != nil {
+		return configMapId, fmt.Errorf("configmap removed, projection remains: %w", err)
+	}
+	return configMapId, nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"condenser/internal/store/psm"
+)
+
+// keys that are no valid variable name are skipped by envFrom
+var envNamePattern = regexp.MustCompile(`^[-._a-zA-Z][-._a-zA-Z0-9]*$`)
+
+// == service: resolve container env ==
+// ResolveEnv expands the envFrom and valueFrom references of a container
+// with the current data. envFrom comes first, valueFrom overrides it and
+// the plain env overrides both.
+func (s *ConfigService) ResolveEnv(namespace string, spec psm.ContainerTemplateSpec) ([]string, error) {
+	if len(spec.EnvFrom) == 0 && len(spec.EnvValueFrom) == 0 {
+		return spec.Env, nil
+	}
+
+	var (
+		names  []string
+		values = map[string]string{}
+	)
+	set := func(name, value string) {
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = value
+	}
+
+	for _, src := range spec.EnvFrom {
+		data, optional, err := s.envFromData(namespace, src)
+		if err != nil {
+			if optional {
+				continue
+			}
+			return nil, err
+		}
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if name := src.Prefix + k; envNamePattern.MatchString(name) {
+				set(name, data[k])
+			}
+		}
+	}
+
+	for _, ref := range spec.EnvValueFrom {
+		value, found, err := s.envValue(namespace, ref)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", ref.Name, err)
+		}
+		if found {
+			set(ref.Name, value)
+		}
+	}
+
+	for _, e := range spec.Env {
+		name, value, _ := strings.Cut(e, "=")
+		set(name, value)
+	}
+
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		env = append(env, name+"="+values[name])
+	}
+	return env, nil
+}
+
+func (s *ConfigService) envFromData(namespace string, src psm.EnvFromSource) (map[string]string, bool, error) {
+	data := map[string]string{}
+	switch {
+	case src.ConfigMapRef != nil:
+		info, err := s.rsmHandler.GetConfigMapByName(src.ConfigMapRef.Name, namespace)
+		if err != nil {
+			return nil, src.ConfigMapRef.Optional, err
+		}
+		for k, v := range configMapFiles(info) {
+			data[k] = string(v)
+		}
+		return data, false, nil
+	case src.SecretRef != nil:
+		secret, err := s.getSecretData(src.SecretRef.Name, namespace)
+		if err != nil {
+			return nil, src.SecretRef.Optional, err
+		}
+		for k, v := range secret {
+			data[k] = string(v)
+		}
+		return data, false, nil
+	}
+	return data, false, nil
+}
+
+func (s *ConfigService) envValue(namespace string, ref psm.EnvVarSource) (string, bool, error) {
+	switch {
+	case ref.ConfigMapKeyRef != nil:
+		sel := ref.ConfigMapKeyRef
+		info, err := s.rsmHandler.GetConfigMapByName(sel.Name, namespace)
+		if err != nil {
+			if sel.Optional {
+				return "", false, nil
+			}
+			return "", false, err
+		}
+		value, ok := configMapFiles(info)[sel.Key]
+		if !ok {
+			if sel.Optional {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("key %q not found in configmap %s/%s", sel.Key, namespace, sel.Name)
+		}
+		return string(value), true, nil
+	case ref.SecretKeyRef != nil:
+		sel := ref.SecretKeyRef
+		data, err := s.getSecretData(sel.Name, namespace)
+		if err != nil {
+			if sel.Optional {
+				return "", false, nil
+			}
+			return "", false, err
+		}
+		value, ok := data[sel.Key]
+		if !ok {
+			if sel.Optional {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("key %q not found in secret %s/%s", sel.Key, namespace, sel.Name)
+		}
+		return string(value), true, nil
+	}
+	return "", false, nil
+}
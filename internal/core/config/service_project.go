@@ -0,0 +1,204 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"condenser/internal/store/psm"
+	"condenser/internal/store/rsm"
+	"condenser/internal/utils"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ProjectionKindConfigMap = "configmap"
+	ProjectionKindSecret    = "secret"
+)
+
+// the projected files are swapped all at once through this symlink, a
+// reader inside the pod never sees half of an update
+const projectionDataLink = "..data"
+
+// projections of the same source share a directory, one writer at a time
+var projectionMu sync.Mutex
+
+// ProjectedVolumePath is the host directory a configMap or secret volume
+// is bind mounted from. Pods that mount the same source share it. Paths
+// that would leave the projection root are refused.
+func ProjectedVolumePath(kind, namespace, name string) (string, error) {
+	for _, elem := range []string{kind, namespace, name} {
+		if elem == "" || elem == "." || elem == ".." || strings.ContainsRune(elem, '/') {
+			return "", fmt.Errorf("invalid projection path element %q", elem)
+		}
+	}
+	dir := filepath.Join(utils.ProjectedVolumeDir, kind, namespace, name)
+	rel, err := filepath.Rel(utils.ProjectedVolumeDir, dir)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("projection path %s is not below %s", dir, utils.ProjectedVolumeDir)
+	}
+	return dir, nil
+}
+
+// == service: project pod volumes ==
+// ProjectVolumes writes the current keys of every configMap and secret
+// volume of a pod to its directory. A missing source fails the pod unless
+// the volume is optional, then the directory stays empty.
+func (s *ConfigService) ProjectVolumes(namespace string, volumes []psm.VolumeSpec) error {
+	for _, v := range volumes {
+		var (
+			kind, name string
+			optional   bool
+			files      map[string][]byte
+			err        error
+		)
+		switch {
+		case v.ConfigMap != nil:
+			kind, name, optional = ProjectionKindConfigMap, v.ConfigMap.Name, v.ConfigMap.Optional
+			var info rsm.ConfigMapInfo
+			info, err = s.rsmHandler.GetConfigMapByName(name, namespace)
+			if err == nil {
+				files = configMapFiles(info)
+			}
+		case v.Secret != nil:
+			kind, name, optional = ProjectionKindSecret, v.Secret.SecretName, v.Secret.Optional
+			files, err = s.getSecretData(name, namespace)
+		default:
+			continue
+		}
+		if err != nil {
+			if !optional {
+				return fmt.Errorf("volume %q: %w", v.Name, err)
+			}
+			files = map[string][]byte{}
+		}
+		dir, err := ProjectedVolumePath(kind, namespace, name)
+		if err != nil {
+			return fmt.Errorf("volume %q: %w", v.Name, err)
+		}
+		if err := projectFiles(dir, files); err != nil {
+			return fmt.Errorf("volume %q: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// refreshProjection rewrites the directory of a source when some pod
+// mounts it already.
+func (s *ConfigService) refreshProjection(kind, namespace, name string, files map[string][]byte) error {
+	dir, err := ProjectedVolumePath(kind, namespace, name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return projectFiles(dir, files)
+}
+
+// == service: remove projection ==
+// RemoveProjection drops the projected files of a source.
+func (s *ConfigService) RemoveProjection(kind, namespace, name string) error {
+	dir, err := ProjectedVolumePath(kind, namespace, name)
+	if err != nil {
+		return err
+	}
+	projectionMu.Lock()
+	defer projectionMu.Unlock()
+	return os.RemoveAll(dir)
+}
+
+// ensureProjectedRoot mounts the tmpfs all projections live on.
+func ensureProjectedRoot() error {
+	if err := os.MkdirAll(utils.ProjectedVolumeDir, 0o700); err != nil {
+		return err
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(utils.ProjectedVolumeDir, &st); err != nil {
+		return err
+	}
+	if st.Type == unix.TMPFS_MAGIC {
+		return nil
+	}
+	if err := unix.Mount("tmpfs", utils.ProjectedVolumeDir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "mode=0700"); err != nil {
+		return fmt.Errorf("mount tmpfs on %s: %w", utils.ProjectedVolumeDir, err)
+	}
+	return nil
+}
+
+// projectFiles lays the files out the way kubernetes does: the content
+// sits in a timestamped directory that ..data points to, and each key is
+// a symlink through ..data. An update writes a new directory and swaps
+// the link, keys that went away are removed afterwards.
+func projectFiles(dir string, files map[string][]byte) error {
+	projectionMu.Lock()
+	defer projectionMu.Unlock()
+
+	if err := ensureProjectedRoot(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	version := "..." + strconv.FormatInt(time.Now().UnixNano(), 10)
+	versionDir := filepath.Join(dir, version)
+	if err := os.Mkdir(versionDir, 0o755); err != nil {
+		return err
+	}
+	for key, content := range files {
+		if err := os.WriteFile(filepath.Join(versionDir, key), content, 0o644); err != nil {
+			_ = os.RemoveAll(versionDir)
+			return err
+		}
+	}
+
+	tmpLink := filepath.Join(dir, projectionDataLink+"_tmp")
+	_ = os.Remove(tmpLink)
+	if err := os.Symlink(version, tmpLink); err != nil {
+		_ = os.RemoveAll(versionDir)
+		return err
+	}
+	if err := os.Rename(tmpLink, filepath.Join(dir, projectionDataLink)); err != nil {
+		_ = os.RemoveAll(versionDir)
+		return err
+	}
+
+	for key := range files {
+		link := filepath.Join(dir, key)
+		if target, err := os.Readlink(link); err == nil && target == filepath.Join(projectionDataLink, key) {
+			continue
+		}
+		_ = os.RemoveAll(link)
+		if err := os.Symlink(filepath.Join(projectionDataLink, key), link); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case name == projectionDataLink || name == version:
+		case strings.HasPrefix(name, "..."):
+			_ = os.RemoveAll(filepath.Join(dir, name))
+		case strings.HasPrefix(name, ".."):
+		default:
+			if _, ok := files[name]; !ok {
+				_ = os.Remove(filepath.Join(dir, name))
+			}
+		}
+	}
+	return nil
+}
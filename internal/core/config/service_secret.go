@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"sort"
+
+	"condenser/internal/store/rsm"
+	"condenser/internal/utils"
+)
+
+const SecretTypeOpaque = "Opaque"
+
+// == service: create or update secret ==
+// ApplySecret seals the data and stores the secret under its name and
+// namespace. The volumes of running pods that mount it are refreshed, an
+// unchanged secret is left alone.
+func (s *ConfigService) ApplySecret(secretParameter ServiceSecretModel, dryRun bool) (string, string, error) {
+	if secretParameter.Name == "" {
+		return "", "", fmt.Errorf("secret name is required")
+	}
+	if err := utils.ValidateDNSSubdomain(secretParameter.Name); err != nil {
+		return "", "", fmt.Errorf("secret: %w", err)
+	}
+	if secretParameter.Namespace == "" {
+		secretParameter.Namespace = "default"
+	}
+	if err := s.nsmHandler.CheckNamespaceActive(secretParameter.Namespace); err != nil {
+		return "", "", err
+	}
+	if secretParameter.Type == "" {
+		secretParameter.Type = SecretTypeOpaque
+	}
+	keys := make([]string, 0, len(secretParameter.Data))
+	size := 0
+	for k, v := range secretParameter.Data {
+		keys = append(keys, k)
+		size += len(v)
+	}
+	sort.Strings(keys)
+	if err := validateDataKeys(keys, size); err != nil {
+		return "", "", err
+	}
+
+	secretId := utils.NewUlid()
+	result := utils.ApplyResultCreated
+	if existing, err := s.rsmHandler.GetSecretByName(secretParameter.Name, secretParameter.Namespace); err == nil {
+		secretId = existing.SecretId
+		result = utils.ApplyResultConfigured
+		changed, err := secretChanged(existing, secretParameter)
+		if err != nil {
+			return "", "", err
+		}
+		if !changed {
+			return secretId, utils.ApplyResultUnchanged, nil
+		}
+	}
+	if dryRun {
+		if result == utils.ApplyResultCreated {
+			secretId = ""
+		}
+		return secretId, result, nil
+	}
+	encrypted, err := sealSecretData(secretId, secretParameter.Data)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.rsmHandler.StoreSecret(secretId, rsm.SecretInfo{
+		Name:          secretParameter.Name,
+		Namespace:     secretParameter.Namespace,
+		Type:          secretParameter.Type,
+		Labels:        secretParameter.Labels,
+		Keys:          keys,
+		EncryptedData: encrypted,
+	}); err != nil {
+		return "", "", err
+	}
+	if result == utils.ApplyResultConfigured {
+		if err := s.refreshProjection(ProjectionKindSecret, secretParameter.Namespace, secretParameter.Name, secretParameter.Data); err != nil {
+			return secretId, result, fmt.Errorf("secret stored, volume refresh failed: %w", err)
+		}
+	}
+	return secretId, result, nil
+}
+
+// secretChanged compares the stored secret with the applied one, the data
+// is opened since every seal uses a fresh nonce.
+func secretChanged(existing rsm.SecretInfo, secretParameter ServiceSecretModel) (bool, error) {
+	if existing.Type != secretParameter.Type || !maps.Equal(existing.Labels, secretParameter.Labels) {
+		return true, nil
+	}
+	data, err := openSecretData(existing.SecretId, existing.EncryptedData)
+	if err != nil {
+		return false, err
+	}
+	return !maps.EqualFunc(data, secretParameter.Data, bytes.Equal), nil
+}
+
+// == service: list secrets ==
+func (s *ConfigService) GetSecretList() ([]SecretState, error) {
+	list, err := s.rsmHandler.GetSecretList()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SecretState, 0, len(list))
+	for _, sec := range list {
+		result = append(result, toSecretState(sec))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// == service: get secret ==
+func (s *ConfigService) GetSecretById(secretId string) (SecretState, error) {
+	info, err := s.rsmHandler.GetSecret(secretId)
+	if err != nil {
+		return SecretState{}, err
+	}
+	return toSecretState(info), nil
+}
+
+func toSecretState(info rsm.SecretInfo) SecretState {
+	return SecretState{
+		SecretId:  info.SecretId,
+		Name:      info.Name,
+		Namespace: info.Namespace,
+		Type:      info.Type,
+		Labels:    info.Labels,
+		Keys:      info.Keys,
+		CreatedAt: info.CreatedAt,
+		UpdatedAt: info.UpdatedAt,
+	}
+}
+
+// == service: remove secret ==
+// The decrypted files go with it, the plaintext must not outlive the
+// secret. Pods that still mount it see an empty directory.
+func (s *ConfigService) RemoveSecret(secretId string) (string, error) {
+	info, err := s.rsmHandler.GetSecret(secretId)
+	if err != nil {
+		return "", err
+	}
+	if err := s.rsmHandler.RemoveSecret(secretId); err != nil {
+		return "", err
+	}
+	if err := s.RemoveProjection(ProjectionKindSecret, info.Namespace, info.Name); err != nil {
+		return secretId, fmt.Errorf("secret removed, projection remains: %w", err)
+	}
+	return secretId, nil
+}
+
+// getSecretData returns the decrypted data of the secret by name.
+func (s *ConfigService) getSecretData(name, namespace string) (map[string][]byte, error) {
+	info, err := s.rsmHandler.GetSecretByName(name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return openSecretData(info.SecretId, info.EncryptedData)
+}
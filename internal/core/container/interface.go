@@ -1,12 +1,15 @@
 package container
 
+import "condenser/internal/utils"
+
 type ContainerServiceHandler interface {
 	Create(createParameter ServiceCreateModel) (string, error)
+	VerifyImage(createParameter ServiceCreateModel) error
 	Start(startParameter ServiceStartModel) (string, error)
 	Delete(deleteParameter ServiceDeleteModel) (string, error)
 	Stop(stopParameter ServiceStopModel) (string, error)
 	Exec(execParameter ServiceExecModel) error
-	GetContainerList() ([]ContainerState, error)
+	GetContainerList(opts utils.ListOptions) ([]ContainerState, error)
 	GetContainerById(containerId string) (ContainerState, error)
 	GetContainerStats(containerId string) (ContainerStats, error)
 	ListContainerStats() ([]ContainerStats, error)
@@ -17,4 +20,5 @@ type ContainerServiceHandler interface {
 
 type CgroupServiceHandler interface {
 	ChangeCgroupMode(containerId string) error
+	MoveToPodCgroup(containerId string, pid int) error
 }
@@ -1,9 +1,13 @@
 package container
 
-import "time"
+import (
+	"condenser/internal/store/psm"
+	"time"
+)
 
 type ServiceCreateModel struct {
 	Image      string
+	Platform   string // os/arch[/variant], the host platform when empty
 	Os         string
 	Arch       string
 	Command    []string
@@ -16,6 +20,12 @@ type ServiceCreateModel struct {
 	BottleId   string
 	PodId      string
 	IsPodInfra bool
+	IsPodInit  bool  // init container, not part of the pod template
+	DiskQuota  int64 // upper dir limit in bytes, none when 0
+
+	// the pod template entry as written, the fields above then carry the
+	// values resolved for this pod (env references, pod volume paths)
+	TemplateSpec *psm.ContainerTemplateSpec
 }
 
 type ServiceStartModel struct {
@@ -38,6 +48,8 @@ type ServiceExecModel struct {
 	ContainerId string
 	Tty         bool
 	Entrypoint  []string
+
+	Timeout time.Duration
 }
 
 type ForwardInfo struct {
@@ -54,6 +66,7 @@ type ContainerState struct {
 	Pid         int      `json:"pid"`
 	Repository  string   `json:"imageRepository"`
 	Reference   string   `json:"imageReference"`
+	ImageDigest string   `json:"imageDigest,omitempty"`
 	Command     []string `json:"command"`
 
 	Address  string        `json:"address"`
@@ -63,6 +76,8 @@ type ContainerState struct {
 	CreatedAt  time.Time `json:"createdAt"`
 	StartedAt  time.Time `json:"statedAt"`
 	StoppedAt  time.Time `json:"stoppedAt"`
+
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 type ContainerStats struct {
@@ -3,6 +3,7 @@ package container
 import (
 	"condenser/internal/core/image"
 	"condenser/internal/core/network"
+	"condenser/internal/core/trust"
 	"condenser/internal/runtime"
 	"condenser/internal/runtime/droplet"
 	"condenser/internal/store/csm"
@@ -25,6 +26,7 @@ func NewContaierService() *ContainerService {
 
 		imageServiceHandler:   image.NewImageService(),
 		networkServiceHandler: network.NewNetworkService(),
+		trustServiceHandler:   trust.NewTrustService(),
 	}
 }
 
@@ -40,6 +42,7 @@ type ContainerService struct {
 
 	imageServiceHandler   image.ImageServiceHandler
 	networkServiceHandler network.NetworkServiceHandler
+	trustServiceHandler   trust.TrustServiceHandler
 }
 
 func (s *ContainerService) getContainerState(containerId string) (string, error) {
@@ -4,8 +4,10 @@ import (
 	"condenser/internal/core/image"
 	"condenser/internal/core/network"
 	"condenser/internal/runtime"
+	"condenser/internal/store/ilm"
 	"condenser/internal/store/psm"
 	"condenser/internal/utils"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
@@ -62,20 +64,28 @@ func (s *ContainerService) Create(createParameter ServiceCreateModel) (id string
 	if err != nil {
 		return "", err
 	}
-
-	// 3. if the image not exist in local, pull image
-	if !s.ilmHandler.IsImageExist(imageRepo, imageRef) {
-		if err := s.pullImage(createParameter.Image, createParameter.Os, createParameter.Arch); err != nil {
+	// the infra image is the daemon's own, not one a user named
+	if !createParameter.IsPodInfra {
+		if err := s.imageServiceHandler.CheckReferencePolicy(createParameter.Image); err != nil {
 			return "", err
 		}
 	}
 
-	// 4. load image config file
-	imageConfigPath, err := s.ilmHandler.GetConfigPath(imageRepo, imageRef)
+	// 3. pick the bundle of the platform to run, pull it when it is not local
+	bundle, err := s.resolveImageBundle(createParameter, imageRepo, imageRef)
 	if err != nil {
 		return "", err
 	}
-	imageConfig, err := s.imageServiceHandler.GetImageConfig(imageConfigPath)
+	// record usage for image gc (best-effort)
+	_ = s.ilmHandler.TouchImage(imageRepo, imageRef)
+
+	// verify the image signature when a trust policy covers the repository
+	if _, err := s.trustServiceHandler.VerifyImage(imageRepo, imageRef); err != nil {
+		return "", err
+	}
+
+	// 4. load image config file
+	imageConfig, err := s.imageServiceHandler.GetImageConfig(bundle.ConfigPath)
 	if err != nil {
 		return "", err
 	}
@@ -127,6 +137,11 @@ func (s *ContainerService) Create(createParameter ServiceCreateModel) (id string
 		return "", err
 	}
 	rollbackFlag.CSMEntry = true
+	if imageInfo, err := s.ilmHandler.GetImageInfo(imageRepo, imageRef); err == nil && imageInfo.ManifestDigest != "" {
+		if err := s.csmHandler.UpdateImageDigest(containerId, imageInfo.ManifestDigest); err != nil {
+			return "", err
+		}
+	}
 
 	// 7. setup container directory
 	if err := s.setupContainerDirectory(containerId); err != nil {
@@ -134,20 +149,28 @@ func (s *ContainerService) Create(createParameter ServiceCreateModel) (id string
 	}
 	rollbackFlag.DirectoryEnv = true
 
+	// 7-1. limit the upper dir when a quota is requested
+	if createParameter.DiskQuota > 0 {
+		rollbackFlag.DiskQuota = true
+		if err := s.setupDiskQuota(containerId, createParameter.DiskQuota); err != nil {
+			return "", fmt.Errorf("setup disk quota failed: %w", err)
+		}
+	}
+
 	// 8. setup etc files
 	if err := s.setupEtcFiles(containerId, containerAddr, containerGateway); err != nil {
 		return "", fmt.Errorf("setup etc files failed: %w", err)
 	}
 
 	// 9. setup cgroup subtree
-	if err := s.setupCgroupSubtree(containerId); err != nil {
+	if err := s.setupCgroupSubtree(containerId, createParameter.PodId); err != nil {
 		return "", fmt.Errorf("setup cgroup subtree failed: %w", err)
 	}
 	rollbackFlag.CgroupEntry = true
 
 	// 10. create spec (config.json)
 	if err := s.createContainerSpec(
-		containerId, createParameter, imageRepo, imageRef, imageConfig,
+		containerId, createParameter, bundle.RootfsPath, imageConfig,
 		bridgeInterface, containerAddr, containerGateway, createParameter.PodId,
 	); err != nil {
 		return "", fmt.Errorf("create spec failed: %w", err)
@@ -174,12 +197,12 @@ func (s *ContainerService) Create(createParameter ServiceCreateModel) (id string
 		}
 	}
 
-	if createParameter.PodId != "" && !createParameter.IsPodInfra {
+	if createParameter.PodId != "" && !createParameter.IsPodInfra && !createParameter.IsPodInit {
 		templateName := baseName
 		if templateName == "" {
 			templateName = containerName
 		}
-		if err := s.psmHandler.AddContainerToPodTemplate(createParameter.PodId, psm.ContainerTemplateSpec{
+		spec := psm.ContainerTemplateSpec{
 			Name:    templateName,
 			Image:   createParameter.Image,
 			Command: createParameter.Command,
@@ -188,7 +211,12 @@ func (s *ContainerService) Create(createParameter ServiceCreateModel) (id string
 			Env:     createParameter.Env,
 			Network: createParameter.Network,
 			Tty:     createParameter.Tty,
-		}); err != nil {
+		}
+		if createParameter.TemplateSpec != nil {
+			spec = *createParameter.TemplateSpec
+			spec.Name = templateName
+		}
+		if err := s.psmHandler.AddContainerToPodTemplate(createParameter.PodId, spec); err != nil {
 			return "", err
 		}
 	}
@@ -202,9 +230,16 @@ type RollbackFlag struct {
 	DirectoryEnv bool
 	CgroupEntry  bool
 	ForwardRule  bool
+	DiskQuota    bool
 }
 
 func (s *ContainerService) rollback(rollbackFlag RollbackFlag, containerId string) error {
+	if rollbackFlag.DiskQuota {
+		// the project id is only known while the csm entry exists
+		if containerInfo, err := s.csmHandler.GetContainerById(containerId); err == nil {
+			_ = s.releaseDiskQuota(containerInfo)
+		}
+	}
 	if rollbackFlag.AllocateAddr {
 		if err := s.releaseAddress(containerId); err != nil {
 			return err
@@ -270,31 +305,77 @@ func (s *ContainerService) generateContainerName() (string, error) {
 	}
 }
 
-func (s *ContainerService) pullImage(targetImage string, os string, arch string) error {
-	var (
-		targetOs   string
-		targetArch string
-	)
-	if os == "" {
-		targetOs = utils.HostOs()
+// == service: verify the image a container would be created from ==
+// The image is pulled when it is not local, as its signature is checked
+// against the stored manifest digest.
+func (s *ContainerService) VerifyImage(createParameter ServiceCreateModel) error {
+	imageRepo, imageRef, err := s.parseImageRef(createParameter.Image)
+	if err != nil {
+		return err
 	}
-	if arch == "" {
-		hostArch, err := utils.HostArch()
-		if err != nil {
-			return err
-		}
-		targetArch = hostArch
+	if err := s.imageServiceHandler.CheckReferencePolicy(createParameter.Image); err != nil {
+		return err
 	}
-	if err := s.imageServiceHandler.Pull(image.ServicePullModel{
-		Image: targetImage,
-		Os:    targetOs,
-		Arch:  targetArch,
-	}); err != nil {
+	if _, err := s.resolveImageBundle(createParameter, imageRepo, imageRef); err != nil {
+		return err
+	}
+	if _, err := s.trustServiceHandler.VerifyImage(imageRepo, imageRef); err != nil {
 		return err
 	}
 	return nil
 }
 
+// resolveImageBundle returns the image bundle a container runs from. A
+// requested platform must be there and is pulled when missing. Otherwise
+// the host platform is preferred, falling back to whatever the image has,
+// as locally built images cannot be pulled for another platform.
+func (s *ContainerService) resolveImageBundle(createParameter ServiceCreateModel, imageRepo, imageRef string) (ilm.PlatformBundle, error) {
+	platform := createParameter.Platform
+	if platform == "" && (createParameter.Os != "" || createParameter.Arch != "") {
+		p, err := utils.HostPlatform()
+		if err != nil {
+			return ilm.PlatformBundle{}, err
+		}
+		if createParameter.Os != "" {
+			p.Os = createParameter.Os
+		}
+		if createParameter.Arch != "" {
+			p.Arch = createParameter.Arch
+		}
+		platform = p.String()
+	}
+
+	if platform != "" {
+		if s.ilmHandler.IsImageExist(imageRepo, imageRef) {
+			if bundle, err := s.ilmHandler.ResolvePlatform(imageRepo, imageRef, platform); err == nil {
+				return bundle, nil
+			}
+		}
+		if err := s.imageServiceHandler.Pull(image.ServicePullModel{
+			Image:    createParameter.Image,
+			Platform: platform,
+		}); err != nil {
+			return ilm.PlatformBundle{}, err
+		}
+		return s.ilmHandler.ResolvePlatform(imageRepo, imageRef, platform)
+	}
+
+	if !s.ilmHandler.IsImageExist(imageRepo, imageRef) {
+		// pulls the host platform
+		if err := s.imageServiceHandler.Pull(image.ServicePullModel{Image: createParameter.Image}); err != nil {
+			return ilm.PlatformBundle{}, err
+		}
+	}
+	host, err := utils.HostPlatform()
+	if err != nil {
+		return ilm.PlatformBundle{}, err
+	}
+	if bundle, err := s.ilmHandler.ResolvePlatform(imageRepo, imageRef, host.String()); err == nil {
+		return bundle, nil
+	}
+	return s.ilmHandler.ResolvePlatform(imageRepo, imageRef, "")
+}
+
 func (s *ContainerService) setupContainerDirectory(containerId string) error {
 	containerDir := filepath.Join(utils.ContainerRootDir, containerId)
 	dirs := []string{
@@ -344,12 +425,49 @@ func (s *ContainerService) setupEtcFiles(containerId string, containerAddr strin
 	return nil
 }
 
-func (s *ContainerService) setupCgroupSubtree(containerId string) error {
+func (s *ContainerService) setupCgroupSubtree(containerId string, podId string) error {
 	cgroupPath := filepath.Join(utils.CgroupRuntimeDir, containerId)
 
 	if err := s.filesystemHandler.MkdirAll(cgroupPath, 0o755); err != nil {
 		return err
 	}
+	if podId == "" {
+		return nil
+	}
+	// the runtime starts the process in the cgroup above, the
+	// createContainer hook moves it below the pod
+	if err := s.setupPodCgroup(podId); err != nil {
+		return err
+	}
+	if err := s.filesystemHandler.MkdirAll(utils.ContainerCgroupPath(containerId, podId), 0o755); err != nil {
+		return err
+	}
+	return nil
+}
+
+// setupPodCgroup creates the pod cgroup and hands the controllers of the
+// runtime root down to the members.
+func (s *ContainerService) setupPodCgroup(podId string) error {
+	podPath := utils.PodCgroupPath(podId)
+	if err := s.filesystemHandler.MkdirAll(podPath, 0o755); err != nil {
+		return err
+	}
+	available, err := s.filesystemHandler.ReadFile(utils.CgroupSubtreeControlPath)
+	if err != nil {
+		return err
+	}
+	enabled, err := s.filesystemHandler.ReadFile(filepath.Join(podPath, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+	for _, c := range strings.Fields(string(available)) {
+		if slices.Contains(strings.Fields(string(enabled)), c) {
+			continue
+		}
+		if err := s.filesystemHandler.WriteFile(filepath.Join(podPath, "cgroup.subtree_control"), []byte("+"+c+"\n"), 0o644); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -359,6 +477,30 @@ func (s *ContainerService) ChangeCgroupMode(containerId string) error {
 	if err := s.filesystemHandler.Chmod(cgroupPath, 0o555); err != nil {
 		return err
 	}
+	containerInfo, err := s.csmHandler.GetContainerById(containerId)
+	if err != nil || containerInfo.PodId == "" {
+		return nil
+	}
+	if err := s.filesystemHandler.Chmod(utils.ContainerCgroupPath(containerId, containerInfo.PodId), 0o555); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MoveToPodCgroup puts the process of a pod member into its cgroup below
+// the pod, where the pod limits apply.
+func (s *ContainerService) MoveToPodCgroup(containerId string, pid int) error {
+	containerInfo, err := s.csmHandler.GetContainerById(containerId)
+	if err != nil {
+		return err
+	}
+	if containerInfo.PodId == "" || pid <= 0 {
+		return nil
+	}
+	procsPath := filepath.Join(utils.ContainerCgroupPath(containerId, containerInfo.PodId), "cgroup.procs")
+	if err := s.filesystemHandler.WriteFile(procsPath, []byte(strconv.Itoa(pid)+"\n"), 0o644); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -380,7 +522,7 @@ func (s *ContainerService) allocateAddress(containerId string, bridgeInterface s
 
 func (s *ContainerService) createContainerSpec(
 	containerId string, createParameter ServiceCreateModel,
-	imageRepo, imageRef string, imageConfig image.ImageConfigFile,
+	imageLayer string, imageConfig image.ImageConfigFile,
 	bridge, containerAddr, containerGateway string, podId string,
 ) error {
 
@@ -448,7 +590,11 @@ func (s *ContainerService) createContainerSpec(
 	}
 
 	// mount
-	mount := createParameter.Mount
+	// image VOLUMEs not covered by a user mount become anonymous volumes
+	mount, err := s.imageVolumeMounts(containerId, imageLayer, imageConfig, createParameter.Mount)
+	if err != nil {
+		return err
+	}
 
 	// host interface
 	hostInterface, err := s.ipamHandler.GetDefaultInterface()
@@ -459,11 +605,6 @@ func (s *ContainerService) createContainerSpec(
 	// container interface
 	containerInterface := "rd_" + containerId
 	containerDns := []string{"8.8.8.8"}
-
-	imageLayer, err := s.ilmHandler.GetRootfsPath(imageRepo, imageRef)
-	if err != nil {
-		return err
-	}
 	upperDir := filepath.Join(utils.ContainerRootDir, containerId, "diff")
 	workDir := filepath.Join(utils.ContainerRootDir, containerId, "work")
 	outputDir := filepath.Join(utils.ContainerRootDir, containerId)
@@ -723,6 +864,8 @@ func (s *ContainerService) parseImageRef(imageStr string) (repository, reference
 	// - ubuntu:24.04 			-> library/ubuntu:24.04
 	// - library/ubuntu:24.04 	-> library/ubuntu:24.04
 	// - nginx@sha256:... 		-> library/nginx@sha256:...
+	// - nginx:1.27@sha256:... 	-> library/nginx@sha256:...
+	// - localhost:5000/app 	-> localhost:5000/app:latest
 
 	const defaultRegistry = "registry-1.docker.io"
 	var (
@@ -730,19 +873,27 @@ func (s *ContainerService) parseImageRef(imageStr string) (repository, reference
 		ref      string
 		registry string
 	)
-	if strings.Contains(imageStr, "@") {
-		parts := strings.SplitN(imageStr, "@", 2)
-		repo = parts[0]
-		ref = parts[1]
-	} else {
-		parts := strings.SplitN(imageStr, ":", 2)
-		repo = parts[0]
-		if len(parts) == 2 && parts[1] != "" {
-			ref = parts[1]
-		} else {
-			ref = "latest"
+	name := imageStr
+	if at := strings.Index(name, "@"); at >= 0 {
+		// name:tag@sha256:... pins the digest, the tag is informational only
+		ref = name[at+1:]
+		name = name[:at]
+		if !isDigestReference(ref) {
+			return "", "", fmt.Errorf("invalid digest reference: %s", ref)
+		}
+	}
+	lastColon := strings.LastIndex(name, ":")
+	lastSlash := strings.LastIndex(name, "/")
+	if lastColon > lastSlash {
+		if ref == "" {
+			ref = name[lastColon+1:]
 		}
+		name = name[:lastColon]
+	}
+	if ref == "" {
+		ref = "latest"
 	}
+	repo = name
 
 	if repo == "" {
 		return "", "", errors.New("empty repository")
@@ -836,6 +987,15 @@ func (s *ContainerService) setupForwardRule(containerId string, ports []string)
 	return nil
 }
 
+func isDigestReference(ref string) bool {
+	hexPart, ok := strings.CutPrefix(ref, "sha256:")
+	if !ok || len(hexPart) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(hexPart)
+	return err == nil
+}
+
 func isRegistryHost(host string) bool {
 	if host == "localhost" {
 		return true
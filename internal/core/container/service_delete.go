@@ -42,7 +42,10 @@ func (s *ContainerService) Delete(deleteParameter ServiceDeleteModel) (string, e
 			return "", fmt.Errorf("release address failed: %w", err)
 		}
 
-		// 3. delete container directory
+		// 3. delete container directory, its quota goes with it
+		if err := s.releaseDiskQuota(containerInfo); err != nil {
+			return "", fmt.Errorf("release disk quota failed: %w", err)
+		}
 		if err := s.deleteContainerDirectory(containerId); err != nil {
 			return "", fmt.Errorf("delete container directory failed: %w", err)
 		}
@@ -113,6 +116,13 @@ func (s *ContainerService) deleteContainerDirectory(containerId string) error {
 }
 
 func (s *ContainerService) deleteCgroupSubtree(containerId string) error {
+	// pod members have a second cgroup below their pod
+	nested, _ := filepath.Glob(utils.ContainerCgroupPath(containerId, "*"))
+	for _, cgroupPath := range nested {
+		if err := s.filesystemHandler.Remove(cgroupPath); err != nil && !s.filesystemHandler.IsNotExist(err) {
+			return err
+		}
+	}
 	cgroupPath := filepath.Join(utils.CgroupRuntimeDir, containerId)
 	if err := s.filesystemHandler.Remove(cgroupPath); err != nil {
 		return err
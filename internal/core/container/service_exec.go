@@ -19,6 +19,7 @@ func (s *ContainerService) Exec(execParameter ServiceExecModel) error {
 			ContainerId: containerId,
 			Tty:         execParameter.Tty,
 			Entrypoint:  execParameter.Entrypoint,
+			Timeout:     execParameter.Timeout,
 		},
 	); err != nil {
 		return err
@@ -1,14 +1,42 @@
 package container
 
-import "condenser/internal/store/csm"
+import (
+	"condenser/internal/store/csm"
+	"condenser/internal/utils"
+)
 
 // == service: get container list ==
-func (s *ContainerService) GetContainerList() ([]ContainerState, error) {
+// Containers carry the labels CRI set on them, the name, state, pod id
+// and node can be selected on.
+func (s *ContainerService) GetContainerList(opts utils.ListOptions) ([]ContainerState, error) {
 	containerList, err := s.csmHandler.GetContainerList()
 	if err != nil {
 		return nil, err
 	}
-	return s.buildContainerStateList(containerList)
+	var selected []csm.ContainerInfo
+	for _, c := range containerList {
+		if opts.Matches(c.Labels, containerFields(c)) {
+			selected = append(selected, c)
+		}
+	}
+	return s.buildContainerStateList(selected)
+}
+
+// fields a container list can be narrowed by with a fieldSelector
+var ContainerSelectableFields = []string{
+	utils.FieldMetadataName,
+	utils.FieldStatusPhase,
+	utils.FieldSpecPodId,
+	utils.FieldSpecNodeName,
+}
+
+func containerFields(c csm.ContainerInfo) map[string]string {
+	return map[string]string{
+		utils.FieldMetadataName: c.ContainerName,
+		utils.FieldStatusPhase:  c.State,
+		utils.FieldSpecPodId:    c.PodId,
+		utils.FieldSpecNodeName: utils.NodeName(),
+	}
 }
 
 // == service: get container list by pod ==
@@ -56,6 +84,7 @@ func (s *ContainerService) buildContainerStateList(containerList []csm.Container
 			Pid:         c.Pid,
 			Repository:  c.Repository,
 			Reference:   c.Reference,
+			ImageDigest: c.ImageDigest,
 			Command:     c.Command,
 
 			Address:  address,
@@ -65,6 +94,8 @@ func (s *ContainerService) buildContainerStateList(containerList []csm.Container
 			CreatedAt:  c.CreatedAt,
 			StartedAt:  c.StartedAt,
 			StoppedAt:  c.StoppedAt,
+
+			Labels: c.Labels,
 		})
 	}
 
@@ -100,6 +131,7 @@ func (s *ContainerService) GetContainerById(containerId string) (ContainerState,
 		Pid:         containerState.Pid,
 		Repository:  containerState.Repository,
 		Reference:   containerState.Reference,
+		ImageDigest: containerState.ImageDigest,
 		Command:     containerState.Command,
 
 		Address:  address,
@@ -0,0 +1,33 @@
+package container
+
+import (
+	"condenser/internal/store/csm"
+	"condenser/internal/utils"
+	"fmt"
+	"path/filepath"
+)
+
+// setupDiskQuota limits what the container can write to its upper dir.
+// The upper dir gets a project id of its own and the filesystem enforces
+// the limit, so a runaway write fails with ENOSPC inside the container
+// instead of filling the host.
+func (s *ContainerService) setupDiskQuota(containerId string, limitBytes int64) error {
+	projectId, err := s.csmHandler.SetDiskQuota(containerId, limitBytes)
+	if err != nil {
+		return err
+	}
+	upperDir := filepath.Join(utils.ContainerRootDir, containerId, "diff")
+	if err := utils.SetProjectQuota(upperDir, projectId, limitBytes); err != nil {
+		return fmt.Errorf("disk quota for %s: %w", upperDir, err)
+	}
+	return nil
+}
+
+// releaseDiskQuota lifts the limit of the project id, it is handed out
+// again to a later container.
+func (s *ContainerService) releaseDiskQuota(containerInfo csm.ContainerInfo) error {
+	if containerInfo.QuotaProjectId == 0 {
+		return nil
+	}
+	return utils.ClearProjectQuota(utils.ContainerRootDir, containerInfo.QuotaProjectId)
+}
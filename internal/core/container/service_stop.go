@@ -29,7 +29,8 @@ func (s *ContainerService) Stop(stopParameter ServiceStopModel) (string, error)
 		if err := s.stopContainer(containerId); err != nil {
 			return "", fmt.Errorf("stop failed: %w", err)
 		}
-		if containerInfo.PodId != "" && !strings.HasPrefix(containerInfo.ContainerName, utils.PodInfraContainerNamePrefix) {
+		if containerInfo.PodId != "" && !strings.HasPrefix(containerInfo.ContainerName, utils.PodInfraContainerNamePrefix) &&
+			!strings.HasPrefix(containerInfo.ContainerName, utils.PodInitContainerNamePrefix) {
 			_ = s.psmHandler.UpdatePod(containerInfo.PodId, "degraded")
 		}
 	default:
@@ -0,0 +1,72 @@
+package container
+
+import (
+	"condenser/internal/core/image"
+	"condenser/internal/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// imageVolumeMounts creates an anonymous volume for every VOLUME of the image
+// that is not already covered by a user mount. Each volume lives in the
+// container directory (and is removed with it) and starts with the content
+// the image has at that path.
+func (s *ContainerService) imageVolumeMounts(containerId string, imageLayer string, imageConfig image.ImageConfigFile, mounts []string) ([]string, error) {
+	if len(imageConfig.Config.Volumes) == 0 {
+		return mounts, nil
+	}
+
+	targets := map[string]bool{}
+	for _, m := range mounts {
+		parts := strings.Split(m, ":")
+		if len(parts) >= 2 {
+			targets[filepath.Clean(parts[1])] = true
+		}
+	}
+
+	volumes := make([]string, 0, len(imageConfig.Config.Volumes))
+	for v := range imageConfig.Config.Volumes {
+		volumes = append(volumes, filepath.Clean(v))
+	}
+	sort.Strings(volumes)
+
+	result := append([]string{}, mounts...)
+	for i, dst := range volumes {
+		if targets[dst] {
+			continue
+		}
+		hostDir := filepath.Join(utils.ContainerRootDir, containerId, "volumes", strconv.Itoa(i))
+		if err := s.filesystemHandler.MkdirAll(hostDir, 0o755); err != nil {
+			return nil, err
+		}
+		if err := s.seedVolume(filepath.Join(imageLayer, strings.TrimPrefix(dst, "/")), hostDir); err != nil {
+			return nil, fmt.Errorf("seed volume %s: %w", dst, err)
+		}
+		result = append(result, hostDir+":"+dst)
+	}
+	return result, nil
+}
+
+// seedVolume copies the image content at src into the volume directory.
+// Symlinks are not followed, so a link pointing outside the image is skipped.
+func (s *ContainerService) seedVolume(src string, hostDir string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return nil
+	}
+	out, err := s.commandFactory.Command("cp", "-a", "--", src+"/.", hostDir).CombineOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -36,6 +36,10 @@ func (s *HookService) HookAction(stateParameter ServiceStateModel, eventType str
 		if err := s.csmHandler.UpdateContainer(stateParameter.Id, stateParameter.Status, stateParameter.Pid); err != nil {
 			return fmt.Errorf("csm update failed: %w", err)
 		}
+		// pod members run below the pod cgroup
+		if err := s.cgroupHandler.MoveToPodCgroup(stateParameter.Id, stateParameter.Pid); err != nil {
+			return fmt.Errorf("move to pod cgroup failed: %w", err)
+		}
 		// change cgroup dir mode: 755 -> 555
 		if err := s.cgroupHandler.ChangeCgroupMode(stateParameter.Id); err != nil {
 			return fmt.Errorf("chmod cgroup path failed: %w", err)
@@ -0,0 +1,34 @@
+package image
+
+import (
+	"log"
+	"time"
+)
+
+func NewImageGcController() *ImageGcController {
+	return &ImageGcController{
+		imageHandler: NewImageService(),
+		interval:     1 * time.Minute,
+	}
+}
+
+type ImageGcController struct {
+	imageHandler ImageServiceHandler
+	interval     time.Duration
+}
+
+func (c *ImageGcController) Start() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		result, err := c.imageHandler.CollectGarbage()
+		if err != nil {
+			log.Printf("image gc failed: %v", err)
+			continue
+		}
+		if len(result.Removed) > 0 {
+			log.Printf("image gc removed %d image(s), reclaimed %d bytes", len(result.Removed), result.ReclaimedBytes)
+		}
+	}
+}
@@ -3,9 +3,24 @@ package image
 type ImageServiceHandler interface {
 	Pull(pullParameter ServicePullModel) error
 	Remove(removeParameter ServiceRemoveModel) error
+	Tag(tagParameter ServiceTagModel) (string, error)
+	Prune(pruneParameter ServicePruneModel) (ImagePruneResult, error)
+	CollectGarbage() (ImagePruneResult, error)
+	GetGcPolicy() (ImageGcPolicy, error)
+	SetGcPolicy(policyParameter ServiceGcPolicyModel) (ImageGcPolicy, error)
+	CheckReferencePolicy(imageStr string) error
+	GetReferencePolicy() (ImageReferencePolicy, error)
+	SetReferencePolicy(policyParameter ServiceReferencePolicyModel) (ImageReferencePolicy, error)
 	Build(buildParameter ServiceBuildModel) (string, error)
+	GetBuildCacheList() ([]BuildCacheInfo, error)
+	PruneBuildCache(pruneParameter ServiceBuildCachePruneModel) (BuildCachePruneResult, error)
 	GetImageConfig(filepath string) (ImageConfigFile, error)
 	GetImageList() ([]ImageInfo, error)
 	GetImageStatus(imageStr string) (ImageStatusInfo, error)
 	GetImageFsInfo(imageStr string) (ImageFsInfo, error)
+	GetImageDiskUsage() (ImageDiskUsage, error)
+	InspectImage(inspectParameter ServiceInspectModel) (ImageInspectInfo, error)
+	GetImageHistory(imageStr string) ([]ImageHistoryInfo, error)
+	GenerateSbom(sbomParameter ServiceSbomModel) ([]byte, error)
+	GetImageProvenance(imageStr string) ([]byte, error)
 }
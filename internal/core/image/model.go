@@ -1,41 +1,147 @@
 package image
 
-import "time"
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
 
 type ServicePullModel struct {
-	Image string
-	Os    string
-	Arch  string
+	Image                  string
+	Platform               string // os/arch[/variant], takes precedence over Os and Arch
+	Os                     string
+	Arch                   string
+	MaxConcurrentDownloads int
+	Progress               func(PullProgress)
 }
 
 type ServiceRemoveModel struct {
 	Image string
 }
 
+type ServiceInspectModel struct {
+	Image    string
+	Platform string // os/arch[/variant], the first stored platform when empty
+}
+
+type ServiceTagModel struct {
+	SourceImage string
+	TargetImage string
+}
+
+type ServicePruneModel struct {
+	Mode      string // dangling | unused | age
+	OlderThan time.Duration
+}
+
+type ServiceGcPolicyModel struct {
+	Enabled       bool
+	HighWatermark int
+	LowWatermark  int
+}
+
+type ServiceReferencePolicyModel struct {
+	RequireDigest bool
+}
+
+type ServiceSbomModel struct {
+	Image  string
+	Format string // spdx | cyclonedx
+}
+
 type ServiceBuildModel struct {
-	Image        string
-	ContextDir   string
-	DripfilePath string
-	Network      string
+	Image     string
+	Context   io.Reader // tar stream of the build context
+	Dripfile  string    // path inside the context, "Dripfile" when empty
+	Network   string
+	Target    string            // stage name or index, the last stage when empty
+	BuildArgs map[string]string // values for ARG instructions
+	NoCache   bool              // run every step even when a cached snapshot exists
+	Platform  string            // os/arch[/variant] base images are picked for, FROM --platform overrides it
+	Secrets   map[string][]byte // values for RUN --mount=type=secret, by id, never stored
+	Progress  func(BuildProgress)
+
+	// hermetic builds run RUN steps without network, fetch nothing remote
+	// and set every file time to SourceDateEpoch (unix seconds)
+	Hermetic        bool
+	SourceDateEpoch int64
+}
+
+type ServiceBuildCachePruneModel struct {
+	All       bool          // remove every entry
+	OlderThan time.Duration // entries unused for this long, 7 days when zero
 }
 
 // image bundle object
 type ImageConfigObject struct {
-	Env        []string `json:"Env"`
-	Cmd        []string `json:"Cmd"`
-	Entrypoint []string `json:"Entrypoint"`
-	WorkingDir string   `json:"WorkingDir"`
-	User       string   `json:"User"`
+	Env          []string            `json:"Env"`
+	Cmd          []string            `json:"Cmd"`
+	Entrypoint   []string            `json:"Entrypoint"`
+	WorkingDir   string              `json:"WorkingDir"`
+	User         string              `json:"User"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Volumes      map[string]struct{} `json:"Volumes,omitempty"`
+	Healthcheck  *ImageHealthConfig  `json:"Healthcheck,omitempty"`
+	Shell        []string            `json:"Shell,omitempty"`
+	StopSignal   string              `json:"StopSignal,omitempty"`
+}
+
+// ImageHealthConfig follows the docker image config layout, durations are in nanoseconds.
+type ImageHealthConfig struct {
+	Test          []string      `json:"Test,omitempty"` // ["NONE"] | ["CMD", args...] | ["CMD-SHELL", command]
+	Interval      time.Duration `json:"Interval,omitempty"`
+	Timeout       time.Duration `json:"Timeout,omitempty"`
+	StartPeriod   time.Duration `json:"StartPeriod,omitempty"`
+	StartInterval time.Duration `json:"StartInterval,omitempty"`
+	Retries       int           `json:"Retries,omitempty"`
+}
+
+type ImageHistoryObject struct {
+	Created    string `json:"created,omitempty"`
+	CreatedBy  string `json:"created_by,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	EmptyLayer bool   `json:"empty_layer,omitempty"`
 }
 
 type ImageConfigFile struct {
-	Config ImageConfigObject `json:"config"`
+	Created      string               `json:"created,omitempty"`
+	Architecture string               `json:"architecture,omitempty"`
+	Os           string               `json:"os,omitempty"`
+	Variant      string               `json:"variant,omitempty"`
+	Config       ImageConfigObject    `json:"config"`
+	History      []ImageHistoryObject `json:"history,omitempty"`
+}
+
+type PullProgress struct {
+	Id      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	BuildProgressStep   = "step"   // an instruction starts
+	BuildProgressCached = "cached" // a RUN step was taken from the build cache
+	BuildProgressOutput = "output" // output of a RUN step
+)
+
+type BuildProgress struct {
+	Type    string `json:"type"`
+	Step    int    `json:"step,omitempty"`
+	Total   int    `json:"total,omitempty"`
+	Message string `json:"message"`
 }
 
 type ImageInfo struct {
-	Repository string    `json:"repository"`
-	Reference  string    `json:"reference"`
-	CreatedAt  time.Time `json:"createdAt"`
+	Repository     string    `json:"repository"`
+	Reference      string    `json:"reference"`
+	ManifestDigest string    `json:"manifestDigest,omitempty"`
+	Platforms      []string  `json:"platforms,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
 }
 
 type ImageStatusInfo struct {
@@ -53,3 +159,123 @@ type ImageFsInfo struct {
 	Image     string `json:"image"`
 	UsedBytes int64  `json:"usedBytes"`
 }
+
+type ImageLayerInfo struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	SizeBytes int64  `json:"sizeBytes"`
+}
+
+type ImagePlatform struct {
+	Os           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+type ImageInspectInfo struct {
+	Repository     string             `json:"repository"`
+	Reference      string             `json:"reference"`
+	ManifestDigest string             `json:"manifestDigest"`
+	ConfigDigest   string             `json:"configDigest"`
+	Layers         []ImageLayerInfo   `json:"layers"`
+	Platform       ImagePlatform      `json:"platform"`
+	Platforms      []string           `json:"platforms"`
+	Env            []string           `json:"env"`
+	Entrypoint     []string           `json:"entrypoint"`
+	Cmd            []string           `json:"cmd"`
+	WorkingDir     string             `json:"workingDir"`
+	User           string             `json:"user"`
+	Labels         map[string]string  `json:"labels"`
+	ExposedPorts   []string           `json:"exposedPorts"`
+	Volumes        []string           `json:"volumes"`
+	Healthcheck    *ImageHealthConfig `json:"healthcheck,omitempty"`
+	Shell          []string           `json:"shell,omitempty"`
+	StopSignal     string             `json:"stopSignal,omitempty"`
+	SizeBytes      int64              `json:"sizeBytes"`
+	CreatedAt      time.Time          `json:"createdAt"`
+}
+
+type ImageHistoryInfo struct {
+	Created     string `json:"created"`
+	CreatedBy   string `json:"createdBy"`
+	Comment     string `json:"comment,omitempty"`
+	EmptyLayer  bool   `json:"emptyLayer"`
+	LayerDigest string `json:"layerDigest,omitempty"`
+	SizeBytes   int64  `json:"sizeBytes"`
+}
+
+type PrunedImage struct {
+	Image          string `json:"image"`
+	BundlePath     string `json:"bundlePath"`
+	ReclaimedBytes int64  `json:"reclaimedBytes"`
+}
+
+type SkippedImage struct {
+	Image  string `json:"image"`
+	Reason string `json:"reason"`
+}
+
+type ImageDiskUsage struct {
+	Images           []ImageDiskUsageInfo `json:"images"`
+	ActiveCount      int                  `json:"activeCount"`      // images used by a container
+	TotalBytes       int64                `json:"totalBytes"`       // shared files counted once
+	ReclaimableBytes int64                `json:"reclaimableBytes"` // freed by removing unused images and dangling bundles
+	DanglingBytes    int64                `json:"danglingBytes"`    // bundles no image references
+}
+
+type ImageDiskUsageInfo struct {
+	Image       string   `json:"image"`
+	Platforms   []string `json:"platforms,omitempty"`
+	SizeBytes   int64    `json:"sizeBytes"`
+	SharedBytes int64    `json:"sharedBytes"` // also held by other images
+	UniqueBytes int64    `json:"uniqueBytes"` // freed when only this image is removed
+	Containers  int      `json:"containers"`
+	Reclaimable bool     `json:"reclaimable"`
+}
+
+type BuildCacheInfo struct {
+	Key        string    `json:"key"`
+	Parent     string    `json:"parent"`
+	CreatedBy  string    `json:"createdBy"`
+	SizeBytes  int64     `json:"sizeBytes"`
+	UsageCount int       `json:"usageCount"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+type PrunedBuildCache struct {
+	Key            string `json:"key"`
+	CreatedBy      string `json:"createdBy"`
+	ReclaimedBytes int64  `json:"reclaimedBytes"`
+}
+
+type BuildCachePruneResult struct {
+	Removed        []PrunedBuildCache `json:"removed"`
+	ReclaimedBytes int64              `json:"reclaimedBytes"`
+}
+
+type ImagePruneResult struct {
+	Removed        []PrunedImage  `json:"removed"`
+	Skipped        []SkippedImage `json:"skipped"`
+	ReclaimedBytes int64          `json:"reclaimedBytes"`
+}
+
+type ImageGcPolicy struct {
+	Enabled       bool `json:"enabled"`
+	HighWatermark int  `json:"highWatermark"`
+	LowWatermark  int  `json:"lowWatermark"`
+}
+
+type ImageReferencePolicy struct {
+	RequireDigest bool `json:"requireDigest"`
+}
+
+// ImageInUseError is returned when an image is still referenced by a container.
+type ImageInUseError struct {
+	Image      string
+	Containers []string
+}
+
+func (e *ImageInUseError) Error() string {
+	return fmt.Sprintf("image %s is in use by container(s): %s", e.Image, strings.Join(e.Containers, ", "))
+}
@@ -0,0 +1,658 @@
+package image
+
+import (
+	"bufio"
+	"bytes"
+	"condenser/internal/utils"
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// package types, also used as purl types
+const (
+	sbomTypeDeb    = "deb"
+	sbomTypeApk    = "apk"
+	sbomTypeRpm    = "rpm"
+	sbomTypeGolang = "golang"
+	sbomTypeNpm    = "npm"
+	sbomTypePypi   = "pypi"
+)
+
+// rpm database locations, relative to the rootfs
+var rpmDbDirs = []string{
+	"var/lib/rpm",
+	"usr/lib/sysimage/rpm",
+}
+
+// directories which never hold image content worth scanning
+var sbomSkipDirs = map[string]bool{
+	"proc": true,
+	"sys":  true,
+	"dev":  true,
+}
+
+type sbomPackage struct {
+	Name     string
+	Version  string
+	Type     string
+	Arch     string
+	License  string
+	Source   string
+	Location string
+	Purl     string
+}
+
+type sbomDistro struct {
+	Id        string
+	VersionId string
+	Name      string
+}
+
+type sbomCatalog struct {
+	Distro   sbomDistro
+	Packages []sbomPackage
+	Warnings []string
+}
+
+// rootfsScanner collects the package inventory of an unpacked rootfs.
+// All paths read from the image are resolved inside the rootfs, so a
+// symlink in the image can never point the scanner at a host file.
+type rootfsScanner struct {
+	rootfs         string
+	commandFactory utils.CommandFactory
+	catalog        sbomCatalog
+}
+
+func scanRootfs(rootfs string, commandFactory utils.CommandFactory) (sbomCatalog, error) {
+	sc := &rootfsScanner{
+		rootfs:         rootfs,
+		commandFactory: commandFactory,
+	}
+	sc.catalog.Distro = sc.readOsRelease()
+
+	sc.scanDpkg()
+	sc.scanApk()
+	sc.scanRpm()
+	if err := sc.walk(); err != nil {
+		return sbomCatalog{}, err
+	}
+
+	for i := range sc.catalog.Packages {
+		sc.catalog.Packages[i].Purl = buildPurl(sc.catalog.Packages[i], sc.catalog.Distro)
+	}
+	sc.catalog.Packages = dedupePackages(sc.catalog.Packages)
+	return sc.catalog, nil
+}
+
+func (sc *rootfsScanner) warn(format string, args ...any) {
+	sc.catalog.Warnings = append(sc.catalog.Warnings, fmt.Sprintf(format, args...))
+}
+
+// resolve returns the host path of rel inside the rootfs.
+func (sc *rootfsScanner) resolve(rel string) (string, error) {
+	return resolveInRootfs(sc.rootfs, rel)
+}
+
+func (sc *rootfsScanner) readFile(rel string) ([]byte, error) {
+	p, err := sc.resolve(rel)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a regular file: %s", rel)
+	}
+	return os.ReadFile(p)
+}
+
+func (sc *rootfsScanner) readOsRelease() sbomDistro {
+	var data []byte
+	for _, p := range []string{"etc/os-release", "usr/lib/os-release"} {
+		if b, err := sc.readFile(p); err == nil {
+			data = b
+			break
+		}
+	}
+
+	var d sbomDistro
+	for _, line := range strings.Split(string(data), "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		v = strings.Trim(v, `"'`)
+		switch k {
+		case "ID":
+			d.Id = v
+		case "VERSION_ID":
+			d.VersionId = v
+		case "PRETTY_NAME":
+			d.Name = v
+		}
+	}
+	return d
+}
+
+// == os packages ==
+func (sc *rootfsScanner) scanDpkg() {
+	sources := []string{"var/lib/dpkg/status"}
+
+	// distroless images keep one status file per package
+	if dir, err := sc.resolve("var/lib/dpkg/status.d"); err == nil {
+		if entries, err := os.ReadDir(dir); err == nil {
+			for _, e := range entries {
+				if e.Type().IsRegular() && !strings.HasSuffix(e.Name(), ".md5sums") {
+					sources = append(sources, path.Join("var/lib/dpkg/status.d", e.Name()))
+				}
+			}
+		}
+	}
+
+	for _, src := range sources {
+		data, err := sc.readFile(src)
+		if err != nil {
+			continue
+		}
+		for _, para := range splitParagraphs(data) {
+			fields := parseControlFields(para)
+			if fields["Package"] == "" {
+				continue
+			}
+			// the main status file also lists removed packages
+			if status, ok := fields["Status"]; ok && !strings.HasSuffix(status, " installed") {
+				continue
+			}
+			source, _, _ := strings.Cut(fields["Source"], " ")
+			sc.catalog.Packages = append(sc.catalog.Packages, sbomPackage{
+				Name:     fields["Package"],
+				Version:  fields["Version"],
+				Type:     sbomTypeDeb,
+				Arch:     fields["Architecture"],
+				Source:   source,
+				Location: "/" + src,
+			})
+		}
+	}
+}
+
+func (sc *rootfsScanner) scanApk() {
+	const src = "lib/apk/db/installed"
+	data, err := sc.readFile(src)
+	if err != nil {
+		return
+	}
+	for _, para := range splitParagraphs(data) {
+		var pkg sbomPackage
+		for _, line := range para {
+			k, v, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			switch k {
+			case "P":
+				pkg.Name = v
+			case "V":
+				pkg.Version = v
+			case "A":
+				pkg.Arch = v
+			case "L":
+				pkg.License = v
+			case "o":
+				pkg.Source = v
+			}
+		}
+		if pkg.Name == "" {
+			continue
+		}
+		pkg.Type = sbomTypeApk
+		pkg.Location = "/" + src
+		sc.catalog.Packages = append(sc.catalog.Packages, pkg)
+	}
+}
+
+// scanRpm queries the image's rpm database with the host rpm binary.
+// The database formats (bdb, ndb, sqlite) are not parsed natively.
+func (sc *rootfsScanner) scanRpm() {
+	var dbDir, dbRel string
+	for _, rel := range rpmDbDirs {
+		dir, err := sc.resolve(rel)
+		if err != nil {
+			continue
+		}
+		for _, name := range []string{"rpmdb.sqlite", "Packages.db", "Packages"} {
+			if fi, err := os.Stat(filepath.Join(dir, name)); err == nil && fi.Mode().IsRegular() {
+				dbDir, dbRel = dir, rel
+				break
+			}
+		}
+		if dbDir != "" {
+			break
+		}
+	}
+	if dbDir == "" {
+		return
+	}
+
+	cmd := sc.commandFactory.Command(
+		"rpm", "--dbpath", dbDir, "-qa",
+		"--qf", `%{NAME}\t%{EPOCHNUM}\t%{VERSION}\t%{RELEASE}\t%{ARCH}\t%{LICENSE}\t%{SOURCERPM}\n`,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		sc.warn("rpm database found at /%s but could not be read with the host rpm tool: %v", dbRel, err)
+		return
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) != 7 || f[0] == "" {
+			continue
+		}
+		version := f[2] + "-" + f[3]
+		if f[1] != "" && f[1] != "0" {
+			version = f[1] + ":" + version
+		}
+		arch := f[4]
+		if arch == "(none)" {
+			arch = ""
+		}
+		source := strings.TrimSuffix(f[6], ".src.rpm")
+		if source == "(none)" {
+			source = ""
+		}
+		sc.catalog.Packages = append(sc.catalog.Packages, sbomPackage{
+			Name:     f[0],
+			Version:  version,
+			Type:     sbomTypeRpm,
+			Arch:     arch,
+			License:  f[5],
+			Source:   source,
+			Location: "/" + dbRel,
+		})
+	}
+}
+
+// == language packages ==
+func (sc *rootfsScanner) walk() error {
+	return filepath.WalkDir(sc.rootfs, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// unreadable subtrees are skipped, the rest is still scanned
+			if p == sc.rootfs {
+				return err
+			}
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(sc.rootfs, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if sbomSkipDirs[rel] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		switch name := d.Name(); {
+		case name == "package-lock.json":
+			if !strings.Contains(rel, "node_modules"+string(filepath.Separator)) {
+				sc.scanPackageLock(p, rel)
+			}
+		case name == "requirements.txt":
+			sc.scanRequirements(p, rel)
+		default:
+			info, err := d.Info()
+			if err == nil && info.Mode()&0o111 != 0 && isElfFile(p) {
+				sc.scanGoBinary(p, rel)
+			}
+		}
+		return nil
+	})
+}
+
+func (sc *rootfsScanner) scanGoBinary(hostPath string, rel string) {
+	bi, err := buildinfo.ReadFile(hostPath)
+	if err != nil {
+		return
+	}
+	location := "/" + filepath.ToSlash(rel)
+
+	if bi.Main.Path != "" {
+		sc.catalog.Packages = append(sc.catalog.Packages, sbomPackage{
+			Name:     bi.Main.Path,
+			Version:  bi.Main.Version,
+			Type:     sbomTypeGolang,
+			Location: location,
+		})
+	}
+	sc.catalog.Packages = append(sc.catalog.Packages, sbomPackage{
+		Name:     "stdlib",
+		Version:  bi.GoVersion,
+		Type:     sbomTypeGolang,
+		Location: location,
+	})
+	for _, dep := range bi.Deps {
+		mod := dep
+		if dep.Replace != nil {
+			mod = dep.Replace
+		}
+		sc.catalog.Packages = append(sc.catalog.Packages, sbomPackage{
+			Name:     mod.Path,
+			Version:  mod.Version,
+			Type:     sbomTypeGolang,
+			Location: location,
+		})
+	}
+}
+
+func (sc *rootfsScanner) scanPackageLock(hostPath string, rel string) {
+	data, err := os.ReadFile(hostPath)
+	if err != nil {
+		return
+	}
+	var lock struct {
+		Packages map[string]struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+			License any    `json:"license"`
+			Link    bool   `json:"link"`
+		} `json:"packages"`
+		Dependencies map[string]npmLockDependency `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		sc.warn("skipped malformed /%s: %v", filepath.ToSlash(rel), err)
+		return
+	}
+	location := "/" + filepath.ToSlash(rel)
+
+	// lockfile v2/v3
+	if len(lock.Packages) > 0 {
+		for key, p := range lock.Packages {
+			if key == "" || p.Link {
+				continue
+			}
+			name := p.Name
+			if idx := strings.LastIndex(key, "node_modules/"); idx >= 0 {
+				name = key[idx+len("node_modules/"):]
+			}
+			if name == "" {
+				continue
+			}
+			license, _ := p.License.(string)
+			sc.catalog.Packages = append(sc.catalog.Packages, sbomPackage{
+				Name:     name,
+				Version:  p.Version,
+				Type:     sbomTypeNpm,
+				License:  license,
+				Location: location,
+			})
+		}
+		return
+	}
+
+	// lockfile v1
+	var visit func(deps map[string]npmLockDependency)
+	visit = func(deps map[string]npmLockDependency) {
+		for name, dep := range deps {
+			sc.catalog.Packages = append(sc.catalog.Packages, sbomPackage{
+				Name:     name,
+				Version:  dep.Version,
+				Type:     sbomTypeNpm,
+				Location: location,
+			})
+			visit(dep.Dependencies)
+		}
+	}
+	visit(lock.Dependencies)
+}
+
+type npmLockDependency struct {
+	Version      string                       `json:"version"`
+	Dependencies map[string]npmLockDependency `json:"dependencies"`
+}
+
+func (sc *rootfsScanner) scanRequirements(hostPath string, rel string) {
+	f, err := os.Open(hostPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	location := "/" + filepath.ToSlash(rel)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		// options, includes and editable/url installs carry no name==version
+		if line == "" || strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+			continue
+		}
+		if idx := strings.Index(line, ";"); idx >= 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+
+		name, version := line, ""
+		if idx := strings.IndexAny(line, "=<>!~ "); idx >= 0 {
+			name = line[:idx]
+			if spec := strings.TrimSpace(line[idx:]); strings.HasPrefix(spec, "==") && !strings.Contains(spec, ",") {
+				version = strings.TrimSpace(strings.TrimPrefix(spec, "=="))
+			}
+		}
+		if idx := strings.Index(name, "["); idx >= 0 {
+			name = name[:idx]
+		}
+		if name == "" {
+			continue
+		}
+		sc.catalog.Packages = append(sc.catalog.Packages, sbomPackage{
+			Name:     name,
+			Version:  version,
+			Type:     sbomTypePypi,
+			Location: location,
+		})
+	}
+}
+
+// == helpers ==
+func isElfFile(p string) bool {
+	f, err := os.Open(p)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false
+	}
+	return bytes.Equal(magic, []byte("\x7fELF"))
+}
+
+// splitParagraphs splits a blank-line separated database into its records.
+func splitParagraphs(data []byte) [][]string {
+	var (
+		result  [][]string
+		current []string
+	)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				result = append(result, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+	return result
+}
+
+// parseControlFields parses a debian control paragraph, dropping continuation lines.
+func parseControlFields(lines []string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range lines {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields[k] = strings.TrimSpace(v)
+	}
+	return fields
+}
+
+func buildPurl(pkg sbomPackage, distro sbomDistro) string {
+	var namespace, name string
+	qualifiers := url.Values{}
+
+	switch pkg.Type {
+	case sbomTypeDeb, sbomTypeApk, sbomTypeRpm:
+		namespace = distro.Id
+		if namespace == "" {
+			switch pkg.Type {
+			case sbomTypeDeb:
+				namespace = "debian"
+			case sbomTypeApk:
+				namespace = "alpine"
+			}
+		}
+		name = pkg.Name
+		if pkg.Arch != "" {
+			qualifiers.Set("arch", pkg.Arch)
+		}
+		if distro.Id != "" && distro.VersionId != "" {
+			qualifiers.Set("distro", distro.Id+"-"+distro.VersionId)
+		}
+	case sbomTypeGolang:
+		namespace, name = path.Split(pkg.Name)
+		namespace = strings.TrimSuffix(namespace, "/")
+	case sbomTypeNpm:
+		if strings.HasPrefix(pkg.Name, "@") {
+			namespace, name, _ = strings.Cut(pkg.Name, "/")
+		} else {
+			name = pkg.Name
+		}
+	case sbomTypePypi:
+		name = strings.ToLower(strings.ReplaceAll(pkg.Name, "_", "-"))
+	default:
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("pkg:" + pkg.Type + "/")
+	if namespace != "" {
+		for _, seg := range strings.Split(namespace, "/") {
+			b.WriteString(strings.ReplaceAll(url.PathEscape(seg), "@", "%40") + "/")
+		}
+	}
+	b.WriteString(url.PathEscape(name))
+	if pkg.Version != "" {
+		b.WriteString("@" + url.PathEscape(pkg.Version))
+	}
+	if len(qualifiers) > 0 {
+		b.WriteString("?" + qualifiers.Encode())
+	}
+	return b.String()
+}
+
+// dedupePackages drops repeated entries (e.g. stdlib of several go binaries
+// in one directory) and sorts the inventory for stable output.
+func dedupePackages(pkgs []sbomPackage) []sbomPackage {
+	seen := map[string]bool{}
+	result := make([]sbomPackage, 0, len(pkgs))
+	for _, p := range pkgs {
+		key := p.Purl + "|" + p.Location
+		if p.Purl == "" {
+			key = p.Type + "|" + p.Name + "|" + p.Version + "|" + p.Location
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, p)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		if result[i].Version != result[j].Version {
+			return result[i].Version < result[j].Version
+		}
+		return result[i].Location < result[j].Location
+	})
+	return result
+}
+
+// resolveInRootfs returns the host path of rel inside rootfs, following
+// symlinks with rootfs as their root so they can never leave it.
+func resolveInRootfs(rootfs string, rel string) (string, error) {
+	parts := strings.Split(strings.Trim(path.Clean("/"+rel), "/"), "/")
+	current := ""
+	for hops := 0; len(parts) > 0; {
+		part := parts[0]
+		parts = parts[1:]
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			current = path.Dir(current)
+			if current == "." || current == "/" {
+				current = ""
+			}
+			continue
+		}
+
+		next := path.Join(current, part)
+		fi, err := os.Lstat(filepath.Join(rootfs, next))
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		hops++
+		if hops > 40 {
+			return "", fmt.Errorf("too many symlinks: %s", rel)
+		}
+		target, err := os.Readlink(filepath.Join(rootfs, next))
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(target, "/") {
+			current = ""
+		}
+		parts = append(strings.Split(strings.Trim(target, "/"), "/"), parts...)
+	}
+	return filepath.Join(rootfs, current), nil
+}
@@ -3,7 +3,11 @@ package image
 import (
 	"condenser/internal/registry"
 	"condenser/internal/registry/dockerhub"
+	"condenser/internal/store/bcm"
+	"condenser/internal/store/bsm"
+	"condenser/internal/store/csm"
 	"condenser/internal/store/ilm"
+	"condenser/internal/store/psm"
 	"condenser/internal/utils"
 	"crypto/sha256"
 	"encoding/hex"
@@ -18,15 +22,25 @@ import (
 func NewImageService() *ImageService {
 	return &ImageService{
 		filesystemHandler: utils.NewFilesystemExecutor(),
+		commandFactory:    utils.NewCommandFactory(),
 		registryHandler:   dockerhub.NewRegistryDockerHub(),
 		ilmHandler:        ilm.NewIlmManager(ilm.NewIlmStore(utils.IlmStorePath)),
+		csmHandler:        csm.NewCsmManager(csm.NewCsmStore(utils.CsmStorePath)),
+		psmHandler:        psm.NewPsmManager(psm.NewPsmStore(utils.PsmStorePath)),
+		bsmHandler:        bsm.NewBsmManager(bsm.NewBsmStore(utils.BsmStorePath)),
+		bcmHandler:        bcm.NewBcmManager(bcm.NewBcmStore(utils.BcmStorePath)),
 	}
 }
 
 type ImageService struct {
 	filesystemHandler utils.FilesystemHandler
+	commandFactory    utils.CommandFactory
 	registryHandler   registry.RegistryHandler
 	ilmHandler        ilm.IlmHandler
+	csmHandler        csm.CsmHandler
+	psmHandler        psm.PsmHandler
+	bsmHandler        bsm.BsmHandler
+	bcmHandler        bcm.BcmHandler
 }
 
 type singleManifest struct {
@@ -45,39 +59,73 @@ type singleManifest struct {
 }
 
 func (s *ImageService) Pull(pullParameter ServicePullModel) error {
-	targetOs := pullParameter.Os
-	if targetOs == "" {
-		targetOs = utils.HostOs()
+	platform, explicit, err := pullPlatform(pullParameter)
+	if err != nil {
+		return err
 	}
-	targetArch := pullParameter.Arch
-	if targetArch == "" {
-		hostArch, err := utils.HostArch()
-		if err != nil {
-			return err
+
+	// reject tag-only references when digest pinning is enforced
+	if err := s.CheckReferencePolicy(pullParameter.Image); err != nil {
+		return err
+	}
+
+	var progress func(registry.PullProgress)
+	if pullParameter.Progress != nil {
+		progress = func(ev registry.PullProgress) {
+			pullParameter.Progress(PullProgress{
+				Id:      ev.Id,
+				Status:  ev.Status,
+				Current: ev.Current,
+				Total:   ev.Total,
+				Message: ev.Message,
+			})
 		}
-		targetArch = hostArch
 	}
 
 	// pull image
 	repository, reference, bundlePath, configPath, rootfsPath, err := s.registryHandler.PullImage(
 		registry.RegistryPullModel{
-			Image: pullParameter.Image,
-			Os:    targetOs,
-			Arch:  targetArch,
+			Image:                  pullParameter.Image,
+			Os:                     platform.Os,
+			Arch:                   platform.Arch,
+			Variant:                platform.Variant,
+			MaxConcurrentDownloads: pullParameter.MaxConcurrentDownloads,
+			Progress:               progress,
+			BundleInUse:            s.bundleSharedWith(pullParameter.Image),
 		},
 	)
 	if err != nil {
 		return err
 	}
 
-	// add ilm entry
-	if err := s.ilmHandler.StoreImage(
-		repository, reference,
-		bundlePath, configPath, rootfsPath,
-	); err != nil {
+	// record the digest the reference resolved to
+	manifestDigest, err := s.bundleManifestDigest(bundlePath)
+	if err != nil {
 		return err
 	}
 
+	// a single-platform image is what it is, whatever was asked for
+	stored, err := s.configPlatform(configPath)
+	if err != nil {
+		return err
+	}
+	if explicit && !platform.Matches(stored) {
+		_, _ = s.removeUnreferencedBundles([]string{bundlePath})
+		return fmt.Errorf("image %s is %s, not %s", pullParameter.Image, stored, platform)
+	}
+
+	// add ilm entry
+	dropped, err := s.ilmHandler.StoreImage(
+		repository, reference, stored.String(),
+		bundlePath, configPath, rootfsPath, manifestDigest,
+	)
+	if err != nil {
+		return err
+	}
+	// platforms of the digest the reference pointed to before
+	if _, err := s.removeUnreferencedBundles(dropped); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -87,29 +135,61 @@ func (s *ImageService) Remove(removeParameter ServiceRemoveModel) error {
 		return err
 	}
 
-	// remove directory
-	bundlePath, err := s.ilmHandler.GetBundlePath(repo, ref)
+	// a digest reference may resolve to the tag it was pulled as
+	info, err := s.ilmHandler.GetImageInfo(repo, ref)
 	if err != nil {
 		return err
 	}
-	if err := s.filesystemHandler.RemoveAll(bundlePath); err != nil {
+
+	// refuse removal while a container still uses the image
+	usage, err := s.collectImageUsage()
+	if err != nil {
 		return err
 	}
+	if u, ok := usageOf(usage, info); ok && len(u.containers) > 0 {
+		return &ImageInUseError{Image: imageKey(repo, info.Reference), Containers: u.containers}
+	}
 
-	// remove ilm entry
-	if err := s.ilmHandler.RemoveImage(repo, ref); err != nil {
+	if _, err := s.removeImageReference(repo, info.Reference); err != nil {
 		return err
 	}
-
 	return nil
 }
 
+// removeImageReference removes the ilm entry and the bundle directories of
+// all its platforms. tagged images share bundles, so they are kept while
+// other references remain.
+// returns the removed bundle path, or "" when the bundle is still shared.
+func (s *ImageService) removeImageReference(repo, ref string) (string, error) {
+	info, err := s.ilmHandler.GetImageInfo(repo, ref)
+	if err != nil {
+		return "", err
+	}
+
+	// remove ilm entry
+	if err := s.ilmHandler.RemoveImage(repo, info.Reference); err != nil {
+		return "", err
+	}
+
+	// remove directories
+	removed, err := s.removeUnreferencedBundles(info.BundlePaths)
+	if err != nil {
+		return "", err
+	}
+	if len(removed) == 0 {
+		return "", nil
+	}
+	return removed[0], nil
+}
+
 func (s *ImageService) parseImageRef(imageStr string) (repository, reference string, err error) {
 	// image string pattern
 	// - ubuntu 				-> library/ubuntu:latest
 	// - ubuntu:24.04 			-> library/ubuntu:24.04
 	// - library/ubuntu:24.04 	-> library/ubuntu:24.04
 	// - nginx@sha256:... 		-> library/nginx@sha256:...
+	// - nginx:1.27@sha256:... 	-> library/nginx@sha256:...
+	// - localhost:5000/app 	-> localhost:5000/app:latest
 
 	const defaultRegistry = "registry-1.docker.io"
 	var (
@@ -117,19 +197,27 @@ func (s *ImageService) parseImageRef(imageStr string) (repository, reference str
 		ref      string
 		registry string
 	)
-	if strings.Contains(imageStr, "@") {
-		parts := strings.SplitN(imageStr, "@", 2)
-		repo = parts[0]
-		ref = parts[1]
-	} else {
-		parts := strings.SplitN(imageStr, ":", 2)
-		repo = parts[0]
-		if len(parts) == 2 && parts[1] != "" {
-			ref = parts[1]
-		} else {
-			ref = "latest"
+	name := imageStr
+	if at := strings.Index(name, "@"); at >= 0 {
+		// name:tag@sha256:... pins the digest, the tag is informational only
+		ref = name[at+1:]
+		name = name[:at]
+		if !isDigestReference(ref) {
+			return "", "", fmt.Errorf("invalid digest reference: %s", ref)
+		}
+	}
+	lastColon := strings.LastIndex(name, ":")
+	lastSlash := strings.LastIndex(name, "/")
+	if lastColon > lastSlash {
+		if ref == "" {
+			ref = name[lastColon+1:]
 		}
+		name = name[:lastColon]
 	}
+	if ref == "" {
+		ref = "latest"
+	}
+	repo = name
 
 	if repo == "" {
 		return "", "", errors.New("empty repository")
@@ -175,9 +263,11 @@ func (s *ImageService) GetImageList() ([]ImageInfo, error) {
 	var imageInfo []ImageInfo
 	for _, il := range imageList {
 		imageInfo = append(imageInfo, ImageInfo{
-			Repository: il.Repository,
-			Reference:  il.Reference,
-			CreatedAt:  il.CreatedAt,
+			Repository:     il.Repository,
+			Reference:      il.Reference,
+			ManifestDigest: il.ManifestDigest,
+			Platforms:      il.Platforms,
+			CreatedAt:      il.CreatedAt,
 		})
 	}
 
@@ -210,7 +300,7 @@ func (s *ImageService) GetImageStatus(imageStr string) (ImageStatusInfo, error)
 	manifestBytes, err := s.readManifest(bundlePath)
 	if err != nil {
 		if s.filesystemHandler.IsNotExist(err) {
-			_ = s.ilmHandler.RemoveImage(repo, ref)
+			_ = s.ilmHandler.RemoveImage(repo, info.Reference)
 			_ = s.filesystemHandler.RemoveAll(bundlePath)
 			return ImageStatusInfo{}, fmt.Errorf("%s:%s not found", repo, ref)
 		}
@@ -231,9 +321,14 @@ func (s *ImageService) GetImageStatus(imageStr string) (ImageStatusInfo, error)
 	manifestDigest := sha256.Sum256(manifestBytes)
 	manifestDigestStr := "sha256:" + hex.EncodeToString(manifestDigest[:])
 
+	// prefer the digest the reference resolved to at pull time (the index for multi-arch images)
+	if info.ManifestDigest != "" {
+		manifestDigestStr = info.ManifestDigest
+	}
+
 	var repoTags []string
-	if !strings.HasPrefix(ref, "sha256:") {
-		repoTags = []string{repo + ":" + ref}
+	if !strings.HasPrefix(info.Reference, "sha256:") {
+		repoTags = []string{repo + ":" + info.Reference}
 	}
 
 	repoDigests := []string{repo + "@" + manifestDigestStr}
@@ -256,14 +351,19 @@ func (s *ImageService) GetImageFsInfo(imageStr string) (ImageFsInfo, error) {
 		return ImageFsInfo{}, err
 	}
 
-	bundlePath, err := s.ilmHandler.GetBundlePath(repo, ref)
+	info, err := s.ilmHandler.GetImageInfo(repo, ref)
 	if err != nil {
 		return ImageFsInfo{}, err
 	}
 
-	usedBytes, err := s.dirSize(bundlePath)
-	if err != nil {
-		return ImageFsInfo{}, err
+	// every platform of the reference takes space
+	var usedBytes int64
+	for _, bundlePath := range info.BundlePaths {
+		size, err := s.dirSize(bundlePath)
+		if err != nil {
+			return ImageFsInfo{}, err
+		}
+		usedBytes += size
 	}
 
 	return ImageFsInfo{
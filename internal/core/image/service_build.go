@@ -7,6 +7,8 @@ import (
 	"condenser/internal/store/csm"
 	"condenser/internal/store/ipam"
 	"condenser/internal/utils"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,7 +16,9 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 
 	"al.essio.dev/pkg/shellescape"
@@ -27,9 +31,27 @@ type buildState struct {
 
 	env        []string
 	workdir    string
+	user       string
 	cmd        []string
 	entrypoint []string
-	runScript  []string
+
+	os           string
+	architecture string
+	variant      string
+	labels       map[string]string
+	exposedPorts map[string]struct{}
+	volumes      map[string]struct{}
+	healthcheck  *ImageHealthConfig
+	shell        []string
+	stopSignal   string
+	history      []ImageHistoryObject
+
+	// ARG values declared in the current stage, never stored in the image
+	args map[string]string
+
+	// cache key of the last step and the content hash recorded by COPY/ADD
+	cacheKey    string
+	stepContent string
 }
 
 type buildInstruction struct {
@@ -42,76 +64,71 @@ func (s *ImageService) Build(buildParameter ServiceBuildModel) (string, error) {
 	if buildParameter.Image == "" {
 		return "", errors.New("image tag is required")
 	}
-	if buildParameter.ContextDir == "" {
-		return "", errors.New("context dir is required")
-	}
-	if buildParameter.DripfilePath == "" {
-		return "", errors.New("dripfile path is required")
+	if buildParameter.Context == nil {
+		return "", errors.New("build context is required")
 	}
 	bridge := buildParameter.Network
 	if bridge == "" {
 		bridge = "raind0"
 	}
+	if buildParameter.Hermetic {
+		// no veth, no address: RUN steps only see loopback
+		bridge = ""
+	}
+	platform := ""
+	if buildParameter.Platform != "" {
+		p, err := utils.ParsePlatform(buildParameter.Platform)
+		if err != nil {
+			return "", err
+		}
+		platform = p.String()
+	}
+	startedOn := time.Now()
 
-	// parse and validate dripfile
-	instructions, err := parseDripfile(buildParameter.DripfilePath)
+	// extract the context and parse the dripfile
+	bctx, err := prepareBuildContext(buildParameter.Context, buildParameter.Dripfile)
 	if err != nil {
 		return "", err
 	}
+	defer os.RemoveAll(bctx.dir)
 
-	state := buildState{
-		workdir: "/",
+	stages, globalArgs, err := splitBuildStages(bctx.instructions, buildParameter.BuildArgs)
+	if err != nil {
+		return "", err
 	}
-	defer func() {
-		if state.rootfsPath != "" {
-			_ = os.RemoveAll(state.rootfsPath)
-		}
-	}()
-
-	for _, ins := range instructions {
-		switch ins.op {
-		case "FROM":
-			if state.imageRepo != "" {
-				return "", errors.New("multi-stage build is not supported")
-			}
-			if err := s.applyFrom(&state, ins.args); err != nil {
-				return "", err
-			}
-		case "WORKDIR":
-			if err := s.applyWorkdir(&state, ins.args); err != nil {
-				return "", err
-			}
-		case "ENV":
-			if err := s.applyEnv(&state, ins.args); err != nil {
-				return "", err
-			}
-		case "COPY", "ADD":
-			if err := s.applyCopy(&state, buildParameter.ContextDir, ins.args); err != nil {
-				return "", err
-			}
-		case "RUN":
-			if err := s.applyRun(&state, ins.args); err != nil {
-				return "", err
-			}
-		case "CMD":
-			if err := s.applyCmd(&state, ins.args); err != nil {
-				return "", err
-			}
-		case "ENTRYPOINT":
-			if err := s.applyEntrypoint(&state, ins.args); err != nil {
-				return "", err
-			}
-		default:
-			return "", fmt.Errorf("unsupported instruction: %s", ins.op)
-		}
+	set := &buildStageSet{
+		stages:     stages,
+		built:      map[int]*buildState{},
+		buildArgs:  buildParameter.BuildArgs,
+		globalArgs: globalArgs,
+		noCache:    buildParameter.NoCache,
+		platform:   platform,
+		secrets:    buildParameter.Secrets,
+		hermetic:   buildParameter.Hermetic,
+		sourceDate: time.Unix(buildParameter.SourceDateEpoch, 0).UTC(),
+		resolved:   map[string]string{},
+		progress:   buildParameter.Progress,
+	}
+	// only the target stage is stored, every stage rootfs is temporary
+	defer set.cleanup()
+
+	target, err := set.targetIndex(buildParameter.Target)
+	if err != nil {
+		return "", err
 	}
-
-	if state.rootfsPath == "" {
-		return "", errors.New("missing FROM instruction")
+	required := set.requiredStages(target)
+	for i := range required {
+		set.totalSteps += len(set.stages[i].instructions)
 	}
-
-	if len(state.runScript) > 0 {
-		if err := s.runCommandInContainer(&state, bridge, state.runScript); err != nil {
+	for i := 0; i <= target; i++ {
+		if !required[i] {
+			continue
+		}
+		state, err := s.buildStage(set, i, bctx.dir, bridge)
+		if state != nil {
+			set.built[i] = state
+		}
+		if err != nil {
 			return "", err
 		}
 	}
@@ -121,13 +138,92 @@ func (s *ImageService) Build(buildParameter ServiceBuildModel) (string, error) {
 		return "", err
 	}
 
-	if err := s.storeBuiltImage(imageRepo, imageRef, state); err != nil {
+	if err := s.storeBuiltImage(imageRepo, imageRef, *set.built[target], set); err != nil {
 		return "", err
 	}
+	if err := s.writeBuildProvenance(imageRepo, imageRef, set, bctx, buildParameter, startedOn); err != nil {
+		return "", fmt.Errorf("provenance: %w", err)
+	}
 	return imageRepo + ":" + imageRef, nil
 }
 
-func (s *ImageService) applyFrom(state *buildState, image string) error {
+// buildStage runs the instructions of one stage. Every RUN is its own
+// snapshot step, reused from the build cache when its key is known.
+func (s *ImageService) buildStage(set *buildStageSet, index int, contextDir string, bridge string) (*buildState, error) {
+	stage := set.stages[index]
+	state := &buildState{
+		workdir: "/",
+		args:    map[string]string{},
+	}
+
+	for _, ins := range stage.instructions {
+		set.step++
+		set.emit(BuildProgress{
+			Type:    BuildProgressStep,
+			Step:    set.step,
+			Total:   set.totalSteps,
+			Message: fmt.Sprintf("Step %d/%d : %s %s", set.step, set.totalSteps, ins.op, ins.args),
+		})
+		args := ins.args
+		if expandableInstructions[ins.op] {
+			args = expandBuildVars(args, state.lookupVar)
+		}
+
+		var err error
+		switch ins.op {
+		case "FROM":
+			if dep, ok := set.lookup(stage.base, index); ok {
+				err = s.applyFromStage(state, set.built[dep])
+			} else {
+				err = s.applyFrom(state, set, stage.base, set.stagePlatform(index))
+			}
+		case "ARG":
+			err = applyArg(state, set, args)
+		case "WORKDIR":
+			err = s.applyWorkdir(state, args)
+		case "ENV":
+			err = s.applyEnv(state, args)
+		case "COPY":
+			err = s.applyCopy(state, set, index, contextDir, args)
+		case "ADD":
+			err = s.applyAdd(state, set, contextDir, args)
+		case "RUN":
+			err = s.applyRun(state, set, bridge, args)
+		case "CMD":
+			err = s.applyCmd(state, args)
+		case "ENTRYPOINT":
+			err = s.applyEntrypoint(state, args)
+		case "LABEL":
+			err = s.applyLabel(state, args)
+		case "EXPOSE":
+			err = s.applyExpose(state, args)
+		case "USER":
+			err = s.applyUser(state, args)
+		case "VOLUME":
+			err = s.applyVolume(state, args)
+		case "HEALTHCHECK":
+			err = s.applyHealthcheck(state, args)
+		case "SHELL":
+			err = s.applyShell(state, args)
+		case "STOPSIGNAL":
+			err = s.applyStopSignal(state, args)
+		default:
+			err = fmt.Errorf("unsupported instruction: %s", ins.op)
+		}
+		if err != nil {
+			return state, fmt.Errorf("%s %s: %w", ins.op, ins.args, err)
+		}
+		if ins.op != "FROM" && ins.op != "RUN" {
+			// FROM sets the base key and RUN chains its own
+			state.cacheKey = stepCacheKey(state.cacheKey, ins.op, args+"\n"+state.stepContent)
+		}
+		state.stepContent = ""
+		appendBuildHistory(state, ins, set.now())
+	}
+	return state, nil
+}
+
+func (s *ImageService) applyFrom(state *buildState, set *buildStageSet, image string, platform string) error {
 	image = strings.TrimSpace(strings.Fields(image)[0])
 	if image == "" {
 		return errors.New("FROM requires image")
@@ -138,31 +234,25 @@ func (s *ImageService) applyFrom(state *buildState, image string) error {
 		return err
 	}
 
-	if !s.ilmHandler.IsImageExist(imageRepo, imageRef) {
-		if err := s.Pull(ServicePullModel{Image: image}); err != nil {
-			return err
-		}
-	}
-
-	configPath, err := s.ilmHandler.GetConfigPath(imageRepo, imageRef)
-	if err != nil {
-		return err
-	}
-	imageConfig, err := s.GetImageConfig(configPath)
+	bundle, err := s.resolveImagePlatform(image, imageRepo, imageRef, platform)
 	if err != nil {
 		return err
 	}
+	_ = s.ilmHandler.TouchImage(imageRepo, imageRef)
 
-	baseRootfs, err := s.ilmHandler.GetRootfsPath(imageRepo, imageRef)
+	imageConfig, err := s.GetImageConfig(bundle.ConfigPath)
 	if err != nil {
 		return err
 	}
 
+	baseRootfs := bundle.RootfsPath
+
 	tmpRootfs, err := os.MkdirTemp("", "raind-build-rootfs-")
 	if err != nil {
 		return err
 	}
 	if err := copyDir(baseRootfs, tmpRootfs); err != nil {
+		_ = os.RemoveAll(tmpRootfs)
 		return err
 	}
 
@@ -174,8 +264,25 @@ func (s *ImageService) applyFrom(state *buildState, image string) error {
 	if state.workdir == "" {
 		state.workdir = "/"
 	}
+	state.user = imageConfig.Config.User
 	state.cmd = cloneSlice(imageConfig.Config.Cmd)
 	state.entrypoint = cloneSlice(imageConfig.Config.Entrypoint)
+	state.os = imageConfig.Os
+	state.architecture = imageConfig.Architecture
+	state.variant = imageConfig.Variant
+	state.labels = cloneStringMap(imageConfig.Config.Labels)
+	state.exposedPorts = clonePortSet(imageConfig.Config.ExposedPorts)
+	state.volumes = cloneVolumeSet(imageConfig.Config.Volumes)
+	state.healthcheck = cloneHealthConfig(imageConfig.Config.Healthcheck)
+	state.shell = cloneSlice(imageConfig.Config.Shell)
+	state.stopSignal = imageConfig.Config.StopSignal
+	state.history = append([]ImageHistoryObject{}, imageConfig.History...)
+	// the digest pins the content the following steps are cached on
+	digest, err := s.resolveImage(set, imageRepo, imageRef, bundle)
+	if err != nil {
+		return err
+	}
+	state.cacheKey = stepCacheKey("", "FROM", imageRepo+"@"+digest+" "+bundle.Platform)
 	return nil
 }
 
@@ -193,43 +300,19 @@ func (s *ImageService) applyWorkdir(state *buildState, arg string) error {
 }
 
 func (s *ImageService) applyEnv(state *buildState, arg string) error {
-	arg = strings.TrimSpace(arg)
-	if arg == "" {
-		return errors.New("ENV requires key=value")
+	pairs, err := parseKeyValueArgs(arg)
+	if err != nil {
+		return fmt.Errorf("ENV: %w", err)
 	}
-	parts := strings.Fields(arg)
-	for i := 0; i < len(parts); i++ {
-		if strings.Contains(parts[i], "=") {
-			kv := strings.SplitN(parts[i], "=", 2)
-			state.env = setEnvVar(state.env, kv[0], kv[1])
-			continue
-		}
-		if i+1 >= len(parts) {
-			return errors.New("ENV requires key value")
-		}
-		state.env = setEnvVar(state.env, parts[i], parts[i+1])
-		i++
+	for _, kv := range pairs {
+		state.env = setEnvVar(state.env, kv[0], kv[1])
 	}
 	return nil
 }
 
-func (s *ImageService) applyCopy(state *buildState, contextDir string, arg string) error {
-	parts := strings.Fields(arg)
-	if len(parts) < 2 {
-		return errors.New("COPY/ADD requires src and dest")
-	}
-	if len(parts) > 2 {
-		return errors.New("COPY/ADD multiple sources not supported")
-	}
-	src := parts[0]
-	dst := parts[1]
-	if src == "" || dst == "" {
-		return errors.New("COPY/ADD requires src and dest")
-	}
-	srcPath, err := safeJoin(contextDir, src)
-	if err != nil {
-		return err
-	}
+// buildDestPath maps a COPY/ADD destination to the stage rootfs. A trailing
+// slash marks a directory, which is created.
+func buildDestPath(state *buildState, dst string) (string, error) {
 	dstAbs := dst
 	if !filepath.IsAbs(dstAbs) {
 		dstAbs = filepath.Join(state.workdir, dstAbs)
@@ -237,25 +320,50 @@ func (s *ImageService) applyCopy(state *buildState, contextDir string, arg strin
 	dstPath := filepath.Join(state.rootfsPath, strings.TrimPrefix(filepath.Clean(dstAbs), "/"))
 	if strings.HasSuffix(dst, "/") {
 		if err := os.MkdirAll(dstPath, 0o755); err != nil {
-			return err
+			return "", err
 		}
 	}
+	return dstPath, nil
+}
 
+func copyIntoRootfs(srcPath string, dstPath string) error {
 	info, err := os.Lstat(srcPath)
 	if err != nil {
 		return err
 	}
+	target := copyTarget(srcPath, info, dstPath)
 	if info.IsDir() {
-		return copyDir(srcPath, dstPath)
+		return copyDir(srcPath, target)
+	}
+	return copyFile(srcPath, target, info.Mode())
+}
+
+// copyTarget is where copyIntoRootfs puts srcPath: the content of a
+// directory is merged into dstPath, a file lands inside an existing directory.
+func copyTarget(srcPath string, info fs.FileInfo, dstPath string) string {
+	if info.IsDir() {
+		return dstPath
 	}
 	if dstInfo, err := os.Lstat(dstPath); err == nil && dstInfo.IsDir() {
-		dstPath = filepath.Join(dstPath, filepath.Base(srcPath))
+		return filepath.Join(dstPath, filepath.Base(srcPath))
 	}
-	return copyFile(srcPath, dstPath, info.Mode())
+	return dstPath
 }
 
-func (s *ImageService) applyRun(state *buildState, arg string) error {
-	runLine, err := runLineFromArg(arg)
+func (s *ImageService) applyRun(state *buildState, set *buildStageSet, bridge string, arg string) error {
+	mountSpecs, command, err := splitRunFlags(arg)
+	if err != nil {
+		return err
+	}
+	var secrets []runSecret
+	for _, spec := range mountSpecs {
+		m, err := parseRunSecret(spec, set.secrets)
+		if err != nil {
+			return fmt.Errorf("--mount=%s: %w", spec, err)
+		}
+		secrets = append(secrets, m)
+	}
+	runLine, err := runLineFromArg(command, state.shell)
 	if err != nil {
 		return err
 	}
@@ -265,12 +373,54 @@ func (s *ImageService) applyRun(state *buildState, arg string) error {
 			return err
 		}
 	}
-	state.runScript = append(state.runScript, renderRunBlock(state.env, state.workdir, runLine)...)
+	script := renderRunBlock(state.runEnv(), state.workdir, runLine)
+
+	// the rendered script carries env, args and workdir, so they are part of the key
+	keyText := strings.Join(script, "\n")
+	if set.hermetic {
+		// a step without network may produce something else
+		keyText = "hermetic\n" + keyText
+	}
+	for _, m := range secrets {
+		keyText += "\n" + m.spec()
+	}
+	step := buildStep{
+		key:       stepCacheKey(state.cacheKey, "RUN", keyText),
+		parent:    state.cacheKey,
+		createdBy: "RUN " + strings.TrimSpace(arg),
+	}
+	if !set.noCache {
+		if snapshot, ok := s.lookupBuildCache(step.key); ok {
+			if err := applyOverlayUpper(snapshot, state.rootfsPath); err != nil {
+				return err
+			}
+			set.emit(BuildProgress{Type: BuildProgressCached, Message: "using cache " + strings.TrimPrefix(step.key, "sha256:")[:12]})
+			state.cacheKey = step.key
+			return nil
+		}
+	}
+	// the log is only followed when somebody listens
+	var output func(string)
+	if set.progress != nil {
+		output = set.output
+	}
+	if output != nil && len(secrets) > 0 {
+		forward := output
+		output = func(chunk string) { forward(redactSecrets(chunk, secrets)) }
+	}
+	if err := s.runCommandInContainer(state, bridge, script, secrets, step, output); err != nil {
+		if msg := redactSecrets(err.Error(), secrets); msg != err.Error() {
+			// the failure carries the tail of the step log
+			return errors.New(msg)
+		}
+		return err
+	}
+	state.cacheKey = step.key
 	return nil
 }
 
 func (s *ImageService) applyCmd(state *buildState, arg string) error {
-	cmd, err := parseShellOrExec(arg)
+	cmd, err := parseShellOrExec(arg, state.shell)
 	if err != nil {
 		return err
 	}
@@ -279,7 +429,7 @@ func (s *ImageService) applyCmd(state *buildState, arg string) error {
 }
 
 func (s *ImageService) applyEntrypoint(state *buildState, arg string) error {
-	entry, err := parseShellOrExec(arg)
+	entry, err := parseShellOrExec(arg, state.shell)
 	if err != nil {
 		return err
 	}
@@ -287,7 +437,7 @@ func (s *ImageService) applyEntrypoint(state *buildState, arg string) error {
 	return nil
 }
 
-func (s *ImageService) runCommandInContainer(state *buildState, bridge string, scriptLines []string) error {
+func (s *ImageService) runCommandInContainer(state *buildState, bridge string, scriptLines []string, secrets []runSecret, step buildStep, output func(string)) error {
 	containerId := "build-" + utils.NewUlid()[:12]
 	containerDir := filepath.Join(utils.ContainerRootDir, containerId)
 	upperDir := filepath.Join(containerDir, "diff")
@@ -316,11 +466,30 @@ func (s *ImageService) runCommandInContainer(state *buildState, bridge string, s
 		}
 	}()
 
-	containerGateway, containerAddr, err := allocateBuildAddress(ipamHandler, containerId, bridge)
-	if err != nil {
-		return err
+	// an empty bridge runs the step without network: the container gets its
+	// own network namespace but no veth and no address
+	var (
+		containerGateway string
+		containerAddr    string
+		hostInterface    string
+		containerIf      string
+		containerDns     []string
+		err              error
+	)
+	if bridge != "" {
+		containerGateway, containerAddr, err = allocateBuildAddress(ipamHandler, containerId, bridge)
+		if err != nil {
+			return err
+		}
+		rollback.releaseAddr = true
+
+		hostInterface, err = ipamHandler.GetDefaultInterface()
+		if err != nil {
+			return err
+		}
+		containerIf = buildVethName(containerId)
+		containerDns = []string{"8.8.8.8"}
 	}
-	rollback.releaseAddr = true
 
 	if err := setupBuildContainerDirectory(filesystemHandler, containerDir); err != nil {
 		return err
@@ -335,11 +504,6 @@ func (s *ImageService) runCommandInContainer(state *buildState, bridge string, s
 	}
 	rollback.cgroup = true
 
-	hostInterface, err := ipamHandler.GetDefaultInterface()
-	if err != nil {
-		return err
-	}
-
 	// hook
 	hookAddr, err := ipamHandler.GetDefaultInterfaceAddr()
 	if err != nil {
@@ -417,6 +581,13 @@ func (s *ImageService) runCommandInContainer(state *buildState, bridge string, s
 		return err
 	}
 
+	// secrets live next to the upper dir, never in it, and are removed with the container dir
+	secretDir := filepath.Join(containerDir, "secrets")
+	secretMounts, mountPoints, err := prepareRunSecrets(secrets, secretDir, state.rootfsPath, upperDir)
+	if err != nil {
+		return err
+	}
+
 	if err := csmHandler.StoreContainer(
 		containerId,
 		"creating",
@@ -441,14 +612,14 @@ func (s *ImageService) runCommandInContainer(state *buildState, bridge string, s
 		Namespace: []string{"mount", "network", "uts", "pid", "ipc", "user", "cgroup"},
 		Hostname:  containerId,
 		Env:       cloneSlice(state.env),
-		Mount:     []string{},
+		Mount:     secretMounts,
 
 		HostInterface:          hostInterface,
 		BridgeInterface:        bridge,
-		ContainerInterface:     buildVethName(containerId),
+		ContainerInterface:     containerIf,
 		ContainerInterfaceAddr: containerAddr,
 		ContainerGateway:       containerGateway,
-		ContainerDns:           []string{"8.8.8.8"},
+		ContainerDns:           containerDns,
 
 		ImageLayer: []string{state.rootfsPath},
 		UpperDir:   upperDir,
@@ -479,7 +650,9 @@ func (s *ImageService) runCommandInContainer(state *buildState, bridge string, s
 		return err
 	}
 	// RUN is executed as the container's init process.
+	stopFollow := followBuildLog(filepath.Join(containerDir, "logs", "console.log"), output)
 	info, err := waitBuildContainerStopped(csmHandler, containerId, 10*time.Minute)
+	stopFollow()
 	if err != nil {
 		return err
 	}
@@ -490,8 +663,23 @@ func (s *ImageService) runCommandInContainer(state *buildState, bridge string, s
 	if err := runtimeHandler.Delete(runtime.DeleteModel{ContainerId: containerId}); err != nil {
 		return err
 	}
+	if len(secrets) > 0 {
+		_ = filesystemHandler.RemoveAll(secretDir)
+		if err := removeMountPoints(mountPoints); err != nil {
+			return err
+		}
+		// the step could have copied a secret anywhere, it must not reach the cache or the image
+		if err := verifyNoSecrets(upperDir, secrets); err != nil {
+			return err
+		}
+	}
 
-	if err := applyOverlayUpper(upperDir, state.rootfsPath); err != nil {
+	// keep the step as a snapshot, the build goes on without cache if that fails
+	snapshotDir := upperDir
+	if snapshot, _ := s.storeBuildCache(step, upperDir); snapshot != "" {
+		snapshotDir = snapshot
+	}
+	if err := applyOverlayUpper(snapshotDir, state.rootfsPath); err != nil {
 		return err
 	}
 
@@ -541,7 +729,10 @@ func setupBuildEtcFiles(filesystemHandler utils.FilesystemHandler, containerId s
 	etcDir := filepath.Join(utils.ContainerRootDir, containerId, "etc")
 
 	hostsPath := filepath.Join(etcDir, "hosts")
-	hostsData := fmt.Sprintf("127.0.0.1 localhost\n%s %s\n", strings.SplitN(containerAddr, "/", 2)[0], containerId)
+	hostsData := "127.0.0.1 localhost\n"
+	if containerAddr != "" {
+		hostsData += fmt.Sprintf("%s %s\n", strings.SplitN(containerAddr, "/", 2)[0], containerId)
+	}
 	if err := filesystemHandler.WriteFile(hostsPath, []byte(hostsData), 0o644); err != nil {
 		return err
 	}
@@ -553,7 +744,10 @@ func setupBuildEtcFiles(filesystemHandler utils.FilesystemHandler, containerId s
 	}
 
 	resolvPath := filepath.Join(etcDir, "resolv.conf")
-	resolvData := "nameserver " + containerGateway + "\n"
+	resolvData := ""
+	if containerGateway != "" {
+		resolvData = "nameserver " + containerGateway + "\n"
+	}
 	if err := filesystemHandler.WriteFile(resolvPath, []byte(resolvData), 0o644); err != nil {
 		return err
 	}
@@ -609,7 +803,7 @@ func parseDripfile(path string) ([]buildInstruction, error) {
 	return instructions, nil
 }
 
-func parseShellOrExec(arg string) ([]string, error) {
+func parseShellOrExec(arg string, shell []string) ([]string, error) {
 	arg = strings.TrimSpace(arg)
 	if arg == "" {
 		return nil, errors.New("command is empty")
@@ -621,7 +815,10 @@ func parseShellOrExec(arg string) ([]string, error) {
 		}
 		return arr, nil
 	}
-	return []string{"/bin/sh", "-c", arg}, nil
+	if len(shell) == 0 {
+		shell = defaultBuildShell
+	}
+	return append(cloneSlice(shell), arg), nil
 }
 
 func buildCommand(entrypoint, cmd []string) string {
@@ -636,6 +833,50 @@ func buildCommand(entrypoint, cmd []string) string {
 	return strings.Join(quoted, " ")
 }
 
+func cloneStringMap(in map[string]string) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+func clonePortSet(in map[string]struct{}) map[string]struct{} {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]struct{}, len(in))
+	for k := range in {
+		out[k] = struct{}{}
+	}
+	return out
+}
+
+// appendBuildHistory records a Dripfile instruction as an image config history entry.
+func appendBuildHistory(state *buildState, ins buildInstruction, created time.Time) {
+	createdBy := strings.TrimSpace(ins.op + " " + ins.args)
+	emptyLayer := true
+	switch ins.op {
+	case "FROM":
+		// base image history is inherited as is
+		return
+	case "COPY", "ADD", "RUN", "WORKDIR":
+		emptyLayer = false
+	case "ARG":
+		// build arg values may be secrets, they stay out of the image
+		return
+	}
+	state.history = append(state.history, ImageHistoryObject{
+		Created:    created.UTC().Format(time.RFC3339Nano),
+		CreatedBy:  createdBy,
+		Comment:    "raind.dripfile",
+		EmptyLayer: emptyLayer,
+	})
+}
+
 func cloneSlice(in []string) []string {
 	if len(in) == 0 {
 		return nil
@@ -679,7 +920,9 @@ func buildVethName(containerId string) string {
 	return name
 }
 
-func runLineFromArg(arg string) (string, error) {
+// runLineFromArg renders RUN as a line of the build script, which is run
+// by /bin/sh. Shell form goes through SHELL when one is set.
+func runLineFromArg(arg string, shell []string) (string, error) {
 	arg = strings.TrimSpace(arg)
 	if arg == "" {
 		return "", errors.New("RUN requires command")
@@ -695,6 +938,9 @@ func runLineFromArg(arg string) (string, error) {
 		}
 		return strings.Join(quoted, " "), nil
 	}
+	if len(shell) > 0 && !slices.Equal(shell, defaultBuildShell) {
+		return buildCommand(shell, []string{arg}), nil
+	}
 	return arg, nil
 }
 
@@ -769,6 +1015,61 @@ func waitBuildContainerStopped(csmHandler csm.CsmHandler, containerId string, ti
 	return csm.ContainerInfo{}, fmt.Errorf("timeout waiting for build container to stop: %s", containerId)
 }
 
+// followBuildLog forwards what a build container writes to its log while it
+// runs. The returned stop function forwards the rest and waits for the reader.
+func followBuildLog(logPath string, output func(string)) (stop func()) {
+	if output == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		var (
+			f   *os.File
+			buf = make([]byte, 32*1024)
+		)
+		defer func() {
+			if f != nil {
+				f.Close()
+			}
+		}()
+		for {
+			stopping := false
+			select {
+			case <-done:
+				stopping = true
+			default:
+			}
+			if f == nil {
+				f, _ = os.Open(logPath)
+			}
+			if f != nil {
+				for {
+					n, err := f.Read(buf)
+					if n > 0 {
+						output(string(buf[:n]))
+					}
+					if err != nil || n == 0 {
+						break
+					}
+				}
+			}
+			if stopping {
+				return
+			}
+			select {
+			case <-done:
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
 func buildRunFailedError(info csm.ContainerInfo, containerDir string) error {
 	msg := fmt.Sprintf("RUN command failed: exit_code=%d", info.ExitCode)
 	if strings.TrimSpace(info.Reason) != "" {
@@ -857,27 +1158,54 @@ func copyDir(src string, dst string) error {
 			return err
 		}
 		mode := info.Mode()
-		if mode.IsDir() {
-			return os.MkdirAll(target, mode.Perm())
-		}
-		if mode&os.ModeSymlink != 0 {
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, mode.Perm()); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
 			link, err := os.Readlink(path)
 			if err != nil {
 				return err
 			}
-			return os.Symlink(link, target)
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			if err := copyFile(path, target, mode); err != nil {
+				return err
+			}
+		default:
+			return nil
 		}
-		if mode.IsRegular() {
-			return copyFile(path, target, mode)
+		return copyMetadata(target, info)
+	})
+}
+
+// copyMetadata gives target the owner and mode bits of info, so files
+// owned by a non-root user keep their owner through the build.
+func copyMetadata(target string, info fs.FileInfo) error {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		if err := os.Lchown(target, int(st.Uid), int(st.Gid)); err != nil {
+			return err
 		}
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
 		return nil
-	})
+	}
+	return os.Chmod(target, info.Mode()&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky))
 }
 
 func copyFile(src string, dst string, mode fs.FileMode) error {
 	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
 		return err
 	}
+	// never write through a symlink left at dst
+	if fi, err := os.Lstat(dst); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
 	in, err := os.Open(src)
 	if err != nil {
 		return err
@@ -935,21 +1263,28 @@ func applyOverlayUpper(upperDir string, rootfs string) error {
 		}
 		target := filepath.Join(rootfs, rel)
 		mode := info.Mode()
-		if mode.IsDir() {
-			return os.MkdirAll(target, mode.Perm())
-		}
-		if mode&os.ModeSymlink != 0 {
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, mode.Perm()); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
 			link, err := os.Readlink(path)
 			if err != nil {
 				return err
 			}
 			_ = os.RemoveAll(target)
-			return os.Symlink(link, target)
-		}
-		if mode.IsRegular() {
-			return copyFile(path, target, mode)
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			if err := copyFile(path, target, mode); err != nil {
+				return err
+			}
+		default:
+			return nil
 		}
-		return nil
+		return copyMetadata(target, info)
 	})
 }
 
@@ -963,7 +1298,7 @@ func copyBuildConfig(containerDir, containerId string) error {
 	return os.WriteFile(debugDst, b, 0o644)
 }
 
-func (s *ImageService) storeBuiltImage(imageRepo, imageRef string, state buildState) error {
+func (s *ImageService) storeBuiltImage(imageRepo, imageRef string, state buildState, set *buildStageSet) error {
 	repoName := imageRepo
 	if strings.Contains(imageRepo, "/") {
 		parts := strings.Split(imageRepo, "/")
@@ -972,10 +1307,22 @@ func (s *ImageService) storeBuiltImage(imageRepo, imageRef string, state buildSt
 	repoOut := filepath.Join(utils.LayerRootDir, repoName, imageRef)
 
 	if s.ilmHandler.IsImageExist(imageRepo, imageRef) {
+		info, err := s.ilmHandler.GetImageInfo(imageRepo, imageRef)
+		if err != nil {
+			return err
+		}
 		if err := s.ilmHandler.RemoveImage(imageRepo, imageRef); err != nil {
 			return err
 		}
-		_ = os.RemoveAll(repoOut)
+		// keep the bundles another tag still points to
+		if _, err := s.removeUnreferencedBundles(info.BundlePaths); err != nil {
+			return err
+		}
+	}
+	// a tag alias may share the directory without this tag, so never
+	// build into one that is still there
+	if _, err := os.Stat(repoOut); err == nil {
+		repoOut = repoOut + "-" + utils.NewUlid()[:8]
 	}
 
 	if err := os.MkdirAll(repoOut, 0o755); err != nil {
@@ -985,14 +1332,41 @@ func (s *ImageService) storeBuiltImage(imageRepo, imageRef string, state buildSt
 	if err := copyDir(state.rootfsPath, rootfsPath); err != nil {
 		return err
 	}
+	if set.hermetic {
+		if err := normalizeTimestamps(rootfsPath, set.sourceDate); err != nil {
+			return err
+		}
+	}
 
+	imageOs := state.os
+	if imageOs == "" {
+		imageOs = utils.HostOs()
+	}
+	imageArch := state.architecture
+	if imageArch == "" {
+		if hostArch, err := utils.HostArch(); err == nil {
+			imageArch = hostArch
+		}
+	}
 	config := ImageConfigFile{
+		Created:      set.now().UTC().Format(time.RFC3339Nano),
+		Architecture: imageArch,
+		Os:           imageOs,
+		Variant:      state.variant,
 		Config: ImageConfigObject{
-			Env:        cloneSlice(state.env),
-			Cmd:        cloneSlice(state.cmd),
-			Entrypoint: cloneSlice(state.entrypoint),
-			WorkingDir: state.workdir,
+			Env:          cloneSlice(state.env),
+			Cmd:          cloneSlice(state.cmd),
+			Entrypoint:   cloneSlice(state.entrypoint),
+			WorkingDir:   state.workdir,
+			User:         state.user,
+			Labels:       cloneStringMap(state.labels),
+			ExposedPorts: clonePortSet(state.exposedPorts),
+			Volumes:      cloneVolumeSet(state.volumes),
+			Healthcheck:  cloneHealthConfig(state.healthcheck),
+			Shell:        cloneSlice(state.shell),
+			StopSignal:   state.stopSignal,
 		},
+		History: state.history,
 	}
 	configPath := filepath.Join(repoOut, "config.json")
 	b, err := json.Marshal(config)
@@ -1003,14 +1377,18 @@ func (s *ImageService) storeBuiltImage(imageRepo, imageRef string, state buildSt
 		return err
 	}
 
-	if err := s.ilmHandler.StoreImage(imageRepo, imageRef, repoOut, configPath, rootfsPath); err != nil {
+	platform := utils.Platform{Os: imageOs, Arch: imageArch, Variant: state.variant}
+	if _, err := s.ilmHandler.StoreImage(imageRepo, imageRef, platform.String(), repoOut, configPath, rootfsPath, ""); err != nil {
 		return err
 	}
-	return nil
+	// a built image has no manifest, it is pinned by its config digest
+	configDigest := sha256.Sum256(b)
+	return s.ilmHandler.SetConfigDigest(imageRepo, imageRef, "sha256:"+hex.EncodeToString(configDigest[:]))
 }
 
-// extractTarToDir extracts a tar stream into a directory and prevents path traversal.
-func ExtractTarToDir(r io.Reader, dst string) error {
+// extractTar extracts a tar stream into dst. Entries may not leave dst,
+// neither by their name nor through a symlink already present in dst.
+func extractTar(r io.Reader, dst string, preserveOwner bool) error {
 	tr := tar.NewReader(r)
 	for {
 		hdr, err := tr.Next()
@@ -1027,6 +1405,16 @@ func ExtractTarToDir(r io.Reader, dst string) error {
 		if err != nil {
 			return err
 		}
+		if err := ensureWithinDir(dst, target); err != nil {
+			return err
+		}
+		// never write through an existing symlink
+		if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+
 		switch hdr.Typeflag {
 		case tar.TypeDir:
 			if err := os.MkdirAll(target, os.FileMode(hdr.Mode)); err != nil {
@@ -1054,8 +1442,57 @@ func ExtractTarToDir(r io.Reader, dst string) error {
 			if err := os.Symlink(hdr.Linkname, target); err != nil {
 				return err
 			}
+		case tar.TypeLink:
+			linkTarget, err := safeJoin(dst, hdr.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Link(linkTarget, target); err != nil {
+				return err
+			}
 		default:
 			// ignore other types
+			continue
+		}
+		if preserveOwner {
+			if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ensureWithinDir checks that the deepest existing parent of target
+// still resolves inside dir once symlinks are followed.
+func ensureWithinDir(dir string, target string) error {
+	if filepath.Clean(target) == filepath.Clean(dir) {
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	parent := filepath.Dir(target)
+	for {
+		if _, err := os.Lstat(parent); err == nil {
+			break
+		}
+		next := filepath.Dir(parent)
+		if next == parent {
+			break
 		}
+		parent = next
 	}
+	resolved, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return err
+	}
+	if resolved != root && !strings.HasPrefix(resolved, root+string(os.PathSeparator)) {
+		return fmt.Errorf("invalid path: %s escapes %s", target, dir)
+	}
+	return nil
 }
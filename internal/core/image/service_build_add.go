@@ -0,0 +1,185 @@
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const addDownloadTimeout = 10 * time.Minute
+
+// applyAdd implements ADD. A remote http(s) source is downloaded as is,
+// a local tar archive (plain, gzip or bzip2) is extracted into the
+// destination directory, anything else is copied like COPY.
+func (s *ImageService) applyAdd(state *buildState, set *buildStageSet, contextDir string, arg string) error {
+	flags, parts := splitInstructionFlags(arg)
+	for k := range flags {
+		return fmt.Errorf("unsupported ADD flag: --%s", k)
+	}
+	if len(parts) < 2 {
+		return errors.New("COPY/ADD requires src and dest")
+	}
+	if len(parts) > 2 {
+		return errors.New("COPY/ADD multiple sources not supported")
+	}
+	src := parts[0]
+	dst := parts[1]
+
+	if isRemoteSource(src) {
+		if set.hermetic {
+			return fmt.Errorf("remote source %s is not allowed in a hermetic build", src)
+		}
+		return addRemote(state, src, dst)
+	}
+
+	srcPath, err := safeJoin(contextDir, src)
+	if err != nil {
+		return err
+	}
+	if state.stepContent, err = hashBuildSource(srcPath); err != nil {
+		return err
+	}
+	dstPath, err := buildDestPath(state, dst)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	if info.Mode().IsRegular() {
+		extracted, err := extractArchiveIfTar(srcPath, dstPath)
+		if err != nil {
+			return err
+		}
+		if extracted {
+			return nil
+		}
+	}
+	return copyIntoRootfs(srcPath, dstPath)
+}
+
+func isRemoteSource(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
+
+// addRemote downloads src into the rootfs. A destination ending in "/"
+// receives the file under the last element of the URL path.
+func addRemote(state *buildState, src string, dst string) error {
+	u, err := url.Parse(src)
+	if err != nil {
+		return fmt.Errorf("invalid ADD url: %w", err)
+	}
+	if strings.HasSuffix(dst, "/") {
+		name := path.Base(u.Path)
+		if name == "" || name == "/" || name == "." {
+			return fmt.Errorf("cannot determine file name from %s, specify a destination file", src)
+		}
+		dst += name
+	}
+	dstPath, err := buildDestPath(state, dst)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: addDownloadTimeout}
+	resp, err := client.Get(src)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s: %s", src, resp.Status)
+	}
+
+	tmp := dstPath + ".download"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	// the downloaded content, not the url, decides whether later steps are cached
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	state.stepContent = "sha256:" + hex.EncodeToString(h.Sum(nil))
+	return os.Rename(tmp, dstPath)
+}
+
+// extractArchiveIfTar extracts srcPath into dstDir when it is a tar
+// archive and reports whether it did.
+func extractArchiveIfTar(srcPath string, dstDir string) (bool, error) {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	r, err := decompressedReader(f)
+	if err != nil {
+		return false, nil
+	}
+	br := bufio.NewReaderSize(r, 1024)
+	if !looksLikeTar(br) {
+		return false, nil
+	}
+	if err := os.MkdirAll(dstDir, 0o755); err != nil {
+		return false, err
+	}
+	if err := extractTar(br, dstDir, true); err != nil {
+		return false, fmt.Errorf("extract %s: %w", filepath.Base(srcPath), err)
+	}
+	return true, nil
+}
+
+// decompressedReader detects gzip and bzip2 by their magic bytes.
+func decompressedReader(f *os.File) (io.Reader, error) {
+	magic := make([]byte, 3)
+	n, _ := io.ReadFull(f, magic)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	magic = magic[:n]
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(f)
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return bzip2.NewReader(f), nil
+	default:
+		return f, nil
+	}
+}
+
+// looksLikeTar checks for a valid tar header at the start of r without consuming it.
+func looksLikeTar(r *bufio.Reader) bool {
+	block, err := r.Peek(512)
+	if err != nil {
+		return false
+	}
+	_, err = tar.NewReader(bytes.NewReader(block)).Next()
+	return err == nil
+}
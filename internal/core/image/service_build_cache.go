@@ -0,0 +1,244 @@
+package image
+
+import (
+	"condenser/internal/store/bcm"
+	"condenser/internal/utils"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// cache entries not used for this long are removed by a default prune
+	defaultBuildCacheMaxAge = 7 * 24 * time.Hour
+
+	// snapshot directories without an entry younger than this may belong to a running build
+	buildCacheOrphanGracePeriod = time.Hour
+)
+
+// buildStep identifies the snapshot a RUN instruction produces.
+type buildStep struct {
+	key       string
+	parent    string
+	createdBy string
+}
+
+// stepCacheKey chains a build step onto its parent: the same parent, the
+// same instruction and the same content always give the same key.
+func stepCacheKey(parent string, op string, text string) string {
+	h := sha256.New()
+	io.WriteString(h, parent)
+	io.WriteString(h, "\n")
+	io.WriteString(h, op)
+	io.WriteString(h, "\n")
+	io.WriteString(h, text)
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// hashBuildSource hashes names, modes, link targets and file content below path,
+// so a COPY of unchanged files keeps the cache of the following steps valid.
+func hashBuildSource(path string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		info, err := os.Lstat(p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%o\x00", filepath.ToSlash(rel), info.Mode())
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			io.WriteString(h, link)
+		case info.Mode().IsRegular():
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		io.WriteString(h, "\x00")
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lookupBuildCache returns the snapshot stored for key.
+func (s *ImageService) lookupBuildCache(key string) (string, bool) {
+	entry, err := s.bcmHandler.GetEntry(key)
+	if err != nil {
+		return "", false
+	}
+	if info, err := os.Stat(entry.SnapshotPath); err != nil || !info.IsDir() {
+		// snapshot removed behind our back, forget the entry
+		_ = s.bcmHandler.RemoveEntry(key)
+		return "", false
+	}
+	_ = s.bcmHandler.TouchEntry(key)
+	return entry.SnapshotPath, true
+}
+
+// storeBuildCache moves the upper dir of a finished RUN into the cache and
+// returns the snapshot path. On failure the upper dir is left in place.
+func (s *ImageService) storeBuildCache(step buildStep, upperDir string) (string, error) {
+	if err := s.filesystemHandler.MkdirAll(utils.BuildCacheDir, 0o755); err != nil {
+		return "", err
+	}
+	finalDir := filepath.Join(utils.BuildCacheDir, strings.TrimPrefix(step.key, "sha256:"))
+	tmpDir := finalDir + ".tmp-" + utils.NewUlid()[:12]
+
+	if err := s.filesystemHandler.Rename(upperDir, tmpDir); err != nil {
+		// the cache may live on another filesystem
+		if err := copyDir(upperDir, tmpDir); err != nil {
+			_ = s.filesystemHandler.RemoveAll(tmpDir)
+			return "", err
+		}
+	}
+	if err := s.filesystemHandler.Rename(tmpDir, finalDir); err != nil {
+		if _, statErr := os.Stat(finalDir); statErr == nil {
+			// a concurrent build stored the same step
+			_ = s.filesystemHandler.RemoveAll(tmpDir)
+			return finalDir, nil
+		}
+		if _, statErr := os.Stat(upperDir); os.IsNotExist(statErr) {
+			_ = s.filesystemHandler.Rename(tmpDir, upperDir)
+		}
+		return "", err
+	}
+
+	size, _ := s.dirSize(finalDir)
+	if err := s.bcmHandler.StoreEntry(bcm.CacheEntry{
+		Key:          step.key,
+		Parent:       step.parent,
+		CreatedBy:    step.createdBy,
+		SnapshotPath: finalDir,
+		SizeBytes:    size,
+	}); err != nil {
+		return finalDir, err
+	}
+	return finalDir, nil
+}
+
+// == service: build cache list ==
+func (s *ImageService) GetBuildCacheList() ([]BuildCacheInfo, error) {
+	entries, err := s.bcmHandler.GetEntryList()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]BuildCacheInfo, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, BuildCacheInfo{
+			Key:        e.Key,
+			Parent:     e.Parent,
+			CreatedBy:  e.CreatedBy,
+			SizeBytes:  e.SizeBytes,
+			UsageCount: e.UsageCount,
+			CreatedAt:  e.CreatedAt,
+			LastUsedAt: e.LastUsedAt,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastUsedAt.After(list[j].LastUsedAt)
+	})
+	return list, nil
+}
+
+// == service: build cache prune ==
+func (s *ImageService) PruneBuildCache(pruneParameter ServiceBuildCachePruneModel) (BuildCachePruneResult, error) {
+	result := BuildCachePruneResult{Removed: []PrunedBuildCache{}}
+
+	olderThan := pruneParameter.OlderThan
+	if olderThan <= 0 {
+		olderThan = defaultBuildCacheMaxAge
+	}
+	cutoff := time.Now().Add(-olderThan)
+
+	entries, err := s.bcmHandler.GetEntryList()
+	if err != nil {
+		return result, err
+	}
+	known := map[string]bool{}
+	for _, e := range entries {
+		_, statErr := os.Stat(e.SnapshotPath)
+		missing := os.IsNotExist(statErr)
+		if !pruneParameter.All && !missing && e.LastUsedAt.After(cutoff) {
+			known[e.SnapshotPath] = true
+			continue
+		}
+		var size int64
+		if !missing {
+			size, _ = s.dirSize(e.SnapshotPath)
+			if err := s.filesystemHandler.RemoveAll(e.SnapshotPath); err != nil {
+				return result, err
+			}
+		}
+		if err := s.bcmHandler.RemoveEntry(e.Key); err != nil {
+			return result, err
+		}
+		result.Removed = append(result.Removed, PrunedBuildCache{
+			Key:            e.Key,
+			CreatedBy:      e.CreatedBy,
+			ReclaimedBytes: size,
+		})
+		result.ReclaimedBytes += size
+	}
+
+	// snapshot directories without an entry: interrupted builds
+	dirEntries, err := os.ReadDir(utils.BuildCacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, err
+	}
+	for _, d := range dirEntries {
+		p := filepath.Join(utils.BuildCacheDir, d.Name())
+		if known[p] {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) < buildCacheOrphanGracePeriod {
+			continue
+		}
+		key := "sha256:" + strings.SplitN(d.Name(), ".tmp-", 2)[0]
+		if _, err := s.bcmHandler.GetEntry(key); err == nil && !strings.Contains(d.Name(), ".tmp-") {
+			continue
+		}
+		size, _ := s.dirSize(p)
+		if err := s.filesystemHandler.RemoveAll(p); err != nil {
+			return result, err
+		}
+		result.Removed = append(result.Removed, PrunedBuildCache{
+			Key:            key,
+			ReclaimedBytes: size,
+		})
+		result.ReclaimedBytes += size
+	}
+	return result, nil
+}
@@ -0,0 +1,449 @@
+package image
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	buildArgNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	exposePortPattern   = regexp.MustCompile(`^[0-9]+(-[0-9]+)?(/(tcp|udp|sctp))?$`)
+)
+
+var defaultBuildShell = []string{"/bin/sh", "-c"}
+
+// instructions whose arguments are expanded with ARG and ENV values
+var expandableInstructions = map[string]bool{
+	"ADD":        true,
+	"COPY":       true,
+	"ENV":        true,
+	"EXPOSE":     true,
+	"LABEL":      true,
+	"STOPSIGNAL": true,
+	"USER":       true,
+	"VOLUME":     true,
+	"WORKDIR":    true,
+}
+
+// == ARG ==
+func applyArg(state *buildState, set *buildStageSet, arg string) error {
+	words, err := splitBuildWords(arg)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return errors.New("ARG requires name")
+	}
+	for _, w := range words {
+		name, def, hasDefault := strings.Cut(w, "=")
+		if !buildArgNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid ARG name: %s", name)
+		}
+		// passed build args win over defaults, and a stage inherits the
+		// value of a global ARG it redeclares without a default
+		if v, ok := set.buildArgs[name]; ok {
+			state.args[name] = v
+			continue
+		}
+		if hasDefault {
+			state.args[name] = expandBuildVars(def, state.lookupVar)
+			continue
+		}
+		if v, ok := set.globalArgs[name]; ok {
+			state.args[name] = v
+		}
+	}
+	return nil
+}
+
+// resolveGlobalArgs evaluates the ARG instructions before the first FROM.
+func resolveGlobalArgs(instructions []buildInstruction, buildArgs map[string]string) (map[string]string, error) {
+	set := &buildStageSet{buildArgs: buildArgs, globalArgs: map[string]string{}}
+	state := &buildState{args: map[string]string{}}
+	for _, ins := range instructions {
+		if err := applyArg(state, set, ins.args); err != nil {
+			return nil, err
+		}
+	}
+	return state.args, nil
+}
+
+func (state *buildState) lookupVar(name string) (string, bool) {
+	prefix := name + "="
+	for i := len(state.env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(state.env[i], prefix) {
+			return strings.TrimPrefix(state.env[i], prefix), true
+		}
+	}
+	v, ok := state.args[name]
+	return v, ok
+}
+
+// runEnv returns the environment of RUN: declared ARGs overridden by ENV.
+func (state *buildState) runEnv() []string {
+	env := make([]string, 0, len(state.args)+len(state.env))
+	for _, k := range sortedKeys(state.args) {
+		env = append(env, k+"="+state.args[k])
+	}
+	return append(env, state.env...)
+}
+
+// expandBuildVars substitutes $VAR, ${VAR}, ${VAR:-default} and ${VAR:+alt}.
+// \$ keeps a literal dollar sign.
+func expandBuildVars(in string, lookup func(string) (string, bool)) string {
+	var b strings.Builder
+	for i := 0; i < len(in); i++ {
+		c := in[i]
+		if c == '\\' && i+1 < len(in) && in[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		if c != '$' || i+1 >= len(in) {
+			b.WriteByte(c)
+			continue
+		}
+
+		if in[i+1] == '{' {
+			end := strings.IndexByte(in[i+2:], '}')
+			if end < 0 {
+				b.WriteString(in[i:])
+				break
+			}
+			expr := in[i+2 : i+2+end]
+			i += 2 + end
+
+			name, op, word := expr, "", ""
+			if idx := strings.Index(expr, ":-"); idx >= 0 {
+				name, op, word = expr[:idx], ":-", expr[idx+2:]
+			} else if idx := strings.Index(expr, ":+"); idx >= 0 {
+				name, op, word = expr[:idx], ":+", expr[idx+2:]
+			}
+			val, ok := lookup(name)
+			switch op {
+			case ":-":
+				if !ok || val == "" {
+					val = expandBuildVars(word, lookup)
+				}
+			case ":+":
+				if ok && val != "" {
+					val = expandBuildVars(word, lookup)
+				} else {
+					val = ""
+				}
+			}
+			b.WriteString(val)
+			continue
+		}
+
+		j := i + 1
+		for j < len(in) && (in[j] == '_' || in[j] >= 'a' && in[j] <= 'z' || in[j] >= 'A' && in[j] <= 'Z' || j > i+1 && in[j] >= '0' && in[j] <= '9') {
+			j++
+		}
+		if j == i+1 {
+			b.WriteByte(c)
+			continue
+		}
+		val, _ := lookup(in[i+1 : j])
+		b.WriteString(val)
+		i = j - 1
+	}
+	return b.String()
+}
+
+// == LABEL ==
+func (s *ImageService) applyLabel(state *buildState, arg string) error {
+	pairs, err := parseKeyValueArgs(arg)
+	if err != nil {
+		return fmt.Errorf("LABEL: %w", err)
+	}
+	if state.labels == nil {
+		state.labels = map[string]string{}
+	}
+	for _, kv := range pairs {
+		state.labels[kv[0]] = kv[1]
+	}
+	return nil
+}
+
+// == EXPOSE ==
+func (s *ImageService) applyExpose(state *buildState, arg string) error {
+	ports := strings.Fields(arg)
+	if len(ports) == 0 {
+		return errors.New("EXPOSE requires port")
+	}
+	if state.exposedPorts == nil {
+		state.exposedPorts = map[string]struct{}{}
+	}
+	for _, p := range ports {
+		p = strings.ToLower(p)
+		if !exposePortPattern.MatchString(p) {
+			return fmt.Errorf("invalid EXPOSE port: %s", p)
+		}
+		if !strings.Contains(p, "/") {
+			p += "/tcp"
+		}
+		state.exposedPorts[p] = struct{}{}
+	}
+	return nil
+}
+
+// == USER ==
+func (s *ImageService) applyUser(state *buildState, arg string) error {
+	user := strings.TrimSpace(arg)
+	if user == "" || len(strings.Fields(user)) != 1 {
+		return errors.New("USER requires a single user[:group]")
+	}
+	state.user = user
+	return nil
+}
+
+// == VOLUME ==
+func (s *ImageService) applyVolume(state *buildState, arg string) error {
+	arg = strings.TrimSpace(arg)
+	var paths []string
+	if strings.HasPrefix(arg, "[") {
+		if err := json.Unmarshal([]byte(arg), &paths); err != nil {
+			return fmt.Errorf("invalid VOLUME exec form: %w", err)
+		}
+	} else {
+		paths = strings.Fields(arg)
+	}
+	if len(paths) == 0 {
+		return errors.New("VOLUME requires path")
+	}
+	if state.volumes == nil {
+		state.volumes = map[string]struct{}{}
+	}
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("VOLUME path must be absolute: %s", p)
+		}
+		state.volumes[p] = struct{}{}
+	}
+	return nil
+}
+
+// == HEALTHCHECK ==
+func (s *ImageService) applyHealthcheck(state *buildState, arg string) error {
+	flags, rest := splitInstructionFlags(arg)
+	if len(rest) == 0 {
+		return errors.New("HEALTHCHECK requires CMD or NONE")
+	}
+
+	switch strings.ToUpper(rest[0]) {
+	case "NONE":
+		if len(flags) > 0 || len(rest) > 1 {
+			return errors.New("HEALTHCHECK NONE takes no options")
+		}
+		state.healthcheck = &ImageHealthConfig{Test: []string{"NONE"}}
+		return nil
+	case "CMD":
+	default:
+		return fmt.Errorf("HEALTHCHECK: unknown type %s", rest[0])
+	}
+
+	hc := &ImageHealthConfig{}
+	for k, v := range flags {
+		switch k {
+		case "interval", "timeout", "start-period", "start-interval":
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				return fmt.Errorf("HEALTHCHECK --%s: invalid duration %q", k, v)
+			}
+			switch k {
+			case "interval":
+				hc.Interval = d
+			case "timeout":
+				hc.Timeout = d
+			case "start-period":
+				hc.StartPeriod = d
+			case "start-interval":
+				hc.StartInterval = d
+			}
+		case "retries":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("HEALTHCHECK --retries: invalid value %q", v)
+			}
+			hc.Retries = n
+		default:
+			return fmt.Errorf("HEALTHCHECK: unknown flag --%s", k)
+		}
+	}
+
+	// keep the command text as written, after "CMD"
+	cmdText := strings.TrimSpace(arg[strings.Index(strings.ToUpper(arg), strings.ToUpper(rest[0]))+len(rest[0]):])
+	if cmdText == "" {
+		return errors.New("HEALTHCHECK CMD requires command")
+	}
+	if strings.HasPrefix(cmdText, "[") {
+		var cmd []string
+		if err := json.Unmarshal([]byte(cmdText), &cmd); err != nil {
+			return fmt.Errorf("invalid HEALTHCHECK exec form: %w", err)
+		}
+		hc.Test = append([]string{"CMD"}, cmd...)
+	} else {
+		hc.Test = []string{"CMD-SHELL", cmdText}
+	}
+	state.healthcheck = hc
+	return nil
+}
+
+// == SHELL ==
+func (s *ImageService) applyShell(state *buildState, arg string) error {
+	var shell []string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(arg)), &shell); err != nil {
+		return fmt.Errorf("SHELL requires JSON form: %w", err)
+	}
+	if len(shell) == 0 {
+		return errors.New("SHELL requires command")
+	}
+	state.shell = shell
+	return nil
+}
+
+// == STOPSIGNAL ==
+func (s *ImageService) applyStopSignal(state *buildState, arg string) error {
+	sig := strings.TrimSpace(arg)
+	if _, err := parseSignal(sig); err != nil {
+		return err
+	}
+	state.stopSignal = sig
+	return nil
+}
+
+// parseSignal accepts a signal number or name, with or without the SIG prefix.
+func parseSignal(sig string) (syscall.Signal, error) {
+	if sig == "" {
+		return 0, errors.New("STOPSIGNAL requires signal")
+	}
+	if n, err := strconv.Atoi(sig); err == nil {
+		if n <= 0 || n > 64 {
+			return 0, fmt.Errorf("invalid signal: %s", sig)
+		}
+		return syscall.Signal(n), nil
+	}
+	name := strings.ToUpper(sig)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if s := unix.SignalNum(name); s != 0 {
+		return s, nil
+	}
+	return 0, fmt.Errorf("invalid signal: %s", sig)
+}
+
+// == helpers ==
+
+// parseKeyValueArgs parses `k=v k2="v 2"` or the legacy `key value...` form.
+func parseKeyValueArgs(arg string) ([][2]string, error) {
+	arg = strings.TrimSpace(arg)
+	words, err := splitBuildWords(arg)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, errors.New("requires key=value")
+	}
+
+	if !strings.Contains(words[0], "=") {
+		key := words[0]
+		value := strings.TrimSpace(strings.TrimPrefix(arg, strings.Fields(arg)[0]))
+		if value == "" {
+			return nil, fmt.Errorf("%s requires value", key)
+		}
+		if unq, err := splitBuildWords(value); err == nil && len(unq) == 1 {
+			value = unq[0]
+		}
+		return [][2]string{{key, value}}, nil
+	}
+
+	var pairs [][2]string
+	for _, w := range words {
+		k, v, ok := strings.Cut(w, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid key=value: %s", w)
+		}
+		pairs = append(pairs, [2]string{k, v})
+	}
+	return pairs, nil
+}
+
+// splitBuildWords splits on whitespace, honouring quotes and backslash escapes.
+func splitBuildWords(s string) ([]string, error) {
+	var (
+		words   []string
+		cur     strings.Builder
+		inWord  bool
+		quote   byte
+		escaped bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			cur.WriteByte(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func cloneVolumeSet(in map[string]struct{}) map[string]struct{} {
+	return clonePortSet(in)
+}
+
+func cloneHealthConfig(in *ImageHealthConfig) *ImageHealthConfig {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	out.Test = cloneSlice(in.Test)
+	return &out
+}
@@ -0,0 +1,265 @@
+package image
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const dripignoreFile = ".dripignore"
+
+// ErrInvalidBuildContext is returned when the uploaded context cannot be
+// extracted or the Dripfile is not inside it.
+var ErrInvalidBuildContext = errors.New("invalid build context")
+
+// buildContext is an extracted build context.
+type buildContext struct {
+	dir            string
+	dripfile       string
+	dripfileDigest string
+	instructions   []buildInstruction
+}
+
+// prepareBuildContext extracts the context tar into a temporary directory,
+// reads the Dripfile and then drops everything .dripignore excludes. The
+// Dripfile and .dripignore are read even when they are excluded themselves.
+func prepareBuildContext(r io.Reader, dripfile string) (buildContext, error) {
+	if dripfile == "" {
+		dripfile = "Dripfile"
+	}
+	contextDir, err := os.MkdirTemp("", "raind-build-context-")
+	if err != nil {
+		return buildContext{}, err
+	}
+	fail := func(err error) (buildContext, error) {
+		_ = os.RemoveAll(contextDir)
+		return buildContext{}, err
+	}
+
+	if err := extractTar(r, contextDir, false); err != nil {
+		return fail(fmt.Errorf("%w: %v", ErrInvalidBuildContext, err))
+	}
+
+	dripfilePath, err := safeJoin(contextDir, dripfile)
+	if err != nil {
+		return fail(fmt.Errorf("%w: dripfile %s is outside the context", ErrInvalidBuildContext, dripfile))
+	}
+	instructions, err := parseDripfile(dripfilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fail(fmt.Errorf("%w: dripfile %s not found", ErrInvalidBuildContext, dripfile))
+		}
+		return fail(err)
+	}
+	dripfileDigest, err := fileDigest(dripfilePath)
+	if err != nil {
+		return fail(err)
+	}
+
+	patterns, err := readDripignore(filepath.Join(contextDir, dripignoreFile))
+	if err != nil {
+		return fail(fmt.Errorf("%w: %v", ErrInvalidBuildContext, err))
+	}
+	if err := applyDripignore(contextDir, patterns); err != nil {
+		return fail(err)
+	}
+	return buildContext{
+		dir:            contextDir,
+		dripfile:       dripfile,
+		dripfileDigest: dripfileDigest,
+		instructions:   instructions,
+	}, nil
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ignorePattern is one line of .dripignore.
+type ignorePattern struct {
+	re      *regexp.Regexp
+	exclude bool // false for "!" exceptions
+}
+
+// readDripignore parses Docker style ignore rules: one pattern per line,
+// "#" comments, "!" exceptions, "*", "?", "[...]" and "**" for any depth.
+func readDripignore(path string) ([]ignorePattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []ignorePattern
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := ignorePattern{exclude: true}
+		if strings.HasPrefix(line, "!") {
+			p.exclude = false
+			line = strings.TrimSpace(line[1:])
+		}
+		line = filepath.ToSlash(filepath.Clean(strings.TrimPrefix(line, "/")))
+		if line == "." || line == "" {
+			continue
+		}
+		re, err := compileIgnorePattern(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad pattern %q: %w", dripignoreFile, line, err)
+		}
+		p.re = re
+		patterns = append(patterns, p)
+	}
+	return patterns, sc.Err()
+}
+
+func compileIgnorePattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					b.WriteString("(.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, errors.New("unterminated [")
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// ignored reports whether rel is excluded. A pattern that matches a
+// directory also matches everything below it, the last match wins.
+func ignored(patterns []ignorePattern, rel string) bool {
+	excluded := false
+	for _, p := range patterns {
+		if p.re.MatchString(rel) || matchesParent(p.re, rel) {
+			excluded = p.exclude
+		}
+	}
+	return excluded
+}
+
+func matchesParent(re *regexp.Regexp, rel string) bool {
+	for dir := filepath.Dir(rel); dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+		if re.MatchString(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDripignore removes the excluded paths from the extracted context.
+func applyDripignore(contextDir string, patterns []ignorePattern) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+	hasExceptions := false
+	for _, p := range patterns {
+		if !p.exclude {
+			hasExceptions = true
+		}
+	}
+
+	var excludedDirs []string
+	err := filepath.WalkDir(contextDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == contextDir {
+			return nil
+		}
+		rel, err := filepath.Rel(contextDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !ignored(patterns, rel) {
+			return nil
+		}
+		if d.IsDir() {
+			if !hasExceptions {
+				if err := os.RemoveAll(path); err != nil {
+					return err
+				}
+				return filepath.SkipDir
+			}
+			// an exception may keep something below, decide once the walk is done
+			excludedDirs = append(excludedDirs, path)
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return err
+	}
+
+	// deepest first, so parents become empty before they are checked
+	sort.Sort(sort.Reverse(sort.StringSlice(excludedDirs)))
+	for _, dir := range excludedDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			if err := os.Remove(dir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
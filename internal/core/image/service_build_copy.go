@@ -0,0 +1,450 @@
+package image
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// copyOptions are the COPY flags that change what lands in the rootfs.
+type copyOptions struct {
+	uid, gid int // owner of every copied entry, root unless --chown
+	mode     fs.FileMode
+	hasMode  bool
+	link     bool
+}
+
+func (o copyOptions) String() string {
+	s := fmt.Sprintf("%d:%d", o.uid, o.gid)
+	if o.hasMode {
+		s += fmt.Sprintf(" %04o", o.mode)
+	}
+	return s
+}
+
+func (s *ImageService) applyCopy(state *buildState, set *buildStageSet, stageIndex int, contextDir string, arg string) error {
+	flags, parts := splitInstructionFlags(arg)
+	for k := range flags {
+		switch k {
+		case "from", "chown", "chmod", "link":
+		default:
+			return fmt.Errorf("unsupported COPY flag: --%s", k)
+		}
+	}
+	opts, err := parseCopyOptions(state.rootfsPath, flags)
+	if err != nil {
+		return err
+	}
+
+	baseDir := contextDir
+	from, fromRootfs := flags["from"]
+	if fromRootfs {
+		if from == "" {
+			return errors.New("COPY --from requires a stage or image")
+		}
+		rootfs, err := s.sourceRootfs(set, stageIndex, from)
+		if err != nil {
+			return err
+		}
+		baseDir = rootfs
+	}
+
+	if len(parts) < 2 {
+		return errors.New("COPY/ADD requires src and dest")
+	}
+	dst := parts[len(parts)-1]
+	var srcPaths []string
+	for _, src := range parts[:len(parts)-1] {
+		matches, err := expandCopySource(baseDir, src, fromRootfs)
+		if err != nil {
+			return err
+		}
+		srcPaths = append(srcPaths, matches...)
+	}
+	if len(srcPaths) > 1 && !strings.HasSuffix(dst, "/") {
+		return fmt.Errorf("COPY with more than one source requires a directory destination ending with /: %s", dst)
+	}
+	if state.stepContent, err = hashCopySources(baseDir, srcPaths); err != nil {
+		return err
+	}
+
+	if opts.link {
+		return s.applyCopyLink(state, set, dst, srcPaths, opts)
+	}
+	return copySources(state, dst, srcPaths, opts)
+}
+
+// parseCopyOptions reads --chown, --chmod and --link. Names in --chown are
+// resolved against /etc/passwd and /etc/group of the stage rootfs.
+func parseCopyOptions(rootfs string, flags map[string]string) (copyOptions, error) {
+	opts := copyOptions{}
+	if _, ok := flags["link"]; ok {
+		switch flags["link"] {
+		case "", "true":
+			opts.link = true
+		case "false":
+		default:
+			return opts, fmt.Errorf("invalid --link value: %s", flags["link"])
+		}
+	}
+	if spec, ok := flags["chown"]; ok {
+		uid, gid, err := resolveChown(rootfs, spec)
+		if err != nil {
+			return opts, err
+		}
+		opts.uid, opts.gid = uid, gid
+	}
+	if v, ok := flags["chmod"]; ok {
+		mode, err := strconv.ParseUint(v, 8, 32)
+		if err != nil || mode > 0o7777 {
+			return opts, fmt.Errorf("invalid --chmod value, expected octal mode: %s", v)
+		}
+		opts.mode = permBits(uint32(mode))
+		opts.hasMode = true
+	}
+	return opts, nil
+}
+
+// permBits converts a unix mode to the fs.FileMode bits os.Chmod understands.
+func permBits(mode uint32) fs.FileMode {
+	m := fs.FileMode(mode) & fs.ModePerm
+	if mode&0o4000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if mode&0o2000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if mode&0o1000 != 0 {
+		m |= fs.ModeSticky
+	}
+	return m
+}
+
+// resolveChown turns "user[:group]" into ids. Without a group the gid is
+// the uid, as in Docker.
+func resolveChown(rootfs string, spec string) (int, int, error) {
+	if spec == "" {
+		return 0, 0, errors.New("--chown requires user[:group]")
+	}
+	userPart, groupPart, hasGroup := strings.Cut(spec, ":")
+	uid, err := lookupId(rootfs, "/etc/passwd", userPart)
+	if err != nil {
+		return 0, 0, fmt.Errorf("--chown=%s: %w", spec, err)
+	}
+	if !hasGroup {
+		return uid, uid, nil
+	}
+	gid, err := lookupId(rootfs, "/etc/group", groupPart)
+	if err != nil {
+		return 0, 0, fmt.Errorf("--chown=%s: %w", spec, err)
+	}
+	return uid, gid, nil
+}
+
+// lookupId returns the numeric id of name from a passwd or group file in
+// the rootfs. Numeric names are taken as they are.
+func lookupId(rootfs string, file string, name string) (int, error) {
+	if name == "" {
+		return 0, errors.New("empty user or group")
+	}
+	if id, err := strconv.Atoi(name); err == nil {
+		if id < 0 {
+			return 0, fmt.Errorf("invalid id: %s", name)
+		}
+		return id, nil
+	}
+	p, err := resolveInRootfs(rootfs, file)
+	if err != nil {
+		return 0, fmt.Errorf("%s not found in image: %w", name, err)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return 0, fmt.Errorf("%s not found in image: %w", name, err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// name:password:id:...
+		fields := strings.Split(sc.Text(), ":")
+		if len(fields) < 3 || fields[0] != name {
+			continue
+		}
+		id, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid id for %s in %s", name, file)
+		}
+		return id, nil
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%s not found in %s", name, file)
+}
+
+func hasGlobMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// expandCopySource resolves one COPY source. Wildcards may appear in any
+// path element; they are matched one directory at a time, so matching
+// never leaves the context or the source rootfs.
+func expandCopySource(baseDir string, src string, fromRootfs bool) ([]string, error) {
+	if !hasGlobMeta(src) {
+		p, err := copySourcePath(baseDir, src, fromRootfs)
+		if err != nil {
+			return nil, err
+		}
+		return []string{p}, nil
+	}
+
+	elems := strings.Split(strings.Trim(path.Clean("/"+filepath.ToSlash(src)), "/"), "/")
+	rels := []string{"."}
+	for _, elem := range elems {
+		var next []string
+		for _, rel := range rels {
+			if !hasGlobMeta(elem) {
+				next = append(next, path.Join(rel, elem))
+				continue
+			}
+			dir, err := copySourcePath(baseDir, rel, fromRootfs)
+			if err != nil {
+				continue
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				ok, err := path.Match(elem, e.Name())
+				if err != nil {
+					return nil, fmt.Errorf("bad pattern %s: %w", src, err)
+				}
+				if ok {
+					next = append(next, path.Join(rel, e.Name()))
+				}
+			}
+		}
+		rels = next
+	}
+
+	var matches []string
+	for _, rel := range rels {
+		p, err := copySourcePath(baseDir, rel, fromRootfs)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Lstat(p); err != nil {
+			continue
+		}
+		matches = append(matches, p)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no source files match %s", src)
+	}
+	return matches, nil
+}
+
+// hashCopySources combines the content hashes of all sources with their
+// names, a renamed source changes the key like a changed one.
+func hashCopySources(baseDir string, srcPaths []string) (string, error) {
+	h := sha256.New()
+	for _, p := range srcPaths {
+		rel, err := filepath.Rel(baseDir, p)
+		if err != nil {
+			return "", err
+		}
+		digest, err := hashBuildSource(p)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), digest)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copySources copies every source to dst in the stage rootfs and gives the
+// copied entries the owner and mode from opts.
+func copySources(state *buildState, dst string, srcPaths []string, opts copyOptions) error {
+	dstAbs := dst
+	if !filepath.IsAbs(dstAbs) {
+		dstAbs = filepath.Join(state.workdir, dstAbs)
+	}
+	_, statErr := os.Lstat(filepath.Join(state.rootfsPath, strings.TrimPrefix(filepath.Clean(dstAbs), "/")))
+	dstPath, err := buildDestPath(state, dst)
+	if err != nil {
+		return err
+	}
+	if os.IsNotExist(statErr) && strings.HasSuffix(dst, "/") {
+		// the destination directory is created by this COPY, it belongs to the owner too
+		if err := os.Lchown(dstPath, opts.uid, opts.gid); err != nil {
+			return err
+		}
+	}
+
+	for _, srcPath := range srcPaths {
+		info, err := os.Lstat(srcPath)
+		if err != nil {
+			return err
+		}
+		target := copyTarget(srcPath, info, dstPath)
+		_, statErr := os.Lstat(target)
+		if err := copyIntoRootfs(srcPath, target); err != nil {
+			return err
+		}
+		if err := applyCopyOptions(srcPath, target, opts, statErr == nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyCopyOptions walks the source again and sets owner and mode on the
+// entries it produced below target. A directory target that already
+// existed keeps its owner and mode.
+func applyCopyOptions(srcPath string, target string, opts copyOptions, targetExisted bool) error {
+	return filepath.WalkDir(srcPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcPath, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." && d.IsDir() && targetExisted {
+			return nil
+		}
+		dstEntry := filepath.Join(target, rel)
+		if err := os.Lchown(dstEntry, opts.uid, opts.gid); err != nil {
+			return err
+		}
+		if opts.hasMode && d.Type()&fs.ModeSymlink == 0 {
+			return os.Chmod(dstEntry, opts.mode)
+		}
+		return nil
+	})
+}
+
+// applyCopyLink builds the copied files as a layer of their own, keyed
+// only by the sources, destination and options. The layer is cached
+// independently of the steps before it, so changing the base image or an
+// earlier step reuses it instead of copying again.
+func (s *ImageService) applyCopyLink(state *buildState, set *buildStageSet, dst string, srcPaths []string, opts copyOptions) error {
+	// the layer starts empty, a destination that is a directory in the
+	// stage is made one in the layer too so files land under their own name
+	dst = linkDest(state, dst)
+	step := buildStep{
+		key:       stepCacheKey("", "COPY --link", state.workdir+"\n"+dst+"\n"+opts.String()+"\n"+state.stepContent),
+		createdBy: "COPY --link " + strings.TrimSpace(dst),
+	}
+	if !set.noCache {
+		if snapshot, ok := s.lookupBuildCache(step.key); ok {
+			set.emit(BuildProgress{Type: BuildProgressCached, Message: "using cache " + strings.TrimPrefix(step.key, "sha256:")[:12]})
+			return mergeLinkLayer(snapshot, state.rootfsPath)
+		}
+	}
+
+	layerDir, err := os.MkdirTemp("", "raind-build-link-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(layerDir)
+
+	layer := *state
+	layer.rootfsPath = layerDir
+	if err := copySources(&layer, dst, srcPaths, opts); err != nil {
+		return err
+	}
+	if snapshot, _ := s.storeBuildCache(step, layerDir); snapshot != "" {
+		return mergeLinkLayer(snapshot, state.rootfsPath)
+	}
+	return mergeLinkLayer(layerDir, state.rootfsPath)
+}
+
+// linkDest marks dst as a directory when it is one in the stage rootfs.
+func linkDest(state *buildState, dst string) string {
+	if strings.HasSuffix(dst, "/") {
+		return dst
+	}
+	dstAbs := dst
+	if !filepath.IsAbs(dstAbs) {
+		dstAbs = filepath.Join(state.workdir, dstAbs)
+	}
+	existing, err := resolveInRootfs(state.rootfsPath, dstAbs)
+	if err != nil {
+		return dst
+	}
+	if fi, err := os.Stat(existing); err == nil && fi.IsDir() {
+		return dst + "/"
+	}
+	return dst
+}
+
+// mergeLinkLayer adds a --link layer to the rootfs. Unlike a RUN snapshot
+// the layer holds no whiteouts, and directories that already exist keep
+// their owner and mode since the layer only created them as parents.
+func mergeLinkLayer(layerDir string, rootfs string) error {
+	return filepath.WalkDir(layerDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == layerDir {
+			return nil
+		}
+		rel, err := filepath.Rel(layerDir, p)
+		if err != nil {
+			return err
+		}
+		// parents are resolved inside the rootfs, /lib may well be a link to /usr/lib
+		parent, err := resolveInRootfs(rootfs, filepath.Dir(rel))
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(parent, filepath.Base(rel))
+		info, err := os.Lstat(p)
+		if err != nil {
+			return err
+		}
+		existing, existErr := resolveInRootfs(rootfs, rel)
+		existingIsDir := false
+		if existErr == nil {
+			if fi, err := os.Stat(existing); err == nil && fi.IsDir() {
+				existingIsDir = true
+			}
+		}
+		switch {
+		case info.IsDir():
+			if existingIsDir {
+				return nil
+			}
+			_ = os.RemoveAll(target)
+			if err := os.Mkdir(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			_ = os.RemoveAll(target)
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if err := copyFile(p, target, info.Mode()); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+		return copyMetadata(target, info)
+	})
+}
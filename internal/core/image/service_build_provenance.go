@@ -0,0 +1,310 @@
+package image
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	provenanceFile      = "provenance.json"
+	provenanceBuildType = "urn:raind:condenser:dripfile-build:v1"
+	provenanceBuilderId = "urn:raind:condenser"
+)
+
+// ErrProvenanceNotFound is returned for images that were pulled, or built
+// before provenance was recorded.
+var ErrProvenanceNotFound = errors.New("image has no provenance")
+
+// == in-toto statement with a slsa v1 provenance predicate ==
+type provenanceStatement struct {
+	Type          string              `json:"_type"`
+	Subject       []provenanceSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     provenancePredicate `json:"predicate"`
+}
+
+type provenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type provenancePredicate struct {
+	BuildDefinition provenanceBuildDefinition `json:"buildDefinition"`
+	RunDetails      provenanceRunDetails      `json:"runDetails"`
+}
+
+type provenanceBuildDefinition struct {
+	BuildType            string                 `json:"buildType"`
+	ExternalParameters   provenanceExternal     `json:"externalParameters"`
+	InternalParameters   provenanceInternal     `json:"internalParameters"`
+	ResolvedDependencies []provenanceDescriptor `json:"resolvedDependencies"`
+}
+
+type provenanceExternal struct {
+	Dripfile  provenanceDescriptor `json:"dripfile"`
+	Target    string               `json:"target,omitempty"`
+	BuildArgs map[string]string    `json:"buildArgs,omitempty"`
+}
+
+type provenanceInternal struct {
+	Hermetic        bool  `json:"hermetic"`
+	NoCache         bool  `json:"noCache"`
+	SourceDateEpoch int64 `json:"sourceDateEpoch,omitempty"`
+}
+
+type provenanceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	Uri    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+type provenanceRunDetails struct {
+	Builder    provenanceBuilder      `json:"builder"`
+	Metadata   provenanceMetadata     `json:"metadata"`
+	Byproducts []provenanceDescriptor `json:"byproducts,omitempty"`
+}
+
+type provenanceBuilder struct {
+	Id string `json:"id"`
+}
+
+type provenanceMetadata struct {
+	InvocationId string `json:"invocationId"`
+	StartedOn    string `json:"startedOn"`
+	FinishedOn   string `json:"finishedOn"`
+}
+
+// imageDigest is the digest an image reference stands for: the manifest it
+// was pulled as, or the config of a locally built image.
+func (s *ImageService) imageDigest(imageRepo, imageRef string) (string, error) {
+	if info, err := s.ilmHandler.GetImageInfo(imageRepo, imageRef); err == nil && info.ManifestDigest != "" {
+		return info.ManifestDigest, nil
+	}
+	configPath, err := s.ilmHandler.GetConfigPath(imageRepo, imageRef)
+	if err != nil {
+		return "", err
+	}
+	return fileDigest(configPath)
+}
+
+// digestMap turns "sha256:<hex>" into the in-toto digest set.
+func digestMap(digest string) map[string]string {
+	algo, hexPart, ok := strings.Cut(digest, ":")
+	if !ok {
+		return map[string]string{"sha256": digest}
+	}
+	return map[string]string{algo: hexPart}
+}
+
+// writeBuildProvenance stores the provenance of a finished build next to the
+// image bundle and records it in ilm.
+func (s *ImageService) writeBuildProvenance(imageRepo, imageRef string, set *buildStageSet, bctx buildContext, buildParameter ServiceBuildModel, startedOn time.Time) error {
+	bundlePath, err := s.ilmHandler.GetBundlePath(imageRepo, imageRef)
+	if err != nil {
+		return err
+	}
+	rootfsPath, err := s.ilmHandler.GetRootfsPath(imageRepo, imageRef)
+	if err != nil {
+		return err
+	}
+	configPath, err := s.ilmHandler.GetConfigPath(imageRepo, imageRef)
+	if err != nil {
+		return err
+	}
+	outputDigest, err := rootfsDigest(rootfsPath)
+	if err != nil {
+		return err
+	}
+	configDigest, err := fileDigest(configPath)
+	if err != nil {
+		return err
+	}
+
+	deps := make([]provenanceDescriptor, 0, len(set.resolved))
+	for _, uri := range sortedKeys(set.resolved) {
+		deps = append(deps, provenanceDescriptor{Uri: uri, Digest: digestMap(set.resolved[uri])})
+	}
+	statement := provenanceStatement{
+		Type: "https://in-toto.io/Statement/v1",
+		Subject: []provenanceSubject{{
+			Name:   imageRepo + ":" + imageRef,
+			Digest: digestMap(outputDigest),
+		}},
+		PredicateType: "https://slsa.dev/provenance/v1",
+		Predicate: provenancePredicate{
+			BuildDefinition: provenanceBuildDefinition{
+				BuildType: provenanceBuildType,
+				ExternalParameters: provenanceExternal{
+					Dripfile:  provenanceDescriptor{Name: bctx.dripfile, Digest: digestMap(bctx.dripfileDigest)},
+					Target:    buildParameter.Target,
+					BuildArgs: buildParameter.BuildArgs,
+				},
+				InternalParameters: provenanceInternal{
+					Hermetic: buildParameter.Hermetic,
+					NoCache:  buildParameter.NoCache,
+				},
+				ResolvedDependencies: deps,
+			},
+			RunDetails: provenanceRunDetails{
+				Builder: provenanceBuilder{Id: provenanceBuilderId},
+				Metadata: provenanceMetadata{
+					InvocationId: uuid.NewString(),
+					StartedOn:    startedOn.UTC().Format(time.RFC3339),
+					FinishedOn:   time.Now().UTC().Format(time.RFC3339),
+				},
+				Byproducts: []provenanceDescriptor{{Name: "config.json", Digest: digestMap(configDigest)}},
+			},
+		},
+	}
+	if buildParameter.Hermetic {
+		statement.Predicate.BuildDefinition.InternalParameters.SourceDateEpoch = buildParameter.SourceDateEpoch
+	}
+
+	data, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return err
+	}
+	provenancePath := filepath.Join(bundlePath, provenanceFile)
+	tmp := provenancePath + ".tmp"
+	if err := s.filesystemHandler.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := s.filesystemHandler.Rename(tmp, provenancePath); err != nil {
+		return err
+	}
+	return s.ilmHandler.SetProvenance(imageRepo, imageRef, provenancePath)
+}
+
+// == service: image provenance ==
+func (s *ImageService) GetImageProvenance(imageStr string) ([]byte, error) {
+	repo, ref, err := s.parseImageRef(imageStr)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.ilmHandler.GetImageInfo(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	if info.ProvenancePath == "" {
+		return nil, ErrProvenanceNotFound
+	}
+	data, err := s.filesystemHandler.ReadFile(info.ProvenancePath)
+	if err != nil {
+		if s.filesystemHandler.IsNotExist(err) {
+			return nil, ErrProvenanceNotFound
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// normalizeTimestamps sets the times of everything below root to epoch,
+// without following symlinks.
+func normalizeTimestamps(root string, epoch time.Time) error {
+	ts := []unix.Timespec{unix.NsecToTimespec(epoch.UnixNano()), unix.NsecToTimespec(epoch.UnixNano())}
+	// children first, touching an entry changes the mtime of its directory
+	var paths []string
+	if err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		paths = append(paths, p)
+		return nil
+	}); err != nil {
+		return err
+	}
+	for i := len(paths) - 1; i >= 0; i-- {
+		if err := unix.UtimesNanoAt(unix.AT_FDCWD, paths[i], ts, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rootfsDigest is the sha256 of the rootfs as a tar with sorted entries and
+// only reproducible header fields, so equal trees always give equal digests.
+func rootfsDigest(root string) (string, error) {
+	h := sha256.New()
+	tw := tar.NewWriter(h)
+	var paths []string
+	if err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != root {
+			paths = append(paths, p)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		info, err := os.Lstat(p)
+		if err != nil {
+			return "", err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return "", err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			// sockets and the like are not part of an image
+			continue
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return "", err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.ModTime = info.ModTime().Truncate(time.Second)
+		hdr.AccessTime = time.Time{}
+		hdr.ChangeTime = time.Time{}
+		hdr.Uname = ""
+		hdr.Gname = ""
+		hdr.Format = tar.FormatPAX
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			hdr.Uid = int(st.Uid)
+			hdr.Gid = int(st.Gid)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return "", err
+		}
+		if info.Mode().IsRegular() {
+			f, err := os.Open(p)
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(tw, f)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,327 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSecretDir = "/run/secrets"
+
+	// shorter secrets are not searched for in the step output, they would
+	// match by chance
+	minScannedSecretLen = 6
+)
+
+var secretIdPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ErrSecretLeaked is returned when a RUN step wrote a secret into its
+// snapshot. The step is not cached and the build fails.
+var ErrSecretLeaked = errors.New("secret written to the image")
+
+// runSecret is a secret mounted into one RUN step.
+type runSecret struct {
+	id       string
+	target   string
+	required bool
+	mode     os.FileMode
+	uid, gid int
+	value    []byte // nil when the build was not given the secret
+}
+
+// spec is the mount as far as it changes the step: the cache key never
+// sees the value.
+func (m runSecret) spec() string {
+	return fmt.Sprintf("secret id=%s target=%s mode=%04o uid=%d gid=%d", m.id, m.target, m.mode, m.uid, m.gid)
+}
+
+// splitRunFlags takes the leading --mount flags off a RUN argument and
+// returns the rest unchanged, so shell and exec form parse as before.
+func splitRunFlags(arg string) ([]string, string, error) {
+	var mounts []string
+	rest := strings.TrimSpace(arg)
+	for strings.HasPrefix(rest, "--") {
+		end := strings.IndexAny(rest, " \t")
+		token := rest
+		if end >= 0 {
+			token = rest[:end]
+			rest = strings.TrimSpace(rest[end:])
+		} else {
+			rest = ""
+		}
+		k, v, _ := strings.Cut(strings.TrimPrefix(token, "--"), "=")
+		if strings.ToLower(k) != "mount" {
+			return nil, "", fmt.Errorf("unsupported RUN flag: --%s", k)
+		}
+		mounts = append(mounts, v)
+	}
+	return mounts, rest, nil
+}
+
+// parseRunSecret parses "type=secret,id=npmrc[,target=...][,required][,mode=0400][,uid=0][,gid=0]".
+func parseRunSecret(spec string, secrets map[string][]byte) (runSecret, error) {
+	m := runSecret{mode: 0o400}
+	mountType := ""
+	for _, field := range strings.Split(spec, ",") {
+		k, v, hasValue := strings.Cut(strings.TrimSpace(field), "=")
+		switch strings.ToLower(k) {
+		case "type":
+			mountType = v
+		case "id":
+			m.id = v
+		case "target", "dst", "destination":
+			m.target = v
+		case "required":
+			if !hasValue {
+				m.required = true
+				continue
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return m, fmt.Errorf("invalid required value: %s", v)
+			}
+			m.required = b
+		case "mode":
+			mode, err := strconv.ParseUint(v, 8, 32)
+			if err != nil || mode > 0o777 {
+				return m, fmt.Errorf("invalid mode, expected octal: %s", v)
+			}
+			m.mode = os.FileMode(mode)
+		case "uid", "gid":
+			id, err := strconv.Atoi(v)
+			if err != nil || id < 0 {
+				return m, fmt.Errorf("invalid %s: %s", k, v)
+			}
+			if k == "uid" {
+				m.uid = id
+			} else {
+				m.gid = id
+			}
+		case "":
+		default:
+			return m, fmt.Errorf("unsupported mount option: %s", k)
+		}
+	}
+	if mountType != "secret" {
+		return m, fmt.Errorf("unsupported mount type %q, only type=secret is supported", mountType)
+	}
+	if m.id == "" && m.target != "" {
+		m.id = path.Base(m.target)
+	}
+	if !secretIdPattern.MatchString(m.id) {
+		return m, fmt.Errorf("invalid secret id: %q", m.id)
+	}
+	if m.target == "" {
+		m.target = path.Join(defaultSecretDir, m.id)
+	}
+	if !path.IsAbs(m.target) {
+		return m, fmt.Errorf("secret target must be absolute: %s", m.target)
+	}
+	m.target = path.Clean(m.target)
+	if m.target == "/" {
+		return m, errors.New("secret target must not be /")
+	}
+
+	if value, ok := secrets[m.id]; ok {
+		m.value = value
+	} else if m.required {
+		return m, fmt.Errorf("secret %s is required but was not provided", m.id)
+	}
+	return m, nil
+}
+
+// prepareRunSecrets writes the secrets of a step below secretDir, outside
+// the overlay, and returns them as read-only bind mounts. Mount points the
+// rootfs does not have yet are created in the upper dir and recorded, so
+// they can be removed again once the step is done.
+func prepareRunSecrets(secrets []runSecret, secretDir string, rootfs string, upperDir string) ([]string, []string, error) {
+	var (
+		mounts  []string
+		created []string
+	)
+	for i, m := range secrets {
+		if m.value == nil {
+			continue
+		}
+		if err := os.MkdirAll(secretDir, 0o700); err != nil {
+			return nil, created, err
+		}
+		hostPath := filepath.Join(secretDir, strconv.Itoa(i))
+		if err := os.WriteFile(hostPath, m.value, m.mode); err != nil {
+			return nil, created, err
+		}
+		if err := os.Chmod(hostPath, m.mode); err != nil {
+			return nil, created, err
+		}
+		if err := os.Chown(hostPath, m.uid, m.gid); err != nil {
+			return nil, created, err
+		}
+
+		stubs, err := createMountPoint(rootfs, upperDir, m.target)
+		created = append(created, stubs...)
+		if err != nil {
+			return nil, created, err
+		}
+		mounts = append(mounts, hostPath+":"+m.target+":ro")
+	}
+	return mounts, created, nil
+}
+
+// createMountPoint creates target as an empty file in the upper dir, along
+// with the parent directories missing in the rootfs, and returns what it created.
+func createMountPoint(rootfs string, upperDir string, target string) ([]string, error) {
+	if _, err := resolveInRootfs(rootfs, target); err == nil {
+		return nil, nil
+	}
+	var created []string
+	rel := strings.TrimPrefix(target, "/")
+	elems := strings.Split(rel, "/")
+	for i := 1; i < len(elems); i++ {
+		dirRel := path.Join(elems[:i]...)
+		p := filepath.Join(upperDir, dirRel)
+		if _, err := os.Lstat(p); err == nil {
+			continue
+		}
+		if err := os.Mkdir(p, 0o755); err != nil {
+			return created, err
+		}
+		created = append(created, p)
+		// the upper dir shadows the metadata of a directory the rootfs already has
+		if lower, err := resolveInRootfs(rootfs, dirRel); err == nil {
+			info, err := os.Stat(lower)
+			if err != nil {
+				return created, err
+			}
+			if err := copyMetadata(p, info); err != nil {
+				return created, err
+			}
+		}
+	}
+	p := filepath.Join(upperDir, rel)
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o400)
+	if err != nil {
+		return created, err
+	}
+	created = append(created, p)
+	return created, f.Close()
+}
+
+// removeMountPoints drops the mount point stubs from the upper dir again,
+// deepest first. A directory the step wrote something into is kept.
+func removeMountPoints(created []string) error {
+	sort.Sort(sort.Reverse(sort.StringSlice(created)))
+	for _, p := range created {
+		info, err := os.Lstat(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if info.IsDir() {
+			entries, err := os.ReadDir(p)
+			if err != nil {
+				return err
+			}
+			if len(entries) > 0 {
+				continue
+			}
+		} else if !info.Mode().IsRegular() || info.Size() != 0 {
+			// the step replaced the stub, the verification reports it
+			continue
+		}
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyNoSecrets makes sure nothing of the mounted secrets ended up in the
+// upper dir: no file at a secret target and no file holding a secret value.
+func verifyNoSecrets(upperDir string, secrets []runSecret) error {
+	var scanned []runSecret
+	for _, m := range secrets {
+		if m.value == nil {
+			continue
+		}
+		if info, err := os.Lstat(filepath.Join(upperDir, strings.TrimPrefix(m.target, "/"))); err == nil && info.Mode().IsRegular() {
+			return fmt.Errorf("%w: %s left at %s", ErrSecretLeaked, m.id, m.target)
+		}
+		if len(bytes.TrimSpace(m.value)) >= minScannedSecretLen {
+			scanned = append(scanned, m)
+		}
+	}
+	if len(scanned) == 0 {
+		return nil
+	}
+	return filepath.WalkDir(upperDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		for _, m := range scanned {
+			found, err := fileContains(p, bytes.TrimSpace(m.value))
+			if err != nil {
+				return err
+			}
+			if found {
+				rel, _ := filepath.Rel(upperDir, p)
+				return fmt.Errorf("%w: %s found in /%s", ErrSecretLeaked, m.id, filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	})
+}
+
+// redactSecrets masks secret values in step output and errors.
+func redactSecrets(text string, secrets []runSecret) string {
+	for _, m := range secrets {
+		value := string(bytes.TrimSpace(m.value))
+		if len(value) >= minScannedSecretLen {
+			text = strings.ReplaceAll(text, value, "****")
+		}
+	}
+	return text
+}
+
+// fileContains searches needle in the file chunk by chunk, the tail of each
+// chunk is kept so matches across chunk borders are found.
+func fileContains(p string, needle []byte) (bool, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 0, 64*1024+len(needle))
+	chunk := make([]byte, 64*1024)
+	for {
+		n, err := f.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if bytes.Contains(buf, needle) {
+			return true, nil
+		}
+		if len(buf) >= len(needle) {
+			buf = append(buf[:0], buf[len(buf)-len(needle)+1:]...)
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
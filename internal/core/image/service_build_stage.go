@@ -0,0 +1,337 @@
+package image
+
+import (
+	"condenser/internal/store/ilm"
+	"condenser/internal/utils"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var stageNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+
+// buildStage is one FROM section of a Dripfile.
+type buildStage struct {
+	index        int
+	name         string
+	base         string
+	platform     string // FROM --platform, the build platform when empty
+	instructions []buildInstruction
+}
+
+// buildStageSet tracks the stages of one build and the states of the
+// stages already built, so later stages can start from or copy out of them.
+type buildStageSet struct {
+	stages     []buildStage
+	built      map[int]*buildState
+	buildArgs  map[string]string
+	globalArgs map[string]string
+	noCache    bool
+
+	// platform images are resolved for, whatever the reference has first when empty
+	platform string
+
+	// hermetic builds stamp everything with sourceDate instead of the clock
+	hermetic   bool
+	sourceDate time.Time
+
+	// values for RUN --mount=type=secret, by id
+	secrets map[string][]byte
+
+	// images the build read from, reference -> digest, for the provenance
+	resolved map[string]string
+
+	// progress reporting, steps are counted over the required stages
+	progress   func(BuildProgress)
+	totalSteps int
+	step       int
+}
+
+// now is the time recorded in the image.
+func (set *buildStageSet) now() time.Time {
+	if set.hermetic {
+		return set.sourceDate
+	}
+	return time.Now()
+}
+
+// stagePlatform is the platform the base images of a stage are used in.
+func (set *buildStageSet) stagePlatform(index int) string {
+	if index < len(set.stages) && set.stages[index].platform != "" {
+		return set.stages[index].platform
+	}
+	return set.platform
+}
+
+// resolveImage records the digest of an image the build depends on. The
+// platforms of a pulled image share the manifest digest, so the platform
+// the bundle was picked for is recorded with it.
+func (s *ImageService) resolveImage(set *buildStageSet, imageRepo, imageRef string, bundle ilm.PlatformBundle) (string, error) {
+	digest, err := s.imageDigest(imageRepo, imageRef)
+	if err != nil {
+		return "", err
+	}
+	key := imageRepo + ":" + imageRef
+	if bundle.Platform != "" {
+		key += " " + bundle.Platform
+	}
+	set.resolved[key] = digest
+	return digest, nil
+}
+
+func (set *buildStageSet) emit(ev BuildProgress) {
+	if set.progress != nil {
+		set.progress(ev)
+	}
+}
+
+// output forwards the output of a RUN step.
+func (set *buildStageSet) output(chunk string) {
+	set.emit(BuildProgress{Type: BuildProgressOutput, Step: set.step, Total: set.totalSteps, Message: chunk})
+}
+
+// splitBuildStages groups instructions by FROM. Only ARG may come before
+// the first FROM; those global args are returned and can be used in FROM.
+func splitBuildStages(instructions []buildInstruction, buildArgs map[string]string) ([]buildStage, map[string]string, error) {
+	var (
+		stages     []buildStage
+		globalIns  []buildInstruction
+		globalArgs = map[string]string{}
+	)
+	names := map[string]bool{}
+	for _, ins := range instructions {
+		if ins.op != "FROM" {
+			if len(stages) == 0 {
+				if ins.op != "ARG" {
+					return nil, nil, fmt.Errorf("%s before FROM instruction", ins.op)
+				}
+				globalIns = append(globalIns, ins)
+				continue
+			}
+			last := &stages[len(stages)-1]
+			last.instructions = append(last.instructions, ins)
+			continue
+		}
+
+		if len(stages) == 0 && len(globalIns) > 0 {
+			var err error
+			if globalArgs, err = resolveGlobalArgs(globalIns, buildArgs); err != nil {
+				return nil, nil, err
+			}
+		}
+		base, name, platform, err := parseFromArgs(expandBuildVars(ins.args, func(k string) (string, bool) {
+			v, ok := globalArgs[k]
+			return v, ok
+		}))
+		if err != nil {
+			return nil, nil, err
+		}
+		if name != "" {
+			if names[name] {
+				return nil, nil, fmt.Errorf("duplicate stage name: %s", name)
+			}
+			names[name] = true
+		}
+		stages = append(stages, buildStage{
+			index:        len(stages),
+			name:         name,
+			base:         base,
+			platform:     platform,
+			instructions: []buildInstruction{ins},
+		})
+	}
+	if len(stages) == 0 {
+		return nil, nil, errors.New("missing FROM instruction")
+	}
+	return stages, globalArgs, nil
+}
+
+// parseFromArgs parses "[--platform=os/arch[/variant]] <image> [AS <name>]".
+// Other flags are ignored.
+func parseFromArgs(args string) (base string, name string, platform string, err error) {
+	var parts []string
+	for _, p := range strings.Fields(args) {
+		if flag, ok := strings.CutPrefix(p, "--"); ok {
+			k, v, _ := strings.Cut(flag, "=")
+			if strings.ToLower(k) == "platform" {
+				parsed, err := utils.ParsePlatform(v)
+				if err != nil {
+					return "", "", "", err
+				}
+				platform = parsed.String()
+			}
+			continue
+		}
+		parts = append(parts, p)
+	}
+	switch {
+	case len(parts) == 1:
+		return parts[0], "", platform, nil
+	case len(parts) == 3 && strings.EqualFold(parts[1], "AS"):
+		name = strings.ToLower(parts[2])
+		if !stageNamePattern.MatchString(name) {
+			return "", "", "", fmt.Errorf("invalid stage name: %s", parts[2])
+		}
+		return parts[0], name, platform, nil
+	case len(parts) == 0:
+		return "", "", "", errors.New("FROM requires image")
+	default:
+		return "", "", "", fmt.Errorf("invalid FROM instruction: %s", args)
+	}
+}
+
+// lookup resolves a stage name or index among the stages before stage "before".
+func (set *buildStageSet) lookup(ref string, before int) (int, bool) {
+	ref = strings.ToLower(ref)
+	for i := 0; i < before && i < len(set.stages); i++ {
+		if set.stages[i].name != "" && set.stages[i].name == ref {
+			return i, true
+		}
+	}
+	if idx, err := strconv.Atoi(ref); err == nil && idx >= 0 && idx < before {
+		return idx, true
+	}
+	return 0, false
+}
+
+// targetIndex returns the stage selected by --target, the last stage by default.
+func (set *buildStageSet) targetIndex(target string) (int, error) {
+	if target == "" {
+		return len(set.stages) - 1, nil
+	}
+	if idx, ok := set.lookup(target, len(set.stages)); ok {
+		return idx, nil
+	}
+	return 0, fmt.Errorf("target stage %q not found", target)
+}
+
+// requiredStages returns the stages the target depends on through
+// FROM <stage> and COPY --from=<stage>, including the target itself.
+func (set *buildStageSet) requiredStages(target int) map[int]bool {
+	required := map[int]bool{}
+	var visit func(i int)
+	visit = func(i int) {
+		if required[i] {
+			return
+		}
+		required[i] = true
+		st := set.stages[i]
+		if dep, ok := set.lookup(st.base, i); ok {
+			visit(dep)
+		}
+		for _, ins := range st.instructions {
+			if ins.op != "COPY" && ins.op != "ADD" {
+				continue
+			}
+			flags, _ := splitInstructionFlags(ins.args)
+			if from, ok := flags["from"]; ok {
+				if dep, ok := set.lookup(from, i); ok {
+					visit(dep)
+				}
+			}
+		}
+	}
+	visit(target)
+	return required
+}
+
+// cleanup removes the rootfs of every built stage.
+func (set *buildStageSet) cleanup() {
+	for _, st := range set.built {
+		if st.rootfsPath != "" {
+			_ = os.RemoveAll(st.rootfsPath)
+		}
+	}
+}
+
+// sourceRootfs returns the rootfs COPY --from reads from: an earlier
+// stage of this build, or else a local (or pulled) image.
+func (s *ImageService) sourceRootfs(set *buildStageSet, current int, from string) (string, error) {
+	if idx, ok := set.lookup(from, current); ok {
+		st, built := set.built[idx]
+		if !built {
+			return "", fmt.Errorf("stage %q has not been built", from)
+		}
+		return st.rootfsPath, nil
+	}
+
+	imageRepo, imageRef, err := s.parseImageRef(from)
+	if err != nil {
+		return "", fmt.Errorf("COPY --from=%s: %w", from, err)
+	}
+	bundle, err := s.resolveImagePlatform(from, imageRepo, imageRef, set.stagePlatform(current))
+	if err != nil {
+		return "", err
+	}
+	_ = s.ilmHandler.TouchImage(imageRepo, imageRef)
+	if _, err := s.resolveImage(set, imageRepo, imageRef, bundle); err != nil {
+		return "", err
+	}
+	return bundle.RootfsPath, nil
+}
+
+// applyFromStage starts a stage from the result of an earlier stage.
+func (s *ImageService) applyFromStage(state *buildState, base *buildState) error {
+	tmpRootfs, err := os.MkdirTemp("", "raind-build-rootfs-")
+	if err != nil {
+		return err
+	}
+	if err := copyDir(base.rootfsPath, tmpRootfs); err != nil {
+		_ = os.RemoveAll(tmpRootfs)
+		return err
+	}
+
+	*state = buildState{
+		imageRepo:    base.imageRepo,
+		imageRef:     base.imageRef,
+		rootfsPath:   tmpRootfs,
+		env:          cloneSlice(base.env),
+		workdir:      base.workdir,
+		user:         base.user,
+		cmd:          cloneSlice(base.cmd),
+		entrypoint:   cloneSlice(base.entrypoint),
+		os:           base.os,
+		architecture: base.architecture,
+		variant:      base.variant,
+		labels:       cloneStringMap(base.labels),
+		exposedPorts: clonePortSet(base.exposedPorts),
+		volumes:      cloneVolumeSet(base.volumes),
+		healthcheck:  cloneHealthConfig(base.healthcheck),
+		shell:        cloneSlice(base.shell),
+		stopSignal:   base.stopSignal,
+		history:      append([]ImageHistoryObject{}, base.history...),
+		args:         state.args,
+		cacheKey:     base.cacheKey,
+	}
+	return nil
+}
+
+// splitInstructionFlags separates leading --key=value flags from the arguments.
+func splitInstructionFlags(args string) (map[string]string, []string) {
+	flags := map[string]string{}
+	parts := strings.Fields(args)
+	i := 0
+	for ; i < len(parts); i++ {
+		if !strings.HasPrefix(parts[i], "--") {
+			break
+		}
+		k, v, _ := strings.Cut(strings.TrimPrefix(parts[i], "--"), "=")
+		flags[strings.ToLower(k)] = v
+	}
+	return flags, parts[i:]
+}
+
+// copySourcePath resolves a COPY source inside the build context or,
+// with --from, inside the source rootfs where paths are absolute and
+// symlinks are resolved against that rootfs.
+func copySourcePath(baseDir string, src string, fromRootfs bool) (string, error) {
+	if fromRootfs {
+		return resolveInRootfs(baseDir, filepath.ToSlash(src))
+	}
+	return safeJoin(baseDir, src)
+}
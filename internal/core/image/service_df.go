@@ -0,0 +1,159 @@
+package image
+
+import (
+	"condenser/internal/utils"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+)
+
+// inodeKey identifies a file across bundles, hard links and tags that
+// share a bundle resolve to the same key.
+type inodeKey struct {
+	dev uint64
+	ino uint64
+}
+
+// == service: image disk usage ==
+// GetImageDiskUsage measures the bundles of every image. Files shared by
+// several images, through tags or hard links, are counted once in the
+// totals. Images no container uses are reclaimable, as are bundles no
+// image references anymore.
+func (s *ImageService) GetImageDiskUsage() (ImageDiskUsage, error) {
+	result := ImageDiskUsage{Images: []ImageDiskUsageInfo{}}
+
+	imageList, err := s.ilmHandler.GetImageList()
+	if err != nil {
+		return result, err
+	}
+	usage, err := s.collectImageUsage()
+	if err != nil {
+		return result, err
+	}
+
+	// inode -> size and the images holding it
+	sizes := map[inodeKey]int64{}
+	owners := map[inodeKey]map[int]struct{}{}
+	referenced := map[string]struct{}{}
+	perImage := make([]map[inodeKey]struct{}, len(imageList))
+	for i, img := range imageList {
+		perImage[i] = map[inodeKey]struct{}{}
+		for _, bundlePath := range img.BundlePaths {
+			referenced[filepath.Clean(bundlePath)] = struct{}{}
+			if err := walkInodes(bundlePath, func(key inodeKey, size int64) {
+				sizes[key] = size
+				perImage[i][key] = struct{}{}
+				if owners[key] == nil {
+					owners[key] = map[int]struct{}{}
+				}
+				owners[key][i] = struct{}{}
+			}); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	inUse := make([]bool, len(imageList))
+	for i, img := range imageList {
+		if u, ok := usageOf(usage, img); ok && len(u.containers) > 0 {
+			inUse[i] = true
+			result.ActiveCount++
+		}
+	}
+
+	for i, img := range imageList {
+		info := ImageDiskUsageInfo{
+			Image:       imageKey(img.Repository, img.Reference),
+			Platforms:   img.Platforms,
+			Reclaimable: !inUse[i],
+		}
+		if u, ok := usageOf(usage, img); ok {
+			info.Containers = len(u.containers)
+		}
+		for key := range perImage[i] {
+			info.SizeBytes += sizes[key]
+			if len(owners[key]) == 1 {
+				info.UniqueBytes += sizes[key]
+			} else {
+				info.SharedBytes += sizes[key]
+			}
+		}
+		result.Images = append(result.Images, info)
+	}
+	for key, size := range sizes {
+		result.TotalBytes += size
+		reclaimable := true
+		for i := range owners[key] {
+			if inUse[i] {
+				reclaimable = false
+				break
+			}
+		}
+		if reclaimable {
+			result.ReclaimableBytes += size
+		}
+	}
+
+	// bundles left behind by removed images or failed pulls
+	bundles, err := s.findBundleDirs(utils.LayerRootDir)
+	if err != nil {
+		return result, err
+	}
+	for _, b := range bundles {
+		if _, ok := referenced[b]; ok {
+			continue
+		}
+		var size int64
+		if err := walkInodes(b, func(key inodeKey, n int64) {
+			if _, counted := sizes[key]; counted {
+				return
+			}
+			sizes[key] = n
+			size += n
+		}); err != nil {
+			return result, err
+		}
+		result.DanglingBytes += size
+		result.TotalBytes += size
+		result.ReclaimableBytes += size
+	}
+
+	sort.Slice(result.Images, func(i, j int) bool {
+		return result.Images[i].SizeBytes > result.Images[j].SizeBytes
+	})
+	return result, nil
+}
+
+// walkInodes calls fn once per inode below root with the bytes allocated
+// for it on disk. Directories count too, they take blocks as well.
+func walkInodes(root string, fn func(key inodeKey, size int64)) error {
+	seen := map[inodeKey]struct{}{}
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		st, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		key := inodeKey{dev: uint64(st.Dev), ino: st.Ino}
+		if _, dup := seen[key]; dup {
+			return nil
+		}
+		seen[key] = struct{}{}
+		fn(key, st.Blocks*512)
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package image
+
+import (
+	"condenser/internal/store/ilm"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ErrDigestRequired is returned when the reference policy rejects a tag-only image reference.
+var ErrDigestRequired = errors.New("tag-only image reference rejected by policy, pin the image with @sha256:<digest>")
+
+// == service: reference policy ==
+// CheckReferencePolicy rejects tag references while the policy requires
+// digests. Pulled images are pinned by their manifest (or index) digest,
+// locally built images by their config digest, the image id.
+func (s *ImageService) CheckReferencePolicy(imageStr string) error {
+	policy, err := s.ilmHandler.GetReferencePolicy()
+	if err != nil {
+		return err
+	}
+	if !policy.RequireDigest {
+		return nil
+	}
+
+	_, ref, err := s.parseImageRef(imageStr)
+	if err != nil {
+		return err
+	}
+	if !isDigestReference(ref) {
+		return fmt.Errorf("%s: %w", imageStr, ErrDigestRequired)
+	}
+	return nil
+}
+
+func (s *ImageService) GetReferencePolicy() (ImageReferencePolicy, error) {
+	policy, err := s.ilmHandler.GetReferencePolicy()
+	if err != nil {
+		return ImageReferencePolicy{}, err
+	}
+	return ImageReferencePolicy{
+		RequireDigest: policy.RequireDigest,
+	}, nil
+}
+
+func (s *ImageService) SetReferencePolicy(policyParameter ServiceReferencePolicyModel) (ImageReferencePolicy, error) {
+	if err := s.ilmHandler.SetReferencePolicy(ilm.ReferencePolicy{
+		RequireDigest: policyParameter.RequireDigest,
+	}); err != nil {
+		return ImageReferencePolicy{}, err
+	}
+	return ImageReferencePolicy{
+		RequireDigest: policyParameter.RequireDigest,
+	}, nil
+}
+
+// bundleManifestDigest returns the digest of the top-level manifest stored at pull time.
+// for multi-arch images this is the index digest, which is what name@sha256:... refers to.
+func (s *ImageService) bundleManifestDigest(bundlePath string) (string, error) {
+	b, err := s.filesystemHandler.ReadFile(filepath.Join(bundlePath, "manifest.json"))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+func isDigestReference(ref string) bool {
+	hexPart, ok := strings.CutPrefix(ref, "sha256:")
+	if !ok || len(hexPart) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(hexPart)
+	return err == nil
+}
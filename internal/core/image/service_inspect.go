@@ -0,0 +1,174 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// == service: inspect image ==
+func (s *ImageService) InspectImage(inspectParameter ServiceInspectModel) (ImageInspectInfo, error) {
+	repo, ref, err := s.parseImageRef(inspectParameter.Image)
+	if err != nil {
+		return ImageInspectInfo{}, err
+	}
+
+	info, err := s.ilmHandler.GetImageInfo(repo, ref)
+	if err != nil {
+		return ImageInspectInfo{}, err
+	}
+	// the first stored platform unless one is asked for
+	bundle, err := s.ilmHandler.ResolvePlatform(repo, ref, inspectParameter.Platform)
+	if err != nil {
+		return ImageInspectInfo{}, err
+	}
+	bundlePath := bundle.BundlePath
+	configPath := bundle.ConfigPath
+
+	configBytes, err := s.filesystemHandler.ReadFile(configPath)
+	if err != nil {
+		return ImageInspectInfo{}, err
+	}
+	var cfg ImageConfigFile
+	if err := json.Unmarshal(configBytes, &cfg); err != nil {
+		return ImageInspectInfo{}, err
+	}
+
+	// config.json is a copy of the config blob for pulled images,
+	// so its digest matches the one referenced by the manifest.
+	configDigest := sha256.Sum256(configBytes)
+
+	result := ImageInspectInfo{
+		Repository:   info.Repository,
+		Reference:    info.Reference,
+		ConfigDigest: "sha256:" + hex.EncodeToString(configDigest[:]),
+		Layers:       []ImageLayerInfo{},
+		Platform: ImagePlatform{
+			Os:           cfg.Os,
+			Architecture: cfg.Architecture,
+			Variant:      cfg.Variant,
+		},
+		Env:          cfg.Config.Env,
+		Entrypoint:   cfg.Config.Entrypoint,
+		Cmd:          cfg.Config.Cmd,
+		WorkingDir:   cfg.Config.WorkingDir,
+		User:         cfg.Config.User,
+		Labels:       cfg.Config.Labels,
+		ExposedPorts: sortedSet(cfg.Config.ExposedPorts),
+		Volumes:      sortedSet(cfg.Config.Volumes),
+		Healthcheck:  cfg.Config.Healthcheck,
+		Shell:        cfg.Config.Shell,
+		StopSignal:   cfg.Config.StopSignal,
+		Platforms:    info.Platforms,
+		CreatedAt:    info.CreatedAt,
+	}
+
+	// built images have no registry manifest, only a flattened rootfs
+	manifestBytes, err := s.readManifest(bundlePath)
+	if err != nil {
+		if !s.filesystemHandler.IsNotExist(err) {
+			return ImageInspectInfo{}, err
+		}
+		usedBytes, err := s.dirSize(bundle.RootfsPath)
+		if err != nil {
+			return ImageInspectInfo{}, err
+		}
+		result.SizeBytes = usedBytes
+		return result, nil
+	}
+
+	var m singleManifest
+	if err := json.Unmarshal(manifestBytes, &m); err != nil {
+		return ImageInspectInfo{}, err
+	}
+	// the digest name@sha256:... refers to, as ilm and csm record it: the
+	// index for multi-arch images, not the selected platform manifest
+	result.ManifestDigest = info.ManifestDigest
+	if result.ManifestDigest == "" {
+		if result.ManifestDigest, err = s.bundleManifestDigest(bundlePath); err != nil {
+			return ImageInspectInfo{}, err
+		}
+	}
+	if m.Config.Digest != "" {
+		result.ConfigDigest = m.Config.Digest
+	}
+	result.SizeBytes = m.Config.Size
+	for _, l := range m.Layers {
+		result.Layers = append(result.Layers, ImageLayerInfo{
+			MediaType: l.MediaType,
+			Digest:    l.Digest,
+			SizeBytes: l.Size,
+		})
+		result.SizeBytes += l.Size
+	}
+
+	return result, nil
+}
+
+// == service: image history ==
+func (s *ImageService) GetImageHistory(imageStr string) ([]ImageHistoryInfo, error) {
+	repo, ref, err := s.parseImageRef(imageStr)
+	if err != nil {
+		return nil, err
+	}
+
+	configPath, err := s.ilmHandler.GetConfigPath(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := s.GetImageConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// layers are matched with the non-empty history entries in order
+	var layers []ImageLayerInfo
+	if bundlePath, err := s.ilmHandler.GetBundlePath(repo, ref); err == nil {
+		if manifestBytes, err := s.readManifest(bundlePath); err == nil {
+			var m singleManifest
+			if err := json.Unmarshal(manifestBytes, &m); err == nil {
+				for _, l := range m.Layers {
+					layers = append(layers, ImageLayerInfo{
+						MediaType: l.MediaType,
+						Digest:    l.Digest,
+						SizeBytes: l.Size,
+					})
+				}
+			}
+		}
+	}
+
+	history := make([]ImageHistoryInfo, 0, len(cfg.History))
+	layerIdx := 0
+	for _, h := range cfg.History {
+		entry := ImageHistoryInfo{
+			Created:    h.Created,
+			CreatedBy:  h.CreatedBy,
+			Comment:    h.Comment,
+			EmptyLayer: h.EmptyLayer,
+		}
+		if !h.EmptyLayer && layerIdx < len(layers) {
+			entry.LayerDigest = layers[layerIdx].Digest
+			entry.SizeBytes = layers[layerIdx].SizeBytes
+			layerIdx++
+		}
+		history = append(history, entry)
+	}
+
+	// newest first, as docker history does
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
+
+// sortedSet lists the keys of a docker style set (ExposedPorts, Volumes).
+func sortedSet(set map[string]struct{}) []string {
+	list := make([]string, 0, len(set))
+	for p := range set {
+		list = append(list, p)
+	}
+	sort.Strings(list)
+	return list
+}
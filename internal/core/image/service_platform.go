@@ -0,0 +1,119 @@
+package image
+
+import (
+	"condenser/internal/store/ilm"
+	"condenser/internal/utils"
+	"fmt"
+	"slices"
+)
+
+// pullPlatform is the platform a pull asks for: Platform when given, else
+// Os and Arch, each defaulting to the host. explicit tells whether the
+// caller named one.
+func pullPlatform(pullParameter ServicePullModel) (utils.Platform, bool, error) {
+	if pullParameter.Platform != "" {
+		p, err := utils.ParsePlatform(pullParameter.Platform)
+		return p, true, err
+	}
+	host, err := utils.HostPlatform()
+	if err != nil {
+		return utils.Platform{}, false, err
+	}
+	p := host
+	if pullParameter.Os != "" {
+		p.Os = pullParameter.Os
+	}
+	if pullParameter.Arch != "" {
+		p.Arch = pullParameter.Arch
+	}
+	return p, pullParameter.Os != "" || pullParameter.Arch != "", nil
+}
+
+// configPlatform reads the platform an image config declares.
+func (s *ImageService) configPlatform(configPath string) (utils.Platform, error) {
+	cfg, err := s.GetImageConfig(configPath)
+	if err != nil {
+		return utils.Platform{}, err
+	}
+	if cfg.Os == "" || cfg.Architecture == "" {
+		return utils.Platform{}, fmt.Errorf("image config %s names no platform", configPath)
+	}
+	return utils.Platform{Os: cfg.Os, Arch: cfg.Architecture, Variant: cfg.Variant}, nil
+}
+
+// resolveImagePlatform returns the bundle of an image for platform, pulling
+// the image, or just that platform of it, when it is not stored yet. An
+// empty platform takes whatever the reference has, as before platforms.
+func (s *ImageService) resolveImagePlatform(image string, imageRepo, imageRef string, platform string) (ilm.PlatformBundle, error) {
+	if platform == "" {
+		if !s.ilmHandler.IsImageExist(imageRepo, imageRef) {
+			if err := s.Pull(ServicePullModel{Image: image}); err != nil {
+				return ilm.PlatformBundle{}, err
+			}
+		}
+		return s.ilmHandler.ResolvePlatform(imageRepo, imageRef, "")
+	}
+	if s.ilmHandler.IsImageExist(imageRepo, imageRef) {
+		if bundle, err := s.ilmHandler.ResolvePlatform(imageRepo, imageRef, platform); err == nil {
+			return bundle, nil
+		}
+	}
+	if err := s.Pull(ServicePullModel{Image: image, Platform: platform}); err != nil {
+		return ilm.PlatformBundle{}, err
+	}
+	return s.ilmHandler.ResolvePlatform(imageRepo, imageRef, platform)
+}
+
+// removeUnreferencedBundles removes bundle directories no reference of
+// any platform points to anymore.
+func (s *ImageService) removeUnreferencedBundles(bundlePaths []string) ([]string, error) {
+	var removed []string
+	for _, p := range bundlePaths {
+		if p == "" {
+			continue
+		}
+		refCount, err := s.ilmHandler.CountBundleReferences(p)
+		if err != nil {
+			return removed, err
+		}
+		if refCount > 0 {
+			continue
+		}
+		if err := s.filesystemHandler.RemoveAll(p); err != nil {
+			return removed, err
+		}
+		removed = append(removed, p)
+	}
+	return removed, nil
+}
+
+// bundleSharedWith reports whether a bundle is used by a reference other
+// than image, which a pull of image must then leave as it is.
+func (s *ImageService) bundleSharedWith(image string) func(string) bool {
+	var own []string
+	if repo, ref, err := s.parseImageRef(image); err == nil {
+		if info, err := s.ilmHandler.GetImageInfo(repo, ref); err == nil && info.Reference == ref {
+			own = info.BundlePaths
+		}
+	}
+	return func(bundlePath string) bool {
+		refCount, err := s.ilmHandler.CountBundleReferences(bundlePath)
+		if err != nil {
+			return true
+		}
+		if slices.Contains(own, bundlePath) {
+			refCount--
+		}
+		return refCount > 0
+	}
+}
+
+// bundlesSize is the disk usage of every platform bundle of an image.
+func (s *ImageService) bundlesSize(info ilm.ImageInfo) int64 {
+	var total int64
+	for _, p := range info.BundlePaths {
+		size, _ := s.dirSize(p)
+		total += size
+	}
+	return total
+}
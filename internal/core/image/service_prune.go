@@ -0,0 +1,513 @@
+package image
+
+import (
+	"condenser/internal/store/ilm"
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	pruneModeDangling = "dangling"
+	pruneModeUnused   = "unused"
+	pruneModeAge      = "age"
+
+	// bundles younger than this are left alone, they may belong to a pull in progress
+	danglingGracePeriod = time.Hour
+
+	defaultGcHighWatermark = 85
+	defaultGcLowWatermark  = 75
+)
+
+type imageUsage struct {
+	containers []string
+	templates  []string
+	bottles    []string
+}
+
+func (u *imageUsage) reason() string {
+	var parts []string
+	if len(u.containers) > 0 {
+		parts = append(parts, "used by container(s): "+strings.Join(u.containers, ", "))
+	}
+	if len(u.templates) > 0 {
+		parts = append(parts, "referenced by pod template(s): "+strings.Join(u.templates, ", "))
+	}
+	if len(u.bottles) > 0 {
+		parts = append(parts, "referenced by bottle(s): "+strings.Join(u.bottles, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// == service: prune images ==
+func (s *ImageService) Prune(pruneParameter ServicePruneModel) (ImagePruneResult, error) {
+	mode := pruneParameter.Mode
+	if mode == "" {
+		mode = pruneModeDangling
+	}
+
+	switch mode {
+	case pruneModeDangling:
+		return s.pruneDangling()
+	case pruneModeUnused:
+		return s.pruneImages(pruneParameter.OlderThan)
+	case pruneModeAge:
+		if pruneParameter.OlderThan <= 0 {
+			return ImagePruneResult{}, errors.New("olderThan is required for age mode")
+		}
+		return s.pruneImages(pruneParameter.OlderThan)
+	default:
+		return ImagePruneResult{}, fmt.Errorf("unsupported prune mode: %s", mode)
+	}
+}
+
+// pruneDangling removes ilm entries whose bundle is gone and
+// bundle directories that no ilm entry references anymore.
+func (s *ImageService) pruneDangling() (ImagePruneResult, error) {
+	result := ImagePruneResult{
+		Removed: []PrunedImage{},
+		Skipped: []SkippedImage{},
+	}
+
+	imageList, err := s.ilmHandler.GetImageList()
+	if err != nil {
+		return result, err
+	}
+
+	referenced := map[string]struct{}{}
+	for _, img := range imageList {
+		if _, err := os.Stat(img.BundlePath); err != nil && os.IsNotExist(err) {
+			if err := s.ilmHandler.RemoveImage(img.Repository, img.Reference); err != nil {
+				return result, err
+			}
+			result.Removed = append(result.Removed, PrunedImage{
+				Image:      imageKey(img.Repository, img.Reference),
+				BundlePath: img.BundlePath,
+			})
+			continue
+		}
+		for _, p := range img.BundlePaths {
+			referenced[filepath.Clean(p)] = struct{}{}
+		}
+	}
+
+	bundles, err := s.findBundleDirs(utils.LayerRootDir)
+	if err != nil {
+		return result, err
+	}
+	for _, b := range bundles {
+		if _, ok := referenced[b]; ok {
+			continue
+		}
+		if s.isBundleRecent(b) {
+			result.Skipped = append(result.Skipped, SkippedImage{
+				Image:  b,
+				Reason: "bundle modified recently, possibly a pull in progress",
+			})
+			continue
+		}
+		size, _ := s.dirSize(b)
+		if err := s.filesystemHandler.RemoveAll(b); err != nil {
+			return result, err
+		}
+		result.Removed = append(result.Removed, PrunedImage{
+			Image:          "<none>",
+			BundlePath:     b,
+			ReclaimedBytes: size,
+		})
+		result.ReclaimedBytes += size
+	}
+
+	return result, nil
+}
+
+// pruneImages removes images that are not in use, optionally only those
+// older than olderThan. Containers, pod templates (those of replica sets
+// and deployments included) and bottle specs count as usage.
+func (s *ImageService) pruneImages(olderThan time.Duration) (ImagePruneResult, error) {
+	result := ImagePruneResult{
+		Removed: []PrunedImage{},
+		Skipped: []SkippedImage{},
+	}
+
+	imageList, err := s.ilmHandler.GetImageList()
+	if err != nil {
+		return result, err
+	}
+	usage, err := s.collectImageUsage()
+	if err != nil {
+		return result, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	for _, img := range imageList {
+		key := imageKey(img.Repository, img.Reference)
+		if olderThan > 0 && img.CreatedAt.After(cutoff) {
+			continue
+		}
+		if u, ok := usageOf(usage, img); ok {
+			result.Skipped = append(result.Skipped, SkippedImage{Image: key, Reason: u.reason()})
+			continue
+		}
+
+		size := s.bundlesSize(img)
+		removedPath, err := s.removeImageReference(img.Repository, img.Reference)
+		if err != nil {
+			result.Skipped = append(result.Skipped, SkippedImage{Image: key, Reason: err.Error()})
+			continue
+		}
+		if removedPath == "" {
+			size = 0
+		}
+		result.Removed = append(result.Removed, PrunedImage{
+			Image:          key,
+			BundlePath:     removedPath,
+			ReclaimedBytes: size,
+		})
+		result.ReclaimedBytes += size
+	}
+
+	return result, nil
+}
+
+// == service: disk high-watermark gc ==
+func (s *ImageService) CollectGarbage() (ImagePruneResult, error) {
+	result := ImagePruneResult{
+		Removed: []PrunedImage{},
+		Skipped: []SkippedImage{},
+	}
+
+	policy, err := s.GetGcPolicy()
+	if err != nil {
+		return result, err
+	}
+	if !policy.Enabled {
+		return result, nil
+	}
+
+	usedPercent, err := diskUsagePercent(utils.ImageRootDir)
+	if err != nil {
+		return result, err
+	}
+	if usedPercent < policy.HighWatermark {
+		return result, nil
+	}
+
+	// dangling bundles first, they are never needed
+	dangling, err := s.pruneDangling()
+	if err != nil {
+		return result, err
+	}
+	result.Removed = append(result.Removed, dangling.Removed...)
+	result.ReclaimedBytes += dangling.ReclaimedBytes
+
+	imageList, err := s.ilmHandler.GetImageList()
+	if err != nil {
+		return result, err
+	}
+	usage, err := s.collectImageUsage()
+	if err != nil {
+		return result, err
+	}
+
+	// least recently used first
+	sort.Slice(imageList, func(i, j int) bool {
+		return lastUsed(imageList[i]).Before(lastUsed(imageList[j]))
+	})
+
+	for _, img := range imageList {
+		usedPercent, err := diskUsagePercent(utils.ImageRootDir)
+		if err != nil {
+			return result, err
+		}
+		if usedPercent <= policy.LowWatermark {
+			break
+		}
+
+		key := imageKey(img.Repository, img.Reference)
+		// an image a template or bottle references is pulled again on the
+		// next pod or bottle start, gc keeps it as prune does
+		if _, ok := usageOf(usage, img); ok {
+			continue
+		}
+		size := s.bundlesSize(img)
+		removedPath, err := s.removeImageReference(img.Repository, img.Reference)
+		if err != nil {
+			result.Skipped = append(result.Skipped, SkippedImage{Image: key, Reason: err.Error()})
+			continue
+		}
+		if removedPath == "" {
+			size = 0
+		}
+		result.Removed = append(result.Removed, PrunedImage{
+			Image:          key,
+			BundlePath:     removedPath,
+			ReclaimedBytes: size,
+		})
+		result.ReclaimedBytes += size
+	}
+
+	return result, nil
+}
+
+// == service: gc policy ==
+func (s *ImageService) GetGcPolicy() (ImageGcPolicy, error) {
+	policy, err := s.ilmHandler.GetGcPolicy()
+	if err != nil {
+		return ImageGcPolicy{}, err
+	}
+	if policy.HighWatermark == 0 {
+		policy.HighWatermark = defaultGcHighWatermark
+	}
+	if policy.LowWatermark == 0 {
+		policy.LowWatermark = defaultGcLowWatermark
+	}
+	return ImageGcPolicy{
+		Enabled:       policy.Enabled,
+		HighWatermark: policy.HighWatermark,
+		LowWatermark:  policy.LowWatermark,
+	}, nil
+}
+
+func (s *ImageService) SetGcPolicy(policyParameter ServiceGcPolicyModel) (ImageGcPolicy, error) {
+	high := policyParameter.HighWatermark
+	if high == 0 {
+		high = defaultGcHighWatermark
+	}
+	low := policyParameter.LowWatermark
+	if low == 0 {
+		low = defaultGcLowWatermark
+	}
+	if high <= 0 || high > 100 || low <= 0 || low >= high {
+		return ImageGcPolicy{}, fmt.Errorf("invalid watermark: require 0 < low(%d) < high(%d) <= 100", low, high)
+	}
+
+	if err := s.ilmHandler.SetGcPolicy(ilm.GcPolicy{
+		Enabled:       policyParameter.Enabled,
+		HighWatermark: high,
+		LowWatermark:  low,
+	}); err != nil {
+		return ImageGcPolicy{}, err
+	}
+	return ImageGcPolicy{
+		Enabled:       policyParameter.Enabled,
+		HighWatermark: high,
+		LowWatermark:  low,
+	}, nil
+}
+
+// collectImageUsage returns who references each image, keyed by imageKey.
+func (s *ImageService) collectImageUsage() (map[string]*imageUsage, error) {
+	usage := map[string]*imageUsage{}
+	get := func(key string) *imageUsage {
+		u, ok := usage[key]
+		if !ok {
+			u = &imageUsage{}
+			usage[key] = u
+		}
+		return u
+	}
+
+	// containers
+	containerList, err := s.csmHandler.GetContainerList()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containerList {
+		if c.Repository == "" || c.Reference == "" {
+			continue
+		}
+		name := c.ContainerName + " (" + c.ContainerId + ")"
+		u := get(imageKey(c.Repository, c.Reference))
+		u.containers = append(u.containers, name)
+		if c.ImageDigest != "" && c.ImageDigest != c.Reference {
+			d := get(imageKey(c.Repository, c.ImageDigest))
+			d.containers = append(d.containers, name)
+		}
+	}
+
+	// pod templates, replica sets point to theirs, init containers included
+	addTemplate := func(spec psm.PodTemplateSpec, owner string) {
+		for _, c := range append(slices.Clone(spec.InitContainers), spec.Containers...) {
+			if c.Image == "" {
+				continue
+			}
+			repo, ref, err := s.parseImageRef(c.Image)
+			if err != nil {
+				continue
+			}
+			u := get(imageKey(repo, ref))
+			if !slices.Contains(u.templates, owner) {
+				u.templates = append(u.templates, owner)
+			}
+		}
+	}
+	templates, err := s.psmHandler.GetPodTemplateList()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		addTemplate(t.Spec, t.Spec.Namespace+"/"+t.Spec.Name)
+	}
+
+	// deployments keep their template until a rollout stores it
+	deployments, err := s.psmHandler.GetDeploymentList()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments {
+		addTemplate(d.Spec.Template, "deployment "+d.Spec.Namespace+"/"+d.Spec.Name)
+	}
+
+	// bottles
+	bottles, err := s.bsmHandler.GetBottleList()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bottles {
+		for name, svc := range b.Services {
+			if svc.Image == "" {
+				continue
+			}
+			repo, ref, err := s.parseImageRef(svc.Image)
+			if err != nil {
+				continue
+			}
+			u := get(imageKey(repo, ref))
+			u.bottles = append(u.bottles, b.BottleName+"/"+name)
+		}
+	}
+
+	for _, u := range usage {
+		sort.Strings(u.containers)
+		sort.Strings(u.templates)
+		sort.Strings(u.bottles)
+	}
+	return usage, nil
+}
+
+// findBundleDirs lists image bundle directories (those holding rootfs or config.json) under root.
+func (s *ImageService) findBundleDirs(root string) ([]string, error) {
+	var bundles []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		if isBundleDir(path) {
+			bundles = append(bundles, filepath.Clean(path))
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+func (s *ImageService) isBundleRecent(bundlePath string) bool {
+	cutoff := time.Now().Add(-danglingGracePeriod)
+	for _, p := range []string{bundlePath, filepath.Join(bundlePath, "blobs"), filepath.Join(bundlePath, "rootfs")} {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(cutoff) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBundleDir(path string) bool {
+	if info, err := os.Stat(filepath.Join(path, "rootfs")); err == nil && info.IsDir() {
+		return true
+	}
+	if info, err := os.Stat(filepath.Join(path, "config.json")); err == nil && !info.IsDir() {
+		return true
+	}
+	// failed pulls keep only their blobs for resuming
+	if info, err := os.Stat(filepath.Join(path, "blobs")); err == nil && info.IsDir() {
+		return true
+	}
+	return false
+}
+
+// usageOf merges the usage recorded under the image's reference and under its manifest digest.
+func usageOf(usage map[string]*imageUsage, img ilm.ImageInfo) (*imageUsage, bool) {
+	byRef, okRef := usage[imageKey(img.Repository, img.Reference)]
+	var (
+		byDigest *imageUsage
+		okDigest bool
+	)
+	if img.ManifestDigest != "" && img.ManifestDigest != img.Reference {
+		byDigest, okDigest = usage[imageKey(img.Repository, img.ManifestDigest)]
+	}
+	switch {
+	case okRef && okDigest:
+		return &imageUsage{
+			containers: mergeUnique(byRef.containers, byDigest.containers),
+			templates:  mergeUnique(byRef.templates, byDigest.templates),
+			bottles:    mergeUnique(byRef.bottles, byDigest.bottles),
+		}, true
+	case okRef:
+		return byRef, true
+	case okDigest:
+		return byDigest, true
+	}
+	return nil, false
+}
+
+func mergeUnique(a, b []string) []string {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, v := range append(append([]string{}, a...), b...) {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func imageKey(repo, ref string) string {
+	if strings.HasPrefix(ref, "sha256:") {
+		return repo + "@" + ref
+	}
+	return repo + ":" + ref
+}
+
+func lastUsed(img ilm.ImageInfo) time.Time {
+	if !img.LastUsedAt.IsZero() {
+		return img.LastUsedAt
+	}
+	return img.CreatedAt
+}
+
+func diskUsagePercent(path string) (int, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	if st.Blocks == 0 {
+		return 0, nil
+	}
+	used := st.Blocks - st.Bavail
+	return int(used * 100 / st.Blocks), nil
+}
@@ -0,0 +1,330 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SbomFormatSpdx      = "spdx"
+	SbomFormatCycloneDx = "cyclonedx"
+
+	sbomToolName = "raind-condenser"
+)
+
+// ErrUnsupportedSbomFormat is returned for formats other than spdx and cyclonedx.
+var ErrUnsupportedSbomFormat = errors.New("unsupported sbom format, use spdx or cyclonedx")
+
+// == service: sbom ==
+func (s *ImageService) GenerateSbom(sbomParameter ServiceSbomModel) ([]byte, error) {
+	format := strings.ToLower(sbomParameter.Format)
+	if format == "" {
+		format = SbomFormatSpdx
+	}
+	if format != SbomFormatSpdx && format != SbomFormatCycloneDx {
+		return nil, fmt.Errorf("%s: %w", sbomParameter.Format, ErrUnsupportedSbomFormat)
+	}
+
+	repo, ref, err := s.parseImageRef(sbomParameter.Image)
+	if err != nil {
+		return nil, err
+	}
+	configPath, err := s.ilmHandler.GetConfigPath(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	rootfsPath, err := s.ilmHandler.GetRootfsPath(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// the cache is keyed by the config digest, the image id. A re-pull of
+	// a moving tag may replace the rootfs in the same bundle directory.
+	config, err := s.filesystemHandler.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	configDigest := sha256.Sum256(config)
+	cachePath := sbomCachePath(configPath, format, hex.EncodeToString(configDigest[:]))
+	if cached, err := s.filesystemHandler.ReadFile(cachePath); err == nil {
+		return cached, nil
+	} else if !s.filesystemHandler.IsNotExist(err) {
+		return nil, err
+	}
+
+	catalog, err := scanRootfs(rootfsPath, s.commandFactory)
+	if err != nil {
+		return nil, fmt.Errorf("scan rootfs: %w", err)
+	}
+
+	imageName := repo + ":" + ref
+	var doc any
+	switch format {
+	case SbomFormatSpdx:
+		doc = renderSpdx(imageName, catalog)
+	case SbomFormatCycloneDx:
+		doc = renderCycloneDx(imageName, catalog)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// documents of the image the tag pointed to before are stale
+	stale, _ := filepath.Glob(sbomCachePath(configPath, format, "*"))
+	stale = append(stale, filepath.Join(filepath.Dir(configPath), "sbom."+format+".json"))
+	for _, p := range stale {
+		if err := s.filesystemHandler.Remove(p); err != nil && !s.filesystemHandler.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	tmp := cachePath + ".tmp"
+	if err := s.filesystemHandler.WriteFile(tmp, data, 0o644); err != nil {
+		return nil, err
+	}
+	if err := s.filesystemHandler.Rename(tmp, cachePath); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// sbomCachePath is the cached document of the format next to the config.
+func sbomCachePath(configPath string, format string, configDigest string) string {
+	return filepath.Join(filepath.Dir(configPath), "sbom."+format+"."+configDigest+".json")
+}
+
+// == spdx 2.3 ==
+type spdxDocument struct {
+	SpdxVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SpdxId            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+	ExtractedLicenses []spdxLicenseInfo  `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+type spdxLicenseInfo struct {
+	LicenseId     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+	Comment  string   `json:"comment,omitempty"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SpdxId           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseDeclared  string            `json:"licenseDeclared,omitempty"`
+	SourceInfo       string            `json:"sourceInfo,omitempty"`
+	PrimaryPurpose   string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+func renderSpdx(imageName string, catalog sbomCatalog) spdxDocument {
+	doc := spdxDocument{
+		SpdxVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SpdxId:            "SPDXRef-DOCUMENT",
+		Name:              imageName,
+		DocumentNamespace: "urn:uuid:" + uuid.NewString(),
+		CreationInfo: spdxCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + sbomToolName},
+			Comment:  strings.Join(catalog.Warnings, "\n"),
+		},
+		Packages: []spdxPackage{{
+			Name:             imageName,
+			SpdxId:           "SPDXRef-Image",
+			DownloadLocation: "NOASSERTION",
+			PrimaryPurpose:   "CONTAINER",
+		}},
+		Relationships: []spdxRelationship{{
+			SpdxElementId:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSpdxElement: "SPDXRef-Image",
+		}},
+	}
+
+	licenseRefs := map[string]string{}
+	for i, p := range catalog.Packages {
+		id := "SPDXRef-Package-" + p.Type + "-" + strconv.Itoa(i+1)
+		pkg := spdxPackage{
+			Name:             p.Name,
+			SpdxId:           id,
+			VersionInfo:      p.Version,
+			DownloadLocation: "NOASSERTION",
+			LicenseDeclared:  "NOASSERTION",
+			SourceInfo:       "found in " + p.Location,
+		}
+		if p.License != "" {
+			// package managers do not always use spdx expressions, so keep them as extracted text
+			ref, ok := licenseRefs[p.License]
+			if !ok {
+				ref = "LicenseRef-" + strconv.Itoa(len(licenseRefs)+1) + "-" + spdxSanitize(p.License)
+				licenseRefs[p.License] = ref
+				doc.ExtractedLicenses = append(doc.ExtractedLicenses, spdxLicenseInfo{
+					LicenseId:     ref,
+					ExtractedText: p.License,
+				})
+			}
+			pkg.LicenseDeclared = ref
+		}
+		if p.Purl != "" {
+			pkg.ExternalRefs = []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  p.Purl,
+			}}
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SpdxElementId:      "SPDXRef-Image",
+			RelationshipType:   "CONTAINS",
+			RelatedSpdxElement: id,
+		})
+	}
+	return doc
+}
+
+// spdxSanitize maps a free-form string onto the characters allowed in LicenseRef ids.
+func spdxSanitize(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// == cyclonedx 1.5 ==
+type cycloneDxDocument struct {
+	BomFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDxMetadata    `json:"metadata"`
+	Components   []cycloneDxComponent `json:"components"`
+}
+
+type cycloneDxMetadata struct {
+	Timestamp  string              `json:"timestamp"`
+	Tools      cycloneDxTools      `json:"tools"`
+	Component  cycloneDxComponent  `json:"component"`
+	Properties []cycloneDxProperty `json:"properties,omitempty"`
+}
+
+type cycloneDxTools struct {
+	Components []cycloneDxComponent `json:"components"`
+}
+
+type cycloneDxComponent struct {
+	BomRef     string              `json:"bom-ref,omitempty"`
+	Type       string              `json:"type"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	Purl       string              `json:"purl,omitempty"`
+	Licenses   []cycloneDxLicense  `json:"licenses,omitempty"`
+	Properties []cycloneDxProperty `json:"properties,omitempty"`
+}
+
+type cycloneDxLicense struct {
+	License struct {
+		Name string `json:"name"`
+	} `json:"license"`
+}
+
+type cycloneDxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func renderCycloneDx(imageName string, catalog sbomCatalog) cycloneDxDocument {
+	doc := cycloneDxDocument{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Metadata: cycloneDxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: cycloneDxTools{Components: []cycloneDxComponent{{
+				Type: "application",
+				Name: sbomToolName,
+			}}},
+			Component: cycloneDxComponent{
+				BomRef: "image",
+				Type:   "container",
+				Name:   imageName,
+			},
+		},
+		Components: []cycloneDxComponent{},
+	}
+	if catalog.Distro.Name != "" {
+		doc.Metadata.Properties = append(doc.Metadata.Properties, cycloneDxProperty{Name: "raind:distro", Value: catalog.Distro.Name})
+	}
+	for _, w := range catalog.Warnings {
+		doc.Metadata.Properties = append(doc.Metadata.Properties, cycloneDxProperty{Name: "raind:warning", Value: w})
+	}
+
+	for i, p := range catalog.Packages {
+		c := cycloneDxComponent{
+			BomRef:  p.Purl,
+			Type:    "library",
+			Name:    p.Name,
+			Version: p.Version,
+			Purl:    p.Purl,
+			Properties: []cycloneDxProperty{
+				{Name: "raind:package:type", Value: p.Type},
+				{Name: "raind:location", Value: p.Location},
+			},
+		}
+		// bom-ref has to be unique, the same purl may be found in several places
+		if c.BomRef == "" || i > 0 && catalog.Packages[i-1].Purl == p.Purl {
+			c.BomRef = fmt.Sprintf("pkg-%d", i+1)
+		}
+		if p.License != "" {
+			// package managers do not always use spdx expressions, so keep them as names
+			var l cycloneDxLicense
+			l.License.Name = p.License
+			c.Licenses = []cycloneDxLicense{l}
+		}
+		if p.Source != "" {
+			c.Properties = append(c.Properties, cycloneDxProperty{Name: "raind:package:source", Value: p.Source})
+		}
+		doc.Components = append(doc.Components, c)
+	}
+	return doc
+}
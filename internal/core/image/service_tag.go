@@ -0,0 +1,43 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+)
+
+// == service: tag image ==
+func (s *ImageService) Tag(tagParameter ServiceTagModel) (string, error) {
+	if tagParameter.SourceImage == "" {
+		return "", errors.New("source image is required")
+	}
+	if tagParameter.TargetImage == "" {
+		return "", errors.New("target image is required")
+	}
+
+	srcRepo, srcRef, err := s.parseImageRef(tagParameter.SourceImage)
+	if err != nil {
+		return "", err
+	}
+	dstRepo, dstRef, err := s.parseImageRef(tagParameter.TargetImage)
+	if err != nil {
+		return "", err
+	}
+	if srcRepo == dstRepo && srcRef == dstRef {
+		return dstRepo + ":" + dstRef, nil
+	}
+
+	if !s.ilmHandler.IsImageExist(srcRepo, srcRef) {
+		return "", fmt.Errorf("%s:%s not found", srcRepo, srcRef)
+	}
+
+	// alias the existing bundle under the new reference, no copy
+	dropped, err := s.ilmHandler.TagImage(srcRepo, srcRef, dstRepo, dstRef)
+	if err != nil {
+		return "", err
+	}
+	// the image the target pointed to before, unless still tagged elsewhere
+	if _, err := s.removeUnreferencedBundles(dropped); err != nil {
+		return "", err
+	}
+	return dstRepo + ":" + dstRef, nil
+}
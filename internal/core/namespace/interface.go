@@ -0,0 +1,18 @@
+package namespace
+
+type NamespaceServiceHandler interface {
+	ApplyNamespace(namespaceParameter ServiceNamespaceModel, dryRun bool) (string, error)
+	GetNamespaceList() ([]NamespaceState, error)
+	GetNamespace(name string) (NamespaceState, error)
+	RemoveNamespace(name string) (string, error)
+	CheckNamespaceActive(name string) error
+	AdoptNamespaces() error
+	ApplyResourceQuota(quotaParameter ServiceResourceQuotaModel, dryRun bool) (string, string, error)
+	GetResourceQuotaList() ([]ResourceQuotaState, error)
+	GetResourceQuotaById(resourceQuotaId string) (ResourceQuotaState, error)
+	RemoveResourceQuota(resourceQuotaId string) (string, error)
+	ApplyLimitRange(limitRangeParameter ServiceLimitRangeModel, dryRun bool) (string, string, error)
+	GetLimitRangeList() ([]LimitRangeState, error)
+	GetLimitRangeById(limitRangeId string) (LimitRangeState, error)
+	RemoveLimitRange(limitRangeId string) (string, error)
+}
@@ -0,0 +1,143 @@
+package namespace
+
+import (
+	"fmt"
+	"strconv"
+
+	"condenser/internal/store/nsm"
+
+	"gopkg.in/yaml.v3"
+)
+
+const limitTypeContainer = "Container"
+
+type manifestMeta struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+type namespaceManifest struct {
+	APIVersion string       `yaml:"apiVersion"`
+	Kind       string       `yaml:"kind"`
+	Metadata   manifestMeta `yaml:"metadata"`
+}
+
+type resourceQuotaManifest struct {
+	APIVersion string       `yaml:"apiVersion"`
+	Kind       string       `yaml:"kind"`
+	Metadata   manifestMeta `yaml:"metadata"`
+	Spec       struct {
+		Hard map[string]string `yaml:"hard"`
+	} `yaml:"spec"`
+}
+
+type resourceListManifest struct {
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+}
+
+type limitRangeItemManifest struct {
+	Type           string               `yaml:"type"`
+	Default        resourceListManifest `yaml:"default"`
+	DefaultRequest resourceListManifest `yaml:"defaultRequest"`
+	Max            resourceListManifest `yaml:"max"`
+}
+
+type limitRangeManifest struct {
+	APIVersion string       `yaml:"apiVersion"`
+	Kind       string       `yaml:"kind"`
+	Metadata   manifestMeta `yaml:"metadata"`
+	Spec       struct {
+		Limits []limitRangeItemManifest `yaml:"limits"`
+	} `yaml:"spec"`
+}
+
+// DecodeK8sNamespaceManifest reads a single Namespace document.
+func DecodeK8sNamespaceManifest(body []byte) (ServiceNamespaceModel, error) {
+	var ns namespaceManifest
+	if err := yaml.Unmarshal(body, &ns); err != nil {
+		return ServiceNamespaceModel{}, err
+	}
+	if ns.Kind != "Namespace" {
+		return ServiceNamespaceModel{}, fmt.Errorf("unsupported kind: %s", ns.Kind)
+	}
+	return ServiceNamespaceModel{
+		Name:   ns.Metadata.Name,
+		Labels: ns.Metadata.Labels,
+	}, nil
+}
+
+// DecodeK8sResourceQuotaManifest reads a single ResourceQuota document.
+// cpu and memory are the same as requests.cpu and requests.memory, as in
+// kubernetes.
+func DecodeK8sResourceQuotaManifest(body []byte) (ServiceResourceQuotaModel, error) {
+	var rq resourceQuotaManifest
+	if err := yaml.Unmarshal(body, &rq); err != nil {
+		return ServiceResourceQuotaModel{}, err
+	}
+	if rq.Kind != "ResourceQuota" {
+		return ServiceResourceQuotaModel{}, fmt.Errorf("unsupported kind: %s", rq.Kind)
+	}
+	if rq.Metadata.Namespace == "" {
+		rq.Metadata.Namespace = nsm.DefaultNamespace
+	}
+	result := ServiceResourceQuotaModel{
+		Name:      rq.Metadata.Name,
+		Namespace: rq.Metadata.Namespace,
+	}
+	for k, v := range rq.Spec.Hard {
+		switch k {
+		case "pods":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return ServiceResourceQuotaModel{}, fmt.Errorf("invalid pods: %q", v)
+			}
+			result.Hard.Pods = &n
+		case "cpu", "requests.cpu":
+			result.Hard.Requests.CPU = v
+		case "memory", "requests.memory":
+			result.Hard.Requests.Memory = v
+		case "limits.cpu":
+			result.Hard.Limits.CPU = v
+		case "limits.memory":
+			result.Hard.Limits.Memory = v
+		default:
+			return ServiceResourceQuotaModel{}, fmt.Errorf("unsupported quota resource: %s", k)
+		}
+	}
+	return result, nil
+}
+
+// DecodeK8sLimitRangeManifest reads a single LimitRange document, only
+// limits of the Container type are supported.
+func DecodeK8sLimitRangeManifest(body []byte) (ServiceLimitRangeModel, error) {
+	var lr limitRangeManifest
+	if err := yaml.Unmarshal(body, &lr); err != nil {
+		return ServiceLimitRangeModel{}, err
+	}
+	if lr.Kind != "LimitRange" {
+		return ServiceLimitRangeModel{}, fmt.Errorf("unsupported kind: %s", lr.Kind)
+	}
+	if lr.Metadata.Namespace == "" {
+		lr.Metadata.Namespace = nsm.DefaultNamespace
+	}
+	result := ServiceLimitRangeModel{
+		Name:      lr.Metadata.Name,
+		Namespace: lr.Metadata.Namespace,
+	}
+	var found bool
+	for _, item := range lr.Spec.Limits {
+		if item.Type != limitTypeContainer {
+			return ServiceLimitRangeModel{}, fmt.Errorf("unsupported limit type: %q (Container only)", item.Type)
+		}
+		if found {
+			return ServiceLimitRangeModel{}, fmt.Errorf("only one Container limit is supported")
+		}
+		found = true
+		result.Default = nsm.ResourceList(item.Default)
+		result.DefaultRequest = nsm.ResourceList(item.DefaultRequest)
+		result.Max = nsm.ResourceList(item.Max)
+	}
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package namespace
+
+import (
+	"condenser/internal/store/nsm"
+	"time"
+)
+
+type ServiceNamespaceModel struct {
+	Name   string
+	Labels map[string]string
+}
+
+type ServiceResourceQuotaModel struct {
+	Name      string
+	Namespace string
+	Hard      nsm.QuotaHard
+}
+
+type ServiceLimitRangeModel struct {
+	Name           string
+	Namespace      string
+	Default        nsm.ResourceList // container limits when unset
+	DefaultRequest nsm.ResourceList // container requests when unset
+	Max            nsm.ResourceList
+}
+
+type NamespaceState struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Phase       string            `json:"phase"`
+	Pods        int               `json:"pods"`
+	ReplicaSets int               `json:"replicaSets"`
+	Deployments int               `json:"deployments"`
+	Services    int               `json:"services"`
+	CreatedAt   time.Time         `json:"createdAt"`
+
+	ResourceQuotas []ResourceQuotaState `json:"resourceQuotas,omitempty"`
+	LimitRanges    []LimitRangeState    `json:"limitRanges,omitempty"`
+}
+
+// ResourceQuotaState shows the hard limits next to what the pods of the
+// namespace use, keyed by the kubernetes resource names.
+type ResourceQuotaState struct {
+	ResourceQuotaId string            `json:"resourceQuotaId"`
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace"`
+	Hard            map[string]string `json:"hard"`
+	Used            map[string]string `json:"used"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	UpdatedAt       time.Time         `json:"updatedAt"`
+}
+
+type LimitRangeState struct {
+	LimitRangeId   string           `json:"limitRangeId"`
+	Name           string           `json:"name"`
+	Namespace      string           `json:"namespace"`
+	Default        nsm.ResourceList `json:"default"`
+	DefaultRequest nsm.ResourceList `json:"defaultRequest"`
+	Max            nsm.ResourceList `json:"max"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	UpdatedAt      time.Time        `json:"updatedAt"`
+}
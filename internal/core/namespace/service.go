@@ -0,0 +1,386 @@
+package namespace
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+
+	"condenser/internal/core/config"
+	"condenser/internal/core/pod"
+	"condenser/internal/store/nsm"
+	"condenser/internal/store/psm"
+	"condenser/internal/store/ssm"
+	"condenser/internal/utils"
+)
+
+// namespaces are DNS labels, as in kubernetes
+var namespaceNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+func NewNamespaceService() *NamespaceService {
+	return &NamespaceService{
+		nsmHandler:    nsm.NewNsmManager(nsm.NewNsmStore(utils.NsmStorePath)),
+		psmHandler:    psm.NewPsmManager(psm.NewPsmStore(utils.PsmStorePath)),
+		ssmHandler:    ssm.NewSsmManager(ssm.NewSsmStore(utils.SsmStorePath)),
+		podHandler:    pod.NewPodService(),
+		configHandler: config.NewConfigService(),
+	}
+}
+
+type NamespaceService struct {
+	nsmHandler    nsm.NsmHandler
+	psmHandler    psm.PsmHandler
+	ssmHandler    ssm.SsmHandler
+	podHandler    pod.PodServiceHandler
+	configHandler config.ConfigServiceHandler
+}
+
+func validateNamespaceName(name string) error {
+	if name == "" {
+		return fmt.Errorf("namespace name is required")
+	}
+	if len(name) > 63 || !namespaceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid namespace name %q: lowercase alphanumerics and '-' only", name)
+	}
+	return nil
+}
+
+// == service: create or update namespace ==
+// ApplyNamespace creates the namespace or replaces its labels.
+func (s *NamespaceService) ApplyNamespace(namespaceParameter ServiceNamespaceModel, dryRun bool) (string, error) {
+	if err := validateNamespaceName(namespaceParameter.Name); err != nil {
+		return "", err
+	}
+	result := utils.ApplyResultCreated
+	if existing, err := s.nsmHandler.GetNamespace(namespaceParameter.Name); err == nil {
+		if existing.Phase == nsm.NamespacePhaseTerminating {
+			return "", fmt.Errorf("namespace %s is terminating", existing.Name)
+		}
+		if maps.Equal(existing.Labels, namespaceParameter.Labels) {
+			return utils.ApplyResultUnchanged, nil
+		}
+		result = utils.ApplyResultConfigured
+	}
+	if dryRun {
+		return result, nil
+	}
+	if err := s.nsmHandler.StoreNamespace(nsm.NamespaceInfo{
+		Name:   namespaceParameter.Name,
+		Labels: namespaceParameter.Labels,
+	}); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// == service: list namespaces ==
+func (s *NamespaceService) GetNamespaceList() ([]NamespaceState, error) {
+	list, err := s.nsmHandler.GetNamespaceList()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.countResources()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]NamespaceState, 0, len(list))
+	for _, n := range list {
+		state := counts[n.Name]
+		state.Name = n.Name
+		state.Labels = n.Labels
+		state.Phase = n.Phase
+		state.CreatedAt = n.CreatedAt
+		result = append(result, state)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// == service: get namespace ==
+// GetNamespace adds the quotas, with their usage, and the limit ranges.
+func (s *NamespaceService) GetNamespace(name string) (NamespaceState, error) {
+	n, err := s.nsmHandler.GetNamespace(name)
+	if err != nil {
+		return NamespaceState{}, err
+	}
+	counts, err := s.countResources()
+	if err != nil {
+		return NamespaceState{}, err
+	}
+	state := counts[n.Name]
+	state.Name = n.Name
+	state.Labels = n.Labels
+	state.Phase = n.Phase
+	state.CreatedAt = n.CreatedAt
+
+	quotas, err := s.GetResourceQuotaList()
+	if err != nil {
+		return NamespaceState{}, err
+	}
+	for _, q := range quotas {
+		if q.Namespace == name {
+			state.ResourceQuotas = append(state.ResourceQuotas, q)
+		}
+	}
+	ranges, err := s.GetLimitRangeList()
+	if err != nil {
+		return NamespaceState{}, err
+	}
+	for _, lr := range ranges {
+		if lr.Namespace == name {
+			state.LimitRanges = append(state.LimitRanges, lr)
+		}
+	}
+	return state, nil
+}
+
+func (s *NamespaceService) countResources() (map[string]NamespaceState, error) {
+	counts := map[string]NamespaceState{}
+	pods, err := s.psmHandler.GetPodList()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pods {
+		c := counts[p.Namespace]
+		c.Pods++
+		counts[p.Namespace] = c
+	}
+	replicaSets, err := s.psmHandler.GetReplicaSetList()
+	if err != nil {
+		return nil, err
+	}
+	for _, rs := range replicaSets {
+		c := counts[rs.Spec.Namespace]
+		c.ReplicaSets++
+		counts[rs.Spec.Namespace] = c
+	}
+	deployments, err := s.psmHandler.GetDeploymentList()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments {
+		c := counts[d.Spec.Namespace]
+		c.Deployments++
+		counts[d.Spec.Namespace] = c
+	}
+	services, err := s.ssmHandler.GetServiceList()
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range services {
+		c := counts[svc.Namespace]
+		c.Services++
+		counts[svc.Namespace] = c
+	}
+	return counts, nil
+}
+
+// == service: check namespace ==
+func (s *NamespaceService) CheckNamespaceActive(name string) error {
+	return s.nsmHandler.CheckNamespaceActive(name)
+}
+
+// == service: remove namespace ==
+// RemoveNamespace deletes everything in the namespace, then the namespace
+// itself. It is marked terminating first so nothing new is created in it
+// meanwhile, a failed removal can be retried.
+func (s *NamespaceService) RemoveNamespace(name string) (string, error) {
+	if name == nsm.DefaultNamespace {
+		return "", fmt.Errorf("namespace %s cannot be removed", name)
+	}
+	if _, err := s.nsmHandler.GetNamespace(name); err != nil {
+		return "", err
+	}
+	if err := s.nsmHandler.UpdateNamespacePhase(name, nsm.NamespacePhaseTerminating); err != nil {
+		return "", err
+	}
+
+	// owners go before their pods so no controller replaces them
+	deployments, err := s.psmHandler.GetDeploymentList()
+	if err != nil {
+		return "", err
+	}
+	for _, d := range deployments {
+		if d.Spec.Namespace != name {
+			continue
+		}
+		if _, err := s.podHandler.RemoveDeployment(d.DeploymentId); err != nil {
+			return "", fmt.Errorf("deployment %s: %w", d.Spec.Name, err)
+		}
+	}
+	replicaSets, err := s.psmHandler.GetReplicaSetList()
+	if err != nil {
+		return "", err
+	}
+	var templateIds []string
+	for _, rs := range replicaSets {
+		if rs.Spec.Namespace != name {
+			continue
+		}
+		if err := s.psmHandler.RemoveReplicaSet(rs.ReplicaSetId); err != nil {
+			return "", fmt.Errorf("replicaset %s: %w", rs.Spec.Name, err)
+		}
+		templateIds = append(templateIds, rs.Spec.TemplateId)
+	}
+	pods, err := s.psmHandler.GetPodList()
+	if err != nil {
+		return "", err
+	}
+	for _, p := range pods {
+		if p.Namespace != name {
+			continue
+		}
+		if _, err := s.podHandler.Remove(p.PodId); err != nil {
+			return "", fmt.Errorf("pod %s: %w", p.Name, err)
+		}
+	}
+	// templates of replica sets that had no pods left
+	for _, templateId := range templateIds {
+		inUse, err := s.psmHandler.IsTemplateReferenced(templateId)
+		if err == nil && !inUse {
+			_ = s.psmHandler.RemovePodTemplate(templateId)
+		}
+	}
+
+	services, err := s.ssmHandler.GetServiceList()
+	if err != nil {
+		return "", err
+	}
+	for _, svc := range services {
+		if svc.Namespace != name {
+			continue
+		}
+		if err := s.ssmHandler.RemoveService(svc.ServiceId); err != nil {
+			return "", fmt.Errorf("service %s: %w", svc.Name, err)
+		}
+	}
+	if err := s.removeConfig(name); err != nil {
+		return "", err
+	}
+	if err := s.removePolicies(name); err != nil {
+		return "", err
+	}
+	if err := s.nsmHandler.RemoveNamespace(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (s *NamespaceService) removeConfig(name string) error {
+	configMaps, err := s.configHandler.GetConfigMapList()
+	if err != nil {
+		return err
+	}
+	for _, cm := range configMaps {
+		if cm.Namespace != name {
+			continue
+		}
+		if _, err := s.configHandler.RemoveConfigMap(cm.ConfigMapId); err != nil {
+			return fmt.Errorf("configmap %s: %w", cm.Name, err)
+		}
+	}
+	secrets, err := s.configHandler.GetSecretList()
+	if err != nil {
+		return err
+	}
+	for _, sec := range secrets {
+		if sec.Namespace != name {
+			continue
+		}
+		if _, err := s.configHandler.RemoveSecret(sec.SecretId); err != nil {
+			return fmt.Errorf("secret %s: %w", sec.Name, err)
+		}
+	}
+	return nil
+}
+
+// removePolicies drops the quotas and limit ranges of the namespace.
+func (s *NamespaceService) removePolicies(name string) error {
+	quotas, err := s.nsmHandler.GetResourceQuotaList()
+	if err != nil {
+		return err
+	}
+	for _, q := range quotas {
+		if q.Namespace != name {
+			continue
+		}
+		if err := s.nsmHandler.RemoveResourceQuota(q.ResourceQuotaId); err != nil {
+			return err
+		}
+	}
+	ranges, err := s.nsmHandler.GetLimitRangeList()
+	if err != nil {
+		return err
+	}
+	for _, lr := range ranges {
+		if lr.Namespace != name {
+			continue
+		}
+		if err := s.nsmHandler.RemoveLimitRange(lr.LimitRangeId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// == service: adopt namespaces in use ==
+// AdoptNamespaces registers the namespaces resources were created in
+// before namespaces were objects of their own, so they keep working.
+func (s *NamespaceService) AdoptNamespaces() error {
+	names := map[string]bool{}
+	pods, err := s.psmHandler.GetPodList()
+	if err != nil {
+		return err
+	}
+	for _, p := range pods {
+		names[p.Namespace] = true
+	}
+	replicaSets, err := s.psmHandler.GetReplicaSetList()
+	if err != nil {
+		return err
+	}
+	for _, rs := range replicaSets {
+		names[rs.Spec.Namespace] = true
+	}
+	deployments, err := s.psmHandler.GetDeploymentList()
+	if err != nil {
+		return err
+	}
+	for _, d := range deployments {
+		names[d.Spec.Namespace] = true
+	}
+	services, err := s.ssmHandler.GetServiceList()
+	if err != nil {
+		return err
+	}
+	for _, svc := range services {
+		names[svc.Namespace] = true
+	}
+	configMaps, err := s.configHandler.GetConfigMapList()
+	if err != nil {
+		return err
+	}
+	for _, cm := range configMaps {
+		names[cm.Namespace] = true
+	}
+	secrets, err := s.configHandler.GetSecretList()
+	if err != nil {
+		return err
+	}
+	for _, sec := range secrets {
+		names[sec.Namespace] = true
+	}
+
+	for name := range names {
+		if name == "" {
+			continue
+		}
+		if _, err := s.nsmHandler.GetNamespace(name); err == nil {
+			continue
+		}
+		if err := s.nsmHandler.StoreNamespace(nsm.NamespaceInfo{Name: name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
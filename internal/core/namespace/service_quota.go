@@ -0,0 +1,260 @@
+package namespace
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"condenser/internal/core/pod"
+	"condenser/internal/store/nsm"
+	"condenser/internal/utils"
+)
+
+func validateResourceList(field string, l nsm.ResourceList) error {
+	if l.CPU != "" {
+		if _, err := utils.ParseCPUQuantity(l.CPU); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	if l.Memory != "" {
+		if _, err := utils.ParseByteSize(l.Memory); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// == service: create or update resource quota ==
+// ApplyResourceQuota stores the quota under its name and namespace. It is
+// enforced when pods are created, running pods are left alone.
+func (s *NamespaceService) ApplyResourceQuota(quotaParameter ServiceResourceQuotaModel, dryRun bool) (string, string, error) {
+	if quotaParameter.Name == "" {
+		return "", "", fmt.Errorf("resourcequota name is required")
+	}
+	if quotaParameter.Namespace == "" {
+		quotaParameter.Namespace = nsm.DefaultNamespace
+	}
+	if err := s.nsmHandler.CheckNamespaceActive(quotaParameter.Namespace); err != nil {
+		return "", "", err
+	}
+	if err := validateResourceList("requests", quotaParameter.Hard.Requests); err != nil {
+		return "", "", err
+	}
+	if err := validateResourceList("limits", quotaParameter.Hard.Limits); err != nil {
+		return "", "", err
+	}
+
+	resourceQuotaId := utils.NewUlid()
+	result := utils.ApplyResultCreated
+	if existing, err := s.nsmHandler.GetResourceQuotaByName(quotaParameter.Name, quotaParameter.Namespace); err == nil {
+		resourceQuotaId = existing.ResourceQuotaId
+		result = utils.ApplyResultConfigured
+		if !utils.SpecChanged(existing.Hard, quotaParameter.Hard) {
+			return resourceQuotaId, utils.ApplyResultUnchanged, nil
+		}
+	}
+	if dryRun {
+		if result == utils.ApplyResultCreated {
+			resourceQuotaId = ""
+		}
+		return resourceQuotaId, result, nil
+	}
+	if err := s.nsmHandler.StoreResourceQuota(resourceQuotaId, nsm.ResourceQuotaInfo{
+		Name:      quotaParameter.Name,
+		Namespace: quotaParameter.Namespace,
+		Hard:      quotaParameter.Hard,
+	}); err != nil {
+		return "", "", err
+	}
+	return resourceQuotaId, result, nil
+}
+
+// == service: list resource quotas ==
+func (s *NamespaceService) GetResourceQuotaList() ([]ResourceQuotaState, error) {
+	list, err := s.nsmHandler.GetResourceQuotaList()
+	if err != nil {
+		return nil, err
+	}
+	usage := map[string]pod.NamespaceUsage{}
+	result := make([]ResourceQuotaState, 0, len(list))
+	for _, q := range list {
+		used, ok := usage[q.Namespace]
+		if !ok {
+			used, err = s.podHandler.GetNamespaceUsage(q.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			usage[q.Namespace] = used
+		}
+		result = append(result, toResourceQuotaState(q, used))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// == service: get resource quota ==
+func (s *NamespaceService) GetResourceQuotaById(resourceQuotaId string) (ResourceQuotaState, error) {
+	q, err := s.nsmHandler.GetResourceQuota(resourceQuotaId)
+	if err != nil {
+		return ResourceQuotaState{}, err
+	}
+	used, err := s.podHandler.GetNamespaceUsage(q.Namespace)
+	if err != nil {
+		return ResourceQuotaState{}, err
+	}
+	return toResourceQuotaState(q, used), nil
+}
+
+// toResourceQuotaState lists usage only for what the quota limits, as
+// kubernetes reports it.
+func toResourceQuotaState(q nsm.ResourceQuotaInfo, used pod.NamespaceUsage) ResourceQuotaState {
+	state := ResourceQuotaState{
+		ResourceQuotaId: q.ResourceQuotaId,
+		Name:            q.Name,
+		Namespace:       q.Namespace,
+		Hard:            map[string]string{},
+		Used:            map[string]string{},
+		CreatedAt:       q.CreatedAt,
+		UpdatedAt:       q.UpdatedAt,
+	}
+	if q.Hard.Pods != nil {
+		state.Hard[pod.QuotaResourcePods] = strconv.Itoa(*q.Hard.Pods)
+		state.Used[pod.QuotaResourcePods] = strconv.Itoa(used.Pods)
+	}
+	for _, r := range []struct {
+		name string
+		hard string
+		used string
+	}{
+		{pod.QuotaResourceRequestsCPU, q.Hard.Requests.CPU, utils.FormatCPUQuantity(used.RequestsCPU)},
+		{pod.QuotaResourceRequestsMemory, q.Hard.Requests.Memory, utils.FormatByteSize(used.RequestsMemory)},
+		{pod.QuotaResourceLimitsCPU, q.Hard.Limits.CPU, utils.FormatCPUQuantity(used.LimitsCPU)},
+		{pod.QuotaResourceLimitsMemory, q.Hard.Limits.Memory, utils.FormatByteSize(used.LimitsMemory)},
+	} {
+		if r.hard == "" {
+			continue
+		}
+		state.Hard[r.name] = r.hard
+		state.Used[r.name] = r.used
+	}
+	return state
+}
+
+// == service: remove resource quota ==
+func (s *NamespaceService) RemoveResourceQuota(resourceQuotaId string) (string, error) {
+	if err := s.nsmHandler.RemoveResourceQuota(resourceQuotaId); err != nil {
+		return "", err
+	}
+	return resourceQuotaId, nil
+}
+
+// == service: create or update limit range ==
+// ApplyLimitRange stores the limit range under its name and namespace. The
+// defaults are filled into pod templates when they are applied.
+func (s *NamespaceService) ApplyLimitRange(limitRangeParameter ServiceLimitRangeModel, dryRun bool) (string, string, error) {
+	if limitRangeParameter.Name == "" {
+		return "", "", fmt.Errorf("limitrange name is required")
+	}
+	if limitRangeParameter.Namespace == "" {
+		limitRangeParameter.Namespace = nsm.DefaultNamespace
+	}
+	if err := s.nsmHandler.CheckNamespaceActive(limitRangeParameter.Namespace); err != nil {
+		return "", "", err
+	}
+	for field, l := range map[string]nsm.ResourceList{
+		"default":        limitRangeParameter.Default,
+		"defaultRequest": limitRangeParameter.DefaultRequest,
+		"max":            limitRangeParameter.Max,
+	} {
+		if err := validateResourceList(field, l); err != nil {
+			return "", "", err
+		}
+	}
+
+	info := nsm.LimitRangeInfo{
+		Name:           limitRangeParameter.Name,
+		Namespace:      limitRangeParameter.Namespace,
+		Default:        limitRangeParameter.Default,
+		DefaultRequest: limitRangeParameter.DefaultRequest,
+		Max:            limitRangeParameter.Max,
+	}
+	limitRangeId := utils.NewUlid()
+	result := utils.ApplyResultCreated
+	if existing, err := s.nsmHandler.GetLimitRangeByName(info.Name, info.Namespace); err == nil {
+		limitRangeId = existing.LimitRangeId
+		result = utils.ApplyResultConfigured
+		if !utils.SpecChanged(limitRangeContent(existing), limitRangeContent(info)) {
+			return limitRangeId, utils.ApplyResultUnchanged, nil
+		}
+	}
+	if dryRun {
+		if result == utils.ApplyResultCreated {
+			limitRangeId = ""
+		}
+		return limitRangeId, result, nil
+	}
+	if err := s.nsmHandler.StoreLimitRange(limitRangeId, info); err != nil {
+		return "", "", err
+	}
+	return limitRangeId, result, nil
+}
+
+// limitRangeContent is the part of a limit range an apply compares.
+func limitRangeContent(info nsm.LimitRangeInfo) nsm.LimitRangeInfo {
+	return nsm.LimitRangeInfo{Default: info.Default, DefaultRequest: info.DefaultRequest, Max: info.Max}
+}
+
+// == service: list limit ranges ==
+func (s *NamespaceService) GetLimitRangeList() ([]LimitRangeState, error) {
+	list, err := s.nsmHandler.GetLimitRangeList()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]LimitRangeState, 0, len(list))
+	for _, lr := range list {
+		result = append(result, toLimitRangeState(lr))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// == service: get limit range ==
+func (s *NamespaceService) GetLimitRangeById(limitRangeId string) (LimitRangeState, error) {
+	lr, err := s.nsmHandler.GetLimitRange(limitRangeId)
+	if err != nil {
+		return LimitRangeState{}, err
+	}
+	return toLimitRangeState(lr), nil
+}
+
+func toLimitRangeState(lr nsm.LimitRangeInfo) LimitRangeState {
+	return LimitRangeState{
+		LimitRangeId:   lr.LimitRangeId,
+		Name:           lr.Name,
+		Namespace:      lr.Namespace,
+		Default:        lr.Default,
+		DefaultRequest: lr.DefaultRequest,
+		Max:            lr.Max,
+		CreatedAt:      lr.CreatedAt,
+		UpdatedAt:      lr.UpdatedAt,
+	}
+}
+
+// == service: remove limit range ==
+// Pods created under it keep the resources it filled in.
+func (s *NamespaceService) RemoveLimitRange(limitRangeId string) (string, error) {
+	if err := s.nsmHandler.RemoveLimitRange(limitRangeId); err != nil {
+		return "", err
+	}
+	return limitRangeId, nil
+}
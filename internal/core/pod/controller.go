@@ -2,6 +2,7 @@ package pod
 
 import (
 	"condenser/internal/core/container"
+	"condenser/internal/store/csm"
 	"condenser/internal/store/psm"
 	"condenser/internal/utils"
 	"log"
@@ -12,6 +13,7 @@ import (
 func NewPodController() *PodController {
 	return &PodController{
 		psmHandler:       psm.NewPsmManager(psm.NewPsmStore(utils.PsmStorePath)),
+		csmHandler:       csm.NewCsmManager(csm.NewCsmStore(utils.CsmStorePath)),
 		podHandler:       NewPodService(),
 		containerHandler: container.NewContaierService(),
 		interval:         5 * time.Second,
@@ -20,6 +22,7 @@ func NewPodController() *PodController {
 
 type PodController struct {
 	psmHandler       psm.PsmHandler
+	csmHandler       csm.CsmHandler
 	podHandler       PodServiceHandler
 	containerHandler container.ContainerServiceHandler
 	interval         time.Duration
@@ -37,6 +40,11 @@ func (c *PodController) Start() {
 }
 
 func (c *PodController) reconcileOnce() error {
+	// deployments only set replica counts, the replica sets act on them below
+	if err := c.reconcileDeployments(); err != nil {
+		log.Printf("pod controller deployment reconcile failed: %v", err)
+	}
+
 	replicaSets, err := c.psmHandler.GetReplicaSetList()
 	if err != nil {
 		return err
@@ -65,7 +73,19 @@ func (c *PodController) reconcileOnce() error {
 			podsByTemplate[p.TemplateId] = append(podsByTemplate[p.TemplateId], p)
 		}
 		for _, rs := range replicaSets {
-			podList := podsByTemplate[rs.Spec.TemplateId]
+			var podList []psm.PodInfo
+			for _, p := range podsByTemplate[rs.Spec.TemplateId] {
+				// failed replicas, evicted ones or those whose init containers
+				// failed, are replaced, the log keeps the reason
+				if p.State == "failed" {
+					log.Printf("pod controller replacing failed pod: podId=%s reason=%s message=%s", p.PodId, p.Reason, p.Message)
+					if err := c.deletePod(p); err != nil {
+						log.Printf("pod controller delete failed: podId=%s err=%v", p.PodId, err)
+					}
+					continue
+				}
+				podList = append(podList, p)
+			}
 			current := len(podList)
 			if current < rs.Spec.Replicas {
 				for i := 0; i < rs.Spec.Replicas-current; i++ {
@@ -105,10 +125,8 @@ func (c *PodController) reconcileOnce() error {
 						}
 						continue
 					}
-					// Infra is running, so only recover member containers.
-					if _, err := c.podHandler.Start(p.PodId); err != nil {
-						log.Printf("pod controller start failed: podId=%s err=%v", p.PodId, err)
-					}
+					// Infra is running, members are restarted by
+					// reconcileRestarts following the restart policy.
 					continue
 				}
 				if p.State == "stopped" {
@@ -145,7 +163,6 @@ func (c *PodController) reconcileOnce() error {
 		}
 
 		var hasActive bool
-		var degradedPodId string
 		var stoppedPodId string
 		for _, p := range podList {
 			if !p.StoppedByUser {
@@ -160,9 +177,6 @@ func (c *PodController) reconcileOnce() error {
 					break
 				}
 			}
-			if p.State == "degraded" && !p.StoppedByUser && degradedPodId == "" {
-				degradedPodId = p.PodId
-			}
 			if p.State != "stopped" {
 				hasActive = true
 				break
@@ -174,12 +188,6 @@ func (c *PodController) reconcileOnce() error {
 				stoppedPodId = p.PodId
 			}
 		}
-		if degradedPodId != "" {
-			if _, err := c.podHandler.Start(degradedPodId); err != nil {
-				log.Printf("pod controller start failed: podId=%s err=%v", degradedPodId, err)
-			}
-			continue
-		}
 		if hasActive || stoppedPodId == "" {
 			continue
 		}
@@ -188,9 +196,31 @@ func (c *PodController) reconcileOnce() error {
 		}
 	}
 
+	// member restarts, for standalone pods as well as replicas
+	c.reconcileRestarts(pods)
+
+	// init containers whose run was cut short by a daemon restart
+	c.reconcileInitializing(pods)
+
+	// pods whose emptyDir volumes outgrew their sizeLimit are evicted
+	c.reconcileEmptyDirLimits(pods)
+
 	return nil
 }
 
+// reconcileInitializing starts the pods again that are initializing with
+// nothing running their init containers. Those that completed are skipped.
+func (c *PodController) reconcileInitializing(pods []psm.PodInfo) {
+	for _, p := range pods {
+		if p.State != "initializing" || p.StoppedByUser || isInitInProgress(p.PodId) {
+			continue
+		}
+		if _, err := c.podHandler.Start(p.PodId); err != nil {
+			log.Printf("pod controller init resume failed: podId=%s err=%v", p.PodId, err)
+		}
+	}
+}
+
 func (c *PodController) isPodInfraDown(podId string) (bool, error) {
 	state, err := c.getPodInfraState(podId)
 	if err != nil {
@@ -230,6 +260,12 @@ func (c *PodController) recreatePod(podInfo psm.PodInfo) error {
 		}
 		_, _ = c.containerHandler.Delete(container.ServiceDeleteModel{ContainerId: cinfo.ContainerId})
 	}
+	if err := removePodVolumes(podInfo.PodId); err != nil {
+		log.Printf("pod controller volume cleanup failed: podId=%s err=%v", podInfo.PodId, err)
+	}
+	if err := removePodCgroup(podInfo.PodId); err != nil {
+		log.Printf("pod controller cgroup cleanup failed: podId=%s err=%v", podInfo.PodId, err)
+	}
 	if err := c.psmHandler.RemovePod(podInfo.PodId); err != nil {
 		// ignore already removed
 		if !strings.Contains(err.Error(), "not found") {
@@ -258,5 +294,11 @@ func (c *PodController) deletePod(podInfo psm.PodInfo) error {
 		}
 		_, _ = c.containerHandler.Delete(container.ServiceDeleteModel{ContainerId: cinfo.ContainerId})
 	}
+	if err := removePodVolumes(podInfo.PodId); err != nil {
+		log.Printf("pod controller volume cleanup failed: podId=%s err=%v", podInfo.PodId, err)
+	}
+	if err := removePodCgroup(podInfo.PodId); err != nil {
+		log.Printf("pod controller cgroup cleanup failed: podId=%s err=%v", podInfo.PodId, err)
+	}
 	return c.psmHandler.RemovePod(podInfo.PodId)
 }
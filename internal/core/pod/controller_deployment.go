@@ -0,0 +1,253 @@
+package pod
+
+import (
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+	"log"
+	"maps"
+)
+
+// reconcileDeployments rolls each deployment towards the replica set of
+// its current pod template. It only changes replica counts, the replica
+// set reconcile that follows creates and deletes the pods.
+func (c *PodController) reconcileDeployments() error {
+	deployments, err := c.psmHandler.GetDeploymentList()
+	if err != nil {
+		return err
+	}
+	if len(deployments) == 0 {
+		return nil
+	}
+	replicaSets, err := c.psmHandler.GetReplicaSetList()
+	if err != nil {
+		return err
+	}
+	pods, err := c.psmHandler.GetPodList()
+	if err != nil {
+		return err
+	}
+	podsByTemplate := map[string][]psm.PodInfo{}
+	for _, p := range pods {
+		if p.TemplateId != "" {
+			podsByTemplate[p.TemplateId] = append(podsByTemplate[p.TemplateId], p)
+		}
+	}
+
+	for _, d := range deployments {
+		if err := c.reconcileDeployment(d, ownedReplicaSets(d.DeploymentId, replicaSets), podsByTemplate); err != nil {
+			log.Printf("pod controller deployment reconcile failed: deploymentId=%s err=%v", d.DeploymentId, err)
+		}
+	}
+	return nil
+}
+
+func (c *PodController) reconcileDeployment(d psm.DeploymentInfo, owned []psm.ReplicaSetInfo, podsByTemplate map[string][]psm.PodInfo) error {
+	hash, err := podTemplateHash(d.Spec.Template)
+	if err != nil {
+		return err
+	}
+	latest := 0
+	newIdx := -1
+	for i, rs := range owned {
+		latest = max(latest, rs.Spec.Revision)
+		if rs.Spec.TemplateHash == hash {
+			newIdx = i
+		}
+	}
+
+	switch {
+	case newIdx < 0:
+		rs, err := c.createDeploymentReplicaSet(d, hash, latest+1)
+		if err != nil {
+			return err
+		}
+		owned = append(owned, rs)
+		newIdx = len(owned) - 1
+	case owned[newIdx].Spec.Revision < latest:
+		// the template went back to an earlier revision
+		if err := c.psmHandler.UpdateReplicaSetRevision(owned[newIdx].ReplicaSetId, latest+1); err != nil {
+			return err
+		}
+		owned[newIdx].Spec.Revision = latest + 1
+	}
+	newRS := owned[newIdx]
+	if d.Revision != newRS.Spec.Revision {
+		if err := c.psmHandler.UpdateDeploymentRevision(d.DeploymentId, newRS.Spec.Revision); err != nil {
+			return err
+		}
+	}
+
+	var old []psm.ReplicaSetInfo
+	for i, rs := range owned {
+		if i != newIdx {
+			old = append(old, rs)
+		}
+	}
+
+	replicas := map[string]int{}
+	for _, rs := range owned {
+		replicas[rs.ReplicaSetId] = rs.Spec.Replicas
+	}
+	if d.Spec.Strategy.Type == DeploymentStrategyRecreate {
+		c.planRecreate(d, newRS, old, podsByTemplate, replicas)
+	} else {
+		c.planRollingUpdate(d, newRS, old, podsByTemplate, replicas)
+	}
+	for _, rs := range owned {
+		if replicas[rs.ReplicaSetId] == rs.Spec.Replicas {
+			continue
+		}
+		if err := c.psmHandler.UpdateReplicaSetReplicas(rs.ReplicaSetId, replicas[rs.ReplicaSetId]); err != nil {
+			return err
+		}
+	}
+
+	c.cleanupDeploymentHistory(d, old, podsByTemplate, replicas)
+	return nil
+}
+
+func (c *PodController) createDeploymentReplicaSet(d psm.DeploymentInfo, hash string, revision int) (psm.ReplicaSetInfo, error) {
+	tpl := d.Spec.Template
+	tpl.Labels = maps.Clone(tpl.Labels)
+	if tpl.Labels == nil {
+		tpl.Labels = map[string]string{}
+	}
+	tpl.Labels[podTemplateHashLabel] = hash
+	selector := maps.Clone(d.Spec.Selector)
+	if selector == nil {
+		selector = map[string]string{}
+	}
+	selector[podTemplateHashLabel] = hash
+
+	templateId := utils.NewUlid()
+	if err := c.psmHandler.StorePodTemplate(templateId, tpl); err != nil {
+		return psm.ReplicaSetInfo{}, err
+	}
+	spec := psm.ReplicaSetSpec{
+		Name:         d.Spec.Name + "-" + hash,
+		Namespace:    d.Spec.Namespace,
+		Replicas:     0,
+		TemplateId:   templateId,
+		Selector:     selector,
+		DeploymentId: d.DeploymentId,
+		TemplateHash: hash,
+		Revision:     revision,
+
+		MatchExpressions: d.Spec.MatchExpressions,
+	}
+	replicaSetId := utils.NewUlid()
+	if err := c.psmHandler.StoreReplicaSet(replicaSetId, spec); err != nil {
+		_ = c.psmHandler.RemovePodTemplate(templateId)
+		return psm.ReplicaSetInfo{}, err
+	}
+	return c.psmHandler.GetReplicaSet(replicaSetId)
+}
+
+// planRollingUpdate scales the new replica set up as far as maxSurge allows
+// and the old ones down, oldest first, as far as maxUnavailable allows.
+// Old pods that are not ready are not available anyway and go first.
+func (c *PodController) planRollingUpdate(d psm.DeploymentInfo, newRS psm.ReplicaSetInfo, old []psm.ReplicaSetInfo, podsByTemplate map[string][]psm.PodInfo, replicas map[string]int) {
+	desired := d.Spec.Replicas
+	surge, _ := resolveIntOrPercent(d.Spec.Strategy.MaxSurge, desired, true)
+	unavailable, _ := resolveIntOrPercent(d.Spec.Strategy.MaxUnavailable, desired, false)
+	if surge == 0 && unavailable == 0 {
+		unavailable = 1
+	}
+
+	if replicas[newRS.ReplicaSetId] > desired {
+		replicas[newRS.ReplicaSetId] = desired
+	}
+	total := 0
+	for _, n := range replicas {
+		total += n
+	}
+	if up := min(desired+surge-total, desired-replicas[newRS.ReplicaSetId]); up > 0 {
+		replicas[newRS.ReplicaSetId] += up
+	}
+
+	ready := 0
+	for _, p := range podsByTemplate[newRS.Spec.TemplateId] {
+		if p.Ready {
+			ready++
+		}
+	}
+	for _, rs := range old {
+		for _, p := range podsByTemplate[rs.Spec.TemplateId] {
+			if p.Ready {
+				ready++
+			}
+		}
+	}
+	budget := ready - (desired - unavailable)
+
+	for _, rs := range old {
+		target := replicas[rs.ReplicaSetId]
+		if target == 0 {
+			continue
+		}
+		rsPods := podsByTemplate[rs.Spec.TemplateId]
+		var rsReady, unready []psm.PodInfo
+		for _, p := range rsPods {
+			if p.Ready {
+				rsReady = append(rsReady, p)
+			} else {
+				unready = append(unready, p)
+			}
+		}
+		// replicas that never got a pod cost nothing to drop
+		if missing := target - len(rsPods); missing > 0 {
+			target -= missing
+		}
+		for _, p := range unready {
+			if target == 0 {
+				break
+			}
+			target--
+			// the replica set would pick any pod, this one is known unready
+			if err := c.deletePod(p); err != nil {
+				log.Printf("pod controller delete failed: podId=%s err=%v", p.PodId, err)
+			}
+		}
+		if budget > 0 {
+			down := min(budget, target, len(rsReady))
+			target -= down
+			budget -= down
+		}
+		replicas[rs.ReplicaSetId] = max(target, 0)
+	}
+}
+
+// planRecreate scales every old replica set to zero and brings the new one
+// up once their pods are gone.
+func (c *PodController) planRecreate(d psm.DeploymentInfo, newRS psm.ReplicaSetInfo, old []psm.ReplicaSetInfo, podsByTemplate map[string][]psm.PodInfo, replicas map[string]int) {
+	oldPods := 0
+	for _, rs := range old {
+		replicas[rs.ReplicaSetId] = 0
+		oldPods += len(podsByTemplate[rs.Spec.TemplateId])
+	}
+	if oldPods == 0 {
+		replicas[newRS.ReplicaSetId] = d.Spec.Replicas
+	}
+}
+
+// cleanupDeploymentHistory removes the oldest scaled down replica sets
+// beyond the revision history limit, along with their templates.
+func (c *PodController) cleanupDeploymentHistory(d psm.DeploymentInfo, old []psm.ReplicaSetInfo, podsByTemplate map[string][]psm.PodInfo, replicas map[string]int) {
+	var idle []psm.ReplicaSetInfo
+	for _, rs := range old {
+		if replicas[rs.ReplicaSetId] == 0 && len(podsByTemplate[rs.Spec.TemplateId]) == 0 {
+			idle = append(idle, rs)
+		}
+	}
+	for i := 0; i < len(idle)-d.Spec.RevisionHistoryLimit; i++ {
+		rs := idle[i]
+		if err := c.psmHandler.RemoveReplicaSet(rs.ReplicaSetId); err != nil {
+			log.Printf("pod controller history cleanup failed: replicaSetId=%s err=%v", rs.ReplicaSetId, err)
+			continue
+		}
+		inUse, err := c.psmHandler.IsTemplateReferenced(rs.Spec.TemplateId)
+		if err == nil && !inUse {
+			_ = c.psmHandler.RemovePodTemplate(rs.Spec.TemplateId)
+		}
+	}
+}
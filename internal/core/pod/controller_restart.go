@@ -0,0 +1,186 @@
+package pod
+
+import (
+	"condenser/internal/core/container"
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	RestartPolicyAlways    = "Always"
+	RestartPolicyOnFailure = "OnFailure"
+	RestartPolicyNever     = "Never"
+)
+
+// crashing members are restarted after 10s, 20s, 40s, ... up to 5 minutes.
+// A member that ran for 10 minutes starts over without delay.
+const (
+	restartBackoffInitial = 10 * time.Second
+	restartBackoffMax     = 5 * time.Minute
+	restartBackoffReset   = 10 * time.Minute
+)
+
+func normalizeRestartPolicy(policy string) (string, error) {
+	switch policy {
+	case "", RestartPolicyAlways:
+		return RestartPolicyAlways, nil
+	case RestartPolicyOnFailure, RestartPolicyNever:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid restartPolicy: %s (Always, OnFailure or Never)", policy)
+}
+
+func shouldRestart(policy string, exitCode int) bool {
+	switch policy {
+	case RestartPolicyNever:
+		return false
+	case RestartPolicyOnFailure:
+		return exitCode != 0
+	}
+	return true
+}
+
+// reconcileRestarts applies the restart policy of each pod to the members
+// that exited. Pods whose infra is gone are recreated elsewhere.
+func (c *PodController) reconcileRestarts(pods []psm.PodInfo) {
+	for _, p := range pods {
+		if p.StoppedByUser || (p.State != "running" && p.State != "degraded") {
+			continue
+		}
+		if err := c.restartPodMembers(p); err != nil {
+			log.Printf("pod controller restart failed: podId=%s err=%v", p.PodId, err)
+		}
+	}
+}
+
+func (c *PodController) restartPodMembers(p psm.PodInfo) error {
+	policy := RestartPolicyAlways
+	if p.TemplateId != "" {
+		tpl, err := c.psmHandler.GetPodTemplate(p.TemplateId)
+		if err != nil {
+			return err
+		}
+		if tpl.Spec.RestartPolicy != "" {
+			policy = tpl.Spec.RestartPolicy
+		}
+	}
+
+	containers, err := c.containerHandler.GetContainersByPodId(p.PodId)
+	if err != nil {
+		return err
+	}
+	var (
+		members      []container.ContainerState
+		infraRunning bool
+	)
+	for _, cinfo := range containers {
+		switch {
+		case strings.HasPrefix(cinfo.Name, utils.PodInfraContainerNamePrefix):
+			infraRunning = cinfo.State == "running"
+		case strings.HasPrefix(cinfo.Name, utils.PodInitContainerNamePrefix):
+		default:
+			members = append(members, cinfo)
+		}
+	}
+	if !infraRunning || len(members) == 0 {
+		return nil
+	}
+
+	statusByName := map[string]psm.ContainerStatus{}
+	for _, st := range p.ContainerStatuses {
+		statusByName[st.Name] = st
+	}
+
+	var (
+		running int
+		pending int
+		failed  []string
+	)
+	for _, m := range members {
+		if m.State == "running" {
+			running++
+			continue
+		}
+		if m.State != "stopped" {
+			pending++
+			continue
+		}
+		info, err := c.csmHandler.GetContainerById(m.ContainerId)
+		if err != nil {
+			return err
+		}
+		name := podMemberBaseName(m.Name, p.PodId)
+		status := statusByName[name]
+		termination := psm.ContainerTermination{
+			ExitCode:   info.ExitCode,
+			Reason:     info.Reason,
+			Message:    info.Message,
+			StartedAt:  info.StartedAt,
+			FinishedAt: info.StoppedAt,
+		}
+		recorded := status.LastTermination != nil && status.LastTermination.FinishedAt.Equal(termination.FinishedAt)
+
+		if !shouldRestart(policy, info.ExitCode) {
+			if info.ExitCode != 0 {
+				failed = append(failed, fmt.Sprintf("container %q exited with %d", name, info.ExitCode))
+			}
+			if !recorded {
+				_ = c.psmHandler.UpdateContainerTermination(p.PodId, name, termination, "")
+			}
+			continue
+		}
+
+		pending++
+		delay := time.Duration(status.BackoffSeconds) * time.Second
+		if termination.FinishedAt.Sub(termination.StartedAt) >= restartBackoffReset {
+			delay = 0
+		}
+		if time.Now().Before(termination.FinishedAt.Add(delay)) {
+			if !recorded || status.Waiting != "CrashLoopBackOff" {
+				_ = c.psmHandler.UpdateContainerTermination(p.PodId, name, termination, "CrashLoopBackOff")
+			}
+			continue
+		}
+
+		if _, err := c.containerHandler.Start(container.ServiceStartModel{ContainerId: m.ContainerId, Tty: false}); err != nil {
+			log.Printf("pod controller container restart failed: podId=%s container=%s err=%v", p.PodId, m.Name, err)
+			continue
+		}
+		next := restartBackoffInitial
+		if delay > 0 {
+			next = min(delay*2, restartBackoffMax)
+		}
+		if err := c.psmHandler.RecordContainerRestart(p.PodId, name, termination, int(next/time.Second)); err != nil {
+			return err
+		}
+		pending--
+		running++
+	}
+
+	switch {
+	case running == len(members):
+		if p.State != "running" {
+			return c.psmHandler.UpdatePod(p.PodId, "running")
+		}
+	case running == 0 && pending == 0:
+		// every member exited for good
+		if len(failed) > 0 {
+			return c.psmHandler.UpdatePodFailed(p.PodId, "ContainerFailed", strings.Join(failed, ", "))
+		}
+		return c.psmHandler.UpdatePod(p.PodId, "succeeded")
+	}
+	return nil
+}
+
+// podMemberBaseName is the template name of a pod member.
+func podMemberBaseName(name, podId string) string {
+	suffix := podId
+	if len(suffix) > 8 {
+		suffix = suffix[len(suffix)-8:]
+	}
+	return strings.TrimSuffix(name, "-"+suffix)
+}
@@ -0,0 +1,57 @@
+package pod
+
+import (
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+	"fmt"
+	"log"
+)
+
+const podEvictedReason = "Evicted"
+
+// reconcileEmptyDirLimits evicts running pods whose disk emptyDir volumes
+// grew past their sizeLimit, as the kubelet does. Memory volumes are held
+// to the limit by the tmpfs itself.
+func (c *PodController) reconcileEmptyDirLimits(pods []psm.PodInfo) {
+	for _, p := range pods {
+		if p.TemplateId == "" || (p.State != "running" && p.State != "degraded") {
+			continue
+		}
+		tpl, err := c.psmHandler.GetPodTemplate(p.TemplateId)
+		if err != nil {
+			continue
+		}
+		message, exceeded := checkEmptyDirLimits(p.PodId, tpl.Spec.Volumes)
+		if !exceeded {
+			continue
+		}
+		if _, err := c.podHandler.Stop(p.PodId); err != nil {
+			log.Printf("pod controller evict failed: podId=%s err=%v", p.PodId, err)
+			continue
+		}
+		if err := c.psmHandler.UpdatePodFailed(p.PodId, podEvictedReason, message); err != nil {
+			log.Printf("pod controller evict failed: podId=%s err=%v", p.PodId, err)
+		}
+	}
+}
+
+func checkEmptyDirLimits(podId string, volumes []psm.VolumeSpec) (string, bool) {
+	for _, v := range volumes {
+		if v.EmptyDir == nil || v.EmptyDir.SizeLimit == "" || v.EmptyDir.Medium == EmptyDirMediumMemory {
+			continue
+		}
+		limit, err := utils.ParseByteSize(v.EmptyDir.SizeLimit)
+		if err != nil {
+			continue
+		}
+		usage, err := emptyDirUsage(emptyDirPath(podId, v.Name))
+		if err != nil {
+			log.Printf("pod controller emptyDir usage failed: podId=%s volume=%s err=%v", podId, v.Name, err)
+			continue
+		}
+		if usage > limit {
+			return fmt.Sprintf("Usage of EmptyDir volume %q exceeds the limit %q.", v.Name, v.EmptyDir.SizeLimit), true
+		}
+	}
+	return "", false
+}
@@ -1,12 +1,30 @@
 package pod
 
+import (
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+)
+
 type PodServiceHandler interface {
 	Create(createParameter ServiceCreateModel) (string, error)
+	CheckImagePolicy(containers []psm.ContainerTemplateSpec) error
 	RecreateFromTemplate(templateId string) (string, error)
 	CreateFromTemplate(templateId string, nameOverride string) (string, error)
+	CreateMember(podId string, spec psm.ContainerTemplateSpec) (string, error)
 	Start(podId string) (string, error)
 	Stop(podId string) (string, error)
 	Remove(podId string) (string, error)
-	GetPodList() ([]PodState, error)
+	GetPodList(opts utils.ListOptions) ([]PodState, error)
 	GetPodById(podId string) (PodState, error)
+	GetPodStats(podId string) (PodStats, error)
+	ListPodStats() ([]PodStats, error)
+	GetNamespaceUsage(namespace string) (NamespaceUsage, error)
+	ApplyPod(createParameter ServiceCreateModel, dryRun bool) (ServiceApplyPodResult, error)
+	ApplyReplicaSet(rsParameter ServiceReplicaSetModel, dryRun bool) (string, string, error)
+	ApplyDeployment(deployParameter ServiceDeploymentModel, dryRun bool) (string, string, error)
+	GetDeploymentList() ([]DeploymentState, error)
+	GetDeploymentById(deploymentId string) (DeploymentState, error)
+	RollbackDeployment(deploymentId string, revision int) (int, error)
+	RemoveDeployment(deploymentId string) (string, error)
+	GetReplicaSetList(opts utils.ListOptions) ([]psm.ReplicaSetInfo, error)
 }
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io"
 
+	"condenser/internal/core/config"
 	"condenser/internal/store/psm"
+	"condenser/internal/utils"
 
 	"gopkg.in/yaml.v3"
 )
@@ -19,6 +21,18 @@ type PodManifest struct {
 	Containers  []psm.ContainerTemplateSpec
 	Replicas    int
 	Selector    map[string]string
+
+	InitContainers []psm.ContainerTemplateSpec
+	RestartPolicy  string
+	Volumes        []psm.VolumeSpec // configMap, secret and emptyDir volumes
+	Overhead       *psm.ResourceList
+
+	// Deployment only
+	Strategy             psm.DeploymentStrategy
+	RevisionHistoryLimit *int
+
+	// ReplicaSet and Deployment, next to the matchLabels in Selector
+	MatchExpressions []utils.LabelSelectorRequirement
 }
 
 type manifestMeta struct {
@@ -29,8 +43,11 @@ type manifestMeta struct {
 }
 
 type podManifestSpec struct {
-	Containers []containerManifest `yaml:"containers"`
-	Volumes    []manifestVolume    `yaml:"volumes"`
+	InitContainers []containerManifest   `yaml:"initContainers"`
+	Containers     []containerManifest   `yaml:"containers"`
+	Volumes        []manifestVolume      `yaml:"volumes"`
+	RestartPolicy  string                `yaml:"restartPolicy"`
+	Overhead       *manifestResourceList `yaml:"overhead"`
 }
 
 type podManifest struct {
@@ -50,11 +67,51 @@ type replicaSetManifest struct {
 	Kind       string       `yaml:"kind"`
 	Metadata   manifestMeta `yaml:"metadata"`
 	Spec       struct {
-		Selector struct {
-			MatchLabels map[string]string `yaml:"matchLabels"`
-		} `yaml:"selector"`
-		Replicas int        `yaml:"replicas"`
-		Template rsTemplate `yaml:"template"`
+		Selector manifestLabelSelector `yaml:"selector"`
+		Replicas int                   `yaml:"replicas"`
+		Template rsTemplate            `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+type manifestLabelSelector struct {
+	MatchLabels      map[string]string                `yaml:"matchLabels"`
+	MatchExpressions []utils.LabelSelectorRequirement `yaml:"matchExpressions"`
+}
+
+// apply sets the selector of a replica set or deployment, the template
+// labels stand in when it has none.
+func (s manifestLabelSelector) apply(manifest *PodManifest) error {
+	for _, r := range s.MatchExpressions {
+		if err := utils.ValidateLabelSelectorRequirement(r); err != nil {
+			return fmt.Errorf("selector.matchExpressions: %w", err)
+		}
+	}
+	manifest.MatchExpressions = s.MatchExpressions
+	if s.MatchLabels != nil || len(s.MatchExpressions) > 0 {
+		manifest.Selector = s.MatchLabels
+	} else {
+		manifest.Selector = manifest.Labels
+	}
+	return nil
+}
+
+type deploymentManifest struct {
+	APIVersion string       `yaml:"apiVersion"`
+	Kind       string       `yaml:"kind"`
+	Metadata   manifestMeta `yaml:"metadata"`
+	Spec       struct {
+		Selector manifestLabelSelector `yaml:"selector"`
+		Replicas *int                  `yaml:"replicas"`
+		Template rsTemplate            `yaml:"template"`
+		Strategy struct {
+			Type          string `yaml:"type"`
+			RollingUpdate struct {
+				// an int or a percentage, yaml hands both over as a string
+				MaxSurge       string `yaml:"maxSurge"`
+				MaxUnavailable string `yaml:"maxUnavailable"`
+			} `yaml:"rollingUpdate"`
+		} `yaml:"strategy"`
+		RevisionHistoryLimit *int `yaml:"revisionHistoryLimit"`
 	} `yaml:"spec"`
 }
 
@@ -68,11 +125,74 @@ type containerManifest struct {
 	Mount        []string              `yaml:"mount"`
 	VolumeMounts []manifestVolumeMount `yaml:"volumeMounts"`
 	Tty          bool                  `yaml:"tty"`
+	EnvFrom      []manifestEnvFrom     `yaml:"envFrom"`
+
+	LivenessProbe  *manifestProbe `yaml:"livenessProbe"`
+	ReadinessProbe *manifestProbe `yaml:"readinessProbe"`
+	StartupProbe   *manifestProbe `yaml:"startupProbe"`
+
+	Resources *manifestResources `yaml:"resources"`
+}
+
+type manifestResources struct {
+	Requests manifestResourceList `yaml:"requests"`
+	Limits   manifestResourceList `yaml:"limits"`
+}
+
+type manifestResourceList struct {
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+}
+
+type manifestProbe struct {
+	Exec *struct {
+		Command []string `yaml:"command"`
+	} `yaml:"exec"`
+	HTTPGet *struct {
+		Path        string `yaml:"path"`
+		Port        int    `yaml:"port"`
+		Host        string `yaml:"host"`
+		Scheme      string `yaml:"scheme"`
+		HTTPHeaders []struct {
+			Name  string `yaml:"name"`
+			Value string `yaml:"value"`
+		} `yaml:"httpHeaders"`
+	} `yaml:"httpGet"`
+	TCPSocket *struct {
+		Port int    `yaml:"port"`
+		Host string `yaml:"host"`
+	} `yaml:"tcpSocket"`
+	InitialDelaySeconds int `yaml:"initialDelaySeconds"`
+	PeriodSeconds       int `yaml:"periodSeconds"`
+	TimeoutSeconds      int `yaml:"timeoutSeconds"`
+	SuccessThreshold    int `yaml:"successThreshold"`
+	FailureThreshold    int `yaml:"failureThreshold"`
 }
 
 type manifestEnvVar struct {
-	Name  string `yaml:"name"`
-	Value string `yaml:"value"`
+	Name      string `yaml:"name"`
+	Value     string `yaml:"value"`
+	ValueFrom *struct {
+		ConfigMapKeyRef *manifestKeyRef `yaml:"configMapKeyRef"`
+		SecretKeyRef    *manifestKeyRef `yaml:"secretKeyRef"`
+	} `yaml:"valueFrom"`
+}
+
+type manifestKeyRef struct {
+	Name     string `yaml:"name"`
+	Key      string `yaml:"key"`
+	Optional bool   `yaml:"optional"`
+}
+
+type manifestEnvFrom struct {
+	Prefix       string           `yaml:"prefix"`
+	ConfigMapRef *manifestNameRef `yaml:"configMapRef"`
+	SecretRef    *manifestNameRef `yaml:"secretRef"`
+}
+
+type manifestNameRef struct {
+	Name     string `yaml:"name"`
+	Optional bool   `yaml:"optional"`
 }
 
 type manifestPort struct {
@@ -81,8 +201,20 @@ type manifestPort struct {
 }
 
 type manifestVolume struct {
-	Name     string           `yaml:"name"`
-	HostPath manifestHostPath `yaml:"hostPath"`
+	Name      string           `yaml:"name"`
+	HostPath  manifestHostPath `yaml:"hostPath"`
+	ConfigMap *struct {
+		Name     string `yaml:"name"`
+		Optional bool   `yaml:"optional"`
+	} `yaml:"configMap"`
+	Secret *struct {
+		SecretName string `yaml:"secretName"`
+		Optional   bool   `yaml:"optional"`
+	} `yaml:"secret"`
+	EmptyDir *struct {
+		Medium    string `yaml:"medium"`
+		SizeLimit string `yaml:"sizeLimit"`
+	} `yaml:"emptyDir"`
 }
 
 type manifestHostPath struct {
@@ -126,7 +258,7 @@ func DecodeK8sManifests(body []byte) ([]PodManifest, error) {
 			if err := yaml.Unmarshal(rawBytes, &pod); err != nil {
 				return nil, err
 			}
-			manifest, err := buildPodManifest(pod.Metadata, pod.Spec.Containers, pod.Spec.Volumes)
+			manifest, err := buildPodManifest(pod.Metadata, pod.Spec)
 			if err != nil {
 				return nil, err
 			}
@@ -144,24 +276,60 @@ func DecodeK8sManifests(body []byte) ([]PodManifest, error) {
 			if meta.Name == "" {
 				meta.Name = rs.Metadata.Name
 			}
-			manifest, err := buildPodManifest(meta, rs.Spec.Template.Spec.Containers, rs.Spec.Template.Spec.Volumes)
+			manifest, err := buildPodManifest(meta, rs.Spec.Template.Spec)
 			if err != nil {
 				return nil, err
 			}
 			manifest.Kind = "ReplicaSet"
+			// replicas are replaced rather than left exited, as in kubernetes
+			if manifest.RestartPolicy != RestartPolicyAlways {
+				return nil, fmt.Errorf("replicaset template restartPolicy must be Always")
+			}
 			manifest.Replicas = rs.Spec.Replicas
 			if manifest.Replicas == 0 {
 				manifest.Replicas = 1
 			}
-			if rs.Spec.Selector.MatchLabels != nil {
-				manifest.Selector = rs.Spec.Selector.MatchLabels
-			} else {
-				manifest.Selector = manifest.Labels
+			if err := rs.Spec.Selector.apply(&manifest); err != nil {
+				return nil, err
 			}
 			if manifest.Name == "" {
 				return nil, fmt.Errorf("replicaset template name is required")
 			}
 			result = append(result, manifest)
+		case "Deployment":
+			var d deploymentManifest
+			if err := yaml.Unmarshal(rawBytes, &d); err != nil {
+				return nil, err
+			}
+			// the deployment names its replica sets and pods
+			meta := d.Spec.Template.Metadata
+			meta.Name = d.Metadata.Name
+			meta.Namespace = d.Metadata.Namespace
+			manifest, err := buildPodManifest(meta, d.Spec.Template.Spec)
+			if err != nil {
+				return nil, err
+			}
+			manifest.Kind = "Deployment"
+			if manifest.RestartPolicy != RestartPolicyAlways {
+				return nil, fmt.Errorf("deployment template restartPolicy must be Always")
+			}
+			manifest.Replicas = 1
+			if d.Spec.Replicas != nil {
+				manifest.Replicas = *d.Spec.Replicas
+			}
+			if err := d.Spec.Selector.apply(&manifest); err != nil {
+				return nil, err
+			}
+			manifest.Strategy = psm.DeploymentStrategy{
+				Type:           d.Spec.Strategy.Type,
+				MaxSurge:       d.Spec.Strategy.RollingUpdate.MaxSurge,
+				MaxUnavailable: d.Spec.Strategy.RollingUpdate.MaxUnavailable,
+			}
+			manifest.RevisionHistoryLimit = d.Spec.RevisionHistoryLimit
+			if manifest.Name == "" {
+				return nil, fmt.Errorf("deployment name is required")
+			}
+			result = append(result, manifest)
 		default:
 			return nil, fmt.Errorf("unsupported kind: %s", kind)
 		}
@@ -170,21 +338,112 @@ func DecodeK8sManifests(body []byte) ([]PodManifest, error) {
 	return result, nil
 }
 
-func buildPodManifest(meta manifestMeta, containers []containerManifest, volumes []manifestVolume) (PodManifest, error) {
+func buildPodManifest(meta manifestMeta, spec podManifestSpec) (PodManifest, error) {
 	if meta.Namespace == "" {
 		meta.Namespace = "default"
 	}
 	volumeHostPath := map[string]string{}
-	for _, v := range volumes {
+	var volumes []psm.VolumeSpec
+	for _, v := range spec.Volumes {
 		if v.Name == "" {
 			continue
 		}
-		if v.HostPath.Path == "" {
-			return PodManifest{}, fmt.Errorf("volume %q: only hostPath volumes are supported", v.Name)
+		if err := utils.ValidateDNSLabel(v.Name); err != nil {
+			return PodManifest{}, fmt.Errorf("volume: %w", err)
 		}
-		volumeHostPath[v.Name] = v.HostPath.Path
+		switch {
+		case v.ConfigMap != nil:
+			if v.ConfigMap.Name == "" {
+				return PodManifest{}, fmt.Errorf("volume %q: configMap name is required", v.Name)
+			}
+			if err := utils.ValidateDNSSubdomain(v.ConfigMap.Name); err != nil {
+				return PodManifest{}, fmt.Errorf("volume %q: configMap: %w", v.Name, err)
+			}
+			dir, err := config.ProjectedVolumePath(config.ProjectionKindConfigMap, meta.Namespace, v.ConfigMap.Name)
+			if err != nil {
+				return PodManifest{}, fmt.Errorf("volume %q: %w", v.Name, err)
+			}
+			volumes = append(volumes, psm.VolumeSpec{
+				Name:      v.Name,
+				ConfigMap: &psm.ConfigMapVolumeSource{Name: v.ConfigMap.Name, Optional: v.ConfigMap.Optional},
+			})
+			volumeHostPath[v.Name] = dir
+		case v.Secret != nil:
+			if v.Secret.SecretName == "" {
+				return PodManifest{}, fmt.Errorf("volume %q: secretName is required", v.Name)
+			}
+			if err := utils.ValidateDNSSubdomain(v.Secret.SecretName); err != nil {
+				return PodManifest{}, fmt.Errorf("volume %q: secret: %w", v.Name, err)
+			}
+			dir, err := config.ProjectedVolumePath(config.ProjectionKindSecret, meta.Namespace, v.Secret.SecretName)
+			if err != nil {
+				return PodManifest{}, fmt.Errorf("volume %q: %w", v.Name, err)
+			}
+			volumes = append(volumes, psm.VolumeSpec{
+				Name:   v.Name,
+				Secret: &psm.SecretVolumeSource{SecretName: v.Secret.SecretName, Optional: v.Secret.Optional},
+			})
+			volumeHostPath[v.Name] = dir
+		case v.EmptyDir != nil:
+			src := psm.EmptyDirVolumeSource{Medium: v.EmptyDir.Medium, SizeLimit: v.EmptyDir.SizeLimit}
+			if err := validateEmptyDir(src); err != nil {
+				return PodManifest{}, fmt.Errorf("volume %q: %w", v.Name, err)
+			}
+			volumes = append(volumes, psm.VolumeSpec{Name: v.Name, EmptyDir: &src})
+			// resolved per pod instance when the members are created
+			volumeHostPath[v.Name] = emptyDirSourcePrefix + v.Name
+		case v.HostPath.Path != "":
+			volumeHostPath[v.Name] = v.HostPath.Path
+		default:
+			return PodManifest{}, fmt.Errorf("volume %q: only hostPath, configMap, secret and emptyDir volumes are supported", v.Name)
+		}
+	}
+	projected := map[string]bool{}
+	for _, v := range volumes {
+		if v.ConfigMap != nil || v.Secret != nil {
+			projected[v.Name] = true
+		}
+	}
+
+	initContainers, err := buildContainerSpecs(spec.InitContainers, volumeHostPath, projected)
+	if err != nil {
+		return PodManifest{}, err
+	}
+	if err := validateInitContainers(initContainers); err != nil {
+		return PodManifest{}, err
+	}
+	containers, err := buildContainerSpecs(spec.Containers, volumeHostPath, projected)
+	if err != nil {
+		return PodManifest{}, err
+	}
+	if err := validateContainerResources(append(append([]psm.ContainerTemplateSpec{}, initContainers...), containers...)); err != nil {
+		return PodManifest{}, err
 	}
+	var overhead *psm.ResourceList
+	if spec.Overhead != nil {
+		overhead = &psm.ResourceList{CPU: spec.Overhead.CPU, Memory: spec.Overhead.Memory}
+		if err := validateOverhead(overhead); err != nil {
+			return PodManifest{}, err
+		}
+	}
+	restartPolicy, err := normalizeRestartPolicy(spec.RestartPolicy)
+	if err != nil {
+		return PodManifest{}, err
+	}
+	return PodManifest{
+		Name:           meta.Name,
+		Namespace:      meta.Namespace,
+		Labels:         meta.Labels,
+		Annotations:    meta.Annotations,
+		Containers:     containers,
+		InitContainers: initContainers,
+		RestartPolicy:  restartPolicy,
+		Volumes:        volumes,
+		Overhead:       overhead,
+	}, nil
+}
 
+func buildContainerSpecs(containers []containerManifest, volumeHostPath map[string]string, projected map[string]bool) ([]psm.ContainerTemplateSpec, error) {
 	specs := make([]psm.ContainerTemplateSpec, 0, len(containers))
 	for _, c := range containers {
 		cmd := c.Command
@@ -192,11 +451,40 @@ func buildPodManifest(meta manifestMeta, containers []containerManifest, volumes
 			cmd = append(append([]string{}, c.Command...), c.Args...)
 		}
 		envs := make([]string, 0, len(c.Env))
+		var valueFrom []psm.EnvVarSource
 		for _, e := range c.Env {
 			if e.Name == "" {
 				continue
 			}
-			envs = append(envs, e.Name+"="+e.Value)
+			if e.ValueFrom == nil {
+				envs = append(envs, e.Name+"="+e.Value)
+				continue
+			}
+			ref := psm.EnvVarSource{Name: e.Name}
+			switch {
+			case e.ValueFrom.ConfigMapKeyRef != nil:
+				k := e.ValueFrom.ConfigMapKeyRef
+				ref.ConfigMapKeyRef = &psm.KeySelectorSpec{Name: k.Name, Key: k.Key, Optional: k.Optional}
+			case e.ValueFrom.SecretKeyRef != nil:
+				k := e.ValueFrom.SecretKeyRef
+				ref.SecretKeyRef = &psm.KeySelectorSpec{Name: k.Name, Key: k.Key, Optional: k.Optional}
+			default:
+				return nil, fmt.Errorf("container %q: env %s: only configMapKeyRef and secretKeyRef are supported", c.Name, e.Name)
+			}
+			valueFrom = append(valueFrom, ref)
+		}
+		var envFrom []psm.EnvFromSource
+		for _, e := range c.EnvFrom {
+			src := psm.EnvFromSource{Prefix: e.Prefix}
+			switch {
+			case e.ConfigMapRef != nil:
+				src.ConfigMapRef = &psm.NameRefSpec{Name: e.ConfigMapRef.Name, Optional: e.ConfigMapRef.Optional}
+			case e.SecretRef != nil:
+				src.SecretRef = &psm.NameRefSpec{Name: e.SecretRef.Name, Optional: e.SecretRef.Optional}
+			default:
+				return nil, fmt.Errorf("container %q: envFrom needs a configMapRef or secretRef", c.Name)
+			}
+			envFrom = append(envFrom, src)
 		}
 		ports := make([]string, 0, len(c.Ports))
 		for _, p := range c.Ports {
@@ -214,15 +502,16 @@ func buildPodManifest(meta manifestMeta, containers []containerManifest, volumes
 			}
 			hostPath, ok := volumeHostPath[vm.Name]
 			if !ok {
-				return PodManifest{}, fmt.Errorf("container %q: volume %q not found", c.Name, vm.Name)
+				return nil, fmt.Errorf("container %q: volume %q not found", c.Name, vm.Name)
 			}
 			m := hostPath + ":" + vm.MountPath
-			if vm.ReadOnly {
+			// projected volumes are never writable from inside the pod
+			if vm.ReadOnly || projected[vm.Name] {
 				m += ":ro"
 			}
 			mounts = append(mounts, m)
 		}
-		specs = append(specs, psm.ContainerTemplateSpec{
+		spec := psm.ContainerTemplateSpec{
 			Name:    c.Name,
 			Image:   c.Image,
 			Command: cmd,
@@ -230,15 +519,63 @@ func buildPodManifest(meta manifestMeta, containers []containerManifest, volumes
 			Port:    ports,
 			Mount:   mounts,
 			Tty:     c.Tty,
-		})
+
+			EnvValueFrom: valueFrom,
+			EnvFrom:      envFrom,
+		}
+		if c.Resources != nil {
+			spec.Resources = &psm.ResourceRequirements{
+				Requests: psm.ResourceList{CPU: c.Resources.Requests.CPU, Memory: c.Resources.Requests.Memory},
+				Limits:   psm.ResourceList{CPU: c.Resources.Limits.CPU, Memory: c.Resources.Limits.Memory},
+			}
+		}
+		var err error
+		if spec.LivenessProbe, err = buildProbe(c.LivenessProbe); err != nil {
+			return nil, fmt.Errorf("container %q: livenessProbe: %w", c.Name, err)
+		}
+		if spec.ReadinessProbe, err = buildProbe(c.ReadinessProbe); err != nil {
+			return nil, fmt.Errorf("container %q: readinessProbe: %w", c.Name, err)
+		}
+		if spec.StartupProbe, err = buildProbe(c.StartupProbe); err != nil {
+			return nil, fmt.Errorf("container %q: startupProbe: %w", c.Name, err)
+		}
+		specs = append(specs, spec)
 	}
-	return PodManifest{
-		Name:        meta.Name,
-		Namespace:   meta.Namespace,
-		Labels:      meta.Labels,
-		Annotations: meta.Annotations,
-		Containers:  specs,
-	}, nil
+	return specs, nil
+}
+
+func buildProbe(p *manifestProbe) (*psm.ProbeSpec, error) {
+	if p == nil {
+		return nil, nil
+	}
+	probe := &psm.ProbeSpec{
+		InitialDelaySeconds: p.InitialDelaySeconds,
+		PeriodSeconds:       p.PeriodSeconds,
+		TimeoutSeconds:      p.TimeoutSeconds,
+		SuccessThreshold:    p.SuccessThreshold,
+		FailureThreshold:    p.FailureThreshold,
+	}
+	if p.Exec != nil {
+		probe.Exec = &psm.ExecAction{Command: p.Exec.Command}
+	}
+	if p.HTTPGet != nil {
+		probe.HTTPGet = &psm.HTTPGetAction{
+			Path:   p.HTTPGet.Path,
+			Port:   p.HTTPGet.Port,
+			Host:   p.HTTPGet.Host,
+			Scheme: p.HTTPGet.Scheme,
+		}
+		for _, h := range p.HTTPGet.HTTPHeaders {
+			probe.HTTPGet.HTTPHeaders = append(probe.HTTPGet.HTTPHeaders, psm.HTTPHeader{Name: h.Name, Value: h.Value})
+		}
+	}
+	if p.TCPSocket != nil {
+		probe.TCPSocket = &psm.TCPSocketAction{Port: p.TCPSocket.Port, Host: p.TCPSocket.Host}
+	}
+	if err := validateProbe(probe); err != nil {
+		return nil, err
+	}
+	return probe, nil
 }
 
 func mergeLabels(base, extra map[string]string) map[string]string {
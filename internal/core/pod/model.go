@@ -1,7 +1,9 @@
 package pod
 
 import (
+	"condenser/internal/core/container"
 	"condenser/internal/store/psm"
+	"condenser/internal/utils"
 	"time"
 )
 
@@ -16,6 +18,11 @@ type ServiceCreateModel struct {
 	Labels      map[string]string
 	Annotations map[string]string
 	Containers  []psm.ContainerTemplateSpec
+
+	InitContainers []psm.ContainerTemplateSpec
+	RestartPolicy  string            // Always when empty
+	Volumes        []psm.VolumeSpec  // configMap, secret and emptyDir volumes
+	Overhead       *psm.ResourceList // added to the pod cgroup limits
 }
 
 type PodState struct {
@@ -35,4 +42,126 @@ type PodState struct {
 	CreatedAt         time.Time         `json:"createdAt"`
 	StartedAt         time.Time         `json:"startedAt"`
 	StoppedAt         time.Time         `json:"stoppedAt"`
+
+	Reason         string                    `json:"reason,omitempty"` // why the pod failed
+	Message        string                    `json:"message,omitempty"`
+	InitContainers []psm.InitContainerStatus `json:"initContainers,omitempty"`
+
+	Ready             bool                  `json:"ready"`
+	ContainerStatuses []psm.ContainerStatus `json:"containerStatuses,omitempty"`
+}
+
+type ServiceReplicaSetModel struct {
+	Name      string
+	Namespace string
+	Replicas  int
+	Selector  map[string]string
+	Template  psm.PodTemplateSpec
+
+	MatchExpressions []utils.LabelSelectorRequirement
+}
+
+// ServiceApplyPodResult is the outcome of applying a pod, Result is one of
+// created, configured or unchanged.
+type ServiceApplyPodResult struct {
+	PodId        string
+	Result       string
+	ContainerIds []string
+}
+
+// NamespaceUsage is what the pods of a namespace count against its
+// resource quotas, cpu in millicores and memory in bytes.
+type NamespaceUsage struct {
+	Pods           int
+	RequestsCPU    int64
+	RequestsMemory int64
+	LimitsCPU      int64
+	LimitsMemory   int64
+}
+
+type ServiceDeploymentModel struct {
+	Name                 string
+	Namespace            string
+	Replicas             int
+	Selector             map[string]string
+	Template             psm.PodTemplateSpec
+	Strategy             psm.DeploymentStrategy
+	RevisionHistoryLimit *int // 10 when nil
+
+	MatchExpressions []utils.LabelSelectorRequirement
+}
+
+type DeploymentState struct {
+	DeploymentId         string                 `json:"deploymentId"`
+	Name                 string                 `json:"name"`
+	Namespace            string                 `json:"namespace"`
+	Replicas             int                    `json:"replicas"`
+	Selector             map[string]string      `json:"selector,omitempty"`
+	Strategy             psm.DeploymentStrategy `json:"strategy"`
+	RevisionHistoryLimit int                    `json:"revisionHistoryLimit"`
+	Template             psm.PodTemplateSpec    `json:"template"`
+	Rollout              RolloutStatus          `json:"rollout"`
+	Revisions            []DeploymentRevision   `json:"revisions"`
+	CreatedAt            time.Time              `json:"createdAt"`
+	UpdatedAt            time.Time              `json:"updatedAt"`
+
+	MatchExpressions []utils.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+type RolloutStatus struct {
+	Revision          int    `json:"revision"`
+	Replicas          int    `json:"replicas"`
+	UpdatedReplicas   int    `json:"updatedReplicas"` // pods of the current revision
+	ReadyReplicas     int    `json:"readyReplicas"`
+	AvailableReplicas int    `json:"availableReplicas"` // ready pods of the current revision
+	OldReplicas       int    `json:"oldReplicas"`
+	Complete          bool   `json:"complete"`
+	Message           string `json:"message"`
+}
+
+type DeploymentRevision struct {
+	Revision     int       `json:"revision"`
+	ReplicaSetId string    `json:"replicaSetId"`
+	TemplateHash string    `json:"templateHash"`
+	Replicas     int       `json:"replicas"`
+	Images       []string  `json:"images"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// PodStats are the totals of the pod cgroup as last sampled by the
+// monitor, with the stats of each member.
+type PodStats struct {
+	GeneratedTS string `json:"generated_ts"`
+
+	PodId      string `json:"pod_id"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Status     string `json:"status"`
+	CgroupPath string `json:"cgroup_path"`
+
+	CPUUsageUsec     uint64  `json:"cpu_usage_usec"`
+	CPUUserUsec      uint64  `json:"cpu_user_usec"`
+	CPUSystemUsec    uint64  `json:"cpu_system_usec"`
+	CPUNrPeriods     uint64  `json:"cpu_nr_periods"`
+	CPUNrThrottled   uint64  `json:"cpu_nr_throttled"`
+	CPUThrottledUsec uint64  `json:"cpu_throttled_usec"`
+	CPUQuotaUsec     uint64  `json:"cpu_quota_usec"`
+	CPUPeriodUsec    uint64  `json:"cpu_period_usec"`
+	CPUUnlimited     bool    `json:"cpu_unlimited"`
+	CPUPercent       float64 `json:"cpu_percent"`
+
+	MemoryCurrentBytes uint64  `json:"memory_current_bytes"`
+	MemoryMaxBytes     *uint64 `json:"memory_max_bytes"`
+	MemoryLimited      bool    `json:"memory_limited"`
+	MemoryPercent      float64 `json:"memory_percent"`
+
+	IOReadBytes  uint64 `json:"io_read_bytes"`
+	IOWriteBytes uint64 `json:"io_write_bytes"`
+	IOReadOps    uint64 `json:"io_read_ops"`
+	IOWriteOps   uint64 `json:"io_write_ops"`
+
+	MemoryOOM     uint64 `json:"memory_oom"`
+	MemoryOOMKill uint64 `json:"memory_oom_kill"`
+
+	Containers []container.ContainerStats `json:"containers"`
 }
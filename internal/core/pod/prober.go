@@ -0,0 +1,440 @@
+package pod
+
+import (
+	"condenser/internal/core/container"
+	"condenser/internal/store/ipam"
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	probeStartup   = "startup"
+	probeLiveness  = "liveness"
+	probeReadiness = "readiness"
+
+	// pods probed at the same time, a slow probe holds back only its own pod
+	probeConcurrency = 16
+)
+
+func NewProbeController() *ProbeController {
+	return &ProbeController{
+		psmHandler:       psm.NewPsmManager(psm.NewPsmStore(utils.PsmStorePath)),
+		containerHandler: container.NewContaierService(),
+		ipamHandler:      ipam.NewIpamManager(ipam.NewIpamStore(utils.IpamStorePath)),
+		interval:         1 * time.Second,
+		states:           map[string]*probeState{},
+	}
+}
+
+// ProbeController runs the probes of pod members and keeps the readiness
+// of every pod in PSM. Readiness is what the Service controller routes by.
+type ProbeController struct {
+	psmHandler       psm.PsmHandler
+	containerHandler container.ContainerServiceHandler
+	ipamHandler      ipam.IpamHandler
+	interval         time.Duration
+	states           map[string]*probeState // by container id
+	mu               sync.Mutex             // guards states, pods are probed concurrently
+}
+
+// probeState is the probe bookkeeping of one container run. It starts over
+// whenever the container is started again.
+type probeState struct {
+	startedAt time.Time
+	lastRun   map[string]time.Time
+	successes map[string]int
+	failures  map[string]int
+	started   bool
+	ready     bool
+	lastError string
+}
+
+func (c *ProbeController) Start() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := c.reconcileOnce(); err != nil {
+			log.Printf("probe controller reconcile failed: %v", err)
+		}
+	}
+}
+
+func (c *ProbeController) reconcileOnce() error {
+	pods, err := c.psmHandler.GetPodList()
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]struct{}{}
+	sem := make(chan struct{}, probeConcurrency)
+	var wg sync.WaitGroup
+	for _, p := range pods {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if err := c.reconcilePod(p, seen); err != nil {
+				log.Printf("probe controller pod failed: podId=%s err=%v", p.PodId, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for containerId := range c.states {
+		if _, ok := seen[containerId]; !ok {
+			delete(c.states, containerId)
+		}
+	}
+	return nil
+}
+
+func (c *ProbeController) reconcilePod(p psm.PodInfo, seen map[string]struct{}) error {
+	var specs []psm.ContainerTemplateSpec
+	if p.TemplateId != "" {
+		tpl, err := c.psmHandler.GetPodTemplate(p.TemplateId)
+		if err != nil {
+			return err
+		}
+		specs = tpl.Spec.Containers
+	}
+	containers, err := c.containerHandler.GetContainersByPodId(p.PodId)
+	if err != nil {
+		return err
+	}
+
+	byName := map[string]container.ContainerState{}
+	var infraId string
+	for _, cinfo := range containers {
+		switch {
+		case strings.HasPrefix(cinfo.Name, utils.PodInfraContainerNamePrefix):
+			infraId = cinfo.ContainerId
+		case strings.HasPrefix(cinfo.Name, utils.PodInitContainerNamePrefix):
+		default:
+			byName[cinfo.Name] = cinfo
+		}
+	}
+	// pods without a template are judged by the containers they have
+	if len(specs) == 0 {
+		for _, cinfo := range byName {
+			specs = append(specs, psm.ContainerTemplateSpec{Name: cinfo.Name})
+		}
+	}
+	prev := map[string]psm.ContainerStatus{}
+	for _, st := range p.ContainerStatuses {
+		prev[st.Name] = st
+	}
+
+	podAddr := ""
+	if infraId != "" {
+		_, _, podAddr, _ = c.ipamHandler.GetContainerAddress(infraId)
+	}
+
+	ready := p.State == "running" && len(specs) > 0
+	statuses := make([]psm.ContainerStatus, 0, len(specs))
+	for _, spec := range specs {
+		// restart counts and terminations are kept by the pod controller
+		status := prev[spec.Name]
+		status.Name = spec.Name
+		status.ContainerId = ""
+		status.Started = false
+		status.Ready = false
+		status.LastProbe = ""
+		cinfo, ok := byName[spec.Name]
+		if !ok {
+			cinfo, ok = byName[podMemberName(spec.Name, p.PodId)]
+		}
+		if !ok || cinfo.State != "running" {
+			if ok {
+				status.ContainerId = cinfo.ContainerId
+			}
+			ready = false
+			statuses = append(statuses, status)
+			continue
+		}
+		status.ContainerId = cinfo.ContainerId
+		c.mu.Lock()
+		seen[cinfo.ContainerId] = struct{}{}
+		c.mu.Unlock()
+
+		st := c.stateOf(cinfo)
+		if kill := c.probeContainer(spec, cinfo, st, podAddr); kill {
+			// the pod controller restarts it following the restart policy
+			log.Printf("probe controller stopping unhealthy container: podId=%s container=%s reason=%s", p.PodId, cinfo.Name, st.lastError)
+			if _, err := c.containerHandler.Stop(container.ServiceStopModel{ContainerId: cinfo.ContainerId}); err != nil {
+				log.Printf("probe controller stop failed: podId=%s container=%s err=%v", p.PodId, cinfo.Name, err)
+			}
+			c.mu.Lock()
+			delete(c.states, cinfo.ContainerId)
+			c.mu.Unlock()
+			st = &probeState{lastError: st.lastError}
+		}
+		status.Started = st.started
+		status.Ready = st.ready
+		status.LastProbe = st.lastError
+		if !st.ready {
+			ready = false
+		}
+		statuses = append(statuses, status)
+	}
+
+	if ready == p.Ready && equalContainerStatuses(statuses, p.ContainerStatuses) {
+		return nil
+	}
+	return c.psmHandler.UpdatePodReadiness(p.PodId, ready, statuses)
+}
+
+func (c *ProbeController) stateOf(cinfo container.ContainerState) *probeState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st, ok := c.states[cinfo.ContainerId]
+	if !ok || !st.startedAt.Equal(cinfo.StartedAt) {
+		st = &probeState{
+			startedAt: cinfo.StartedAt,
+			lastRun:   map[string]time.Time{},
+			successes: map[string]int{},
+			failures:  map[string]int{},
+		}
+		c.states[cinfo.ContainerId] = st
+	}
+	return st
+}
+
+// probeContainer runs the probes that are due and reports whether the
+// container has to be killed. Until the startup probe succeeds the
+// other probes are held back, as in kubernetes.
+func (c *ProbeController) probeContainer(spec psm.ContainerTemplateSpec, cinfo container.ContainerState, st *probeState, podAddr string) bool {
+	if spec.StartupProbe == nil {
+		st.started = true
+	}
+	if !st.started {
+		ok, due := c.runIfDue(probeStartup, spec.StartupProbe, cinfo, st, podAddr)
+		if !due {
+			return false
+		}
+		if ok {
+			st.started = true
+		} else {
+			return st.failures[probeStartup] >= failureThreshold(spec.StartupProbe)
+		}
+	}
+
+	if spec.LivenessProbe != nil {
+		if ok, due := c.runIfDue(probeLiveness, spec.LivenessProbe, cinfo, st, podAddr); due && !ok &&
+			st.failures[probeLiveness] >= failureThreshold(spec.LivenessProbe) {
+			return true
+		}
+	}
+
+	if spec.ReadinessProbe == nil {
+		st.ready = true
+		return false
+	}
+	if ok, due := c.runIfDue(probeReadiness, spec.ReadinessProbe, cinfo, st, podAddr); due {
+		if ok && st.successes[probeReadiness] >= successThreshold(spec.ReadinessProbe) {
+			st.ready = true
+		}
+		if !ok && st.failures[probeReadiness] >= failureThreshold(spec.ReadinessProbe) {
+			st.ready = false
+		}
+	}
+	return false
+}
+
+func (c *ProbeController) runIfDue(kind string, probe *psm.ProbeSpec, cinfo container.ContainerState, st *probeState, podAddr string) (ok bool, due bool) {
+	now := time.Now()
+	if now.Sub(cinfo.StartedAt) < time.Duration(probe.InitialDelaySeconds)*time.Second {
+		return false, false
+	}
+	if last, ran := st.lastRun[kind]; ran && now.Sub(last) < probePeriod(probe) {
+		return false, false
+	}
+	st.lastRun[kind] = now
+
+	if err := c.runProbe(probe, cinfo.ContainerId, podAddr); err != nil {
+		st.successes[kind] = 0
+		st.failures[kind]++
+		st.lastError = kind + ": " + err.Error()
+		return false, true
+	}
+	st.failures[kind] = 0
+	st.successes[kind]++
+	if strings.HasPrefix(st.lastError, kind+": ") {
+		st.lastError = ""
+	}
+	return true, true
+}
+
+func (c *ProbeController) runProbe(probe *psm.ProbeSpec, containerId, podAddr string) error {
+	timeout := probeTimeout(probe)
+	switch {
+	case probe.Exec != nil:
+		// a hanging command is killed with its droplet exec at the timeout
+		return c.containerHandler.Exec(container.ServiceExecModel{
+			ContainerId: containerId,
+			Entrypoint:  probe.Exec.Command,
+			Timeout:     timeout,
+		})
+	case probe.HTTPGet != nil:
+		host := probe.HTTPGet.Host
+		if host == "" {
+			host = podAddr
+		}
+		if host == "" {
+			return fmt.Errorf("pod has no address")
+		}
+		scheme := strings.ToLower(probe.HTTPGet.Scheme)
+		if scheme == "" {
+			scheme = "http"
+		}
+		path := probe.HTTPGet.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		req, err := http.NewRequest(http.MethodGet, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(probe.HTTPGet.Port))+path, nil)
+		if err != nil {
+			return err
+		}
+		for _, h := range probe.HTTPGet.HTTPHeaders {
+			req.Header.Add(h.Name, h.Value)
+		}
+		client := &http.Client{
+			Timeout: timeout,
+			// kubernetes does not verify certificates of https probes either
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 10<<10))
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("http status %d", resp.StatusCode)
+		}
+		return nil
+	case probe.TCPSocket != nil:
+		host := probe.TCPSocket.Host
+		if host == "" {
+			host = podAddr
+		}
+		if host == "" {
+			return fmt.Errorf("pod has no address")
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(probe.TCPSocket.Port)), timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	return fmt.Errorf("probe has no handler")
+}
+
+func validateProbe(probe *psm.ProbeSpec) error {
+	handlers := 0
+	if probe.Exec != nil {
+		if len(probe.Exec.Command) == 0 {
+			return fmt.Errorf("exec.command is required")
+		}
+		handlers++
+	}
+	if probe.HTTPGet != nil {
+		if probe.HTTPGet.Port <= 0 || probe.HTTPGet.Port > 65535 {
+			return fmt.Errorf("httpGet.port is invalid: %d", probe.HTTPGet.Port)
+		}
+		if s := strings.ToLower(probe.HTTPGet.Scheme); s != "" && s != "http" && s != "https" {
+			return fmt.Errorf("httpGet.scheme is invalid: %s", probe.HTTPGet.Scheme)
+		}
+		handlers++
+	}
+	if probe.TCPSocket != nil {
+		if probe.TCPSocket.Port <= 0 || probe.TCPSocket.Port > 65535 {
+			return fmt.Errorf("tcpSocket.port is invalid: %d", probe.TCPSocket.Port)
+		}
+		handlers++
+	}
+	if handlers != 1 {
+		return fmt.Errorf("exactly one of exec, httpGet and tcpSocket is required")
+	}
+	if probe.InitialDelaySeconds < 0 || probe.PeriodSeconds < 0 || probe.TimeoutSeconds < 0 ||
+		probe.SuccessThreshold < 0 || probe.FailureThreshold < 0 {
+		return fmt.Errorf("probe timings must not be negative")
+	}
+	return nil
+}
+
+func validateContainerProbes(specs []psm.ContainerTemplateSpec) error {
+	for _, spec := range specs {
+		probes := []struct {
+			kind  string
+			probe *psm.ProbeSpec
+		}{
+			{"livenessProbe", spec.LivenessProbe},
+			{"readinessProbe", spec.ReadinessProbe},
+			{"startupProbe", spec.StartupProbe},
+		}
+		for _, p := range probes {
+			if p.probe == nil {
+				continue
+			}
+			if err := validateProbe(p.probe); err != nil {
+				return fmt.Errorf("container %q: %s: %w", spec.Name, p.kind, err)
+			}
+		}
+	}
+	return nil
+}
+
+// kubernetes defaults: every 10s, 1s timeout, ready after 1 success,
+// failed after 3 failures
+func probePeriod(probe *psm.ProbeSpec) time.Duration {
+	if probe.PeriodSeconds > 0 {
+		return time.Duration(probe.PeriodSeconds) * time.Second
+	}
+	return 10 * time.Second
+}
+
+func probeTimeout(probe *psm.ProbeSpec) time.Duration {
+	if probe.TimeoutSeconds > 0 {
+		return time.Duration(probe.TimeoutSeconds) * time.Second
+	}
+	return 1 * time.Second
+}
+
+func successThreshold(probe *psm.ProbeSpec) int {
+	if probe.SuccessThreshold > 0 {
+		return probe.SuccessThreshold
+	}
+	return 1
+}
+
+func failureThreshold(probe *psm.ProbeSpec) int {
+	if probe.FailureThreshold > 0 {
+		return probe.FailureThreshold
+	}
+	return 3
+}
+
+func equalContainerStatuses(a, b []psm.ContainerStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
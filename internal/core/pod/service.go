@@ -1,7 +1,12 @@
 package pod
 
 import (
+	"condenser/internal/core/config"
 	"condenser/internal/core/container"
+	"condenser/internal/core/image"
+	"condenser/internal/core/trust"
+	"condenser/internal/store/csm"
+	"condenser/internal/store/nsm"
 	"condenser/internal/store/psm"
 	"condenser/internal/utils"
 	"strings"
@@ -10,15 +15,29 @@ import (
 func NewPodService() *PodService {
 	return &PodService{
 		psmHandler:       psm.NewPsmManager(psm.NewPsmStore(utils.PsmStorePath)),
+		csmHandler:       csm.NewCsmManager(csm.NewCsmStore(utils.CsmStorePath)),
 		containerHandler: container.NewContaierService(),
+		imageHandler:     image.NewImageService(),
+		trustHandler:     trust.NewTrustService(),
+		configHandler:    config.NewConfigService(),
+		nsmHandler:       nsm.NewNsmManager(nsm.NewNsmStore(utils.NsmStorePath)),
 	}
 }
 
 type PodService struct {
 	psmHandler       psm.PsmHandler
+	csmHandler       csm.CsmHandler
 	containerHandler container.ContainerServiceHandler
+	imageHandler     image.ImageServiceHandler
+	trustHandler     trust.TrustServiceHandler
+	configHandler    config.ConfigServiceHandler
+	nsmHandler       nsm.NsmHandler
 }
 
 func (s *PodService) isPodInfraName(name string) bool {
 	return strings.HasPrefix(name, utils.PodInfraContainerNamePrefix)
 }
+
+func (s *PodService) isPodInitName(name string) bool {
+	return strings.HasPrefix(name, utils.PodInitContainerNamePrefix)
+}
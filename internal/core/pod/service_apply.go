@@ -0,0 +1,229 @@
+package pod
+
+import (
+	"fmt"
+
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+)
+
+// == service: apply pod ==
+// ApplyPod creates the pod with its members, or replaces it when the spec
+// differs from the stored template. Pods are immutable as in kubernetes,
+// a changed pod is recreated and started again if it was running.
+func (s *PodService) ApplyPod(createParameter ServiceCreateModel, dryRun bool) (ServiceApplyPodResult, error) {
+	tpl, err := s.buildPodTemplate(createParameter)
+	if err != nil {
+		return ServiceApplyPodResult{}, err
+	}
+
+	result := ServiceApplyPodResult{Result: utils.ApplyResultCreated}
+	var restart bool
+	if existingId, err := s.psmHandler.GetPodIdByName(createParameter.Name, createParameter.Namespace); err == nil {
+		existing, err := s.psmHandler.GetPodById(existingId)
+		if err != nil {
+			return ServiceApplyPodResult{}, err
+		}
+		if existing.TemplateId == "" {
+			return ServiceApplyPodResult{}, fmt.Errorf("pod %s/%s has no template to compare", existing.Namespace, existing.Name)
+		}
+		if owned, err := s.psmHandler.IsTemplateReferenced(existing.TemplateId); err != nil {
+			return ServiceApplyPodResult{}, err
+		} else if owned {
+			return ServiceApplyPodResult{}, fmt.Errorf("pod %s/%s is managed by a replicaset", existing.Namespace, existing.Name)
+		}
+		stored, err := s.psmHandler.GetPodTemplate(existing.TemplateId)
+		if err != nil {
+			return ServiceApplyPodResult{}, err
+		}
+		if !utils.SpecChanged(stored.Spec, tpl) {
+			return ServiceApplyPodResult{PodId: existingId, Result: utils.ApplyResultUnchanged}, nil
+		}
+		result = ServiceApplyPodResult{PodId: existingId, Result: utils.ApplyResultConfigured}
+		if dryRun {
+			return result, nil
+		}
+		if err := s.checkReplacement(existingId, createParameter.Namespace, tpl); err != nil {
+			return ServiceApplyPodResult{}, err
+		}
+		restart = existing.State == "running" || existing.State == "degraded"
+		if _, err := s.Remove(existingId); err != nil {
+			return ServiceApplyPodResult{}, err
+		}
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	podId, err := s.createWithNewTemplate(tpl, createParameter)
+	if err != nil {
+		return ServiceApplyPodResult{}, replacedError(result, err)
+	}
+	result.PodId = podId
+	for _, c := range tpl.Containers {
+		if c.Image == "" {
+			continue
+		}
+		containerId, err := s.CreateMember(podId, c)
+		if err != nil {
+			_, _ = s.Remove(podId)
+			return ServiceApplyPodResult{}, replacedError(result, fmt.Errorf("container %s: %w", c.Name, err))
+		}
+		result.ContainerIds = append(result.ContainerIds, containerId)
+	}
+	if restart {
+		if _, err := s.Start(podId); err != nil {
+			return result, fmt.Errorf("pod recreated, start failed: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// checkReplacement runs the checks the new pod can fail, quota admission
+// and the env references of its members, while the old pod still exists.
+func (s *PodService) checkReplacement(existingId string, namespace string, tpl psm.PodTemplateSpec) error {
+	if err := s.admitPod(namespace, tpl, existingId); err != nil {
+		return err
+	}
+	for _, c := range tpl.Containers {
+		if c.Image == "" {
+			continue
+		}
+		if _, err := s.configHandler.ResolveEnv(namespace, c); err != nil {
+			return fmt.Errorf("container %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// replacedError tells that the old pod is gone when its replacement failed.
+func replacedError(result ServiceApplyPodResult, err error) error {
+	if result.Result != utils.ApplyResultConfigured {
+		return err
+	}
+	return fmt.Errorf("old pod %s removed, replacement failed: %w", result.PodId, err)
+}
+
+// == service: apply replica set ==
+// ApplyReplicaSet creates the replica set or updates its replicas and
+// selector in place. A changed pod template replaces the template, the
+// pods of the old one are removed and the controller creates new ones.
+func (s *PodService) ApplyReplicaSet(rsParameter ServiceReplicaSetModel, dryRun bool) (string, string, error) {
+	if rsParameter.Name == "" {
+		return "", "", fmt.Errorf("replicaset name is required")
+	}
+	if rsParameter.Namespace == "" {
+		rsParameter.Namespace = "default"
+	}
+	if rsParameter.Replicas < 0 {
+		return "", "", fmt.Errorf("replicas must be >= 0")
+	}
+	tpl, err := s.buildPodTemplate(ServiceCreateModel{
+		Name:           rsParameter.Name,
+		Namespace:      rsParameter.Namespace,
+		Labels:         rsParameter.Template.Labels,
+		Annotations:    rsParameter.Template.Annotations,
+		Containers:     rsParameter.Template.Containers,
+		InitContainers: rsParameter.Template.InitContainers,
+		RestartPolicy:  rsParameter.Template.RestartPolicy,
+		Volumes:        rsParameter.Template.Volumes,
+		Overhead:       rsParameter.Template.Overhead,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	list, err := s.psmHandler.GetReplicaSetList()
+	if err != nil {
+		return "", "", err
+	}
+	for _, rs := range list {
+		if rs.Spec.Name != rsParameter.Name || rs.Spec.Namespace != rsParameter.Namespace {
+			continue
+		}
+		if rs.Spec.DeploymentId != "" {
+			return "", "", fmt.Errorf("replicaset %s/%s is managed by a deployment", rs.Spec.Namespace, rs.Spec.Name)
+		}
+		return s.updateReplicaSet(rs, rsParameter, tpl, dryRun)
+	}
+
+	if dryRun {
+		return "", utils.ApplyResultCreated, nil
+	}
+	templateId := utils.NewUlid()
+	if err := s.psmHandler.StorePodTemplate(templateId, tpl); err != nil {
+		return "", "", err
+	}
+	replicaSetId := utils.NewUlid()
+	if err := s.psmHandler.StoreReplicaSet(replicaSetId, psm.ReplicaSetSpec{
+		Name:       rsParameter.Name,
+		Namespace:  rsParameter.Namespace,
+		Replicas:   rsParameter.Replicas,
+		TemplateId: templateId,
+		Selector:   rsParameter.Selector,
+
+		MatchExpressions: rsParameter.MatchExpressions,
+	}); err != nil {
+		return "", "", err
+	}
+	return replicaSetId, utils.ApplyResultCreated, nil
+}
+
+func (s *PodService) updateReplicaSet(rs psm.ReplicaSetInfo, rsParameter ServiceReplicaSetModel, tpl psm.PodTemplateSpec, dryRun bool) (string, string, error) {
+	stored, err := s.psmHandler.GetPodTemplate(rs.Spec.TemplateId)
+	if err != nil {
+		return "", "", err
+	}
+	templateChanged := utils.SpecChanged(stored.Spec, tpl)
+	specChanged := rs.Spec.Replicas != rsParameter.Replicas || !equalStringMap(rs.Spec.Selector, rsParameter.Selector) ||
+		utils.SpecChanged(rs.Spec.MatchExpressions, rsParameter.MatchExpressions)
+	if !templateChanged && !specChanged {
+		return rs.ReplicaSetId, utils.ApplyResultUnchanged, nil
+	}
+	if dryRun {
+		return rs.ReplicaSetId, utils.ApplyResultConfigured, nil
+	}
+
+	oldTemplateId := rs.Spec.TemplateId
+	spec := rs.Spec
+	spec.Replicas = rsParameter.Replicas
+	spec.Selector = rsParameter.Selector
+	spec.MatchExpressions = rsParameter.MatchExpressions
+	if templateChanged {
+		spec.TemplateId = utils.NewUlid()
+		if err := s.psmHandler.StorePodTemplate(spec.TemplateId, tpl); err != nil {
+			return "", "", err
+		}
+	}
+	if err := s.psmHandler.UpdateReplicaSet(rs.ReplicaSetId, spec); err != nil {
+		return "", "", err
+	}
+	if templateChanged {
+		if err := s.removeTemplatePods(oldTemplateId); err != nil {
+			return rs.ReplicaSetId, utils.ApplyResultConfigured, fmt.Errorf("replicaset updated, old pods remain: %w", err)
+		}
+	}
+	return rs.ReplicaSetId, utils.ApplyResultConfigured, nil
+}
+
+// removeTemplatePods removes the pods of a template no replica set points
+// to anymore, and the template with them.
+func (s *PodService) removeTemplatePods(templateId string) error {
+	pods, err := s.psmHandler.GetPodList()
+	if err != nil {
+		return err
+	}
+	for _, p := range pods {
+		if p.TemplateId != templateId {
+			continue
+		}
+		if _, err := s.Remove(p.PodId); err != nil {
+			return err
+		}
+	}
+	// Remove drops the template with its last pod, it may have had none
+	if _, err := s.psmHandler.GetPodTemplate(templateId); err == nil {
+		return s.psmHandler.RemovePodTemplate(templateId)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package pod
+
+import (
+	"condenser/internal/core/container"
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// podResources are the summed cgroup values of a pod, -1 when unset.
+type podResources struct {
+	cpuRequest    int64
+	cpuLimit      int64
+	memoryRequest int64 // only counted against resource quotas
+	memoryLimit   int64
+}
+
+func validateContainerResources(containers []psm.ContainerTemplateSpec) error {
+	for _, c := range containers {
+		if c.Resources == nil {
+			continue
+		}
+		cpuRequest, cpuLimit, memRequest, memLimit, err := parseResources(c.Resources)
+		if err != nil {
+			return fmt.Errorf("container %q: %w", c.Name, err)
+		}
+		if cpuLimit >= 0 && cpuRequest > cpuLimit {
+			return fmt.Errorf("container %q: cpu request %s exceeds limit %s", c.Name, c.Resources.Requests.CPU, c.Resources.Limits.CPU)
+		}
+		if memLimit >= 0 && memRequest > memLimit {
+			return fmt.Errorf("container %q: memory request %s exceeds limit %s", c.Name, c.Resources.Requests.Memory, c.Resources.Limits.Memory)
+		}
+	}
+	return nil
+}
+
+func validateOverhead(overhead *psm.ResourceList) error {
+	if overhead == nil {
+		return nil
+	}
+	if _, _, err := parseResourceList(*overhead); err != nil {
+		return fmt.Errorf("overhead: %w", err)
+	}
+	return nil
+}
+
+// parseResources returns millicores and bytes, -1 for what is not set. A
+// request defaults to the limit as in kubernetes.
+func parseResources(r *psm.ResourceRequirements) (cpuRequest, cpuLimit, memRequest, memLimit int64, err error) {
+	if r == nil {
+		return -1, -1, -1, -1, nil
+	}
+	if cpuRequest, memRequest, err = parseResourceList(r.Requests); err != nil {
+		return
+	}
+	if cpuLimit, memLimit, err = parseResourceList(r.Limits); err != nil {
+		return
+	}
+	if cpuRequest < 0 {
+		cpuRequest = cpuLimit
+	}
+	if memRequest < 0 {
+		memRequest = memLimit
+	}
+	return
+}
+
+func parseResourceList(l psm.ResourceList) (int64, int64, error) {
+	cpu, memory := int64(-1), int64(-1)
+	if l.CPU != "" {
+		v, err := utils.ParseCPUQuantity(l.CPU)
+		if err != nil {
+			return 0, 0, err
+		}
+		cpu = v
+	}
+	if l.Memory != "" {
+		v, err := utils.ParseByteSize(l.Memory)
+		if err != nil {
+			return 0, 0, err
+		}
+		memory = v
+	}
+	return cpu, memory, nil
+}
+
+// computePodResources sums the containers the way the kubelet sizes the
+// pod cgroup. Init containers run alone, the largest of them counts when
+// it is above the sum. A limit is only set when every container has one,
+// the overhead is added on top.
+func computePodResources(spec psm.PodTemplateSpec) podResources {
+	total := podResources{cpuRequest: -1, cpuLimit: 0, memoryRequest: -1, memoryLimit: 0}
+	cpuLimited, memLimited := true, true
+
+	var initCPURequest, initCPULimit, initMemRequest, initMemLimit int64
+	for _, c := range spec.InitContainers {
+		cpuRequest, cpuLimit, memRequest, memLimit, _ := parseResources(c.Resources)
+		initCPURequest = max(initCPURequest, cpuRequest)
+		initMemRequest = max(initMemRequest, memRequest)
+		if cpuLimit < 0 {
+			cpuLimited = false
+		}
+		if memLimit < 0 {
+			memLimited = false
+		}
+		initCPULimit = max(initCPULimit, cpuLimit)
+		initMemLimit = max(initMemLimit, memLimit)
+	}
+
+	var appCPURequest, appMemRequest int64
+	var hasRequest, hasMemRequest bool
+	for _, c := range spec.Containers {
+		cpuRequest, cpuLimit, memRequest, memLimit, _ := parseResources(c.Resources)
+		if cpuRequest >= 0 {
+			appCPURequest += cpuRequest
+			hasRequest = true
+		}
+		if memRequest >= 0 {
+			appMemRequest += memRequest
+			hasMemRequest = true
+		}
+		if cpuLimit < 0 {
+			cpuLimited = false
+		}
+		if memLimit < 0 {
+			memLimited = false
+		}
+		total.cpuLimit += max(cpuLimit, 0)
+		total.memoryLimit += max(memLimit, 0)
+	}
+
+	var overheadCPU, overheadMemory int64
+	if spec.Overhead != nil {
+		cpu, memory, _ := parseResourceList(*spec.Overhead)
+		overheadCPU, overheadMemory = max(cpu, 0), max(memory, 0)
+	}
+
+	if hasRequest || initCPURequest > 0 {
+		total.cpuRequest = max(appCPURequest, initCPURequest) + overheadCPU
+	}
+	if hasMemRequest || initMemRequest > 0 {
+		total.memoryRequest = max(appMemRequest, initMemRequest) + overheadMemory
+	}
+	if cpuLimited && (len(spec.Containers) > 0 || len(spec.InitContainers) > 0) {
+		total.cpuLimit = max(total.cpuLimit, initCPULimit) + overheadCPU
+	} else {
+		total.cpuLimit = -1
+	}
+	if memLimited && (len(spec.Containers) > 0 || len(spec.InitContainers) > 0) {
+		total.memoryLimit = max(total.memoryLimit, initMemLimit) + overheadMemory
+	} else {
+		total.memoryLimit = -1
+	}
+	return total
+}
+
+// applyPodCgroup writes the limits of the pod and of each member before
+// the pod starts, cgroups do not survive a reboot of the host.
+func (s *PodService) applyPodCgroup(podInfo psm.PodInfo, containers []container.ContainerState) error {
+	if podInfo.TemplateId == "" {
+		return nil
+	}
+	tpl, err := s.psmHandler.GetPodTemplate(podInfo.TemplateId)
+	if err != nil {
+		return err
+	}
+	podPath := utils.PodCgroupPath(podInfo.PodId)
+	if err := os.MkdirAll(podPath, 0o755); err != nil {
+		return err
+	}
+	total := computePodResources(tpl.Spec)
+	if err := writeCgroupValues(podPath, total.cpuRequest, total.cpuLimit, total.memoryLimit); err != nil {
+		return fmt.Errorf("pod cgroup: %w", err)
+	}
+
+	byName := make(map[string]container.ContainerState, len(containers))
+	for _, c := range containers {
+		byName[c.Name] = c
+	}
+	for _, spec := range tpl.Spec.Containers {
+		c, ok := byName[s.buildPodMemberName(spec.Name, podInfo.PodId)]
+		if !ok {
+			continue
+		}
+		if err := applyMemberCgroup(c.ContainerId, podInfo.PodId, spec.Resources); err != nil {
+			return fmt.Errorf("container %s: %w", spec.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyMemberCgroup(containerId, podId string, resources *psm.ResourceRequirements) error {
+	cpuRequest, cpuLimit, _, memLimit, err := parseResources(resources)
+	if err != nil {
+		return err
+	}
+	return writeCgroupValues(utils.ContainerCgroupPath(containerId, podId), cpuRequest, cpuLimit, memLimit)
+}
+
+// writeCgroupValues maps a cpu request to cpu.weight and the limits to
+// cpu.max and memory.max. Memory requests are not enforced.
+func writeCgroupValues(cgroupPath string, cpuRequest, cpuLimit, memoryLimit int64) error {
+	weight := "100"
+	if cpuRequest >= 0 {
+		weight = strconv.FormatUint(utils.CPUWeightFromMillis(cpuRequest), 10)
+	}
+	cpuMax := "max"
+	if cpuLimit >= 0 {
+		cpuMax = utils.CPUMaxFromMillis(cpuLimit)
+	}
+	memoryMax := "max"
+	if memoryLimit >= 0 {
+		memoryMax = strconv.FormatInt(memoryLimit, 10)
+	}
+	for file, value := range map[string]string{
+		"cpu.weight": weight,
+		"cpu.max":    cpuMax,
+		"memory.max": memoryMax,
+	} {
+		if err := os.WriteFile(filepath.Join(cgroupPath, file), []byte(value+"\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removePodCgroup drops the pod cgroup once its members are deleted.
+func removePodCgroup(podId string) error {
+	if err := os.Remove(utils.PodCgroupPath(podId)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
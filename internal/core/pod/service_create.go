@@ -3,29 +3,116 @@ package pod
 import (
 	"fmt"
 
+	"condenser/internal/core/container"
 	"condenser/internal/store/psm"
 	"condenser/internal/utils"
 )
 
 // == service: create pod sandbox ==
 func (s *PodService) Create(createParameter ServiceCreateModel) (string, error) {
-	templateId := utils.NewUlid()
+	tpl, err := s.buildPodTemplate(createParameter)
+	if err != nil {
+		return "", err
+	}
 
-	if err := s.psmHandler.StorePodTemplate(templateId, psm.PodTemplateSpec{
-		Name:        createParameter.Name,
-		Namespace:   createParameter.Namespace,
-		NetworkNS:   createParameter.NetworkNS,
-		IPCNS:       createParameter.IPCNS,
-		UTSNS:       createParameter.UTSNS,
-		UserNS:      createParameter.UserNS,
-		Labels:      createParameter.Labels,
-		Annotations: createParameter.Annotations,
-		Containers:  createParameter.Containers,
-	}); err != nil {
+	return s.createWithNewTemplate(tpl, createParameter)
+}
+
+// createWithNewTemplate stores the template of a new pod and creates the
+// pod from it. A pod that is not created, a quota rejection included,
+// takes its template along, the controller would recreate the pod from an
+// orphaned template otherwise.
+func (s *PodService) createWithNewTemplate(tpl psm.PodTemplateSpec, createParameter ServiceCreateModel) (string, error) {
+	templateId := utils.NewUlid()
+	if err := s.psmHandler.StorePodTemplate(templateId, tpl); err != nil {
 		return "", err
 	}
+	podId, err := s.createWithTemplate(templateId, createParameter)
+	if err != nil {
+		_ = s.psmHandler.RemovePodTemplate(templateId)
+		return "", err
+	}
+	return podId, nil
+}
 
-	return s.createWithTemplate(templateId, createParameter)
+// buildPodTemplate validates the pod spec and returns the template it is
+// stored as, with the limit range defaults of the namespace filled in.
+func (s *PodService) buildPodTemplate(createParameter ServiceCreateModel) (psm.PodTemplateSpec, error) {
+	if createParameter.Namespace != "" {
+		if err := s.nsmHandler.CheckNamespaceActive(createParameter.Namespace); err != nil {
+			return psm.PodTemplateSpec{}, err
+		}
+		initContainers, err := s.applyLimitRange(createParameter.Namespace, createParameter.InitContainers)
+		if err != nil {
+			return psm.PodTemplateSpec{}, err
+		}
+		containers, err := s.applyLimitRange(createParameter.Namespace, createParameter.Containers)
+		if err != nil {
+			return psm.PodTemplateSpec{}, err
+		}
+		createParameter.InitContainers = initContainers
+		createParameter.Containers = containers
+	}
+	if err := validateInitContainers(createParameter.InitContainers); err != nil {
+		return psm.PodTemplateSpec{}, err
+	}
+	if err := s.CheckImagePolicy(createParameter.InitContainers); err != nil {
+		return psm.PodTemplateSpec{}, err
+	}
+	if err := s.CheckImagePolicy(createParameter.Containers); err != nil {
+		return psm.PodTemplateSpec{}, err
+	}
+	if err := validateContainerProbes(createParameter.Containers); err != nil {
+		return psm.PodTemplateSpec{}, err
+	}
+	if err := validateContainerResources(createParameter.InitContainers); err != nil {
+		return psm.PodTemplateSpec{}, err
+	}
+	if err := validateContainerResources(createParameter.Containers); err != nil {
+		return psm.PodTemplateSpec{}, err
+	}
+	if err := validateOverhead(createParameter.Overhead); err != nil {
+		return psm.PodTemplateSpec{}, err
+	}
+	for _, v := range createParameter.Volumes {
+		if err := validateVolume(v); err != nil {
+			return psm.PodTemplateSpec{}, fmt.Errorf("volume %q: %w", v.Name, err)
+		}
+	}
+
+	restartPolicy, err := normalizeRestartPolicy(createParameter.RestartPolicy)
+	if err != nil {
+		return psm.PodTemplateSpec{}, err
+	}
+
+	return psm.PodTemplateSpec{
+		Name:           createParameter.Name,
+		Namespace:      createParameter.Namespace,
+		NetworkNS:      createParameter.NetworkNS,
+		IPCNS:          createParameter.IPCNS,
+		UTSNS:          createParameter.UTSNS,
+		UserNS:         createParameter.UserNS,
+		Labels:         createParameter.Labels,
+		Annotations:    createParameter.Annotations,
+		Containers:     createParameter.Containers,
+		InitContainers: createParameter.InitContainers,
+		RestartPolicy:  restartPolicy,
+		Volumes:        createParameter.Volumes,
+		Overhead:       createParameter.Overhead,
+	}, nil
+}
+
+// == service: check container images against the image reference policy ==
+func (s *PodService) CheckImagePolicy(containers []psm.ContainerTemplateSpec) error {
+	for _, c := range containers {
+		if c.Image == "" {
+			continue
+		}
+		if err := s.imageHandler.CheckReferencePolicy(c.Image); err != nil {
+			return fmt.Errorf("container %q: %w", c.Name, err)
+		}
+	}
+	return nil
 }
 
 // == service: recreate pod sandbox from template ==
@@ -86,6 +173,16 @@ func (s *PodService) createWithTemplate(templateId string, createParameter Servi
 		}
 	}
 
+	if templateId != "" {
+		tpl, err := s.psmHandler.GetPodTemplate(templateId)
+		if err != nil {
+			return "", err
+		}
+		if err := s.admitPod(createParameter.Namespace, tpl.Spec, ""); err != nil {
+			return "", err
+		}
+	}
+
 	podId := utils.NewUlid()
 	if err := s.psmHandler.StorePod(
 		podId,
@@ -106,3 +203,39 @@ func (s *PodService) createWithTemplate(templateId string, createParameter Servi
 
 	return podId, nil
 }
+
+// == service: create pod member ==
+// CreateMember creates a container of the pod from its template entry. Env
+// references and emptyDir mounts are resolved for this pod, the template
+// keeps them as written.
+func (s *PodService) CreateMember(podId string, spec psm.ContainerTemplateSpec) (string, error) {
+	podInfo, err := s.psmHandler.GetPodById(podId)
+	if err != nil {
+		return "", err
+	}
+	createParameter, err := s.memberCreateModel(podInfo, spec)
+	if err != nil {
+		return "", err
+	}
+	return s.containerHandler.Create(createParameter)
+}
+
+func (s *PodService) memberCreateModel(podInfo psm.PodInfo, spec psm.ContainerTemplateSpec) (container.ServiceCreateModel, error) {
+	env, err := s.configHandler.ResolveEnv(podInfo.Namespace, spec)
+	if err != nil {
+		return container.ServiceCreateModel{}, fmt.Errorf("container %s: %w", spec.Name, err)
+	}
+	templateSpec := spec
+	return container.ServiceCreateModel{
+		Image:        spec.Image,
+		Command:      spec.Command,
+		Port:         spec.Port,
+		Mount:        resolvePodMounts(podInfo.PodId, spec.Mount),
+		Env:          env,
+		Network:      spec.Network,
+		Tty:          spec.Tty,
+		Name:         spec.Name,
+		PodId:        podInfo.PodId,
+		TemplateSpec: &templateSpec,
+	}, nil
+}
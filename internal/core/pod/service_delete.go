@@ -2,6 +2,7 @@ package pod
 
 import (
 	"condenser/internal/core/container"
+	"log"
 	"strings"
 )
 
@@ -39,6 +40,15 @@ func (s *PodService) Remove(podId string) (string, error) {
 			return "", err
 		}
 	}
+	if err := removePodVolumes(podId); err != nil {
+		return "", err
+	}
+	if err := s.releaseProjections(podInfo); err != nil {
+		log.Printf("pod projection release failed: podId=%s err=%v", podId, err)
+	}
+	if err := removePodCgroup(podId); err != nil {
+		log.Printf("pod cgroup remove failed: podId=%s err=%v", podId, err)
+	}
 	if err := s.psmHandler.RemovePod(podId); err != nil {
 		return "", err
 	}
@@ -0,0 +1,465 @@
+package pod
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+)
+
+const (
+	DeploymentStrategyRollingUpdate = "RollingUpdate"
+	DeploymentStrategyRecreate      = "Recreate"
+)
+
+// podTemplateHashLabel tells apart the replica sets, and their pods, of
+// the revisions of a deployment.
+const podTemplateHashLabel = "pod-template-hash"
+
+const (
+	defaultMaxSurge             = "25%"
+	defaultMaxUnavailable       = "25%"
+	defaultRevisionHistoryLimit = 10
+)
+
+// == service: create or update deployment ==
+// ApplyDeployment stores the deployment under its name and namespace and
+// reports whether it was created, configured or left unchanged. A changed
+// pod template starts a rollout on the next controller pass.
+func (s *PodService) ApplyDeployment(deployParameter ServiceDeploymentModel, dryRun bool) (string, string, error) {
+	spec, err := s.buildDeploymentSpec(deployParameter)
+	if err != nil {
+		return "", "", err
+	}
+
+	list, err := s.psmHandler.GetDeploymentList()
+	if err != nil {
+		return "", "", err
+	}
+	for _, d := range list {
+		if d.Spec.Name == spec.Name && d.Spec.Namespace == spec.Namespace {
+			if !utils.SpecChanged(d.Spec, spec) {
+				return d.DeploymentId, utils.ApplyResultUnchanged, nil
+			}
+			if dryRun {
+				return d.DeploymentId, utils.ApplyResultConfigured, nil
+			}
+			if err := s.psmHandler.StoreDeployment(d.DeploymentId, spec); err != nil {
+				return "", "", err
+			}
+			return d.DeploymentId, utils.ApplyResultConfigured, nil
+		}
+	}
+	if dryRun {
+		return "", utils.ApplyResultCreated, nil
+	}
+	deploymentId := utils.NewUlid()
+	if err := s.psmHandler.StoreDeployment(deploymentId, spec); err != nil {
+		return "", "", err
+	}
+	return deploymentId, utils.ApplyResultCreated, nil
+}
+
+func (s *PodService) buildDeploymentSpec(deployParameter ServiceDeploymentModel) (psm.DeploymentSpec, error) {
+	if deployParameter.Name == "" {
+		return psm.DeploymentSpec{}, fmt.Errorf("deployment name is required")
+	}
+	if deployParameter.Namespace == "" {
+		deployParameter.Namespace = "default"
+	}
+	if deployParameter.Replicas < 0 {
+		return psm.DeploymentSpec{}, fmt.Errorf("replicas must be >= 0")
+	}
+
+	if err := s.nsmHandler.CheckNamespaceActive(deployParameter.Namespace); err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	tpl := deployParameter.Template
+	tpl.Name = deployParameter.Name
+	tpl.Namespace = deployParameter.Namespace
+	initContainers, err := s.applyLimitRange(tpl.Namespace, tpl.InitContainers)
+	if err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	containers, err := s.applyLimitRange(tpl.Namespace, tpl.Containers)
+	if err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	tpl.InitContainers = initContainers
+	tpl.Containers = containers
+	if err := validateContainerResources(tpl.InitContainers); err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	if err := validateContainerResources(tpl.Containers); err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	if err := validateOverhead(tpl.Overhead); err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	if err := validateInitContainers(tpl.InitContainers); err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	if err := s.CheckImagePolicy(tpl.InitContainers); err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	if err := s.CheckImagePolicy(tpl.Containers); err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	if err := validateContainerProbes(tpl.Containers); err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	restartPolicy, err := normalizeRestartPolicy(tpl.RestartPolicy)
+	if err != nil {
+		return psm.DeploymentSpec{}, err
+	}
+	// replicas are replaced rather than left exited, as in kubernetes
+	if restartPolicy != RestartPolicyAlways {
+		return psm.DeploymentSpec{}, fmt.Errorf("deployment template restartPolicy must be Always")
+	}
+	tpl.RestartPolicy = restartPolicy
+	if _, ok := tpl.Labels[podTemplateHashLabel]; ok {
+		return psm.DeploymentSpec{}, fmt.Errorf("template label %q is reserved", podTemplateHashLabel)
+	}
+
+	selector := deployParameter.Selector
+	if len(selector) == 0 && len(deployParameter.MatchExpressions) == 0 {
+		selector = tpl.Labels
+	}
+	if len(selector) == 0 && len(deployParameter.MatchExpressions) == 0 {
+		return psm.DeploymentSpec{}, fmt.Errorf("deployment selector or template labels are required")
+	}
+	for k, v := range selector {
+		if tpl.Labels[k] != v {
+			return psm.DeploymentSpec{}, fmt.Errorf("selector %s=%s does not match the template labels", k, v)
+		}
+	}
+	if !utils.LabelSelector(deployParameter.MatchExpressions).Matches(tpl.Labels) {
+		return psm.DeploymentSpec{}, fmt.Errorf("selector matchExpressions do not match the template labels")
+	}
+
+	strategy := deployParameter.Strategy
+	switch strategy.Type {
+	case "", DeploymentStrategyRollingUpdate:
+		strategy.Type = DeploymentStrategyRollingUpdate
+		if strategy.MaxSurge == "" {
+			strategy.MaxSurge = defaultMaxSurge
+		}
+		if strategy.MaxUnavailable == "" {
+			strategy.MaxUnavailable = defaultMaxUnavailable
+		}
+		if _, err := resolveIntOrPercent(strategy.MaxSurge, deployParameter.Replicas, true); err != nil {
+			return psm.DeploymentSpec{}, fmt.Errorf("maxSurge: %w", err)
+		}
+		if _, err := resolveIntOrPercent(strategy.MaxUnavailable, deployParameter.Replicas, false); err != nil {
+			return psm.DeploymentSpec{}, fmt.Errorf("maxUnavailable: %w", err)
+		}
+	case DeploymentStrategyRecreate:
+		strategy.MaxSurge = ""
+		strategy.MaxUnavailable = ""
+	default:
+		return psm.DeploymentSpec{}, fmt.Errorf("invalid strategy type: %s (RollingUpdate or Recreate)", strategy.Type)
+	}
+
+	historyLimit := defaultRevisionHistoryLimit
+	if deployParameter.RevisionHistoryLimit != nil {
+		if *deployParameter.RevisionHistoryLimit < 0 {
+			return psm.DeploymentSpec{}, fmt.Errorf("revisionHistoryLimit must be >= 0")
+		}
+		historyLimit = *deployParameter.RevisionHistoryLimit
+	}
+
+	return psm.DeploymentSpec{
+		Name:                 deployParameter.Name,
+		Namespace:            deployParameter.Namespace,
+		Replicas:             deployParameter.Replicas,
+		Selector:             selector,
+		Template:             tpl,
+		Strategy:             strategy,
+		RevisionHistoryLimit: historyLimit,
+
+		MatchExpressions: deployParameter.MatchExpressions,
+	}, nil
+}
+
+// resolveIntOrPercent turns a count or a percentage of replicas into a
+// count. Percentages of the surge round up, of the unavailable pods down.
+func resolveIntOrPercent(value string, replicas int, roundUp bool) (int, error) {
+	if pct, ok := strings.CutSuffix(value, "%"); ok {
+		n, err := strconv.Atoi(pct)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid percentage: %s", value)
+		}
+		if roundUp {
+			return (n*replicas + 99) / 100, nil
+		}
+		return n * replicas / 100, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid value: %s", value)
+	}
+	return n, nil
+}
+
+// podTemplateHash identifies a revision of the pod template. It is taken
+// before the hash label is added, so a rollback to the same template finds
+// the replica set of that revision again.
+func podTemplateHash(tpl psm.PodTemplateSpec) (string, error) {
+	b, err := json.Marshal(tpl)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])[:10], nil
+}
+
+// == service: list deployments ==
+func (s *PodService) GetDeploymentList() ([]DeploymentState, error) {
+	list, err := s.psmHandler.GetDeploymentList()
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.getDeploymentResources()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]DeploymentState, 0, len(list))
+	for _, d := range list {
+		result = append(result, buildDeploymentState(d, res))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
+
+// == service: get deployment ==
+func (s *PodService) GetDeploymentById(deploymentId string) (DeploymentState, error) {
+	d, err := s.psmHandler.GetDeployment(deploymentId)
+	if err != nil {
+		return DeploymentState{}, err
+	}
+	res, err := s.getDeploymentResources()
+	if err != nil {
+		return DeploymentState{}, err
+	}
+	return buildDeploymentState(d, res), nil
+}
+
+type deploymentResources struct {
+	replicaSets []psm.ReplicaSetInfo
+	templates   map[string]psm.PodTemplateInfo
+	pods        []psm.PodInfo
+}
+
+func (s *PodService) getDeploymentResources() (deploymentResources, error) {
+	var (
+		res deploymentResources
+		err error
+	)
+	if res.replicaSets, err = s.psmHandler.GetReplicaSetList(); err != nil {
+		return res, err
+	}
+	templates, err := s.psmHandler.GetPodTemplateList()
+	if err != nil {
+		return res, err
+	}
+	res.templates = make(map[string]psm.PodTemplateInfo, len(templates))
+	for _, tpl := range templates {
+		res.templates[tpl.TemplateId] = tpl
+	}
+	if res.pods, err = s.psmHandler.GetPodList(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func buildDeploymentState(d psm.DeploymentInfo, res deploymentResources) DeploymentState {
+	state := DeploymentState{
+		DeploymentId:         d.DeploymentId,
+		Name:                 d.Spec.Name,
+		Namespace:            d.Spec.Namespace,
+		Replicas:             d.Spec.Replicas,
+		Selector:             d.Spec.Selector,
+		MatchExpressions:     d.Spec.MatchExpressions,
+		Strategy:             d.Spec.Strategy,
+		RevisionHistoryLimit: d.Spec.RevisionHistoryLimit,
+		Template:             d.Spec.Template,
+		Revisions:            []DeploymentRevision{},
+		CreatedAt:            d.CreatedAt,
+		UpdatedAt:            d.UpdatedAt,
+	}
+
+	hash, _ := podTemplateHash(d.Spec.Template)
+	podsByTemplate := map[string][]psm.PodInfo{}
+	for _, p := range res.pods {
+		if p.TemplateId != "" {
+			podsByTemplate[p.TemplateId] = append(podsByTemplate[p.TemplateId], p)
+		}
+	}
+
+	rollout := RolloutStatus{Revision: d.Revision}
+	var newFound bool
+	for _, rs := range ownedReplicaSets(d.DeploymentId, res.replicaSets) {
+		rsPods := podsByTemplate[rs.Spec.TemplateId]
+		var ready int
+		for _, p := range rsPods {
+			if p.Ready {
+				ready++
+			}
+		}
+		rollout.Replicas += len(rsPods)
+		rollout.ReadyReplicas += ready
+		if rs.Spec.TemplateHash == hash {
+			newFound = true
+			rollout.UpdatedReplicas = len(rsPods)
+			rollout.AvailableReplicas = ready
+		} else {
+			rollout.OldReplicas += len(rsPods)
+		}
+
+		var images []string
+		for _, c := range res.templates[rs.Spec.TemplateId].Spec.Containers {
+			images = append(images, c.Image)
+		}
+		state.Revisions = append(state.Revisions, DeploymentRevision{
+			Revision:     rs.Spec.Revision,
+			ReplicaSetId: rs.ReplicaSetId,
+			TemplateHash: rs.Spec.TemplateHash,
+			Replicas:     rs.Spec.Replicas,
+			Images:       images,
+			CreatedAt:    rs.CreatedAt,
+		})
+	}
+
+	switch {
+	case !newFound:
+		rollout.Message = "waiting for the replica set of the new revision"
+	case rollout.UpdatedReplicas < d.Spec.Replicas:
+		rollout.Message = fmt.Sprintf("%d of %d updated replicas are available", rollout.AvailableReplicas, d.Spec.Replicas)
+	case rollout.OldReplicas > 0:
+		rollout.Message = fmt.Sprintf("%d old replicas are pending termination", rollout.OldReplicas)
+	case rollout.AvailableReplicas < d.Spec.Replicas:
+		rollout.Message = fmt.Sprintf("%d of %d updated replicas are available", rollout.AvailableReplicas, d.Spec.Replicas)
+	default:
+		rollout.Complete = true
+		rollout.Message = fmt.Sprintf("deployment %q successfully rolled out", d.Spec.Name)
+	}
+	state.Rollout = rollout
+	return state
+}
+
+// ownedReplicaSets returns the replica sets of a deployment, oldest
+// revision first.
+func ownedReplicaSets(deploymentId string, replicaSets []psm.ReplicaSetInfo) []psm.ReplicaSetInfo {
+	var owned []psm.ReplicaSetInfo
+	for _, rs := range replicaSets {
+		if rs.Spec.DeploymentId == deploymentId {
+			owned = append(owned, rs)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return owned[i].Spec.Revision < owned[j].Spec.Revision })
+	return owned
+}
+
+// == service: rollback deployment ==
+// RollbackDeployment puts the pod template of an earlier revision back into
+// the deployment, revision 0 is the one before the current. The replica set
+// of that revision becomes the newest revision and the controller rolls it
+// out again.
+func (s *PodService) RollbackDeployment(deploymentId string, revision int) (int, error) {
+	d, err := s.psmHandler.GetDeployment(deploymentId)
+	if err != nil {
+		return 0, err
+	}
+	replicaSets, err := s.psmHandler.GetReplicaSetList()
+	if err != nil {
+		return 0, err
+	}
+	owned := ownedReplicaSets(deploymentId, replicaSets)
+	if len(owned) == 0 {
+		return 0, fmt.Errorf("deployment %s has no revisions", deploymentId)
+	}
+	latest := owned[len(owned)-1].Spec.Revision
+
+	var target *psm.ReplicaSetInfo
+	for i := len(owned) - 1; i >= 0; i-- {
+		rs := owned[i]
+		if (revision == 0 && rs.Spec.Revision < d.Revision) || (revision != 0 && rs.Spec.Revision == revision) {
+			target = &owned[i]
+			break
+		}
+	}
+	if target == nil {
+		if revision == 0 {
+			return 0, fmt.Errorf("no previous revision to roll back to")
+		}
+		return 0, fmt.Errorf("revision %d not found", revision)
+	}
+	if target.Spec.Revision == d.Revision {
+		return d.Revision, nil
+	}
+
+	tpl, err := s.psmHandler.GetPodTemplate(target.Spec.TemplateId)
+	if err != nil {
+		return 0, err
+	}
+	spec := tpl.Spec
+	spec.Labels = maps.Clone(spec.Labels)
+	delete(spec.Labels, podTemplateHashLabel)
+	if len(spec.Labels) == 0 {
+		spec.Labels = nil
+	}
+
+	newRevision := latest + 1
+	if err := s.psmHandler.UpdateReplicaSetRevision(target.ReplicaSetId, newRevision); err != nil {
+		return 0, err
+	}
+	d.Spec.Template = spec
+	if err := s.psmHandler.StoreDeployment(deploymentId, d.Spec); err != nil {
+		return 0, err
+	}
+	if err := s.psmHandler.UpdateDeploymentRevision(deploymentId, newRevision); err != nil {
+		return 0, err
+	}
+	return newRevision, nil
+}
+
+// == service: remove deployment ==
+func (s *PodService) RemoveDeployment(deploymentId string) (string, error) {
+	if _, err := s.psmHandler.GetDeployment(deploymentId); err != nil {
+		return "", err
+	}
+	// the deployment goes first so the controller stops managing its
+	// replica sets while they are torn down
+	if err := s.psmHandler.RemoveDeployment(deploymentId); err != nil {
+		return "", err
+	}
+	res, err := s.getDeploymentResources()
+	if err != nil {
+		return "", err
+	}
+	for _, rs := range ownedReplicaSets(deploymentId, res.replicaSets) {
+		if err := s.psmHandler.RemoveReplicaSet(rs.ReplicaSetId); err != nil {
+			return "", err
+		}
+		for _, p := range res.pods {
+			if p.TemplateId != rs.Spec.TemplateId {
+				continue
+			}
+			if _, err := s.Remove(p.PodId); err != nil {
+				return "", err
+			}
+		}
+		inUse, err := s.psmHandler.IsTemplateReferenced(rs.Spec.TemplateId)
+		if err == nil && !inUse {
+			_ = s.psmHandler.RemovePodTemplate(rs.Spec.TemplateId)
+		}
+	}
+	return deploymentId, nil
+}
@@ -0,0 +1,243 @@
+package pod
+
+import (
+	"condenser/internal/core/container"
+	"condenser/internal/store/csm"
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// an init container that has not exited after this long fails the pod
+const initContainerTimeout = 30 * time.Minute
+
+// the monitor records this when it notices the exit before the hook does,
+// the real exit status usually follows right after.
+const processDownMessage = "process down detected."
+
+func validateInitContainers(specs []psm.ContainerTemplateSpec) error {
+	seen := map[string]struct{}{}
+	for _, c := range specs {
+		if c.Name == "" {
+			return fmt.Errorf("init container name is required")
+		}
+		if c.Image == "" {
+			return fmt.Errorf("init container %q: image is required", c.Name)
+		}
+		if _, dup := seen[c.Name]; dup {
+			return fmt.Errorf("init container %q: duplicate name", c.Name)
+		}
+		seen[c.Name] = struct{}{}
+	}
+	return nil
+}
+
+// pods whose init containers are running in the background, keyed by pod
+// id. A second Start of such a pod does not run them again.
+var initInProgress sync.Map
+
+func isInitInProgress(podId string) bool {
+	_, ok := initInProgress.Load(podId)
+	return ok
+}
+
+// pendingInitContainers lists the init containers of the pod template that
+// have not completed in this sandbox before.
+func (s *PodService) pendingInitContainers(podInfo psm.PodInfo) ([]psm.ContainerTemplateSpec, error) {
+	if podInfo.TemplateId == "" {
+		return nil, nil
+	}
+	tpl, err := s.psmHandler.GetPodTemplate(podInfo.TemplateId)
+	if err != nil {
+		return nil, err
+	}
+	completed := map[string]bool{}
+	for _, st := range podInfo.InitContainers {
+		if st.State == "completed" {
+			completed[st.Name] = true
+		}
+	}
+	var pending []psm.ContainerTemplateSpec
+	for _, spec := range tpl.Spec.InitContainers {
+		if !completed[spec.Name] {
+			pending = append(pending, spec)
+		}
+	}
+	return pending, nil
+}
+
+// startInitContainers marks the pod initializing and runs the pending init
+// containers in the background, the members start when all completed.
+func (s *PodService) startInitContainers(podInfo psm.PodInfo, pending []psm.ContainerTemplateSpec) error {
+	if _, running := initInProgress.LoadOrStore(podInfo.PodId, struct{}{}); running {
+		return nil
+	}
+	if err := s.psmHandler.UpdatePod(podInfo.PodId, "initializing"); err != nil {
+		initInProgress.Delete(podInfo.PodId)
+		return err
+	}
+	_ = s.psmHandler.UpdatePodStoppedByUser(podInfo.PodId, false)
+
+	go func() {
+		defer initInProgress.Delete(podInfo.PodId)
+		if err := s.runInitContainers(podInfo.PodId, pending); err != nil {
+			log.Printf("pod init failed: podId=%s err=%v", podInfo.PodId, err)
+			return
+		}
+		// the pod may have been stopped or removed right after the last one
+		current, err := s.psmHandler.GetPodById(podInfo.PodId)
+		if err != nil || current.StoppedByUser {
+			return
+		}
+		if err := s.startMembers(podInfo.PodId); err != nil {
+			log.Printf("pod start failed: podId=%s err=%v", podInfo.PodId, err)
+			_ = s.psmHandler.UpdatePodFailed(podInfo.PodId, "StartFailed", err.Error())
+		}
+	}()
+	return nil
+}
+
+// runInitContainers runs the given init containers one after another
+// inside the pod namespaces. The first failure marks the pod failed.
+func (s *PodService) runInitContainers(podId string, pending []psm.ContainerTemplateSpec) error {
+	for _, spec := range pending {
+		status, err := s.runInitContainer(podId, spec)
+		if err != nil {
+			status.State = "failed"
+		}
+		_ = s.psmHandler.UpdateInitContainerStatus(podId, status)
+		if err == nil {
+			continue
+		}
+		// stopping the pod kills the init container, that is not a failure
+		if current, getErr := s.psmHandler.GetPodById(podId); getErr == nil && current.StoppedByUser {
+			return fmt.Errorf("pod stopped while init container %q was running", spec.Name)
+		}
+		_ = s.psmHandler.UpdatePodFailed(podId, "InitContainerFailed", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *PodService) runInitContainer(podId string, spec psm.ContainerTemplateSpec) (psm.InitContainerStatus, error) {
+	status := psm.InitContainerStatus{Name: spec.Name, StartedAt: time.Now()}
+
+	// a failed run is kept for its logs until the next attempt
+	if err := s.removeInitContainer(podId, spec.Name); err != nil {
+		return status, fmt.Errorf("init container %q: %w", spec.Name, err)
+	}
+
+	podInfo, err := s.psmHandler.GetPodById(podId)
+	if err != nil {
+		return status, err
+	}
+	createParameter, err := s.memberCreateModel(podInfo, spec)
+	if err != nil {
+		status.FinishedAt = time.Now()
+		return status, fmt.Errorf("init container %q: %w", spec.Name, err)
+	}
+	// init containers are no template members
+	createParameter.Name = utils.PodInitContainerNamePrefix + spec.Name
+	createParameter.IsPodInit = true
+	createParameter.TemplateSpec = nil
+	containerId, err := s.containerHandler.Create(createParameter)
+	if err != nil {
+		status.FinishedAt = time.Now()
+		return status, fmt.Errorf("init container %q: %w", spec.Name, err)
+	}
+	status.ContainerId = containerId
+	if err := applyMemberCgroup(containerId, podId, spec.Resources); err != nil {
+		status.FinishedAt = time.Now()
+		return status, fmt.Errorf("init container %q: %w", spec.Name, err)
+	}
+	status.State = "running"
+	_ = s.psmHandler.UpdateInitContainerStatus(podId, status)
+
+	if _, err := s.containerHandler.Start(container.ServiceStartModel{ContainerId: containerId, Tty: false}); err != nil {
+		status.FinishedAt = time.Now()
+		return status, fmt.Errorf("init container %q: %w", spec.Name, err)
+	}
+	info, err := s.waitInitContainerStopped(containerId)
+	status.FinishedAt = time.Now()
+	if err != nil {
+		_ = s.stopContainerIgnoreStopped(containerId)
+		return status, fmt.Errorf("init container %q: %w", spec.Name, err)
+	}
+	status.ExitCode = info.ExitCode
+	status.Reason = info.Reason
+	status.Message = info.Message
+	if info.ExitCode != 0 && info.Message != processDownMessage {
+		return status, fmt.Errorf("init container %q failed: %s (container %s)", spec.Name, describeExit(info), containerId)
+	}
+
+	status.State = "completed"
+	if _, err := s.containerHandler.Delete(container.ServiceDeleteModel{ContainerId: containerId}); err != nil {
+		log.Printf("init container remove failed: podId=%s container=%s err=%v", podId, containerId, err)
+	}
+	return status, nil
+}
+
+func (s *PodService) waitInitContainerStopped(containerId string) (csm.ContainerInfo, error) {
+	deadline := time.Now().Add(initContainerTimeout)
+	var processDownAt time.Time
+	for time.Now().Before(deadline) {
+		info, err := s.csmHandler.GetContainerById(containerId)
+		if err != nil {
+			return csm.ContainerInfo{}, err
+		}
+		switch info.State {
+		case "stopped":
+			// give the hook a moment to record the real exit status
+			if info.Message == processDownMessage {
+				if processDownAt.IsZero() {
+					processDownAt = time.Now()
+				}
+				if time.Since(processDownAt) < 3*time.Second {
+					time.Sleep(500 * time.Millisecond)
+					continue
+				}
+			}
+			return info, nil
+		case "running", "created", "creating":
+			time.Sleep(500 * time.Millisecond)
+		default:
+			return csm.ContainerInfo{}, fmt.Errorf("unexpected container state: %s", info.State)
+		}
+	}
+	return csm.ContainerInfo{}, fmt.Errorf("timeout waiting for init container to exit: %s", containerId)
+}
+
+func (s *PodService) removeInitContainer(podId, name string) error {
+	containers, err := s.containerHandler.GetContainersByPodId(podId)
+	if err != nil {
+		return err
+	}
+	expectedName := s.buildPodMemberName(utils.PodInitContainerNamePrefix+name, podId)
+	for _, c := range containers {
+		if c.Name != expectedName {
+			continue
+		}
+		if err := s.stopContainerIgnoreStopped(c.ContainerId); err != nil {
+			return err
+		}
+		if _, err := s.containerHandler.Delete(container.ServiceDeleteModel{ContainerId: c.ContainerId}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func describeExit(info csm.ContainerInfo) string {
+	msg := fmt.Sprintf("exit_code=%d", info.ExitCode)
+	if strings.TrimSpace(info.Reason) != "" {
+		msg += ", reason=" + info.Reason
+	}
+	if strings.TrimSpace(info.Message) != "" {
+		msg += ", message=" + info.Message
+	}
+	return msg
+}
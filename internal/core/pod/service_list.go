@@ -1,13 +1,44 @@
 package pod
 
+import (
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+)
+
+// fields a pod list can be narrowed by with a fieldSelector
+var PodSelectableFields = []string{
+	utils.FieldMetadataName,
+	utils.FieldMetadataNamespace,
+	utils.FieldStatusPhase,
+	utils.FieldSpecNodeName,
+}
+
+// fields a replica set list can be narrowed by with a fieldSelector
+var ReplicaSetSelectableFields = []string{
+	utils.FieldMetadataName,
+	utils.FieldMetadataNamespace,
+}
+
+func podFields(p psm.PodInfo) map[string]string {
+	return map[string]string{
+		utils.FieldMetadataName:      p.Name,
+		utils.FieldMetadataNamespace: p.Namespace,
+		utils.FieldStatusPhase:       p.State,
+		utils.FieldSpecNodeName:      utils.NodeName(),
+	}
+}
+
 // == service: list pods ==
-func (s *PodService) GetPodList() ([]PodState, error) {
+func (s *PodService) GetPodList(opts utils.ListOptions) ([]PodState, error) {
 	podList, err := s.psmHandler.GetPodList()
 	if err != nil {
 		return nil, err
 	}
 	var result []PodState
 	for _, p := range podList {
+		if !opts.Matches(p.Labels, podFields(p)) {
+			continue
+		}
 		desired, running, err := s.getContainerCounts(p.PodId, p.TemplateId)
 		if err != nil {
 			return nil, err
@@ -29,6 +60,11 @@ func (s *PodService) GetPodList() ([]PodState, error) {
 			CreatedAt:         p.CreatedAt,
 			StartedAt:         p.StartedAt,
 			StoppedAt:         p.StoppedAt,
+			Reason:            p.Reason,
+			Message:           p.Message,
+			InitContainers:    p.InitContainers,
+			Ready:             p.Ready,
+			ContainerStatuses: p.ContainerStatuses,
 		})
 	}
 	return result, nil
@@ -61,6 +97,11 @@ func (s *PodService) GetPodById(podId string) (PodState, error) {
 		CreatedAt:         p.CreatedAt,
 		StartedAt:         p.StartedAt,
 		StoppedAt:         p.StoppedAt,
+		Reason:            p.Reason,
+		Message:           p.Message,
+		InitContainers:    p.InitContainers,
+		Ready:             p.Ready,
+		ContainerStatuses: p.ContainerStatuses,
 	}, nil
 }
 
@@ -78,7 +119,7 @@ func (s *PodService) getContainerCounts(podId, templateId string) (int, int, err
 	running := 0
 	nonInfra := 0
 	for _, c := range containers {
-		if s.isPodInfraName(c.Name) {
+		if s.isPodInfraName(c.Name) || s.isPodInitName(c.Name) {
 			continue
 		}
 		nonInfra++
@@ -91,3 +132,30 @@ func (s *PodService) getContainerCounts(podId, templateId string) (int, int, err
 	}
 	return desired, running, nil
 }
+
+// == service: list replica sets ==
+// A replica set has no labels of its own here, it is selected by the
+// labels of its pod template.
+func (s *PodService) GetReplicaSetList(opts utils.ListOptions) ([]psm.ReplicaSetInfo, error) {
+	list, err := s.psmHandler.GetReplicaSetList()
+	if err != nil {
+		return nil, err
+	}
+	var result []psm.ReplicaSetInfo
+	for _, rs := range list {
+		var labels map[string]string
+		if len(opts.LabelSelector) > 0 {
+			if tpl, err := s.psmHandler.GetPodTemplate(rs.Spec.TemplateId); err == nil {
+				labels = tpl.Spec.Labels
+			}
+		}
+		fields := map[string]string{
+			utils.FieldMetadataName:      rs.Spec.Name,
+			utils.FieldMetadataNamespace: rs.Spec.Namespace,
+		}
+		if opts.Matches(labels, fields) {
+			result = append(result, rs)
+		}
+	}
+	return result, nil
+}
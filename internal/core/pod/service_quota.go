@@ -0,0 +1,265 @@
+package pod
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"condenser/internal/store/nsm"
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+)
+
+// quota resource names as kubernetes writes them
+const (
+	QuotaResourcePods           = "pods"
+	QuotaResourceRequestsCPU    = "requests.cpu"
+	QuotaResourceRequestsMemory = "requests.memory"
+	QuotaResourceLimitsCPU      = "limits.cpu"
+	QuotaResourceLimitsMemory   = "limits.memory"
+)
+
+// applyLimitRange fills the defaults of the namespace limit ranges into
+// containers that do not set them and checks the max, as the LimitRanger
+// admission plugin does. The given slice is left untouched.
+func (s *PodService) applyLimitRange(namespace string, containers []psm.ContainerTemplateSpec) ([]psm.ContainerTemplateSpec, error) {
+	if len(containers) == 0 {
+		return containers, nil
+	}
+	list, err := s.nsmHandler.GetLimitRangeList()
+	if err != nil {
+		return nil, err
+	}
+	var ranges []nsm.LimitRangeInfo
+	for _, lr := range list {
+		if lr.Namespace == namespace {
+			ranges = append(ranges, lr)
+		}
+	}
+	if len(ranges) == 0 {
+		return containers, nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Name < ranges[j].Name })
+
+	result := make([]psm.ContainerTemplateSpec, 0, len(containers))
+	for _, c := range containers {
+		var r psm.ResourceRequirements
+		if c.Resources != nil {
+			r = *c.Resources
+		}
+		for _, lr := range ranges {
+			setDefault(&r.Limits.CPU, lr.Default.CPU)
+			setDefault(&r.Limits.Memory, lr.Default.Memory)
+			setDefault(&r.Requests.CPU, lr.DefaultRequest.CPU)
+			setDefault(&r.Requests.Memory, lr.DefaultRequest.Memory)
+			if err := checkLimitRangeMax(c.Name, lr, r.Limits); err != nil {
+				return nil, err
+			}
+		}
+		if r != (psm.ResourceRequirements{}) {
+			c.Resources = &r
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func setDefault(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+func checkLimitRangeMax(name string, lr nsm.LimitRangeInfo, limits psm.ResourceList) error {
+	for _, m := range []struct {
+		resource, limit, max string
+		parse                func(string) (int64, error)
+	}{
+		{"cpu", limits.CPU, lr.Max.CPU, utils.ParseCPUQuantity},
+		{"memory", limits.Memory, lr.Max.Memory, utils.ParseByteSize},
+	} {
+		if m.max == "" {
+			continue
+		}
+		if m.limit == "" {
+			return fmt.Errorf("container %q: limits.%s is required by limitrange %s", name, m.resource, lr.Name)
+		}
+		limit, err := m.parse(m.limit)
+		if err != nil {
+			return fmt.Errorf("container %q: %w", name, err)
+		}
+		maxValue, err := m.parse(m.max)
+		if err != nil {
+			return fmt.Errorf("limitrange %s: %w", lr.Name, err)
+		}
+		if limit > maxValue {
+			return fmt.Errorf("container %q: %s limit %s exceeds the max %s of limitrange %s", name, m.resource, m.limit, m.max, lr.Name)
+		}
+	}
+	return nil
+}
+
+// == service: resource usage of a namespace ==
+// GetNamespaceUsage sums what the pods of the namespace count against its
+// resource quotas. Failed and succeeded pods do not count, as in kubernetes.
+func (s *PodService) GetNamespaceUsage(namespace string) (NamespaceUsage, error) {
+	return s.namespaceUsage(namespace, "")
+}
+
+// namespaceUsage leaves out the pod excludePodId, a pod about to be replaced.
+func (s *PodService) namespaceUsage(namespace string, excludePodId string) (NamespaceUsage, error) {
+	pods, err := s.psmHandler.GetPodList()
+	if err != nil {
+		return NamespaceUsage{}, err
+	}
+	var usage NamespaceUsage
+	templates := map[string]podResources{}
+	for _, p := range pods {
+		if p.Namespace != namespace || p.PodId == excludePodId || p.State == "failed" || p.State == "succeeded" {
+			continue
+		}
+		usage.Pods++
+		if p.TemplateId == "" {
+			continue
+		}
+		res, ok := templates[p.TemplateId]
+		if !ok {
+			tpl, err := s.psmHandler.GetPodTemplate(p.TemplateId)
+			if err != nil {
+				continue
+			}
+			res = computePodResources(tpl.Spec)
+			templates[p.TemplateId] = res
+		}
+		usage.add(res)
+	}
+	return usage, nil
+}
+
+func (u *NamespaceUsage) add(res podResources) {
+	u.RequestsCPU += max(res.cpuRequest, 0)
+	u.RequestsMemory += max(res.memoryRequest, 0)
+	u.LimitsCPU += max(res.cpuLimit, 0)
+	u.LimitsMemory += max(res.memoryLimit, 0)
+}
+
+// admitPod checks a new pod against the resource quotas of its namespace.
+// A quota on cpu or memory needs every container to set that resource,
+// the limit range defaults are already filled in at this point. The usage
+// of replacedPodId, a pod the new one takes the place of, is not counted.
+func (s *PodService) admitPod(namespace string, spec psm.PodTemplateSpec, replacedPodId string) error {
+	if err := s.nsmHandler.CheckNamespaceActive(namespace); err != nil {
+		return err
+	}
+	list, err := s.nsmHandler.GetResourceQuotaList()
+	if err != nil {
+		return err
+	}
+	var quotas []nsm.ResourceQuotaInfo
+	for _, q := range list {
+		if q.Namespace == namespace {
+			quotas = append(quotas, q)
+		}
+	}
+	if len(quotas) == 0 {
+		return nil
+	}
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].Name < quotas[j].Name })
+
+	used, err := s.namespaceUsage(namespace, replacedPodId)
+	if err != nil {
+		return err
+	}
+	var requested NamespaceUsage
+	requested.Pods = 1
+	requested.add(computePodResources(spec))
+
+	for _, q := range quotas {
+		if err := checkQuotaSpecified(q, spec); err != nil {
+			return err
+		}
+		for _, r := range quotaResources(q.Hard) {
+			hard, err := r.parse(r.hard)
+			if err != nil {
+				return fmt.Errorf("resourcequota %s: %s: %w", q.Name, r.name, err)
+			}
+			if r.value(used)+r.value(requested) > hard {
+				return fmt.Errorf("exceeded quota: %s, requested: %s=%s, used: %s=%s, limited: %s=%s",
+					q.Name, r.name, r.format(r.value(requested)), r.name, r.format(r.value(used)), r.name, r.hard)
+			}
+		}
+	}
+	return nil
+}
+
+type quotaResource struct {
+	name   string
+	hard   string
+	parse  func(string) (int64, error)
+	format func(int64) string
+	value  func(NamespaceUsage) int64
+}
+
+// quotaResources lists the hard limits a quota sets.
+func quotaResources(hard nsm.QuotaHard) []quotaResource {
+	var list []quotaResource
+	if hard.Pods != nil {
+		list = append(list, quotaResource{
+			name:   QuotaResourcePods,
+			hard:   strconv.Itoa(*hard.Pods),
+			parse:  func(v string) (int64, error) { return strconv.ParseInt(v, 10, 64) },
+			format: func(v int64) string { return strconv.FormatInt(v, 10) },
+			value:  func(u NamespaceUsage) int64 { return int64(u.Pods) },
+		})
+	}
+	for _, r := range []quotaResource{
+		{QuotaResourceRequestsCPU, hard.Requests.CPU, utils.ParseCPUQuantity, utils.FormatCPUQuantity, func(u NamespaceUsage) int64 { return u.RequestsCPU }},
+		{QuotaResourceRequestsMemory, hard.Requests.Memory, utils.ParseByteSize, utils.FormatByteSize, func(u NamespaceUsage) int64 { return u.RequestsMemory }},
+		{QuotaResourceLimitsCPU, hard.Limits.CPU, utils.ParseCPUQuantity, utils.FormatCPUQuantity, func(u NamespaceUsage) int64 { return u.LimitsCPU }},
+		{QuotaResourceLimitsMemory, hard.Limits.Memory, utils.ParseByteSize, utils.FormatByteSize, func(u NamespaceUsage) int64 { return u.LimitsMemory }},
+	} {
+		if r.hard != "" {
+			list = append(list, r)
+		}
+	}
+	return list
+}
+
+func checkQuotaSpecified(q nsm.ResourceQuotaInfo, spec psm.PodTemplateSpec) error {
+	containers := append(append([]psm.ContainerTemplateSpec{}, spec.InitContainers...), spec.Containers...)
+	for _, r := range []struct {
+		name string
+		set  bool
+		of   func(psm.ResourceRequirements) string
+	}{
+		{QuotaResourceRequestsCPU, q.Hard.Requests.CPU != "", func(r psm.ResourceRequirements) string { return firstSet(r.Requests.CPU, r.Limits.CPU) }},
+		{QuotaResourceRequestsMemory, q.Hard.Requests.Memory != "", func(r psm.ResourceRequirements) string { return firstSet(r.Requests.Memory, r.Limits.Memory) }},
+		{QuotaResourceLimitsCPU, q.Hard.Limits.CPU != "", func(r psm.ResourceRequirements) string { return r.Limits.CPU }},
+		{QuotaResourceLimitsMemory, q.Hard.Limits.Memory != "", func(r psm.ResourceRequirements) string { return r.Limits.Memory }},
+	} {
+		if !r.set {
+			continue
+		}
+		var missing []string
+		for _, c := range containers {
+			if c.Resources == nil || r.of(*c.Resources) == "" {
+				missing = append(missing, c.Name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("failed quota: %s: must specify %s for: %s", q.Name, r.name, strings.Join(missing, ","))
+		}
+	}
+	return nil
+}
+
+// a request defaults to the limit, either one satisfies a request quota
+func firstSet(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
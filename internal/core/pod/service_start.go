@@ -24,6 +24,33 @@ func (s *PodService) Start(podId string) (string, error) {
 		return "", err
 	}
 
+	// refuse the whole pod when any member image fails signature verification.
+	// trust policies may have changed since the containers were created.
+	for _, c := range containers {
+		if c.State == "running" || c.Repository == "" || s.isPodInitName(c.Name) {
+			continue
+		}
+		if _, err := s.trustHandler.VerifyImage(c.Repository, c.Reference); err != nil {
+			return "", fmt.Errorf("container %s: %w", c.Name, err)
+		}
+	}
+	// init containers are created in the background, their images are
+	// verified here so that a denied one fails the start itself
+	pending, err := s.pendingInitContainers(podInfo)
+	if err != nil {
+		return "", err
+	}
+	for _, spec := range pending {
+		if err := s.containerHandler.VerifyImage(container.ServiceCreateModel{Image: spec.Image, PodId: podId, IsPodInit: true}); err != nil {
+			return "", fmt.Errorf("init container %s: %w", spec.Name, err)
+		}
+	}
+
+	// limits are in place before any member process runs
+	if err := s.applyPodCgroup(podInfo, containers); err != nil {
+		return "", err
+	}
+
 	for _, c := range containers {
 		if s.isPodInfraName(c.Name) {
 			if c.State == "running" {
@@ -35,24 +62,52 @@ func (s *PodService) Start(podId string) (string, error) {
 		}
 	}
 
+	// emptyDir volumes exist and configMap and secret volumes have their
+	// current keys before anything in the pod reads them
+	if err := s.preparePodVolumes(podInfo); err != nil {
+		return "", err
+	}
+
+	// init containers run to completion inside the pod namespaces before
+	// any member starts. They may take long, the pod stays initializing
+	// and its members are started in the background once they are done.
+	if len(pending) > 0 {
+		if err := s.startInitContainers(podInfo, pending); err != nil {
+			return "", err
+		}
+		return podId, nil
+	}
+
+	if err := s.startMembers(podId); err != nil {
+		return "", err
+	}
+	return podId, nil
+}
+
+// startMembers starts the members of the pod that are not running and
+// marks the pod running.
+func (s *PodService) startMembers(podId string) error {
+	containers, err := s.containerHandler.GetContainersByPodId(podId)
+	if err != nil {
+		return err
+	}
 	for _, c := range containers {
-		if s.isPodInfraName(c.Name) {
+		if s.isPodInfraName(c.Name) || s.isPodInitName(c.Name) {
 			continue
 		}
 		if c.State == "running" {
 			continue
 		}
 		if _, err := s.containerHandler.Start(container.ServiceStartModel{ContainerId: c.ContainerId, Tty: false}); err != nil {
-			return "", err
+			return err
 		}
 	}
 
 	if err := s.psmHandler.UpdatePod(podId, "running"); err != nil {
-		return "", err
+		return err
 	}
 	_ = s.psmHandler.UpdatePodStoppedByUser(podId, false)
-
-	return podId, nil
+	return nil
 }
 
 func (s *PodService) ensurePodTemplateConsistency(podInfo psm.PodInfo) error {
@@ -89,6 +144,9 @@ func (s *PodService) ensurePodTemplateConsistency(podInfo psm.PodInfo) error {
 			}
 		}
 	}
+	if err := validateInitContainers(tpl.Spec.InitContainers); err != nil {
+		return fmt.Errorf("pod template invalid: %w", err)
+	}
 
 	return nil
 }
@@ -123,7 +181,7 @@ func (s *PodService) ensurePodTemplateContainers(podInfo psm.PodInfo) error {
 	}
 	actualByName := make(map[string]container.ContainerState, len(containers))
 	for _, c := range containers {
-		if s.isPodInfraName(c.Name) {
+		if s.isPodInfraName(c.Name) || s.isPodInitName(c.Name) {
 			continue
 		}
 		actualByName[c.Name] = c
@@ -140,17 +198,11 @@ func (s *PodService) ensurePodTemplateContainers(podInfo psm.PodInfo) error {
 		if _, ok := actualByName[expectedName]; ok {
 			continue
 		}
-		if _, err := s.containerHandler.Create(container.ServiceCreateModel{
-			Image:   spec.Image,
-			Command: spec.Command,
-			Port:    spec.Port,
-			Mount:   spec.Mount,
-			Env:     spec.Env,
-			Network: spec.Network,
-			Tty:     spec.Tty,
-			Name:    spec.Name,
-			PodId:   podInfo.PodId,
-		}); err != nil {
+		createParameter, err := s.memberCreateModel(podInfo, spec)
+		if err != nil {
+			return err
+		}
+		if _, err := s.containerHandler.Create(createParameter); err != nil {
 			return err
 		}
 	}
@@ -159,6 +211,10 @@ func (s *PodService) ensurePodTemplateContainers(podInfo psm.PodInfo) error {
 }
 
 func (s *PodService) buildPodMemberName(baseName, podId string) string {
+	return podMemberName(baseName, podId)
+}
+
+func podMemberName(baseName, podId string) string {
 	if baseName == "" {
 		return baseName
 	}
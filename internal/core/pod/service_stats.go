@@ -0,0 +1,101 @@
+package pod
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	"condenser/internal/core/container"
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+)
+
+// == service: pod stats ==
+func (s *PodService) GetPodStats(podId string) (PodStats, error) {
+	podInfo, err := s.psmHandler.GetPodById(podId)
+	if err != nil {
+		return PodStats{}, err
+	}
+	statsMap, err := loadLatestPodStats()
+	if err != nil {
+		return PodStats{}, err
+	}
+	members, err := s.containerHandler.ListContainerStats()
+	if err != nil {
+		return PodStats{}, err
+	}
+	return buildPodStats(podInfo, statsMap, members), nil
+}
+
+// == service: list pod stats ==
+func (s *PodService) ListPodStats() ([]PodStats, error) {
+	podList, err := s.psmHandler.GetPodList()
+	if err != nil {
+		return nil, err
+	}
+	statsMap, err := loadLatestPodStats()
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.containerHandler.ListContainerStats()
+	if err != nil {
+		return nil, err
+	}
+	var list []PodStats
+	for _, p := range podList {
+		list = append(list, buildPodStats(p, statsMap, members))
+	}
+	return list, nil
+}
+
+func buildPodStats(podInfo psm.PodInfo, statsMap map[string]PodStats, members []container.ContainerStats) PodStats {
+	stat, ok := statsMap[podInfo.PodId]
+	if !ok {
+		stat = PodStats{PodId: podInfo.PodId, CgroupPath: utils.PodCgroupPath(podInfo.PodId)}
+	}
+	stat.Name = podInfo.Name
+	stat.Namespace = podInfo.Namespace
+	stat.Status = podInfo.State
+	stat.Containers = []container.ContainerStats{}
+	for _, m := range members {
+		if m.PodId == podInfo.PodId {
+			stat.Containers = append(stat.Containers, m)
+		}
+	}
+	return stat
+}
+
+// loadLatestPodStats picks the pod records out of the metrics log, the
+// ones without a container id.
+func loadLatestPodStats() (map[string]PodStats, error) {
+	f, err := os.Open(utils.MetricsLogPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]PodStats{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	statsMap := map[string]PodStats{}
+	for scanner.Scan() {
+		var record struct {
+			PodStats
+			ContainerID string `json:"container_id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if record.ContainerID != "" || record.PodId == "" {
+			continue
+		}
+		statsMap[record.PodId] = record.PodStats
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return statsMap, nil
+}
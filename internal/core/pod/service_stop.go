@@ -12,6 +12,10 @@ func (s *PodService) Stop(podId string) (string, error) {
 		if s.isPodInfraName(c.Name) {
 			continue
 		}
+		// finished init containers are only kept for their logs
+		if s.isPodInitName(c.Name) && c.State != "running" {
+			continue
+		}
 		if _, err := s.containerHandler.Stop(container.ServiceStopModel{ContainerId: c.ContainerId}); err != nil {
 			return "", err
 		}
@@ -0,0 +1,243 @@
+package pod
+
+import (
+	"condenser/internal/core/config"
+	"condenser/internal/store/psm"
+	"condenser/internal/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const EmptyDirMediumMemory = "Memory"
+
+// the manifest does not know the pod id yet, emptyDir mounts carry this
+// placeholder in front of the volume name until a member is created
+const emptyDirSourcePrefix = "$(emptyDir)/"
+
+func validateEmptyDir(src psm.EmptyDirVolumeSource) error {
+	if src.Medium != "" && src.Medium != EmptyDirMediumMemory {
+		return fmt.Errorf("invalid emptyDir medium: %s (\"\" or Memory)", src.Medium)
+	}
+	if src.SizeLimit != "" {
+		if _, err := utils.ParseByteSize(src.SizeLimit); err != nil {
+			return fmt.Errorf("invalid emptyDir sizeLimit: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateVolume checks the names of a volume that end up in host paths.
+func validateVolume(v psm.VolumeSpec) error {
+	if err := utils.ValidateDNSLabel(v.Name); err != nil {
+		return err
+	}
+	switch {
+	case v.ConfigMap != nil:
+		if err := utils.ValidateDNSSubdomain(v.ConfigMap.Name); err != nil {
+			return fmt.Errorf("configMap: %w", err)
+		}
+	case v.Secret != nil:
+		if err := utils.ValidateDNSSubdomain(v.Secret.SecretName); err != nil {
+			return fmt.Errorf("secret: %w", err)
+		}
+	case v.EmptyDir != nil:
+		return validateEmptyDir(*v.EmptyDir)
+	}
+	return nil
+}
+
+func podVolumeDir(podId string) string {
+	return filepath.Join(utils.PodRootDir, podId, "volumes")
+}
+
+func emptyDirPath(podId, name string) string {
+	return filepath.Join(podVolumeDir(podId), name)
+}
+
+// resolvePodMounts points emptyDir mounts at the directories of the pod.
+func resolvePodMounts(podId string, mounts []string) []string {
+	if len(mounts) == 0 {
+		return mounts
+	}
+	resolved := make([]string, 0, len(mounts))
+	for _, m := range mounts {
+		if rest, ok := strings.CutPrefix(m, emptyDirSourcePrefix); ok {
+			name, target, _ := strings.Cut(rest, ":")
+			m = emptyDirPath(podId, name) + ":" + target
+		}
+		resolved = append(resolved, m)
+	}
+	return resolved
+}
+
+// preparePodVolumes creates the emptyDir volumes of the pod and projects
+// the current keys of its configMap and secret volumes.
+func (s *PodService) preparePodVolumes(podInfo psm.PodInfo) error {
+	if podInfo.TemplateId == "" {
+		return nil
+	}
+	tpl, err := s.psmHandler.GetPodTemplate(podInfo.TemplateId)
+	if err != nil {
+		return err
+	}
+	for _, v := range tpl.Spec.Volumes {
+		if v.EmptyDir == nil {
+			continue
+		}
+		// templates stored before volume names were checked
+		if err := utils.ValidateDNSLabel(v.Name); err != nil {
+			return fmt.Errorf("volume: %w", err)
+		}
+		if err := setupEmptyDir(emptyDirPath(podInfo.PodId, v.Name), *v.EmptyDir); err != nil {
+			return fmt.Errorf("volume %q: %w", v.Name, err)
+		}
+	}
+	return s.configHandler.ProjectVolumes(podInfo.Namespace, tpl.Spec.Volumes)
+}
+
+type projectedSource struct {
+	kind string
+	name string
+}
+
+func projectedSources(volumes []psm.VolumeSpec) map[projectedSource]bool {
+	sources := map[projectedSource]bool{}
+	for _, v := range volumes {
+		switch {
+		case v.ConfigMap != nil:
+			sources[projectedSource{config.ProjectionKindConfigMap, v.ConfigMap.Name}] = true
+		case v.Secret != nil:
+			sources[projectedSource{config.ProjectionKindSecret, v.Secret.SecretName}] = true
+		}
+	}
+	return sources
+}
+
+// releaseProjections drops the projected files of the configMap and secret
+// volumes of a removed pod once no other pod of the namespace mounts the
+// same source, decrypted secret data does not outlive its last reader.
+func (s *PodService) releaseProjections(podInfo psm.PodInfo) error {
+	if podInfo.TemplateId == "" {
+		return nil
+	}
+	tpl, err := s.psmHandler.GetPodTemplate(podInfo.TemplateId)
+	if err != nil {
+		return err
+	}
+	sources := projectedSources(tpl.Spec.Volumes)
+	if len(sources) == 0 {
+		return nil
+	}
+	pods, err := s.psmHandler.GetPodList()
+	if err != nil {
+		return err
+	}
+	for _, p := range pods {
+		if p.PodId == podInfo.PodId || p.Namespace != podInfo.Namespace || p.TemplateId == "" {
+			continue
+		}
+		other, err := s.psmHandler.GetPodTemplate(p.TemplateId)
+		if err != nil {
+			continue
+		}
+		for src := range projectedSources(other.Spec.Volumes) {
+			delete(sources, src)
+		}
+	}
+	for src := range sources {
+		if err := s.configHandler.RemoveProjection(src.kind, podInfo.Namespace, src.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupEmptyDir creates the directory once, a restarted pod keeps the data
+// as in kubernetes. Memory volumes are a tmpfs sized by the limit.
+func setupEmptyDir(dir string, src psm.EmptyDirVolumeSource) error {
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return err
+	}
+	// containers may run as any user, the directory is world writable
+	if err := os.Chmod(dir, 0o777); err != nil {
+		return err
+	}
+	if src.Medium != EmptyDirMediumMemory {
+		return nil
+	}
+	mounted, err := isMountPoint(dir)
+	if err != nil || mounted {
+		return err
+	}
+	data := "mode=0777"
+	if src.SizeLimit != "" {
+		size, err := utils.ParseByteSize(src.SizeLimit)
+		if err != nil {
+			return err
+		}
+		data += ",size=" + strconv.FormatInt(size, 10)
+	}
+	if err := unix.Mount("tmpfs", dir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, data); err != nil {
+		return fmt.Errorf("mount tmpfs on %s: %w", dir, err)
+	}
+	return nil
+}
+
+func isMountPoint(dir string) (bool, error) {
+	var st, parent unix.Stat_t
+	if err := unix.Stat(dir, &st); err != nil {
+		return false, err
+	}
+	if err := unix.Stat(filepath.Dir(dir), &parent); err != nil {
+		return false, err
+	}
+	return st.Dev != parent.Dev, nil
+}
+
+// removePodVolumes drops the emptyDir volumes of a removed pod, memory
+// volumes are unmounted first.
+func removePodVolumes(podId string) error {
+	root := filepath.Join(utils.PodRootDir, podId)
+	entries, err := os.ReadDir(podVolumeDir(podId))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, e := range entries {
+		dir := filepath.Join(podVolumeDir(podId), e.Name())
+		if mounted, err := isMountPoint(dir); err == nil && mounted {
+			if err := unix.Unmount(dir, unix.MNT_DETACH); err != nil {
+				return fmt.Errorf("unmount %s: %w", dir, err)
+			}
+		}
+	}
+	return os.RemoveAll(root)
+}
+
+// emptyDirUsage is the disk space used below dir, counted in allocated
+// blocks like du.
+func emptyDirUsage(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		var st unix.Stat_t
+		if err := unix.Lstat(path, &st); err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		total += st.Blocks * 512
+		return nil
+	})
+	return total, err
+}
@@ -102,7 +102,11 @@ func (c *ServiceController) buildEndpoints(svc ssm.ServiceInfo, pods []psm.PodIn
 		if p.Namespace != svc.Namespace {
 			continue
 		}
-		if !labelsMatch(svc.Selector, p.Labels) {
+		if !utils.MatchWorkloadSelector(svc.Selector, svc.MatchExpressions, p.Labels) {
+			continue
+		}
+		// only ready pods take traffic, readiness is kept by the probe controller
+		if !p.Ready {
 			continue
 		}
 		infraId, err := c.getInfraContainerId(p.PodId)
@@ -138,18 +142,6 @@ func (c *ServiceController) getInfraContainerId(podId string) (string, error) {
 	return "", containerNotFound(podId)
 }
 
-func labelsMatch(selector, labels map[string]string) bool {
-	if len(selector) == 0 {
-		return false
-	}
-	for k, v := range selector {
-		if labels[k] != v {
-			return false
-		}
-	}
-	return true
-}
-
 func itoa(v int) string {
 	return strconv.Itoa(v)
 }
@@ -0,0 +1,10 @@
+package service
+
+import (
+	"condenser/internal/store/ssm"
+	"condenser/internal/utils"
+)
+
+type ServiceServiceHandler interface {
+	GetServiceList(opts utils.ListOptions) ([]ssm.ServiceInfo, error)
+}
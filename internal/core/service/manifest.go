@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"condenser/internal/store/ssm"
+	"condenser/internal/utils"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,6 +16,9 @@ type ServiceManifest struct {
 	Namespace string
 	Selector  map[string]string
 	Ports     []ssm.ServicePort
+
+	MatchExpressions []utils.LabelSelectorRequirement
+	Labels           map[string]string
 }
 
 type serviceMeta struct {
@@ -30,10 +34,38 @@ type servicePortManifest struct {
 }
 
 type serviceSpec struct {
-	Selector map[string]string     `yaml:"selector"`
+	Selector serviceSelector       `yaml:"selector"`
 	Ports    []servicePortManifest `yaml:"ports"`
 }
 
+// serviceSelector is the plain label map of a kubernetes service, or the
+// matchLabels and matchExpressions a replica set uses.
+type serviceSelector struct {
+	MatchLabels      map[string]string
+	MatchExpressions []utils.LabelSelectorRequirement
+}
+
+func (s *serviceSelector) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			if (key == "matchLabels" || key == "matchExpressions") && value.Kind != yaml.ScalarNode {
+				var structured struct {
+					MatchLabels      map[string]string                `yaml:"matchLabels"`
+					MatchExpressions []utils.LabelSelectorRequirement `yaml:"matchExpressions"`
+				}
+				if err := node.Decode(&structured); err != nil {
+					return err
+				}
+				s.MatchLabels = structured.MatchLabels
+				s.MatchExpressions = structured.MatchExpressions
+				return nil
+			}
+		}
+	}
+	return node.Decode(&s.MatchLabels)
+}
+
 type serviceManifest struct {
 	APIVersion string      `yaml:"apiVersion"`
 	Kind       string      `yaml:"kind"`
@@ -69,12 +101,17 @@ func DecodeK8sServiceManifest(body []byte) (ServiceManifest, error) {
 		if err := yaml.Unmarshal(rawBytes, &svc); err != nil {
 			return ServiceManifest{}, err
 		}
-		return buildServiceManifest(svc), nil
+		return buildServiceManifest(svc)
 	}
 	return ServiceManifest{}, fmt.Errorf("service manifest not found")
 }
 
-func buildServiceManifest(in serviceManifest) ServiceManifest {
+func buildServiceManifest(in serviceManifest) (ServiceManifest, error) {
+	for _, r := range in.Spec.Selector.MatchExpressions {
+		if err := utils.ValidateLabelSelectorRequirement(r); err != nil {
+			return ServiceManifest{}, fmt.Errorf("selector.matchExpressions: %w", err)
+		}
+	}
 	if in.Metadata.Namespace == "" {
 		in.Metadata.Namespace = "default"
 	}
@@ -100,7 +137,10 @@ func buildServiceManifest(in serviceManifest) ServiceManifest {
 	return ServiceManifest{
 		Name:      in.Metadata.Name,
 		Namespace: in.Metadata.Namespace,
-		Selector:  in.Spec.Selector,
+		Selector:  in.Spec.Selector.MatchLabels,
 		Ports:     ports,
-	}
+
+		MatchExpressions: in.Spec.Selector.MatchExpressions,
+		Labels:           in.Metadata.Labels,
+	}, nil
 }
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"condenser/internal/store/ssm"
+	"condenser/internal/utils"
+)
+
+func NewServiceService() *ServiceService {
+	return &ServiceService{
+		ssmHandler: ssm.NewSsmManager(ssm.NewSsmStore(utils.SsmStorePath)),
+	}
+}
+
+type ServiceService struct {
+	ssmHandler ssm.SsmHandler
+}
+
+// fields a service list can be narrowed by with a fieldSelector
+var ServiceSelectableFields = []string{
+	utils.FieldMetadataName,
+	utils.FieldMetadataNamespace,
+}
+
+// == service: list services ==
+func (s *ServiceService) GetServiceList(opts utils.ListOptions) ([]ssm.ServiceInfo, error) {
+	list, err := s.ssmHandler.GetServiceList()
+	if err != nil {
+		return nil, err
+	}
+	var result []ssm.ServiceInfo
+	for _, svc := range list {
+		fields := map[string]string{
+			utils.FieldMetadataName:      svc.Name,
+			utils.FieldMetadataNamespace: svc.Namespace,
+		}
+		if opts.Matches(svc.Labels, fields) {
+			result = append(result, svc)
+		}
+	}
+	return result, nil
+}
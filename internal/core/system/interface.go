@@ -0,0 +1,5 @@
+package system
+
+type SystemServiceHandler interface {
+	GetDiskUsage() (DiskUsage, error)
+}
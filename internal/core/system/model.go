@@ -0,0 +1,77 @@
+package system
+
+import "condenser/internal/core/image"
+
+// DiskUsage is what the runtime keeps on disk. Sizes are allocated bytes,
+// files shared by several images are counted once.
+type DiskUsage struct {
+	Filesystem       FilesystemUsage      `json:"filesystem"`
+	Images           image.ImageDiskUsage `json:"images"`
+	BuildCache       BuildCacheUsage      `json:"buildCache"`
+	Containers       ContainerUsage       `json:"containers"`
+	Volumes          VolumeUsage          `json:"volumes"`
+	Logs             LogUsage             `json:"logs"`
+	Store            StoreUsage           `json:"store"`
+	TotalBytes       int64                `json:"totalBytes"`
+	ReclaimableBytes int64                `json:"reclaimableBytes"`
+}
+
+// FilesystemUsage is the filesystem holding the runtime root.
+type FilesystemUsage struct {
+	Path       string `json:"path"`
+	TotalBytes int64  `json:"totalBytes"`
+	UsedBytes  int64  `json:"usedBytes"`
+	AvailBytes int64  `json:"availBytes"`
+}
+
+type BuildCacheUsage struct {
+	Count            int   `json:"count"`
+	SizeBytes        int64 `json:"sizeBytes"`
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+}
+
+type ContainerUsage struct {
+	Containers       []ContainerDiskUsage `json:"containers"`
+	ActiveCount      int                  `json:"activeCount"`
+	UpperBytes       int64                `json:"upperBytes"`
+	LogBytes         int64                `json:"logBytes"`
+	ReclaimableBytes int64                `json:"reclaimableBytes"`
+}
+
+// ContainerDiskUsage is one container directory. Directories without a
+// container entry are listed with state "orphaned".
+type ContainerDiskUsage struct {
+	ContainerId string `json:"containerId"`
+	Name        string `json:"name,omitempty"`
+	State       string `json:"state"`
+	UpperBytes  int64  `json:"upperBytes"`
+	LogBytes    int64  `json:"logBytes"`
+	VolumeBytes int64  `json:"volumeBytes"`
+	QuotaBytes  int64  `json:"quotaBytes,omitempty"`
+	Reclaimable bool   `json:"reclaimable"`
+}
+
+// VolumeUsage covers the anonymous volumes created for image VOLUMEs.
+type VolumeUsage struct {
+	Count            int   `json:"count"`
+	SizeBytes        int64 `json:"sizeBytes"`
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+}
+
+type LogUsage struct {
+	Files            []LogFileUsage `json:"files"`
+	SizeBytes        int64          `json:"sizeBytes"`
+	ReclaimableBytes int64          `json:"reclaimableBytes"`
+}
+
+// LogFileUsage is a daemon log, rotated backups are reclaimable.
+type LogFileUsage struct {
+	Path        string `json:"path"`
+	SizeBytes   int64  `json:"sizeBytes"`
+	Reclaimable bool   `json:"reclaimable"`
+}
+
+type StoreUsage struct {
+	Files     int   `json:"files"`
+	SizeBytes int64 `json:"sizeBytes"`
+}
@@ -0,0 +1,277 @@
+package system
+
+import (
+	"condenser/internal/core/image"
+	"condenser/internal/store/csm"
+	"condenser/internal/utils"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"syscall"
+)
+
+// rotated backups of the jsonl logs end in .1, .2, ...
+var rotatedLogPattern = regexp.MustCompile(`\.(\d+|gz)$`)
+
+func NewSystemService() *SystemService {
+	return &SystemService{
+		csmHandler:          csm.NewCsmManager(csm.NewCsmStore(utils.CsmStorePath)),
+		imageServiceHandler: image.NewImageService(),
+	}
+}
+
+type SystemService struct {
+	csmHandler          csm.CsmHandler
+	imageServiceHandler image.ImageServiceHandler
+}
+
+// == service: disk usage ==
+func (s *SystemService) GetDiskUsage() (DiskUsage, error) {
+	var (
+		result DiskUsage
+		err    error
+	)
+
+	if result.Filesystem, err = filesystemUsage(utils.RootDir); err != nil {
+		return result, err
+	}
+	if result.Images, err = s.imageServiceHandler.GetImageDiskUsage(); err != nil {
+		return result, err
+	}
+	if result.BuildCache, err = s.buildCacheUsage(); err != nil {
+		return result, err
+	}
+	if result.Containers, result.Volumes, err = s.containerUsage(); err != nil {
+		return result, err
+	}
+	if result.Logs, err = logUsage(); err != nil {
+		return result, err
+	}
+	if result.Store, err = storeUsage(); err != nil {
+		return result, err
+	}
+
+	result.TotalBytes = result.Images.TotalBytes +
+		result.BuildCache.SizeBytes +
+		result.Containers.UpperBytes + result.Containers.LogBytes +
+		result.Volumes.SizeBytes +
+		result.Logs.SizeBytes +
+		result.Store.SizeBytes
+	result.ReclaimableBytes = result.Images.ReclaimableBytes +
+		result.BuildCache.ReclaimableBytes +
+		result.Containers.ReclaimableBytes +
+		result.Volumes.ReclaimableBytes +
+		result.Logs.ReclaimableBytes
+	return result, nil
+}
+
+func filesystemUsage(path string) (FilesystemUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return FilesystemUsage{}, err
+	}
+	bsize := int64(st.Bsize)
+	return FilesystemUsage{
+		Path:       path,
+		TotalBytes: int64(st.Blocks) * bsize,
+		UsedBytes:  int64(st.Blocks-st.Bfree) * bsize,
+		AvailBytes: int64(st.Bavail) * bsize,
+	}, nil
+}
+
+// buildCacheUsage measures the cache directory, entries not recorded in
+// the cache state take space all the same. The whole cache can be pruned.
+func (s *SystemService) buildCacheUsage() (BuildCacheUsage, error) {
+	entries, err := s.imageServiceHandler.GetBuildCacheList()
+	if err != nil {
+		return BuildCacheUsage{}, err
+	}
+	size, err := diskUsage(utils.BuildCacheDir)
+	if err != nil {
+		return BuildCacheUsage{}, err
+	}
+	return BuildCacheUsage{
+		Count:            len(entries),
+		SizeBytes:        size,
+		ReclaimableBytes: size,
+	}, nil
+}
+
+// containerUsage measures the upper dir, logs and anonymous volumes of
+// every container directory. The merged dir is skipped, it is the overlay
+// mount of a running container and holds nothing of its own. Everything of
+// a container that is not running goes away when it is removed.
+func (s *SystemService) containerUsage() (ContainerUsage, VolumeUsage, error) {
+	usage := ContainerUsage{Containers: []ContainerDiskUsage{}}
+	var volumes VolumeUsage
+
+	containerList, err := s.csmHandler.GetContainerList()
+	if err != nil {
+		return usage, volumes, err
+	}
+	known := map[string]csm.ContainerInfo{}
+	for _, c := range containerList {
+		known[c.ContainerId] = c
+	}
+
+	dirs, err := os.ReadDir(utils.ContainerRootDir)
+	if err != nil && !os.IsNotExist(err) {
+		return usage, volumes, err
+	}
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		containerDir := filepath.Join(utils.ContainerRootDir, d.Name())
+		entry := ContainerDiskUsage{ContainerId: d.Name(), State: "orphaned", Reclaimable: true}
+		if c, ok := known[d.Name()]; ok {
+			entry.Name = c.ContainerName
+			entry.State = c.State
+			entry.QuotaBytes = c.DiskQuotaBytes
+			entry.Reclaimable = c.State != "running"
+		}
+		if entry.UpperBytes, err = diskUsage(filepath.Join(containerDir, "diff")); err != nil {
+			return usage, volumes, err
+		}
+		if entry.LogBytes, err = diskUsage(filepath.Join(containerDir, "logs")); err != nil {
+			return usage, volumes, err
+		}
+		if entry.VolumeBytes, err = diskUsage(filepath.Join(containerDir, "volumes")); err != nil {
+			return usage, volumes, err
+		}
+		if n, err := os.ReadDir(filepath.Join(containerDir, "volumes")); err == nil {
+			volumes.Count += len(n)
+		}
+
+		usage.UpperBytes += entry.UpperBytes
+		usage.LogBytes += entry.LogBytes
+		volumes.SizeBytes += entry.VolumeBytes
+		if entry.Reclaimable {
+			usage.ReclaimableBytes += entry.UpperBytes + entry.LogBytes
+			volumes.ReclaimableBytes += entry.VolumeBytes
+		} else {
+			usage.ActiveCount++
+		}
+		usage.Containers = append(usage.Containers, entry)
+	}
+
+	sort.Slice(usage.Containers, func(i, j int) bool {
+		a, b := usage.Containers[i], usage.Containers[j]
+		return a.UpperBytes+a.LogBytes+a.VolumeBytes > b.UpperBytes+b.LogBytes+b.VolumeBytes
+	})
+	return usage, volumes, nil
+}
+
+// logUsage lists the daemon logs: the jsonl logs, the audit log dir and
+// the ulog output.
+func logUsage() (LogUsage, error) {
+	usage := LogUsage{Files: []LogFileUsage{}}
+	seen := map[string]struct{}{}
+	add := func(p string, info fs.FileInfo) {
+		if _, ok := seen[p]; ok {
+			return
+		}
+		seen[p] = struct{}{}
+		size := allocatedBytes(info)
+		f := LogFileUsage{Path: p, SizeBytes: size, Reclaimable: rotatedLogPattern.MatchString(p)}
+		usage.SizeBytes += size
+		if f.Reclaimable {
+			usage.ReclaimableBytes += size
+		}
+		usage.Files = append(usage.Files, f)
+	}
+
+	for _, dir := range []string{filepath.Dir(utils.AuditLogPath), utils.AuditLogDir} {
+		if err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			add(p, info)
+			return nil
+		}); err != nil {
+			return usage, err
+		}
+	}
+	matches, _ := filepath.Glob(utils.UlogPath + "*")
+	for _, p := range matches {
+		if info, err := os.Lstat(p); err == nil && info.Mode().IsRegular() {
+			add(p, info)
+		}
+	}
+
+	sort.Slice(usage.Files, func(i, j int) bool { return usage.Files[i].Path < usage.Files[j].Path })
+	return usage, nil
+}
+
+// storeUsage measures the state files, they are never reclaimable.
+func storeUsage() (StoreUsage, error) {
+	var usage StoreUsage
+	err := filepath.WalkDir(utils.StoreDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		usage.Files++
+		usage.SizeBytes += allocatedBytes(info)
+		return nil
+	})
+	return usage, err
+}
+
+// diskUsage is what du reports for root: allocated bytes, hard links once.
+func diskUsage(root string) (int64, error) {
+	var total int64
+	seen := map[uint64]struct{}{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Nlink > 1 && !info.IsDir() {
+			if _, dup := seen[st.Ino]; dup {
+				return nil
+			}
+			seen[st.Ino] = struct{}{}
+		}
+		total += allocatedBytes(info)
+		return nil
+	})
+	return total, err
+}
+
+func allocatedBytes(info fs.FileInfo) int64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return st.Blocks * 512
+	}
+	return info.Size()
+}
@@ -0,0 +1,13 @@
+package trust
+
+type TrustServiceHandler interface {
+	AddKey(keyParameter ServiceAddKeyModel) (string, error)
+	RemoveKey(keyId string) error
+	GetKeyList() ([]KeyInfo, error)
+
+	AddPolicy(policyParameter ServiceAddPolicyModel) (string, error)
+	RemovePolicy(policyId string) error
+	GetPolicyList() ([]PolicyInfo, error)
+
+	VerifyImage(repository, reference string) (VerificationResult, error)
+}
@@ -0,0 +1,67 @@
+package trust
+
+import (
+	"fmt"
+	"time"
+)
+
+type ServiceAddKeyModel struct {
+	Name string
+	Pem  string
+}
+
+type ServiceAddPolicyModel struct {
+	Scope  string
+	KeyIds []string
+}
+
+type KeyInfo struct {
+	KeyId       string    `json:"keyId"`
+	Name        string    `json:"name"`
+	Algorithm   string    `json:"algorithm"`
+	Fingerprint string    `json:"fingerprint"`
+	Pem         string    `json:"pem"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type PolicyInfo struct {
+	PolicyId  string    `json:"policyId"`
+	Scope     string    `json:"scope"`
+	KeyIds    []string  `json:"keyIds"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type VerificationResult struct {
+	Image          string `json:"image"`
+	ManifestDigest string `json:"manifestDigest,omitempty"`
+	Required       bool   `json:"required"` // a trust policy matched the image
+	PolicyId       string `json:"policyId,omitempty"`
+	Scope          string `json:"scope,omitempty"`
+	KeyId          string `json:"keyId,omitempty"` // key that verified the signature
+}
+
+// simple signing payload written by cosign
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]any `json:"optional"`
+}
+
+// VerificationError is returned when a trust policy requires a signature
+// and the image has none, or none that verifies against the policy keys.
+type VerificationError struct {
+	Image    string
+	PolicyId string
+	Reason   string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("image %s failed signature verification: %s", e.Image, e.Reason)
+}
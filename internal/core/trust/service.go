@@ -0,0 +1,170 @@
+package trust
+
+import (
+	"condenser/internal/registry"
+	"condenser/internal/registry/dockerhub"
+	"condenser/internal/store/ilm"
+	"condenser/internal/store/tsm"
+	"condenser/internal/utils"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func NewTrustService() *TrustService {
+	return &TrustService{
+		tsmHandler:      tsm.NewTsmManager(tsm.NewTsmStore(utils.TsmStorePath)),
+		ilmHandler:      ilm.NewIlmManager(ilm.NewIlmStore(utils.IlmStorePath)),
+		registryHandler: dockerhub.NewRegistryDockerHub(),
+	}
+}
+
+type TrustService struct {
+	tsmHandler      tsm.TsmHandler
+	ilmHandler      ilm.IlmHandler
+	registryHandler registry.RegistryHandler
+}
+
+// == service: public keys ==
+func (s *TrustService) AddKey(keyParameter ServiceAddKeyModel) (string, error) {
+	if keyParameter.Name == "" {
+		return "", errors.New("key name is required")
+	}
+	pub, err := parsePublicKey(keyParameter.Pem)
+	if err != nil {
+		return "", err
+	}
+	algorithm, err := keyAlgorithm(pub)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	fingerprint := sha256.Sum256(der)
+
+	keyId := utils.NewUlid()
+	if err := s.tsmHandler.StoreKey(keyId, tsm.PublicKeyInfo{
+		Name:        keyParameter.Name,
+		Pem:         string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		Algorithm:   algorithm,
+		Fingerprint: "sha256:" + hex.EncodeToString(fingerprint[:]),
+	}); err != nil {
+		return "", err
+	}
+	return keyId, nil
+}
+
+func (s *TrustService) RemoveKey(keyId string) error {
+	return s.tsmHandler.RemoveKey(keyId)
+}
+
+func (s *TrustService) GetKeyList() ([]KeyInfo, error) {
+	keys, err := s.tsmHandler.GetKeyList()
+	if err != nil {
+		return nil, err
+	}
+	list := []KeyInfo{}
+	for _, k := range keys {
+		list = append(list, KeyInfo{
+			KeyId:       k.KeyId,
+			Name:        k.Name,
+			Algorithm:   k.Algorithm,
+			Fingerprint: k.Fingerprint,
+			Pem:         k.Pem,
+			CreatedAt:   k.CreatedAt,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// == service: trust policies ==
+func (s *TrustService) AddPolicy(policyParameter ServiceAddPolicyModel) (string, error) {
+	scope, err := normalizeScope(policyParameter.Scope)
+	if err != nil {
+		return "", err
+	}
+
+	keys, err := s.tsmHandler.GetKeyList()
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", errors.New("no public key registered, add a key before requiring signatures")
+	}
+	// a policy naming a key that does not exist could never be satisfied
+	registered := map[string]bool{}
+	for _, k := range keys {
+		registered[k.KeyId] = true
+	}
+	for _, id := range policyParameter.KeyIds {
+		if !registered[id] {
+			return "", fmt.Errorf("keyId=%s is not registered", id)
+		}
+	}
+
+	policyId := utils.NewUlid()
+	if err := s.tsmHandler.StorePolicy(policyId, tsm.TrustPolicy{
+		Scope:  scope,
+		KeyIds: policyParameter.KeyIds,
+	}); err != nil {
+		return "", err
+	}
+	return policyId, nil
+}
+
+func (s *TrustService) RemovePolicy(policyId string) error {
+	return s.tsmHandler.RemovePolicy(policyId)
+}
+
+func (s *TrustService) GetPolicyList() ([]PolicyInfo, error) {
+	policies, err := s.tsmHandler.GetPolicyList()
+	if err != nil {
+		return nil, err
+	}
+	list := []PolicyInfo{}
+	for _, p := range policies {
+		list = append(list, PolicyInfo{
+			PolicyId:  p.PolicyId,
+			Scope:     p.Scope,
+			KeyIds:    p.KeyIds,
+			CreatedAt: p.CreatedAt,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Scope < list[j].Scope })
+	return list, nil
+}
+
+func parsePublicKey(pemStr string) (any, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(pemStr)))
+	if block == nil {
+		return nil, errors.New("invalid pem: no PEM block found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("invalid pem: unsupported block type %q (want PUBLIC KEY)", block.Type)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func keyAlgorithm(pub any) (string, error) {
+	switch pub.(type) {
+	case *ecdsa.PublicKey:
+		return "ecdsa", nil
+	case *rsa.PublicKey:
+		return "rsa", nil
+	case ed25519.PublicKey:
+		return "ed25519", nil
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
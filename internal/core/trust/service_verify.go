@@ -0,0 +1,287 @@
+package trust
+
+import (
+	"condenser/internal/registry"
+	"condenser/internal/store/tsm"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultRegistry          = "docker.io"
+	cosignSignatureType      = "cosign container image signature"
+	scopeMatchRegistry   int = 1
+	scopeMatchPrefix     int = 2
+	scopeMatchRepository int = 3
+)
+
+// == service: verify image signature ==
+// VerifyImage checks a locally stored image against the trust policy matching its repository.
+// images without a matching policy pass with Required=false.
+func (s *TrustService) VerifyImage(repository, reference string) (VerificationResult, error) {
+	name := canonicalName(repository)
+	result := VerificationResult{Image: name + refSeparator(reference) + reference}
+
+	policy, ok, err := s.matchPolicy(name)
+	if err != nil {
+		return result, err
+	}
+	if !ok {
+		return result, nil
+	}
+	result.Required = true
+	result.PolicyId = policy.PolicyId
+	result.Scope = policy.Scope
+
+	deny := func(reason string) (VerificationResult, error) {
+		return result, &VerificationError{Image: result.Image, PolicyId: policy.PolicyId, Reason: reason}
+	}
+
+	info, err := s.ilmHandler.GetImageInfo(repository, reference)
+	if err != nil {
+		return result, err
+	}
+	if info.ManifestDigest == "" {
+		return deny("image has no registry manifest digest (locally built images cannot be verified)")
+	}
+	result.ManifestDigest = info.ManifestDigest
+
+	keys, err := s.policyKeys(policy)
+	if err != nil {
+		return result, err
+	}
+	if len(keys) == 0 {
+		return deny("trust policy " + policy.Scope + " has no usable public key")
+	}
+
+	signatures, err := s.registryHandler.FetchSignatures(registry.RegistrySignatureModel{
+		Image:          repository,
+		ManifestDigest: info.ManifestDigest,
+	})
+	if err != nil {
+		return deny("fetch signatures failed: " + err.Error())
+	}
+	if len(signatures) == 0 {
+		return deny("no signature found for " + info.ManifestDigest)
+	}
+
+	var reasons []string
+	for _, sig := range signatures {
+		keyId, err := verifySignature(sig, keys, name, info.ManifestDigest)
+		if err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		result.KeyId = keyId
+		return result, nil
+	}
+	return deny(strings.Join(reasons, "; "))
+}
+
+// matchPolicy returns the most specific policy for the canonical repository name.
+func (s *TrustService) matchPolicy(name string) (tsm.TrustPolicy, bool, error) {
+	policies, err := s.tsmHandler.GetPolicyList()
+	if err != nil {
+		return tsm.TrustPolicy{}, false, err
+	}
+
+	var (
+		best      tsm.TrustPolicy
+		bestRank  int
+		bestScope int
+	)
+	for _, p := range policies {
+		rank := scopeMatch(p.Scope, name)
+		if rank == 0 {
+			continue
+		}
+		if rank > bestRank || (rank == bestRank && len(p.Scope) > bestScope) {
+			best, bestRank, bestScope = p, rank, len(p.Scope)
+		}
+	}
+	return best, bestRank > 0, nil
+}
+
+func (s *TrustService) policyKeys(policy tsm.TrustPolicy) (map[string]any, error) {
+	var keyInfos []tsm.PublicKeyInfo
+	if len(policy.KeyIds) == 0 {
+		all, err := s.tsmHandler.GetKeyList()
+		if err != nil {
+			return nil, err
+		}
+		keyInfos = all
+	} else {
+		for _, id := range policy.KeyIds {
+			k, err := s.tsmHandler.GetKeyById(id)
+			if err != nil {
+				return nil, err
+			}
+			keyInfos = append(keyInfos, k)
+		}
+	}
+
+	keys := make(map[string]any, len(keyInfos))
+	for _, k := range keyInfos {
+		pub, err := parsePublicKey(k.Pem)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", k.KeyId, err)
+		}
+		keys[k.KeyId] = pub
+	}
+	return keys, nil
+}
+
+// verifySignature checks the signature against each key, then that the payload
+// refers to the expected repository and manifest digest.
+func verifySignature(sig registry.SignatureArtifact, keys map[string]any, name, manifestDigest string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return "", fmt.Errorf("%s: signature is not base64", sig.Digest)
+	}
+
+	keyId := ""
+	for id, pub := range keys {
+		if verifyWithKey(pub, sig.Payload, raw) {
+			keyId = id
+			break
+		}
+	}
+	if keyId == "" {
+		return "", fmt.Errorf("%s: signature does not match any trusted key", sig.Digest)
+	}
+
+	var payload simpleSigningPayload
+	if err := json.Unmarshal(sig.Payload, &payload); err != nil {
+		return "", fmt.Errorf("%s: invalid signature payload: %v", sig.Digest, err)
+	}
+	if payload.Critical.Type != cosignSignatureType {
+		return "", fmt.Errorf("%s: unexpected payload type %q", sig.Digest, payload.Critical.Type)
+	}
+	if payload.Critical.Image.DockerManifestDigest != manifestDigest {
+		return "", fmt.Errorf("%s: signed digest %s does not match %s", sig.Digest, payload.Critical.Image.DockerManifestDigest, manifestDigest)
+	}
+	if ref := payload.Critical.Identity.DockerReference; ref != "" && canonicalName(stripTagOrDigest(ref)) != name {
+		return "", fmt.Errorf("%s: signed for %s, not %s", sig.Digest, ref, name)
+	}
+	return keyId, nil
+}
+
+func verifyWithKey(pub any, payload, signature []byte) bool {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(payload)
+		return ecdsa.VerifyASN1(k, digest[:], signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(payload)
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil {
+			return true
+		}
+		return rsa.VerifyPSS(k, crypto.SHA256, digest[:], signature, nil) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, signature)
+	default:
+		return false
+	}
+}
+
+// canonicalName maps an ilm repository (library/nginx, ghcr.io/org/app) to registry/repository form.
+func canonicalName(repository string) string {
+	first, rest, ok := strings.Cut(repository, "/")
+	if ok && isRegistryHost(first) {
+		return normalizeRegistry(first) + "/" + rest
+	}
+	if !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	return defaultRegistry + "/" + repository
+}
+
+// normalizeScope validates a trust policy scope: registry, registry/repository or registry/prefix/*.
+func normalizeScope(scope string) (string, error) {
+	scope = strings.TrimSpace(scope)
+	if scope == "" {
+		return "", errors.New("scope is required")
+	}
+	if strings.ContainsAny(scope, " @") {
+		return "", fmt.Errorf("invalid scope: %s", scope)
+	}
+	body := strings.TrimSuffix(scope, "/*")
+	if strings.Contains(body, "*") {
+		return "", fmt.Errorf("invalid scope: wildcard is only allowed as a trailing /*: %s", scope)
+	}
+
+	wildcard := ""
+	if strings.HasSuffix(scope, "/*") {
+		wildcard = "/*"
+	}
+
+	first, rest, hasRepo := strings.Cut(body, "/")
+	if !isRegistryHost(first) {
+		// docker hub repository without registry prefix
+		if wildcard != "" {
+			return defaultRegistry + "/" + body + wildcard, nil
+		}
+		return canonicalName(body), nil
+	}
+	body = normalizeRegistry(first)
+	if hasRepo {
+		if body == defaultRegistry && !strings.Contains(rest, "/") && wildcard == "" {
+			rest = "library/" + rest
+		}
+		body += "/" + rest
+	}
+	return body + wildcard, nil
+}
+
+func scopeMatch(scope, name string) int {
+	switch {
+	case scope == name:
+		return scopeMatchRepository
+	case strings.HasSuffix(scope, "/*") && strings.HasPrefix(name, strings.TrimSuffix(scope, "*")):
+		return scopeMatchPrefix
+	case !strings.Contains(scope, "/") && strings.HasPrefix(name, scope+"/"):
+		return scopeMatchRegistry
+	}
+	return 0
+}
+
+func stripTagOrDigest(ref string) string {
+	if at := strings.Index(ref, "@"); at >= 0 {
+		ref = ref[:at]
+	}
+	if lastColon := strings.LastIndex(ref, ":"); lastColon > strings.LastIndex(ref, "/") {
+		ref = ref[:lastColon]
+	}
+	return ref
+}
+
+func refSeparator(reference string) string {
+	if strings.HasPrefix(reference, "sha256:") {
+		return "@"
+	}
+	return ":"
+}
+
+func isRegistryHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	return strings.Contains(host, ".") || strings.Contains(host, ":")
+}
+
+func normalizeRegistry(reg string) string {
+	switch reg {
+	case "registry-1.docker.io", "index.docker.io":
+		return defaultRegistry
+	default:
+		return reg
+	}
+}
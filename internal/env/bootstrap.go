@@ -3,6 +3,7 @@ package env
 import (
 	"bufio"
 	"condenser/internal/core/cert"
+	"condenser/internal/core/namespace"
 	"condenser/internal/core/network"
 	"condenser/internal/core/policy"
 	"condenser/internal/lsm"
@@ -10,6 +11,7 @@ import (
 	"condenser/internal/store/ilm"
 	"condenser/internal/store/ipam"
 	"condenser/internal/store/npm"
+	"condenser/internal/store/nsm"
 	"condenser/internal/utils"
 	"fmt"
 	"net"
@@ -33,6 +35,9 @@ func NewBootstrapManager() *BootstrapManager {
 		ilmStoreHandler:   ilm.NewIlmStore(utils.IlmStorePath),
 		npmStoreHandler:   npm.NewNpmStore(utils.NpmStorePath),
 		appArmorHandler:   lsm.NewAppArmorManager(),
+
+		nsmStoreHandler:  nsm.NewNsmStore(utils.NsmStorePath),
+		namespaceHandler: namespace.NewNamespaceService(),
 	}
 }
 
@@ -49,6 +54,9 @@ type BootstrapManager struct {
 	ilmStoreHandler   ilm.IlmStoreHandler
 	npmStoreHandler   npm.NpmStoreHandler
 	appArmorHandler   lsm.AppArmorHandler
+
+	nsmStoreHandler  nsm.NsmStoreHandler
+	namespaceHandler namespace.NamespaceServiceHandler
 }
 
 func (m *BootstrapManager) SetupRuntime() error {
@@ -101,6 +109,11 @@ func (m *BootstrapManager) SetupRuntime() error {
 		return err
 	}
 
+	// 9. setup NSM (Namespace State Manager)
+	if err := m.setupNsm(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -150,7 +163,7 @@ func (m *BootstrapManager) setupCgroupDirectory() error {
 
 func (m *BootstrapManager) enableCgroupControllers() error {
 	// get current enabled control
-	enabled, err := m.readCgroupEnabledControllers()
+	enabled, err := m.readCgroupEnabledControllers(utils.CgroupSubtreeControlPath)
 	if err != nil {
 		return nil
 	}
@@ -165,7 +178,7 @@ func (m *BootstrapManager) enableCgroupControllers() error {
 		if enabled[c] {
 			continue
 		}
-		if err := m.writeCgroupController("+" + c); err != nil {
+		if err := m.writeCgroupController(utils.CgroupSubtreeControlPath, "+"+c); err != nil {
 			return err
 		}
 	}
@@ -173,6 +186,29 @@ func (m *BootstrapManager) enableCgroupControllers() error {
 	return nil
 }
 
+// enablePodCgroupControllers hands the controllers of the runtime root down
+// to the members of a pod.
+func (m *BootstrapManager) enablePodCgroupControllers(podId string) error {
+	available, err := m.readCgroupEnabledControllers(utils.CgroupSubtreeControlPath)
+	if err != nil {
+		return err
+	}
+	subtreePath := filepath.Join(utils.PodCgroupPath(podId), "cgroup.subtree_control")
+	enabled, err := m.readCgroupEnabledControllers(subtreePath)
+	if err != nil {
+		return err
+	}
+	for c := range available {
+		if enabled[c] {
+			continue
+		}
+		if err := m.writeCgroupController(subtreePath, "+"+c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *BootstrapManager) createContainerCgroup() error {
 	containerList, err := m.csmHandler.GetContainerList()
 	if err != nil {
@@ -182,12 +218,24 @@ func (m *BootstrapManager) createContainerCgroup() error {
 		if err := m.filesystemHandler.MkdirAll(filepath.Join(utils.CgroupRuntimeDir, c.ContainerId), 0o755); err != nil {
 			return err
 		}
+		if c.PodId == "" {
+			continue
+		}
+		// pod members are moved below their pod when they start
+		if err := m.filesystemHandler.MkdirAll(utils.PodCgroupPath(c.PodId), 0o755); err != nil {
+			return err
+		}
+		if err := m.enablePodCgroupControllers(c.PodId); err != nil {
+			return err
+		}
+		if err := m.filesystemHandler.MkdirAll(utils.ContainerCgroupPath(c.ContainerId, c.PodId), 0o755); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (m *BootstrapManager) readCgroupEnabledControllers() (map[string]bool, error) {
-	subtreePath := utils.CgroupSubtreeControlPath
+func (m *BootstrapManager) readCgroupEnabledControllers(subtreePath string) (map[string]bool, error) {
 	f, err := m.filesystemHandler.Open(subtreePath)
 	if err != nil {
 		return nil, err
@@ -210,8 +258,7 @@ func (m *BootstrapManager) readCgroupEnabledControllers() (map[string]bool, erro
 	return enabled, nil
 }
 
-func (m *BootstrapManager) writeCgroupController(token string) error {
-	subtreePath := utils.CgroupSubtreeControlPath
+func (m *BootstrapManager) writeCgroupController(subtreePath string, token string) error {
 	f, err := m.filesystemHandler.OpenFile(subtreePath, os.O_WRONLY, 0)
 	if err != nil {
 		return err
@@ -240,6 +287,14 @@ func (m *BootstrapManager) setupNpm() error {
 	return m.npmStoreHandler.SetNetworkPolicy()
 }
 
+func (m *BootstrapManager) setupNsm() error {
+	if err := m.nsmStoreHandler.SetNamespaceState(); err != nil {
+		return err
+	}
+	// register the namespaces resources were created in before namespaces existed
+	return m.namespaceHandler.AdoptNamespaces()
+}
+
 func (m *BootstrapManager) setupAppArmor() error {
 	if err := m.appArmorHandler.EnsureRaindDefaultProfile(); err != nil {
 		// if apparmor setting failed, runtime ignore apparmor setting
@@ -12,9 +12,10 @@ import (
 	"condenser/internal/utils"
 )
 
-// CgroupV2Path returns the container cgroup v2 path under the Raind root.
-func CgroupV2Path(containerID string) string {
-	return filepath.Join(utils.CgroupRuntimeDir, containerID)
+// CgroupV2Path returns the container cgroup v2 path under the Raind root,
+// pod members are nested below their pod.
+func CgroupV2Path(containerID string, podID string) string {
+	return utils.ContainerCgroupPath(containerID, podID)
 }
 
 // CgroupCPUUsageSample is a snapshot of cpu.stat usage.
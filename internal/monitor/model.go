@@ -12,8 +12,9 @@ type ContainerMeta struct {
 type MetricsRecord struct {
 	GeneratedTS string `json:"generated_ts"`
 
-	ContainerID   string `json:"container_id"`
+	ContainerID   string `json:"container_id"` // empty for pod totals
 	ContainerName string `json:"container_name"`
+	PodID         string `json:"pod_id,omitempty"`
 	SpiffeID      string `json:"spiffe_id"`
 	Pid           int    `json:"pid"`
 	Status        string `json:"status"`
@@ -6,6 +6,7 @@ import (
 	"condenser/internal/utils"
 	"context"
 	"log"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync/atomic"
@@ -69,7 +70,8 @@ func (m *ContainerMonitor) Start() error {
 				); err != nil {
 					continue
 				}
-				if container.PodId != "" && !strings.HasPrefix(container.ContainerName, utils.PodInfraContainerNamePrefix) {
+				if container.PodId != "" && !strings.HasPrefix(container.ContainerName, utils.PodInfraContainerNamePrefix) &&
+					!strings.HasPrefix(container.ContainerName, utils.PodInitContainerNamePrefix) {
 					_ = m.psmHandler.UpdatePod(container.PodId, "degraded")
 				}
 				if err := m.csmHandler.UpdateExitStatus(
@@ -99,13 +101,61 @@ func (m *ContainerMonitor) Start() error {
 				}
 			}
 		}
+
+		if metricTick%10 == 0 {
+			m.writePodMetrics(metricsWriter, prevCPU)
+		}
 	}
 	return nil
 }
 
+// writePodMetrics records the totals of each running pod from the pod
+// cgroup, which holds all of its members.
+func (m *ContainerMonitor) writePodMetrics(metricsWriter *MetricsWriter, prevCPU map[string]CgroupCPUUsageSample) {
+	pods, err := m.psmHandler.GetPodList()
+	if err != nil {
+		return
+	}
+	for _, p := range pods {
+		if p.State != "running" && p.State != "degraded" {
+			continue
+		}
+		cgroupPath := utils.PodCgroupPath(p.PodId)
+		if _, err := os.Stat(cgroupPath); err != nil {
+			continue
+		}
+		key := "pod-" + p.PodId
+		record, sample, err := buildCgroupMetrics(cgroupPath, prevCPU[key])
+		if err != nil {
+			log.Printf("metrics build failed: pod=%s err=%v", p.PodId, err)
+			continue
+		}
+		prevCPU[key] = sample
+		record.PodID = p.PodId
+		record.Status = p.State
+		if err := metricsWriter.WriteJSONL(record); err != nil {
+			log.Printf("metrics write failed: pod=%s err=%v", p.PodId, err)
+		}
+	}
+}
+
 func buildMetricsRecord(container ContainerMeta, prev CgroupCPUUsageSample) (MetricsRecord, CgroupCPUUsageSample, error) {
-	cgroupPath := CgroupV2Path(container.ContainerId)
+	cgroupPath := CgroupV2Path(container.ContainerId, container.PodId)
+
+	record, sample, err := buildCgroupMetrics(cgroupPath, prev)
+	if err != nil {
+		return MetricsRecord{}, CgroupCPUUsageSample{}, err
+	}
+	record.ContainerID = container.ContainerId
+	record.ContainerName = container.ContainerName
+	record.PodID = container.PodId
+	record.SpiffeID = container.SpiffeId
+	record.Pid = container.Pid
+	record.Status = container.Status
+	return record, sample, nil
+}
 
+func buildCgroupMetrics(cgroupPath string, prev CgroupCPUUsageSample) (MetricsRecord, CgroupCPUUsageSample, error) {
 	cpuStat, err := ReadCgroupCPUStat(cgroupPath)
 	if err != nil {
 		return MetricsRecord{}, CgroupCPUUsageSample{}, err
@@ -138,13 +188,7 @@ func buildMetricsRecord(container ContainerMeta, prev CgroupCPUUsageSample) (Met
 
 	return MetricsRecord{
 		GeneratedTS: time.Now().Format(time.RFC3339Nano),
-
-		ContainerID:   container.ContainerId,
-		ContainerName: container.ContainerName,
-		SpiffeID:      container.SpiffeId,
-		Pid:           container.Pid,
-		Status:        container.Status,
-		CgroupPath:    cgroupPath,
+		CgroupPath:  cgroupPath,
 
 		CPUUsageUsec:     cpuStat.UsageUsec,
 		CPUUserUsec:      cpuStat.UserUsec,
@@ -0,0 +1,393 @@
+package dockerhub
+
+import (
+	"condenser/internal/registry"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxConcurrentDownloads = 3
+	maxConcurrentDownloadsLimit   = 16
+
+	blobDownloadAttempts = 5
+	// a blob download is aborted when no bytes arrive for this long,
+	// instead of bounding the whole transfer like the api client does
+	blobStallTimeout = 60 * time.Second
+
+	progressInterval = 200 * time.Millisecond
+)
+
+var errBlobDigestMismatch = errors.New("blob digest mismatch")
+
+// blobDescriptor is a blob to be downloaded into the bundle.
+type blobDescriptor struct {
+	Digest string
+	Size   int64
+}
+
+// == shared pulls ==
+
+// pullFlight is one pull of a bundle directory. Concurrent pulls of the
+// same image wait for the running one and receive its progress events.
+type pullFlight struct {
+	done        chan struct{}
+	mu          sync.Mutex
+	subscribers []func(registry.PullProgress)
+
+	configPath string
+	rootfsPath string
+	err        error
+}
+
+var (
+	pullFlightsMu sync.Mutex
+	pullFlights   = map[string]*pullFlight{}
+)
+
+func (f *pullFlight) subscribe(progress func(registry.PullProgress)) {
+	if progress == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscribers = append(f.subscribers, progress)
+}
+
+func (f *pullFlight) emit(ev registry.PullProgress) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, sub := range f.subscribers {
+		sub(ev)
+	}
+}
+
+// sharePull runs fn once per bundle directory at a time.
+func sharePull(repoOut string, progress func(registry.PullProgress), fn func(emit func(registry.PullProgress)) (string, string, error)) (configPath, rootfsPath string, err error) {
+	pullFlightsMu.Lock()
+	if f, ok := pullFlights[repoOut]; ok {
+		f.subscribe(progress)
+		pullFlightsMu.Unlock()
+		if progress != nil {
+			progress(registry.PullProgress{Status: registry.PullStatusWaitingForPull})
+		}
+		<-f.done
+		return f.configPath, f.rootfsPath, f.err
+	}
+	f := &pullFlight{done: make(chan struct{})}
+	f.subscribe(progress)
+	pullFlights[repoOut] = f
+	pullFlightsMu.Unlock()
+
+	f.configPath, f.rootfsPath, f.err = fn(f.emit)
+
+	pullFlightsMu.Lock()
+	delete(pullFlights, repoOut)
+	pullFlightsMu.Unlock()
+	close(f.done)
+	return f.configPath, f.rootfsPath, f.err
+}
+
+// == blob downloads ==
+func newBlobClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = 30 * time.Second
+	transport.ResponseHeaderTimeout = 60 * time.Second
+	return &http.Client{Transport: transport}
+}
+
+// downloadBlobs fetches blobs with at most limit transfers in flight.
+// The first failure cancels the remaining transfers.
+func (s *RegistryDockerHub) downloadBlobs(ctx context.Context, client *http.Client, ref imageRefParts, token string, blobs []blobDescriptor, blobDir string, limit int, emit func(registry.PullProgress)) error {
+	if limit <= 0 {
+		limit = defaultMaxConcurrentDownloads
+	}
+	if limit > maxConcurrentDownloadsLimit {
+		limit = maxConcurrentDownloadsLimit
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, limit)
+	)
+	for _, b := range blobs {
+		emit(registry.PullProgress{Id: shortBlobId(b.Digest), Status: registry.PullStatusWaiting, Total: b.Size})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			dest := filepath.Join(blobDir, s.digestToFilename(b.Digest))
+			if err := s.downloadBlobResumable(ctx, client, ref, token, b, dest, emit); err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("blob %s: %w", b.Digest, err)
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// downloadBlobResumable downloads a blob into dest, keeping the received
+// bytes in dest.partial so that a retry (or a later pull) continues with
+// an HTTP Range request instead of starting over.
+func (s *RegistryDockerHub) downloadBlobResumable(ctx context.Context, client *http.Client, ref imageRefParts, token string, blob blobDescriptor, dest string, emit func(registry.PullProgress)) error {
+	if !strings.HasPrefix(blob.Digest, "sha256:") {
+		return fmt.Errorf("only sha256 digest supported: %s", blob.Digest)
+	}
+	id := shortBlobId(blob.Digest)
+
+	// a blob kept from an earlier attempt is reused once its digest checks out
+	if err := verifyFileDigest(dest, blob.Digest); err == nil {
+		emit(registry.PullProgress{Id: id, Status: registry.PullStatusAlreadyExists, Current: blob.Size, Total: blob.Size})
+		return nil
+	}
+
+	partial := dest + ".partial"
+	var lastErr error
+	for attempt := 1; attempt <= blobDownloadAttempts; attempt++ {
+		if attempt > 1 {
+			emit(registry.PullProgress{Id: id, Status: registry.PullStatusRetrying, Total: blob.Size, Message: lastErr.Error()})
+			select {
+			case <-time.After(time.Duration(attempt-1) * time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		lastErr = s.fetchBlobRange(ctx, client, ref, token, blob, partial, emit)
+		if lastErr == nil {
+			emit(registry.PullProgress{Id: id, Status: registry.PullStatusVerifying, Current: blob.Size, Total: blob.Size})
+			lastErr = verifyFileDigest(partial, blob.Digest)
+			if lastErr == nil {
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// corrupt data cannot be resumed
+		if errors.Is(lastErr, errBlobDigestMismatch) {
+			_ = os.Remove(partial)
+		}
+		var statusErr *blobStatusError
+		if errors.As(lastErr, &statusErr) && !statusErr.retryable() {
+			return lastErr
+		}
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+
+	if err := os.Rename(partial, dest); err != nil {
+		return err
+	}
+	emit(registry.PullProgress{Id: id, Status: registry.PullStatusDownloadComplete, Current: blob.Size, Total: blob.Size})
+	return nil
+}
+
+type blobStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *blobStatusError) Error() string {
+	return fmt.Sprintf("blob fetch failed: %d: %s", e.StatusCode, e.Body)
+}
+
+func (e *blobStatusError) retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests
+}
+
+// fetchBlobRange appends the missing tail of the blob to the partial file.
+func (s *RegistryDockerHub) fetchBlobRange(ctx context.Context, client *http.Client, ref imageRefParts, token string, blob blobDescriptor, partial string, emit func(registry.PullProgress)) error {
+	id := shortBlobId(blob.Digest)
+	if err := os.MkdirAll(filepath.Dir(partial), 0o755); err != nil {
+		return err
+	}
+
+	var offset int64
+	if fi, err := os.Stat(partial); err == nil {
+		offset = fi.Size()
+	}
+	if blob.Size > 0 && offset == blob.Size {
+		return nil
+	}
+	if blob.Size > 0 && offset > blob.Size {
+		if err := os.Remove(partial); err != nil {
+			return err
+		}
+		offset = 0
+	}
+
+	// the body read is bounded by a stall timer rather than a client timeout
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stall := time.AfterFunc(blobStallTimeout, cancel)
+	defer stall.Stop()
+
+	u := fmt.Sprintf("https://%s/v2/%s/blobs/%s", ref.registry, ref.repository, blob.Digest)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, err := contentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil || start != offset {
+			// the registry answered a different range, start over
+			_ = os.Remove(partial)
+			return fmt.Errorf("unexpected content range %q for offset %d", resp.Header.Get("Content-Range"), offset)
+		}
+		flags |= os.O_APPEND
+		emit(registry.PullProgress{Id: id, Status: registry.PullStatusResuming, Current: offset, Total: blob.Size})
+	case http.StatusOK:
+		// range not honoured, the full blob follows
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		_ = os.Remove(partial)
+		return &blobStatusError{StatusCode: resp.StatusCode, Body: "range not satisfiable"}
+	default:
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &blobStatusError{StatusCode: resp.StatusCode, Body: string(b)}
+	}
+
+	f, err := os.OpenFile(partial, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	current := offset
+	last := time.Time{}
+	buf := make([]byte, 256*1024)
+	for {
+		n, rerr := resp.Body.Read(buf)
+		if n > 0 {
+			stall.Reset(blobStallTimeout)
+			if _, err := f.Write(buf[:n]); err != nil {
+				return err
+			}
+			current += int64(n)
+			if now := time.Now(); now.Sub(last) >= progressInterval {
+				last = now
+				emit(registry.PullProgress{Id: id, Status: registry.PullStatusDownloading, Current: current, Total: blob.Size})
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			if reqCtx.Err() != nil && ctx.Err() == nil {
+				return fmt.Errorf("download stalled for %s: %w", blobStallTimeout, rerr)
+			}
+			return rerr
+		}
+	}
+	emit(registry.PullProgress{Id: id, Status: registry.PullStatusDownloading, Current: current, Total: blob.Size})
+	return f.Close()
+}
+
+// contentRangeStart parses the first byte position of "bytes <start>-<end>/<size>".
+func contentRangeStart(v string) (int64, error) {
+	rest, ok := strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return 0, fmt.Errorf("invalid content range: %q", v)
+	}
+	start, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, fmt.Errorf("invalid content range: %q", v)
+	}
+	return strconv.ParseInt(start, 10, 64)
+}
+
+func verifyFileDigest(path string, digest string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	want := strings.TrimPrefix(digest, "sha256:")
+	if sum != want {
+		return fmt.Errorf("%w: want %s got %s", errBlobDigestMismatch, want, sum)
+	}
+	return nil
+}
+
+// shortBlobId returns the 12 character id docker shows for a blob.
+func shortBlobId(digest string) string {
+	hexPart := strings.TrimPrefix(digest, "sha256:")
+	if len(hexPart) > 12 {
+		return hexPart[:12]
+	}
+	return hexPart
+}
+
+// cleanupFailedPull removes everything a failed pull left behind except
+// the blobs, which a later pull of the same image resumes from.
+func (s *RegistryDockerHub) cleanupFailedPull(repoOut string) error {
+	blobDir := filepath.Join(repoOut, "blobs")
+	if entries, err := os.ReadDir(blobDir); err != nil || len(entries) == 0 {
+		return s.removeOutputDirectory(repoOut)
+	}
+
+	entries, err := os.ReadDir(repoOut)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() == "blobs" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(repoOut, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
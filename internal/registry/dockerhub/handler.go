@@ -36,24 +36,51 @@ func (s *RegistryDockerHub) PullImage(pullParameter registry.RegistryPullModel)
 	if err != nil {
 		return "", "", "", "", "", err
 	}
+	storeRepo := s.storeRepository(imageRef)
+	// every platform of a reference gets a bundle of its own
+	platform := utils.Platform{Os: pullParameter.Os, Arch: pullParameter.Arch, Variant: pullParameter.Variant}
+	repoOut := filepath.Join(utils.LayerRootDir, storeRepo, imageRef.reference+"_"+platform.DirName())
+
+	// concurrent pulls of the same image share one download
+	configPath, rootfsPath, err = sharePull(repoOut, pullParameter.Progress, func(emit func(registry.PullProgress)) (string, string, error) {
+		// a tag alias shares the bundle, so a moving tag must not change it
+		bundleOut := repoOut
+		if pullParameter.BundleInUse != nil && pullParameter.BundleInUse(bundleOut) {
+			bundleOut = repoOut + "-" + utils.NewUlid()[:8]
+		}
+		configPath, rootfsPath, err := s.pullBundle(imageRef, bundleOut, pullParameter, emit)
+		if err != nil {
+			if bundleOut != repoOut {
+				_ = s.removeOutputDirectory(bundleOut)
+				return "", "", err
+			}
+			if err := s.cleanupFailedPull(bundleOut); err != nil {
+				return "", "", err
+			}
+			return "", "", err
+		}
+		return configPath, rootfsPath, nil
+	})
+	if err != nil {
+		return "", "", "", "", "", err
+	}
+	return storeRepo, imageRef.reference, filepath.Dir(configPath), configPath, rootfsPath, nil
+}
 
+func (s *RegistryDockerHub) pullBundle(imageRef imageRefParts, repoOut string, pullParameter registry.RegistryPullModel, emit func(registry.PullProgress)) (configPath, rootfsPath string, err error) {
 	// 2. create output directory
-	storeRepo := s.storeRepository(imageRef)
-	repoOut := filepath.Join(utils.LayerRootDir, storeRepo, imageRef.reference)
 	if err := s.createOutputDirectory(repoOut); err != nil {
-		return "", "", "", "", "", err
+		return "", "", err
 	}
 
 	ctx := context.Background()
 	httpClient := &http.Client{Timeout: 60 * time.Second}
+	emit(registry.PullProgress{Status: registry.PullStatusResolving, Message: pullParameter.Image})
 
 	// 3. get Bearer Challenge
 	realm, service, err := s.getBearerChallenge(ctx, httpClient, imageRef.registry)
 	if err != nil {
-		if err := s.removeOutputDirectory(repoOut); err != nil {
-			return "", "", "", "", "", err
-		}
-		return "", "", "", "", "", err
+		return "", "", err
 	}
 
 	// 4. get token
@@ -62,115 +89,82 @@ func (s *RegistryDockerHub) PullImage(pullParameter registry.RegistryPullModel)
 		scope := fmt.Sprintf("repository:%s:pull", imageRef.repository)
 		token, err = s.fetchToken(ctx, httpClient, realm, service, scope)
 		if err != nil {
-			if err := s.removeOutputDirectory(repoOut); err != nil {
-				return "", "", "", "", "", err
-			}
-			return "", "", "", "", "", err
+			return "", "", err
 		}
 	}
 
 	// 5. get manifest (manifest list) and store .json
 	manifestBytes, mediaType, err := s.fetchManifest(ctx, httpClient, imageRef, token)
 	if err != nil {
-		if err := s.removeOutputDirectory(repoOut); err != nil {
-			return "", "", "", "", "", err
+		return "", "", err
+	}
+	// a digest reference must match the content we got back
+	if strings.HasPrefix(imageRef.reference, "sha256:") {
+		if got := s.computeDigest(manifestBytes); got != imageRef.reference {
+			return "", "", fmt.Errorf("manifest digest mismatch: want %s, got %s", imageRef.reference, got)
 		}
-		return "", "", "", "", "", err
 	}
 	if err := s.storeManifest(repoOut, manifestBytes, "manifest.json"); err != nil {
-		if err := s.removeOutputDirectory(repoOut); err != nil {
-			return "", "", "", "", "", err
-		}
-		return "", "", "", "", "", err
+		return "", "", err
 	}
 
 	// 6. get manifest if the mediaType is list
 	if s.isManifestListMediaType(mediaType) {
 		// pick digest from manifest list
-		dgst, err := s.pickFromManifestList(manifestBytes, pullParameter.Os, pullParameter.Arch)
+		dgst, err := s.pickFromManifestList(manifestBytes, pullParameter.Os, pullParameter.Arch, pullParameter.Variant)
 		if err != nil {
-			if err := s.removeOutputDirectory(repoOut); err != nil {
-				return "", "", "", "", "", err
-			}
-			return "", "", "", "", "", err
+			return "", "", err
 		}
 		imageRef2 := imageRef
 		imageRef2.reference = dgst // set digest to reference
-		manifestBytes, mediaType, err = s.fetchManifest(ctx, httpClient, imageRef2, token)
+		manifestBytes, _, err = s.fetchManifest(ctx, httpClient, imageRef2, token)
 		if err != nil {
-			if err := s.removeOutputDirectory(repoOut); err != nil {
-				return "", "", "", "", "", err
-			}
-			return "", "", "", "", "", err
+			return "", "", err
 		}
 		if err := s.storeManifest(repoOut, manifestBytes, "manifest.selected.json"); err != nil {
-			if err := s.removeOutputDirectory(repoOut); err != nil {
-				return "", "", "", "", "", err
-			}
-			return "", "", "", "", "", err
+			return "", "", err
 		}
 	}
 
 	// 7. parse manifest
 	m, err := s.parseSingleManifest(manifestBytes)
 	if err != nil {
-		if err := s.removeOutputDirectory(repoOut); err != nil {
-			return "", "", "", "", "", err
-		}
-		return "", "", "", "", "", err
-	}
-
-	// 8. download blob
-	if err := s.downloadBlobVerified(
-		ctx, httpClient, imageRef, token,
-		m.Config.Digest, filepath.Join(repoOut, "blobs", s.digestToFilename(m.Config.Digest)),
-	); err != nil {
-		if err := s.removeOutputDirectory(repoOut); err != nil {
-			return "", "", "", "", "", err
-		}
-		return "", "", "", "", "", err
+		return "", "", err
 	}
 
-	// 9. download layers
+	// 8. download config and layers in parallel
+	blobs := []blobDescriptor{{Digest: m.Config.Digest, Size: m.Config.Size}}
 	for _, l := range m.Layers {
-		if err := s.downloadBlobVerified(
-			ctx, httpClient, imageRef, token,
-			l.Digest, filepath.Join(repoOut, "blobs", s.digestToFilename(l.Digest)),
-		); err != nil {
-			if err := s.removeOutputDirectory(repoOut); err != nil {
-				return "", "", "", "", "", err
-			}
-			return "", "", "", "", "", err
-		}
+		blobs = append(blobs, blobDescriptor{Digest: l.Digest, Size: l.Size})
+	}
+	if err := s.downloadBlobs(
+		ctx, newBlobClient(), imageRef, token,
+		blobs, filepath.Join(repoOut, "blobs"), pullParameter.MaxConcurrentDownloads, emit,
+	); err != nil {
+		return "", "", err
 	}
 
-	// 10. create config.json
+	// 9. create config.json
 	configPath = filepath.Join(repoOut, "config.json")
 	if err := s.copyFile(
 		filepath.Join(repoOut, "blobs", s.digestToFilename(m.Config.Digest)),
 		configPath,
 	); err != nil {
-		if err := s.removeOutputDirectory(repoOut); err != nil {
-			return "", "", "", "", "", err
-		}
-		return "", "", "", "", "", err
+		return "", "", err
 	}
 
-	// 11. extract layer
+	// 10. extract layer
 	rootfsPath = filepath.Join(repoOut, "rootfs")
-	var layerPaths []string
-	for _, l := range m.Layers {
+	for i, l := range m.Layers {
+		emit(registry.PullProgress{Id: shortBlobId(l.Digest), Status: registry.PullStatusExtracting, Total: l.Size})
 		p := filepath.Join(repoOut, "blobs", s.digestToFilename(l.Digest))
-		layerPaths = append(layerPaths, p)
-	}
-	if err := s.applyLayers(rootfsPath, layerPaths); err != nil {
-		if err := s.removeOutputDirectory(repoOut); err != nil {
-			return "", "", "", "", "", err
+		if err := s.applyOneLayer(rootfsPath, p); err != nil {
+			return "", "", fmt.Errorf("apply layer %d (%s): %w", i, p, err)
 		}
-		return "", "", "", "", "", err
+		emit(registry.PullProgress{Id: shortBlobId(l.Digest), Status: registry.PullStatusPullComplete, Current: l.Size, Total: l.Size})
 	}
 
-	return s.storeRepository(imageRef), imageRef.reference, repoOut, configPath, rootfsPath, nil
+	return configPath, rootfsPath, nil
 }
 
 func (s *RegistryDockerHub) createOutputDirectory(repoOut string) error {
@@ -202,23 +196,29 @@ func (s *RegistryDockerHub) parseImageRef(imageStr string) (imageRefParts, error
 	// - ubuntu:24.04 			-> library/ubuntu:24.04
 	// - library/ubuntu:24.04 	-> library/ubuntu:24.04
 	// - nginx@sha256:... 		-> library/nginx@sha256:...
+	// - nginx:1.27@sha256:... 	-> library/nginx@sha256:...
 	// - registry.k8s.io/kube-apiserver:v1.32.11
 	// - localhost:5000/app:latest
 
 	var name, repo, ref string
-	if strings.Contains(imageStr, "@") {
-		parts := strings.SplitN(imageStr, "@", 2)
-		name, ref = parts[0], parts[1]
-	} else {
-		name = imageStr
-		lastColon := strings.LastIndex(name, ":")
-		lastSlash := strings.LastIndex(name, "/")
-		if lastColon > lastSlash {
+	name = imageStr
+	if at := strings.Index(name, "@"); at >= 0 {
+		// name:tag@sha256:... pins the digest, the tag is informational only
+		name, ref = name[:at], name[at+1:]
+		if !strings.HasPrefix(ref, "sha256:") {
+			return imageRefParts{}, fmt.Errorf("only sha256 digest supported: %s", ref)
+		}
+	}
+	lastColon := strings.LastIndex(name, ":")
+	lastSlash := strings.LastIndex(name, "/")
+	if lastColon > lastSlash {
+		if ref == "" {
 			ref = name[lastColon+1:]
-			name = name[:lastColon]
-		} else {
-			ref = "latest"
 		}
+		name = name[:lastColon]
+	}
+	if ref == "" {
+		ref = "latest"
 	}
 
 	if name == "" {
@@ -401,7 +401,7 @@ func (s *RegistryDockerHub) isManifestListMediaType(ct string) bool {
 		ct == "application/vnd.oci.image.index.v1+json"
 }
 
-func (s *RegistryDockerHub) pickFromManifestList(b []byte, targetOs, targetArch string) (string, error) {
+func (s *RegistryDockerHub) pickFromManifestList(b []byte, targetOs, targetArch, targetVariant string) (string, error) {
 	var ml manifestList
 	if err := json.Unmarshal(b, &ml); err != nil {
 		return "", err
@@ -411,10 +411,14 @@ func (s *RegistryDockerHub) pickFromManifestList(b []byte, targetOs, targetArch
 			if m.Digest == "" {
 				continue
 			}
+			if targetVariant != "" && m.Platform.Variant != targetVariant {
+				continue
+			}
 			return m.Digest, nil
 		}
 	}
-	return "", fmt.Errorf("no manifest for platform %s/%s", targetOs, targetArch)
+	target := utils.Platform{Os: targetOs, Arch: targetArch, Variant: targetVariant}
+	return "", fmt.Errorf("no manifest for platform %s", target)
 }
 
 func (s *RegistryDockerHub) parseSingleManifest(b []byte) (*singleManifest, error) {
@@ -428,57 +432,16 @@ func (s *RegistryDockerHub) parseSingleManifest(b []byte) (*singleManifest, erro
 	return &m, nil
 }
 
+func (s *RegistryDockerHub) computeDigest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
 func (s *RegistryDockerHub) digestToFilename(d string) string {
 	// sha256:abcd... -> sha256_abcd...
 	return strings.ReplaceAll(d, ":", "_")
 }
 
-func (s *RegistryDockerHub) downloadBlobVerified(ctx context.Context, client *http.Client, ref imageRefParts, token, digest, dest string) error {
-	if !strings.HasPrefix(digest, "sha256:") {
-		return fmt.Errorf("only sha256 digest supported: %s", digest)
-	}
-	u := fmt.Sprintf("https://%s/v2/%s/blobs/%s", ref.registry, ref.repository, digest)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("blob fetch failed: %d: %s", resp.StatusCode, string(b))
-	}
-
-	// store
-	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
-		return err
-	}
-	f, err := os.Create(dest)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	h := sha256.New()
-	tee := io.TeeReader(resp.Body, h)
-
-	if _, err := io.Copy(f, tee); err != nil {
-		return err
-	}
-
-	sum := hex.EncodeToString(h.Sum(nil))
-	want := strings.TrimPrefix(digest, "sha256:")
-	if sum != want {
-		return fmt.Errorf("digest mismatch: want %s got %s", want, sum)
-	}
-	return nil
-}
-
 func (s *RegistryDockerHub) copyFile(src, dst string) error {
 	in, err := os.Open(src)
 	if err != nil {
@@ -498,15 +461,6 @@ func (s *RegistryDockerHub) copyFile(src, dst string) error {
 	return out.Close()
 }
 
-func (s *RegistryDockerHub) applyLayers(rootfs string, layerBlobPaths []string) error {
-	for i, p := range layerBlobPaths {
-		if err := s.applyOneLayer(rootfs, p); err != nil {
-			return fmt.Errorf("apply layer %d (%s): %w", i, p, err)
-		}
-	}
-	return nil
-}
-
 func (s *RegistryDockerHub) applyOneLayer(rootfs, layerBlobPath string) error {
 	f, err := os.Open(layerBlobPath)
 	if err != nil {
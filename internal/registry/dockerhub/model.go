@@ -39,3 +39,14 @@ type singleManifest struct {
 		Digest    string `json:"digest"`
 	} `json:"layers"`
 }
+
+type signatureManifest struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	MediaType     string `json:"mediaType"`
+	Layers        []struct {
+		MediaType   string            `json:"mediaType"`
+		Size        int64             `json:"size"`
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
@@ -0,0 +1,157 @@
+package dockerhub
+
+import (
+	"condenser/internal/registry"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// signature payloads are small json documents, refuse anything unreasonable
+	maxSignaturePayloadBytes = 1 << 20
+)
+
+// FetchSignatures returns cosign signatures attached to the manifest digest.
+// cosign stores them as an OCI artifact tagged sha256-<hex>.sig in the same repository.
+func (s *RegistryDockerHub) FetchSignatures(signatureParameter registry.RegistrySignatureModel) ([]registry.SignatureArtifact, error) {
+	imageRef, err := s.parseImageRef(signatureParameter.Image)
+	if err != nil {
+		return nil, err
+	}
+	hexPart, ok := strings.CutPrefix(signatureParameter.ManifestDigest, "sha256:")
+	if !ok || hexPart == "" {
+		return nil, fmt.Errorf("only sha256 digest supported: %s", signatureParameter.ManifestDigest)
+	}
+
+	ctx := context.Background()
+	httpClient := &http.Client{Timeout: 60 * time.Second}
+
+	token, err := s.authorize(ctx, httpClient, imageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	sigRef := imageRef
+	sigRef.reference = "sha256-" + hexPart + ".sig"
+	manifestBytes, found, err := s.fetchArtifactManifest(ctx, httpClient, sigRef, token)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return []registry.SignatureArtifact{}, nil
+	}
+
+	var m signatureManifest
+	if err := json.Unmarshal(manifestBytes, &m); err != nil {
+		return nil, err
+	}
+
+	artifacts := []registry.SignatureArtifact{}
+	for _, l := range m.Layers {
+		sig := l.Annotations[cosignSignatureAnnotation]
+		if sig == "" {
+			continue
+		}
+		if l.Size > maxSignaturePayloadBytes {
+			return nil, fmt.Errorf("signature payload too large: %s", l.Digest)
+		}
+		payload, err := s.fetchBlobBytes(ctx, httpClient, imageRef, token, l.Digest)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, registry.SignatureArtifact{
+			Digest:    l.Digest,
+			Payload:   payload,
+			Signature: sig,
+		})
+	}
+	return artifacts, nil
+}
+
+func (s *RegistryDockerHub) authorize(ctx context.Context, client *http.Client, imageRef imageRefParts) (string, error) {
+	realm, service, err := s.getBearerChallenge(ctx, client, imageRef.registry)
+	if err != nil {
+		return "", err
+	}
+	if realm == "" || service == "" {
+		return "", nil
+	}
+	scope := fmt.Sprintf("repository:%s:pull", imageRef.repository)
+	return s.fetchToken(ctx, client, realm, service, scope)
+}
+
+// fetchArtifactManifest is fetchManifest for optional artifacts: a missing tag is not an error.
+func (s *RegistryDockerHub) fetchArtifactManifest(ctx context.Context, client *http.Client, ref imageRefParts, token string) ([]byte, bool, error) {
+	u := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.registry, ref.repository, ref.reference)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	req.Header.Set("Accept", strings.Join([]string{
+		"application/vnd.oci.image.manifest.v1+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+	}, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, false, fmt.Errorf("manifest fetch failed: %d: %s", resp.StatusCode, string(b))
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+func (s *RegistryDockerHub) fetchBlobBytes(ctx context.Context, client *http.Client, ref imageRefParts, token, digest string) ([]byte, error) {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return nil, fmt.Errorf("only sha256 digest supported: %s", digest)
+	}
+	u := fmt.Sprintf("https://%s/v2/%s/blobs/%s", ref.registry, ref.repository, digest)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("blob fetch failed: %d: %s", resp.StatusCode, string(b))
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxSignaturePayloadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxSignaturePayloadBytes {
+		return nil, fmt.Errorf("blob too large: %s", digest)
+	}
+
+	sum := sha256.Sum256(b)
+	if got := hex.EncodeToString(sum[:]); got != strings.TrimPrefix(digest, "sha256:") {
+		return nil, fmt.Errorf("digest mismatch: want %s got %s", digest, got)
+	}
+	return b, nil
+}
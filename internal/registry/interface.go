@@ -2,4 +2,5 @@ package registry
 
 type RegistryHandler interface {
 	PullImage(pullParameter RegistryPullModel) (repository, reference, bundlePath, configPath, rootfsPath string, err error)
+	FetchSignatures(signatureParameter RegistrySignatureModel) ([]SignatureArtifact, error)
 }
@@ -1,7 +1,48 @@
 package registry
 
 type RegistryPullModel struct {
-	Image string
-	Os    string
-	Arch  string
+	Image                  string
+	Os                     string
+	Arch                   string
+	Variant                string             // optional, picked from a manifest list when set
+	MaxConcurrentDownloads int                // 0 uses the registry default
+	Progress               func(PullProgress) // optional, called for every progress event
+
+	// optional, reports whether another reference still uses a bundle
+	// directory; such a bundle is never pulled over, the pull goes next to it
+	BundleInUse func(bundlePath string) bool
+}
+
+// pull progress status
+const (
+	PullStatusResolving        = "Resolving"
+	PullStatusWaitingForPull   = "Waiting for concurrent pull"
+	PullStatusWaiting          = "Waiting"
+	PullStatusAlreadyExists    = "Already exists"
+	PullStatusDownloading      = "Downloading"
+	PullStatusResuming         = "Resuming"
+	PullStatusRetrying         = "Retrying"
+	PullStatusVerifying        = "Verifying checksum"
+	PullStatusDownloadComplete = "Download complete"
+	PullStatusExtracting       = "Extracting"
+	PullStatusPullComplete     = "Pull complete"
+)
+
+type PullProgress struct {
+	Id      string // short blob id, empty for image level events
+	Status  string
+	Current int64
+	Total   int64
+	Message string
+}
+
+type RegistrySignatureModel struct {
+	Image          string
+	ManifestDigest string
+}
+
+type SignatureArtifact struct {
+	Digest    string // digest of the signed payload blob
+	Payload   []byte // cosign simple signing payload
+	Signature string // base64 signature over the payload
 }
@@ -3,6 +3,7 @@ package droplet
 import (
 	"condenser/internal/runtime"
 	"condenser/internal/utils"
+	"context"
 	"fmt"
 	"slices"
 	"strconv"
@@ -226,8 +227,17 @@ func (h *DropletHandler) Exec(execParameter runtime.ExecModel) error {
 		}
 	}
 	args = append(args, execParameter.Entrypoint...)
-	runtimeExec := h.commandFactory.Command(runtimePath, args...)
+	ctx := context.Background()
+	if execParameter.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, execParameter.Timeout)
+		defer cancel()
+	}
+	runtimeExec := h.commandFactory.CommandContext(ctx, runtimePath, args...)
 	out, err := runtimeExec.CombineOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("droplet exec timed out after %s", execParameter.Timeout)
+	}
 	if err != nil {
 		msg := strings.TrimSpace(string(out))
 		if msg == "" {
@@ -1,5 +1,7 @@
 package runtime
 
+import "time"
+
 type SpecModel struct {
 	Rootfs    string
 	Cwd       string
@@ -59,4 +61,7 @@ type ExecModel struct {
 	ContainerId string
 	Entrypoint  []string
 	Tty         bool
+
+	// the exec process is killed after Timeout, zero waits for it
+	Timeout time.Duration
 }
@@ -0,0 +1,78 @@
+package bcm
+
+import (
+	"fmt"
+	"time"
+)
+
+func NewBcmManager(bcmStore *BcmStore) *BcmManager {
+	return &BcmManager{
+		bcmStore: bcmStore,
+	}
+}
+
+type BcmManager struct {
+	bcmStore *BcmStore
+}
+
+func (m *BcmManager) StoreEntry(entry CacheEntry) error {
+	return m.bcmStore.withLock(func(st *BuildCacheState) error {
+		now := time.Now()
+		if old, ok := st.Entries[entry.Key]; ok {
+			entry.CreatedAt = old.CreatedAt
+			entry.UsageCount = old.UsageCount
+		} else {
+			entry.CreatedAt = now
+		}
+		entry.LastUsedAt = now
+		st.Entries[entry.Key] = entry
+		return nil
+	})
+}
+
+func (m *BcmManager) GetEntry(key string) (CacheEntry, error) {
+	var entry CacheEntry
+	err := m.bcmStore.withRLock(func(st *BuildCacheState) error {
+		e, ok := st.Entries[key]
+		if !ok {
+			return fmt.Errorf("cache key=%s not found", key)
+		}
+		entry = e
+		return nil
+	})
+	return entry, err
+}
+
+func (m *BcmManager) TouchEntry(key string) error {
+	return m.bcmStore.withLock(func(st *BuildCacheState) error {
+		e, ok := st.Entries[key]
+		if !ok {
+			return fmt.Errorf("cache key=%s not found", key)
+		}
+		e.UsageCount++
+		e.LastUsedAt = time.Now()
+		st.Entries[key] = e
+		return nil
+	})
+}
+
+func (m *BcmManager) RemoveEntry(key string) error {
+	return m.bcmStore.withLock(func(st *BuildCacheState) error {
+		if _, ok := st.Entries[key]; !ok {
+			return fmt.Errorf("cache key=%s not found", key)
+		}
+		delete(st.Entries, key)
+		return nil
+	})
+}
+
+func (m *BcmManager) GetEntryList() ([]CacheEntry, error) {
+	var list []CacheEntry
+	err := m.bcmStore.withRLock(func(st *BuildCacheState) error {
+		for _, e := range st.Entries {
+			list = append(list, e)
+		}
+		return nil
+	})
+	return list, err
+}
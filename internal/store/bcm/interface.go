@@ -0,0 +1,13 @@
+package bcm
+
+type BcmStoreHandler interface {
+	SetBuildCacheState() error
+}
+
+type BcmHandler interface {
+	StoreEntry(entry CacheEntry) error
+	GetEntry(key string) (CacheEntry, error)
+	TouchEntry(key string) error
+	RemoveEntry(key string) error
+	GetEntryList() ([]CacheEntry, error)
+}
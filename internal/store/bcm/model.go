@@ -0,0 +1,21 @@
+package bcm
+
+import "time"
+
+// CacheEntry is the filesystem snapshot of one build step.
+type CacheEntry struct {
+	// sha256 over the parent key, the instruction and the content it used
+	Key          string    `json:"key"`
+	Parent       string    `json:"parent"`
+	CreatedBy    string    `json:"createdBy"`
+	SnapshotPath string    `json:"snapshotPath"`
+	SizeBytes    int64     `json:"sizeBytes"`
+	UsageCount   int       `json:"usageCount"`
+	CreatedAt    time.Time `json:"createdAt"`
+	LastUsedAt   time.Time `json:"lastUsedAt"`
+}
+
+type BuildCacheState struct {
+	Version string                `json:"version"`
+	Entries map[string]CacheEntry `json:"entries"`
+}
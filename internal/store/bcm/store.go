@@ -0,0 +1,144 @@
+package bcm
+
+import (
+	"condenser/internal/utils"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+func NewBcmStore(path string) *BcmStore {
+	return &BcmStore{
+		path:              path,
+		filesystemHandler: utils.NewFilesystemExecutor(),
+	}
+}
+
+type BcmStore struct {
+	path              string
+	mu                sync.Mutex
+	filesystemHandler utils.FilesystemHandler
+}
+
+func (s *BcmStore) withLock(fn func(st *BuildCacheState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lockPath := s.path + ".lock"
+	if err := s.filesystemHandler.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	lf, err := s.filesystemHandler.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	defer lf.Close()
+
+	if err := s.filesystemHandler.Flock(int(lf.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer s.filesystemHandler.Flock(int(lf.Fd()), syscall.LOCK_UN)
+
+	st, err := s.loadOrInit()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(st); err != nil {
+		return err
+	}
+
+	return s.atomicSave(st)
+}
+
+func (s *BcmStore) withRLock(fn func(st *BuildCacheState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lockPath := s.path + ".lock"
+	if err := s.filesystemHandler.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	lf, err := s.filesystemHandler.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	defer lf.Close()
+
+	if err := s.filesystemHandler.Flock(int(lf.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer s.filesystemHandler.Flock(int(lf.Fd()), syscall.LOCK_UN)
+
+	st, err := s.loadOrInit()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(st); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *BcmStore) loadOrInit() (*BuildCacheState, error) {
+	b, err := s.filesystemHandler.ReadFile(s.path)
+	if err != nil {
+		if s.filesystemHandler.IsNotExist(err) {
+			return &BuildCacheState{
+				Version: "0.1.0",
+				Entries: map[string]CacheEntry{},
+			}, nil
+		}
+		return nil, err
+	}
+
+	var st BuildCacheState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("build cache state json broken: %w", err)
+	}
+	if st.Entries == nil {
+		st.Entries = map[string]CacheEntry{}
+	}
+	return &st, nil
+}
+
+func (s *BcmStore) atomicSave(st *BuildCacheState) error {
+	tmp := s.path + ".tmp"
+
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	f, err := s.filesystemHandler.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.filesystemHandler.Rename(tmp, s.path)
+}
+
+func (s *BcmStore) SetBuildCacheState() error {
+	return s.withLock(func(st *BuildCacheState) error {
+		st.Version = "0.1.0"
+		return nil
+	})
+}
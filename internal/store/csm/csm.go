@@ -5,6 +5,9 @@ import (
 	"time"
 )
 
+// quota project ids start high, clear of ids an admin may have assigned by hand
+const quotaProjectIdBase uint32 = 100000
+
 func NewCsmManager(csmStore *CsmStore) *CsmManager {
 	return &CsmManager{
 		csmStore: csmStore,
@@ -108,6 +111,46 @@ func (m *CsmManager) UpdateSpiffe(containerId string, spiffe string) error {
 	})
 }
 
+func (m *CsmManager) UpdateImageDigest(containerId string, digest string) error {
+	return m.csmStore.withLock(func(st *ContainerState) error {
+		c, ok := st.Containers[containerId]
+		if !ok {
+			return fmt.Errorf("containerId=%s not found", containerId)
+		}
+		c.ImageDigest = digest
+		st.Containers[containerId] = c
+		return nil
+	})
+}
+
+// SetDiskQuota records the upper dir quota of a container and returns the
+// quota project id reserved for it, unique among the stored containers.
+func (m *CsmManager) SetDiskQuota(containerId string, limitBytes int64) (uint32, error) {
+	var projectId uint32
+	err := m.csmStore.withLock(func(st *ContainerState) error {
+		c, ok := st.Containers[containerId]
+		if !ok {
+			return fmt.Errorf("containerId=%s not found", containerId)
+		}
+		projectId = c.QuotaProjectId
+		if projectId == 0 {
+			used := map[uint32]bool{}
+			for _, other := range st.Containers {
+				used[other.QuotaProjectId] = true
+			}
+			projectId = quotaProjectIdBase
+			for used[projectId] {
+				projectId++
+			}
+		}
+		c.DiskQuotaBytes = limitBytes
+		c.QuotaProjectId = projectId
+		st.Containers[containerId] = c
+		return nil
+	})
+	return projectId, err
+}
+
 func (m *CsmManager) GetContainerList() ([]ContainerInfo, error) {
 	var containerList []ContainerInfo
 	err := m.csmStore.withRLock(func(st *ContainerState) error {
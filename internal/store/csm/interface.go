@@ -10,6 +10,8 @@ type CsmHandler interface {
 	UpdateContainer(containerId string, state string, pid int) error
 	UpdateExitStatus(containerId string, exitCode int, reason string, message string) error
 	UpdateSpiffe(containerId string, spiffe string) error
+	UpdateImageDigest(containerId string, digest string) error
+	SetDiskQuota(containerId string, limitBytes int64) (uint32, error)
 	GetContainerList() ([]ContainerInfo, error)
 	GetContainerById(containerId string) (ContainerInfo, error)
 	GetContainersByPodId(podId string) ([]ContainerInfo, error)
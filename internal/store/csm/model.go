@@ -16,6 +16,7 @@ type ContainerInfo struct {
 	Tty           bool              `json:"tty"`
 	Repository    string            `json:"imageRepository"`
 	Reference     string            `json:"imageReference"`
+	ImageDigest   string            `json:"imageDigest,omitempty"`
 	Command       []string          `json:"command"`
 	BottleId      string            `json:"bottleId,omitempty"`
 	CreatingAt    time.Time         `json:"creatingAt"`
@@ -26,6 +27,10 @@ type ContainerInfo struct {
 	Labels        map[string]string `json:"labels"`      // CRI required
 	Annotaions    map[string]string `json:"annotations"` // CRI required
 	Attemp        uint32            `json:"attempt"`     // CRI required
+
+	// upper dir quota, the project id tags the upper dir for the filesystem
+	DiskQuotaBytes int64  `json:"diskQuotaBytes,omitempty"`
+	QuotaProjectId uint32 `json:"quotaProjectId,omitempty"`
 }
 
 type ContainerState struct {
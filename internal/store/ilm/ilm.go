@@ -4,6 +4,9 @@ import (
 	"condenser/internal/utils"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -19,8 +22,13 @@ type IlmManager struct {
 	filesystemHandler utils.FilesystemHandler
 }
 
-func (m *IlmManager) StoreImage(repository, reference, bundlePath, configPath, rootfsPath string) error {
-	return m.ilmStore.withLock(func(st *ImageLayerState) error {
+// StoreImage records the bundle of one platform of a reference. A platform
+// pulled from the manifest digest the reference already has is added next
+// to the stored ones, anything else replaces the reference. The bundles the
+// reference no longer points to are returned.
+func (m *IlmManager) StoreImage(repository, reference, platform, bundlePath, configPath, rootfsPath, manifestDigest string) ([]string, error) {
+	var dropped []string
+	err := m.ilmStore.withLock(func(st *ImageLayerState) error {
 		if st.Repositories == nil {
 			st.Repositories = map[string]RepositoryInfo{}
 		}
@@ -34,16 +42,74 @@ func (m *IlmManager) StoreImage(repository, reference, bundlePath, configPath, r
 			repoInfo.References = map[string]ReferenceInfo{}
 		}
 
-		repoInfo.References[reference] = ReferenceInfo{
-			BundlePath: bundlePath,
-			ConfigPath: configPath,
-			RootfsPath: rootfsPath,
-			CreatedAt:  time.Now(),
+		existing, ok := repoInfo.References[reference]
+		sameImage := ok && manifestDigest != "" && existing.ManifestDigest == manifestDigest &&
+			platform != "" && existing.Platform != ""
+		switch {
+		case sameImage && existing.Platform == platform:
+			if existing.BundlePath != bundlePath {
+				dropped = append(dropped, existing.BundlePath)
+			}
+			existing.BundlePath = bundlePath
+			existing.ConfigPath = configPath
+			existing.RootfsPath = rootfsPath
+			repoInfo.References[reference] = existing
+		case sameImage:
+			if existing.Platforms == nil {
+				existing.Platforms = map[string]PlatformBundle{}
+			}
+			if old, ok := existing.Platforms[platform]; ok && old.BundlePath != bundlePath {
+				dropped = append(dropped, old.BundlePath)
+			}
+			existing.Platforms[platform] = PlatformBundle{
+				Platform:   platform,
+				BundlePath: bundlePath,
+				ConfigPath: configPath,
+				RootfsPath: rootfsPath,
+				CreatedAt:  time.Now(),
+			}
+			repoInfo.References[reference] = existing
+		default:
+			if ok {
+				for _, p := range bundlePaths(existing) {
+					if p != bundlePath {
+						dropped = append(dropped, p)
+					}
+				}
+			}
+			repoInfo.References[reference] = ReferenceInfo{
+				BundlePath:     bundlePath,
+				ConfigPath:     configPath,
+				RootfsPath:     rootfsPath,
+				CreatedAt:      time.Now(),
+				ManifestDigest: manifestDigest,
+				Platform:       platform,
+			}
 		}
 
 		st.Repositories[repository] = repoInfo
 		return nil
 	})
+	return dropped, err
+}
+
+// ResolvePlatform returns the bundle of a reference for platform, an empty
+// platform selects the bundle stored first.
+func (s *IlmManager) ResolvePlatform(repository string, reference string, platform string) (PlatformBundle, error) {
+	var bundle PlatformBundle
+	err := s.ilmStore.withRLock(func(st *ImageLayerState) error {
+		_, info, ok := lookupReference(st, repository, reference)
+		if !ok {
+			return fmt.Errorf("%s:%s not found", repository, reference)
+		}
+		var found bool
+		bundle, found = selectPlatform(info, platform)
+		if !found {
+			return fmt.Errorf("%s:%s has no bundle for platform %s", repository, reference, platform)
+		}
+		return nil
+	})
+	return bundle, err
 }
 
 func (m *IlmManager) RemoveImage(repository string, reference string) error {
@@ -60,11 +126,150 @@ func (m *IlmManager) RemoveImage(repository string, reference string) error {
 	})
 }
 
+// TagImage points dstReference at the bundles of the source image. The
+// bundles a replaced target pointed to are returned, as StoreImage does.
+func (m *IlmManager) TagImage(srcRepository, srcReference, dstRepository, dstReference string) ([]string, error) {
+	var dropped []string
+	err := m.ilmStore.withLock(func(st *ImageLayerState) error {
+		_, srcInfo, ok := lookupReference(st, srcRepository, srcReference)
+		if !ok {
+			return fmt.Errorf("%s:%s not found", srcRepository, srcReference)
+		}
+
+		dstRepo, ok := st.Repositories[dstRepository]
+		if !ok {
+			dstRepo = RepositoryInfo{
+				References: map[string]ReferenceInfo{},
+			}
+		}
+		if dstRepo.References == nil {
+			dstRepo.References = map[string]ReferenceInfo{}
+		}
+
+		if existing, ok := dstRepo.References[dstReference]; ok {
+			kept := bundlePaths(srcInfo)
+			for _, p := range bundlePaths(existing) {
+				if !slices.Contains(kept, p) {
+					dropped = append(dropped, p)
+				}
+			}
+		}
+
+		// the new reference shares the bundle of the source image
+		dstInfo := srcInfo
+		dstInfo.CreatedAt = time.Now()
+		dstRepo.References[dstReference] = dstInfo
+
+		st.Repositories[dstRepository] = dstRepo
+		return nil
+	})
+	return dropped, err
+}
+
+func (m *IlmManager) TouchImage(repository string, reference string) error {
+	return m.ilmStore.withLock(func(st *ImageLayerState) error {
+		key, info, ok := lookupReference(st, repository, reference)
+		if !ok {
+			return fmt.Errorf("%s:%s not found", repository, reference)
+		}
+		info.LastUsedAt = time.Now()
+		st.Repositories[repository].References[key] = info
+		return nil
+	})
+}
+
+func (m *IlmManager) SetProvenance(repository string, reference string, provenancePath string) error {
+	return m.ilmStore.withLock(func(st *ImageLayerState) error {
+		key, info, ok := lookupReference(st, repository, reference)
+		if !ok {
+			return fmt.Errorf("%s:%s not found", repository, reference)
+		}
+		info.ProvenancePath = provenancePath
+		st.Repositories[repository].References[key] = info
+		return nil
+	})
+}
+
+func (m *IlmManager) SetConfigDigest(repository string, reference string, configDigest string) error {
+	return m.ilmStore.withLock(func(st *ImageLayerState) error {
+		key, info, ok := lookupReference(st, repository, reference)
+		if !ok {
+			return fmt.Errorf("%s:%s not found", repository, reference)
+		}
+		info.ConfigDigest = configDigest
+		st.Repositories[repository].References[key] = info
+		return nil
+	})
+}
+
+func (m *IlmManager) SetReferencePolicy(policy ReferencePolicy) error {
+	return m.ilmStore.withLock(func(st *ImageLayerState) error {
+		st.ReferencePolicy = policy
+		return nil
+	})
+}
+
+func (s *IlmManager) GetReferencePolicy() (ReferencePolicy, error) {
+	var policy ReferencePolicy
+	err := s.ilmStore.withRLock(func(st *ImageLayerState) error {
+		policy = st.ReferencePolicy
+		return nil
+	})
+	return policy, err
+}
+
+func (s *IlmManager) ResolveReference(repository string, reference string) (string, error) {
+	var resolved string
+	err := s.ilmStore.withRLock(func(st *ImageLayerState) error {
+		key, _, ok := lookupReference(st, repository, reference)
+		if !ok {
+			return fmt.Errorf("%s:%s not found", repository, reference)
+		}
+		resolved = key
+		return nil
+	})
+	return resolved, err
+}
+
+func (m *IlmManager) SetGcPolicy(policy GcPolicy) error {
+	return m.ilmStore.withLock(func(st *ImageLayerState) error {
+		st.GcPolicy = policy
+		return nil
+	})
+}
+
+func (s *IlmManager) GetGcPolicy() (GcPolicy, error) {
+	var policy GcPolicy
+	err := s.ilmStore.withRLock(func(st *ImageLayerState) error {
+		policy = st.GcPolicy
+		return nil
+	})
+	return policy, err
+}
+
+func (s *IlmManager) CountBundleReferences(bundlePath string) (int, error) {
+	var count int
+	err := s.ilmStore.withRLock(func(st *ImageLayerState) error {
+		for _, refs := range st.Repositories {
+			for _, info := range refs.References {
+				for _, p := range bundlePaths(info) {
+					if p == bundlePath {
+						count++
+					}
+				}
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
 func (s *IlmManager) GetBundlePath(repository string, reference string) (string, error) {
 	var bundlePath string
 
 	err := s.ilmStore.withRLock(func(st *ImageLayerState) error {
-		bundlePath = st.Repositories[repository].References[reference].BundlePath
+		_, info, _ := lookupReference(st, repository, reference)
+		bundlePath = info.BundlePath
 		if bundlePath == "" {
 			return fmt.Errorf("bundle path not found.")
 		}
@@ -77,7 +282,8 @@ func (s *IlmManager) GetConfigPath(repository string, reference string) (string,
 	var configPath string
 
 	err := s.ilmStore.withRLock(func(st *ImageLayerState) error {
-		configPath = st.Repositories[repository].References[reference].ConfigPath
+		_, info, _ := lookupReference(st, repository, reference)
+		configPath = info.ConfigPath
 		if configPath == "" {
 			return fmt.Errorf("config path not found.")
 		}
@@ -90,7 +296,8 @@ func (s *IlmManager) GetRootfsPath(repository string, reference string) (string,
 	var rootfsPath string
 
 	err := s.ilmStore.withRLock(func(st *ImageLayerState) error {
-		rootfsPath = st.Repositories[repository].References[reference].RootfsPath
+		_, info, _ := lookupReference(st, repository, reference)
+		rootfsPath = info.RootfsPath
 		if rootfsPath == "" {
 			return fmt.Errorf("bundle path not found.")
 		}
@@ -105,11 +312,7 @@ func (s *IlmManager) GetImageList() ([]ImageInfo, error) {
 	err := s.ilmStore.withRLock(func(st *ImageLayerState) error {
 		for repo, refs := range st.Repositories {
 			for ref, info := range refs.References {
-				imageList = append(imageList, ImageInfo{
-					Repository: repo,
-					Reference:  ref,
-					CreatedAt:  info.CreatedAt,
-				})
+				imageList = append(imageList, imageInfoOf(repo, ref, info))
 			}
 		}
 		return nil
@@ -120,19 +323,11 @@ func (s *IlmManager) GetImageList() ([]ImageInfo, error) {
 func (s *IlmManager) GetImageInfo(repository string, reference string) (ImageInfo, error) {
 	var info ImageInfo
 	err := s.ilmStore.withRLock(func(st *ImageLayerState) error {
-		repo, ok := st.Repositories[repository]
-		if !ok {
-			return fmt.Errorf("%s:%s not found", repository, reference)
-		}
-		refInfo, ok := repo.References[reference]
+		key, refInfo, ok := lookupReference(st, repository, reference)
 		if !ok {
 			return fmt.Errorf("%s:%s not found", repository, reference)
 		}
-		info = ImageInfo{
-			Repository: repository,
-			Reference:  reference,
-			CreatedAt:  refInfo.CreatedAt,
-		}
+		info = imageInfoOf(repository, key, refInfo)
 		return nil
 	})
 	return info, err
@@ -145,20 +340,9 @@ func (s *IlmManager) IsImageExist(imageRepo, imageRef string) bool {
 	)
 
 	s.ilmStore.withRLock(func(st *ImageLayerState) error {
-		for repo, refs := range st.Repositories {
-			if repo != imageRepo {
-				continue
-			}
-			for ref, info := range refs.References {
-				if ref != imageRef {
-					continue
-				}
-				found = true
-				configPath = info.ConfigPath
-				return nil
-			}
-		}
-		found = false
+		_, info, ok := lookupReference(st, imageRepo, imageRef)
+		found = ok
+		configPath = info.ConfigPath
 		return nil
 	})
 
@@ -176,3 +360,99 @@ func (s *IlmManager) IsImageExist(imageRepo, imageRef string) bool {
 	}
 	return true
 }
+
+// lookupReference finds a reference by its key, or, for digest references,
+// by the manifest digest a tag resolved to when it was pulled or the config
+// digest of a built image.
+func lookupReference(st *ImageLayerState, repository string, reference string) (string, ReferenceInfo, bool) {
+	repo, ok := st.Repositories[repository]
+	if !ok {
+		return "", ReferenceInfo{}, false
+	}
+	if info, ok := repo.References[reference]; ok {
+		return reference, info, true
+	}
+	if !strings.HasPrefix(reference, "sha256:") {
+		return "", ReferenceInfo{}, false
+	}
+	// pick the newest when several tags point at the same digest
+	var (
+		foundKey  string
+		foundInfo ReferenceInfo
+		found     bool
+	)
+	for key, info := range repo.References {
+		if info.ManifestDigest != reference && info.ConfigDigest != reference {
+			continue
+		}
+		if !found || info.CreatedAt.After(foundInfo.CreatedAt) {
+			foundKey, foundInfo, found = key, info, true
+		}
+	}
+	return foundKey, foundInfo, found
+}
+
+func imageInfoOf(repository string, reference string, info ReferenceInfo) ImageInfo {
+	imageInfo := ImageInfo{
+		Repository:     repository,
+		Reference:      reference,
+		BundlePath:     info.BundlePath,
+		ManifestDigest: info.ManifestDigest,
+		ProvenancePath: info.ProvenancePath,
+		Platform:       info.Platform,
+		BundlePaths:    bundlePaths(info),
+		CreatedAt:      info.CreatedAt,
+		LastUsedAt:     info.LastUsedAt,
+	}
+	if info.Platform != "" {
+		imageInfo.Platforms = append(imageInfo.Platforms, info.Platform)
+	}
+	imageInfo.Platforms = append(imageInfo.Platforms, sortedPlatforms(info)...)
+	return imageInfo
+}
+
+// bundlePaths lists the bundles of every platform of a reference.
+func bundlePaths(info ReferenceInfo) []string {
+	paths := []string{info.BundlePath}
+	for _, p := range sortedPlatforms(info) {
+		paths = append(paths, info.Platforms[p].BundlePath)
+	}
+	return paths
+}
+
+func sortedPlatforms(info ReferenceInfo) []string {
+	platforms := make([]string, 0, len(info.Platforms))
+	for p := range info.Platforms {
+		platforms = append(platforms, p)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// selectPlatform picks the bundle matching platform. The variant is only
+// compared when both sides name one, so "linux/arm64" finds "linux/arm64/v8".
+func selectPlatform(info ReferenceInfo, platform string) (PlatformBundle, bool) {
+	first := PlatformBundle{
+		Platform:   info.Platform,
+		BundlePath: info.BundlePath,
+		ConfigPath: info.ConfigPath,
+		RootfsPath: info.RootfsPath,
+		CreatedAt:  info.CreatedAt,
+	}
+	if platform == "" {
+		return first, true
+	}
+	want, err := utils.ParsePlatform(platform)
+	if err != nil {
+		return PlatformBundle{}, false
+	}
+	if have, err := utils.ParsePlatform(info.Platform); err == nil && want.Matches(have) {
+		return first, true
+	}
+	for _, p := range sortedPlatforms(info) {
+		if have, err := utils.ParsePlatform(p); err == nil && want.Matches(have) {
+			return info.Platforms[p], true
+		}
+	}
+	return PlatformBundle{}, false
+}
@@ -5,8 +5,19 @@ type IlmStoreHandler interface {
 }
 
 type IlmHandler interface {
-	StoreImage(repository, reference, bundlePath, configPath, rootfsPath string) error
+	StoreImage(repository, reference, platform, bundlePath, configPath, rootfsPath, manifestDigest string) ([]string, error)
+	ResolvePlatform(repository string, reference string, platform string) (PlatformBundle, error)
 	RemoveImage(repository string, reference string) error
+	TagImage(srcRepository, srcReference, dstRepository, dstReference string) ([]string, error)
+	CountBundleReferences(bundlePath string) (int, error)
+	TouchImage(repository string, reference string) error
+	SetProvenance(repository string, reference string, provenancePath string) error
+	SetConfigDigest(repository string, reference string, configDigest string) error
+	SetGcPolicy(policy GcPolicy) error
+	GetGcPolicy() (GcPolicy, error)
+	SetReferencePolicy(policy ReferencePolicy) error
+	GetReferencePolicy() (ReferencePolicy, error)
+	ResolveReference(repository string, reference string) (string, error)
 	GetBundlePath(repository string, reference string) (string, error)
 	GetConfigPath(repository string, reference string) (string, error)
 	GetRootfsPath(repository string, reference string) (string, error)
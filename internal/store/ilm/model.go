@@ -7,19 +7,61 @@ type ReferenceInfo struct {
 	ConfigPath string    `json:"configPath"`
 	RootfsPath string    `json:"rootfsPath"`
 	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt,omitempty"`
+	// digest of the manifest (or index) the reference resolved to at pull time
+	ManifestDigest string `json:"manifestDigest,omitempty"`
+	// provenance document of a locally built image
+	ProvenancePath string `json:"provenancePath,omitempty"`
+	// digest of config.json of a locally built image, which has no manifest.
+	// name@sha256:... resolves to it as it does to ManifestDigest
+	ConfigDigest string `json:"configDigest,omitempty"`
+	// platform of the bundle above, "os/arch[/variant]", empty for images
+	// stored before platforms were recorded
+	Platform string `json:"platform,omitempty"`
+	// further platforms of the reference, all pulled from the same manifest digest
+	Platforms map[string]PlatformBundle `json:"platforms,omitempty"`
+}
+
+type PlatformBundle struct {
+	Platform   string    `json:"platform"`
+	BundlePath string    `json:"bundlePath"`
+	ConfigPath string    `json:"configPath"`
+	RootfsPath string    `json:"rootfsPath"`
+	CreatedAt  time.Time `json:"createdAt"`
 }
 
 type RepositoryInfo struct {
 	References map[string]ReferenceInfo `json:"references"`
 }
 
+// GcPolicy controls the background disk high-watermark image GC.
+type GcPolicy struct {
+	Enabled       bool `json:"enabled"`
+	HighWatermark int  `json:"highWatermark"` // disk usage percent that triggers GC
+	LowWatermark  int  `json:"lowWatermark"`  // disk usage percent GC evicts down to
+}
+
+// ReferencePolicy controls which image references are accepted.
+type ReferencePolicy struct {
+	RequireDigest bool `json:"requireDigest"` // reject tag-only references
+}
+
 type ImageLayerState struct {
-	Version      string                    `json:"version"`
-	Repositories map[string]RepositoryInfo `json:"repositories"`
+	Version         string                    `json:"version"`
+	Repositories    map[string]RepositoryInfo `json:"repositories"`
+	GcPolicy        GcPolicy                  `json:"gcPolicy"`
+	ReferencePolicy ReferencePolicy           `json:"referencePolicy"`
 }
 
 type ImageInfo struct {
-	Repository string
-	Reference  string
-	CreatedAt  time.Time
+	Repository     string
+	Reference      string
+	BundlePath     string
+	ManifestDigest string
+	ProvenancePath string
+	Platform       string   // platform of BundlePath
+	Platforms      []string // every stored platform, Platform first
+	BundlePaths    []string // bundles of every platform, BundlePath first
+	CreatedAt      time.Time
+	LastUsedAt     time.Time
 }
@@ -0,0 +1,24 @@
+package nsm
+
+type NsmStoreHandler interface {
+	SetNamespaceState() error
+}
+
+type NsmHandler interface {
+	StoreNamespace(info NamespaceInfo) error
+	GetNamespace(name string) (NamespaceInfo, error)
+	GetNamespaceList() ([]NamespaceInfo, error)
+	UpdateNamespacePhase(name string, phase string) error
+	RemoveNamespace(name string) error
+	CheckNamespaceActive(name string) error
+	StoreResourceQuota(resourceQuotaId string, info ResourceQuotaInfo) error
+	GetResourceQuota(resourceQuotaId string) (ResourceQuotaInfo, error)
+	GetResourceQuotaByName(name, namespace string) (ResourceQuotaInfo, error)
+	GetResourceQuotaList() ([]ResourceQuotaInfo, error)
+	RemoveResourceQuota(resourceQuotaId string) error
+	StoreLimitRange(limitRangeId string, info LimitRangeInfo) error
+	GetLimitRange(limitRangeId string) (LimitRangeInfo, error)
+	GetLimitRangeByName(name, namespace string) (LimitRangeInfo, error)
+	GetLimitRangeList() ([]LimitRangeInfo, error)
+	RemoveLimitRange(limitRangeId string) error
+}
@@ -0,0 +1,61 @@
+package nsm
+
+import "time"
+
+const DefaultNamespace = "default"
+
+const (
+	NamespacePhaseActive      = "Active"
+	NamespacePhaseTerminating = "Terminating" // its resources are being removed
+)
+
+type NamespaceInfo struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Phase     string            `json:"phase"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// ResourceList keeps the quantities as written in the manifest, such as
+// "500m" or "1Gi".
+type ResourceList struct {
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// QuotaHard are the limits of a resource quota, unset ones are not
+// enforced.
+type QuotaHard struct {
+	Pods     *int         `json:"pods,omitempty"`
+	Requests ResourceList `json:"requests"`
+	Limits   ResourceList `json:"limits"`
+}
+
+type ResourceQuotaInfo struct {
+	ResourceQuotaId string    `json:"resourceQuotaId"`
+	Name            string    `json:"name"`
+	Namespace       string    `json:"namespace"`
+	Hard            QuotaHard `json:"hard"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// LimitRangeInfo holds the container limits of a namespace, defaults are
+// filled into containers that do not set them.
+type LimitRangeInfo struct {
+	LimitRangeId   string       `json:"limitRangeId"`
+	Name           string       `json:"name"`
+	Namespace      string       `json:"namespace"`
+	Default        ResourceList `json:"default"`
+	DefaultRequest ResourceList `json:"defaultRequest"`
+	Max            ResourceList `json:"max"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+}
+
+type NamespaceState struct {
+	Version        string                       `json:"version"`
+	Namespaces     map[string]NamespaceInfo     `json:"namespaces"` // by name
+	ResourceQuotas map[string]ResourceQuotaInfo `json:"resourceQuotas"`
+	LimitRanges    map[string]LimitRangeInfo    `json:"limitRanges"`
+}
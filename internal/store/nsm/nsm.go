@@ -0,0 +1,219 @@
+package nsm
+
+import (
+	"fmt"
+	"time"
+)
+
+func NewNsmManager(nsmStore *NsmStore) *NsmManager {
+	return &NsmManager{
+		nsmStore: nsmStore,
+	}
+}
+
+type NsmManager struct {
+	nsmStore *NsmStore
+}
+
+// StoreNamespace creates the namespace or replaces its labels, the phase
+// and creation time of an existing one are kept.
+func (m *NsmManager) StoreNamespace(info NamespaceInfo) error {
+	return m.nsmStore.withLock(func(st *NamespaceState) error {
+		if existing, ok := st.Namespaces[info.Name]; ok {
+			existing.Labels = info.Labels
+			st.Namespaces[info.Name] = existing
+			return nil
+		}
+		info.Phase = NamespacePhaseActive
+		info.CreatedAt = time.Now()
+		st.Namespaces[info.Name] = info
+		return nil
+	})
+}
+
+func (m *NsmManager) GetNamespace(name string) (NamespaceInfo, error) {
+	var info NamespaceInfo
+	err := m.nsmStore.withRLock(func(st *NamespaceState) error {
+		n, ok := st.Namespaces[name]
+		if !ok {
+			return fmt.Errorf("namespace %s not found", name)
+		}
+		info = n
+		return nil
+	})
+	return info, err
+}
+
+func (m *NsmManager) GetNamespaceList() ([]NamespaceInfo, error) {
+	var list []NamespaceInfo
+	err := m.nsmStore.withRLock(func(st *NamespaceState) error {
+		for _, n := range st.Namespaces {
+			list = append(list, n)
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (m *NsmManager) UpdateNamespacePhase(name string, phase string) error {
+	return m.nsmStore.withLock(func(st *NamespaceState) error {
+		n, ok := st.Namespaces[name]
+		if !ok {
+			return fmt.Errorf("namespace %s not found", name)
+		}
+		n.Phase = phase
+		st.Namespaces[name] = n
+		return nil
+	})
+}
+
+func (m *NsmManager) RemoveNamespace(name string) error {
+	return m.nsmStore.withLock(func(st *NamespaceState) error {
+		if _, ok := st.Namespaces[name]; !ok {
+			return fmt.Errorf("namespace %s not found", name)
+		}
+		delete(st.Namespaces, name)
+		return nil
+	})
+}
+
+// CheckNamespaceActive fails for a namespace that does not exist or is
+// being deleted, nothing new may be created in it.
+func (m *NsmManager) CheckNamespaceActive(name string) error {
+	n, err := m.GetNamespace(name)
+	if err != nil {
+		return err
+	}
+	if n.Phase == NamespacePhaseTerminating {
+		return fmt.Errorf("namespace %s is terminating", name)
+	}
+	return nil
+}
+
+// StoreResourceQuota creates or replaces the quota, the creation time of
+// a replaced one is kept.
+func (m *NsmManager) StoreResourceQuota(resourceQuotaId string, info ResourceQuotaInfo) error {
+	return m.nsmStore.withLock(func(st *NamespaceState) error {
+		now := time.Now()
+		info.ResourceQuotaId = resourceQuotaId
+		info.CreatedAt = now
+		if existing, ok := st.ResourceQuotas[resourceQuotaId]; ok {
+			info.CreatedAt = existing.CreatedAt
+		}
+		info.UpdatedAt = now
+		st.ResourceQuotas[resourceQuotaId] = info
+		return nil
+	})
+}
+
+func (m *NsmManager) GetResourceQuota(resourceQuotaId string) (ResourceQuotaInfo, error) {
+	var info ResourceQuotaInfo
+	err := m.nsmStore.withRLock(func(st *NamespaceState) error {
+		q, ok := st.ResourceQuotas[resourceQuotaId]
+		if !ok {
+			return fmt.Errorf("resourceQuotaId=%s not found", resourceQuotaId)
+		}
+		info = q
+		return nil
+	})
+	return info, err
+}
+
+func (m *NsmManager) GetResourceQuotaByName(name, namespace string) (ResourceQuotaInfo, error) {
+	var info ResourceQuotaInfo
+	err := m.nsmStore.withRLock(func(st *NamespaceState) error {
+		for _, q := range st.ResourceQuotas {
+			if q.Name == name && q.Namespace == namespace {
+				info = q
+				return nil
+			}
+		}
+		return fmt.Errorf("resourcequota %s/%s not found", namespace, name)
+	})
+	return info, err
+}
+
+func (m *NsmManager) GetResourceQuotaList() ([]ResourceQuotaInfo, error) {
+	var list []ResourceQuotaInfo
+	err := m.nsmStore.withRLock(func(st *NamespaceState) error {
+		for _, q := range st.ResourceQuotas {
+			list = append(list, q)
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (m *NsmManager) RemoveResourceQuota(resourceQuotaId string) error {
+	return m.nsmStore.withLock(func(st *NamespaceState) error {
+		if _, ok := st.ResourceQuotas[resourceQuotaId]; !ok {
+			return fmt.Errorf("resourceQuotaId=%s not found", resourceQuotaId)
+		}
+		delete(st.ResourceQuotas, resourceQuotaId)
+		return nil
+	})
+}
+
+// StoreLimitRange creates or replaces the limit range, the creation time
+// of a replaced one is kept.
+func (m *NsmManager) StoreLimitRange(limitRangeId string, info LimitRangeInfo) error {
+	return m.nsmStore.withLock(func(st *NamespaceState) error {
+		now := time.Now()
+		info.LimitRangeId = limitRangeId
+		info.CreatedAt = now
+		if existing, ok := st.LimitRanges[limitRangeId]; ok {
+			info.CreatedAt = existing.CreatedAt
+		}
+		info.UpdatedAt = now
+		st.LimitRanges[limitRangeId] = info
+		return nil
+	})
+}
+
+func (m *NsmManager) GetLimitRange(limitRangeId string) (LimitRangeInfo, error) {
+	var info LimitRangeInfo
+	err := m.nsmStore.withRLock(func(st *NamespaceState) error {
+		l, ok := st.LimitRanges[limitRangeId]
+		if !ok {
+			return fmt.Errorf("limitRangeId=%s not found", limitRangeId)
+		}
+		info = l
+		return nil
+	})
+	return info, err
+}
+
+func (m *NsmManager) GetLimitRangeByName(name, namespace string) (LimitRangeInfo, error) {
+	var info LimitRangeInfo
+	err := m.nsmStore.withRLock(func(st *NamespaceState) error {
+		for _, l := range st.LimitRanges {
+			if l.Name == name && l.Namespace == namespace {
+				info = l
+				return nil
+			}
+		}
+		return fmt.Errorf("limitrange %s/%s not found", namespace, name)
+	})
+	return info, err
+}
+
+func (m *NsmManager) GetLimitRangeList() ([]LimitRangeInfo, error) {
+	var list []LimitRangeInfo
+	err := m.nsmStore.withRLock(func(st *NamespaceState) error {
+		for _, l := range st.LimitRanges {
+			list = append(list, l)
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (m *NsmManager) RemoveLimitRange(limitRangeId string) error {
+	return m.nsmStore.withLock(func(st *NamespaceState) error {
+		if _, ok := st.LimitRanges[limitRangeId]; !ok {
+			return fmt.Errorf("limitRangeId=%s not found", limitRangeId)
+		}
+		delete(st.LimitRanges, limitRangeId)
+		return nil
+	})
+}
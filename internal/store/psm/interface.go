@@ -16,10 +16,22 @@ type PsmHandler interface {
 	GetReplicaSetList() ([]ReplicaSetInfo, error)
 	IsTemplateReferenced(templateId string) (bool, error)
 	UpdateReplicaSetReplicas(replicaSetId string, replicas int) error
+	UpdateReplicaSet(replicaSetId string, spec ReplicaSetSpec) error
 	RemoveReplicaSet(replicaSetId string) error
+	UpdateReplicaSetRevision(replicaSetId string, revision int) error
+	StoreDeployment(deploymentId string, spec DeploymentSpec) error
+	GetDeployment(deploymentId string) (DeploymentInfo, error)
+	GetDeploymentList() ([]DeploymentInfo, error)
+	UpdateDeploymentRevision(deploymentId string, revision int) error
+	RemoveDeployment(deploymentId string) error
 	RemovePod(podId string) error
 	UpdatePod(podId string, state string) error
 	UpdatePodStoppedByUser(podId string, stopped bool) error
+	UpdatePodFailed(podId string, reason string, message string) error
+	UpdateInitContainerStatus(podId string, status InitContainerStatus) error
+	UpdatePodReadiness(podId string, ready bool, statuses []ContainerStatus) error
+	RecordContainerRestart(podId, name string, termination ContainerTermination, backoffSeconds int) error
+	UpdateContainerTermination(podId, name string, termination ContainerTermination, waiting string) error
 	UpdatePodNamespaces(ownerPid int, podId, networkNS, ipcNS, utsNS, userNS string) error
 	ResetPodNamespaces(podId string) error
 	GetPodList() ([]PodInfo, error)
@@ -1,6 +1,9 @@
 package psm
 
-import "time"
+import (
+	"condenser/internal/utils"
+	"time"
+)
 
 type PodInfo struct {
 	PodId         string            `json:"podId"`
@@ -20,6 +23,50 @@ type PodInfo struct {
 	StartedAt     time.Time         `json:"startedAt"`
 	StoppedAt     time.Time         `json:"stoppedAt"`
 	StoppedByUser bool              `json:"stoppedByUser"`
+
+	Reason         string                `json:"reason,omitempty"` // why the pod failed
+	Message        string                `json:"message,omitempty"`
+	InitContainers []InitContainerStatus `json:"initContainers,omitempty"`
+
+	// kept up to date by the probe controller
+	Ready             bool              `json:"ready"`
+	ContainerStatuses []ContainerStatus `json:"containerStatuses,omitempty"`
+}
+
+// ContainerStatus is what the probes found out about a pod member.
+type ContainerStatus struct {
+	Name         string `json:"name"`
+	ContainerId  string `json:"containerId"`
+	Started      bool   `json:"started"`
+	Ready        bool   `json:"ready"`
+	RestartCount int    `json:"restartCount"`
+	LastProbe    string `json:"lastProbe,omitempty"` // message of the last failed probe
+
+	// restart bookkeeping of the pod controller
+	LastTermination *ContainerTermination `json:"lastTermination,omitempty"`
+	Waiting         string                `json:"waiting,omitempty"` // CrashLoopBackOff while a restart is held back
+	BackoffSeconds  int                   `json:"backoffSeconds,omitempty"`
+}
+
+// ContainerTermination is how a pod member last exited.
+type ContainerTermination struct {
+	ExitCode   int       `json:"exitCode"`
+	Reason     string    `json:"reason,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// InitContainerStatus is the outcome of one init container run of a pod.
+type InitContainerStatus struct {
+	Name        string    `json:"name"`
+	ContainerId string    `json:"containerId"`
+	State       string    `json:"state"` // running, completed, failed
+	ExitCode    int       `json:"exitCode"`
+	Reason      string    `json:"reason,omitempty"`
+	Message     string    `json:"message,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+	FinishedAt  time.Time `json:"finishedAt"`
 }
 
 type PodTemplateSpec struct {
@@ -32,6 +79,42 @@ type PodTemplateSpec struct {
 	Labels      map[string]string       `json:"labels,omitempty"`
 	Annotations map[string]string       `json:"annotations,omitempty"`
 	Containers  []ContainerTemplateSpec `json:"containers,omitempty"`
+
+	// run one after another to completion before the containers start
+	InitContainers []ContainerTemplateSpec `json:"initContainers,omitempty"`
+	RestartPolicy  string                  `json:"restartPolicy,omitempty"` // Always (default), OnFailure or Never
+
+	// projected before the pod starts, the members mount them through Mount
+	Volumes []VolumeSpec `json:"volumes,omitempty"`
+
+	// added to the container limits of the pod cgroup
+	Overhead *ResourceList `json:"overhead,omitempty"`
+}
+
+// VolumeSpec is a configMap, secret or emptyDir volume, exactly one
+// source is set.
+type VolumeSpec struct {
+	Name      string                 `json:"name"`
+	ConfigMap *ConfigMapVolumeSource `json:"configMap,omitempty"`
+	Secret    *SecretVolumeSource    `json:"secret,omitempty"`
+	EmptyDir  *EmptyDirVolumeSource  `json:"emptyDir,omitempty"`
+}
+
+// EmptyDirVolumeSource is a scratch directory per pod instance, on disk or
+// on a tmpfs when Medium is "Memory".
+type EmptyDirVolumeSource struct {
+	Medium    string `json:"medium,omitempty"`
+	SizeLimit string `json:"sizeLimit,omitempty"`
+}
+
+type ConfigMapVolumeSource struct {
+	Name     string `json:"name"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+type SecretVolumeSource struct {
+	SecretName string `json:"secretName"`
+	Optional   bool   `json:"optional,omitempty"`
 }
 
 type ContainerTemplateSpec struct {
@@ -43,6 +126,88 @@ type ContainerTemplateSpec struct {
 	Env     []string `json:"env,omitempty"`
 	Network string   `json:"network,omitempty"`
 	Tty     bool     `json:"tty,omitempty"`
+
+	LivenessProbe  *ProbeSpec `json:"livenessProbe,omitempty"`
+	ReadinessProbe *ProbeSpec `json:"readinessProbe,omitempty"`
+	StartupProbe   *ProbeSpec `json:"startupProbe,omitempty"`
+
+	// resolved when the container is created, Env wins over both
+	EnvValueFrom []EnvVarSource  `json:"envValueFrom,omitempty"`
+	EnvFrom      []EnvFromSource `json:"envFrom,omitempty"`
+
+	Resources *ResourceRequirements `json:"resources,omitempty"`
+}
+
+// ResourceRequirements keeps the quantities as written in the manifest,
+// the cgroup values are derived when the pod starts.
+type ResourceRequirements struct {
+	Requests ResourceList `json:"requests,omitempty"`
+	Limits   ResourceList `json:"limits,omitempty"`
+}
+
+type ResourceList struct {
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// EnvVarSource sets one variable from a key, exactly one ref is set.
+type EnvVarSource struct {
+	Name            string           `json:"name"`
+	ConfigMapKeyRef *KeySelectorSpec `json:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *KeySelectorSpec `json:"secretKeyRef,omitempty"`
+}
+
+type KeySelectorSpec struct {
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// EnvFromSource sets a variable for every key, exactly one ref is set.
+type EnvFromSource struct {
+	Prefix       string       `json:"prefix,omitempty"`
+	ConfigMapRef *NameRefSpec `json:"configMapRef,omitempty"`
+	SecretRef    *NameRefSpec `json:"secretRef,omitempty"`
+}
+
+type NameRefSpec struct {
+	Name     string `json:"name"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// ProbeSpec follows the kubernetes probe, exactly one handler is set.
+// Zero values take the kubernetes defaults.
+type ProbeSpec struct {
+	Exec                *ExecAction      `json:"exec,omitempty"`
+	HTTPGet             *HTTPGetAction   `json:"httpGet,omitempty"`
+	TCPSocket           *TCPSocketAction `json:"tcpSocket,omitempty"`
+	InitialDelaySeconds int              `json:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int              `json:"periodSeconds,omitempty"`
+	TimeoutSeconds      int              `json:"timeoutSeconds,omitempty"`
+	SuccessThreshold    int              `json:"successThreshold,omitempty"`
+	FailureThreshold    int              `json:"failureThreshold,omitempty"`
+}
+
+type ExecAction struct {
+	Command []string `json:"command"`
+}
+
+type HTTPGetAction struct {
+	Path        string       `json:"path,omitempty"`
+	Port        int          `json:"port"`
+	Host        string       `json:"host,omitempty"`
+	Scheme      string       `json:"scheme,omitempty"`
+	HTTPHeaders []HTTPHeader `json:"httpHeaders,omitempty"`
+}
+
+type HTTPHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type TCPSocketAction struct {
+	Port int    `json:"port"`
+	Host string `json:"host,omitempty"`
 }
 
 type PodTemplateInfo struct {
@@ -57,6 +222,13 @@ type ReplicaSetSpec struct {
 	Replicas   int               `json:"replicas"`
 	TemplateId string            `json:"templateId"`
 	Selector   map[string]string `json:"selector,omitempty"`
+
+	MatchExpressions []utils.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+
+	// set on replica sets a deployment manages
+	DeploymentId string `json:"deploymentId,omitempty"`
+	TemplateHash string `json:"templateHash,omitempty"`
+	Revision     int    `json:"revision,omitempty"`
 }
 
 type ReplicaSetInfo struct {
@@ -65,9 +237,38 @@ type ReplicaSetInfo struct {
 	CreatedAt    time.Time      `json:"createdAt"`
 }
 
+type DeploymentSpec struct {
+	Name                 string             `json:"name"`
+	Namespace            string             `json:"namespace"`
+	Replicas             int                `json:"replicas"`
+	Selector             map[string]string  `json:"selector,omitempty"`
+	Template             PodTemplateSpec    `json:"template"`
+	Strategy             DeploymentStrategy `json:"strategy"`
+	RevisionHistoryLimit int                `json:"revisionHistoryLimit"`
+
+	MatchExpressions []utils.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+// DeploymentStrategy is RollingUpdate or Recreate. maxSurge and
+// maxUnavailable are a count or a percentage of the replicas ("25%").
+type DeploymentStrategy struct {
+	Type           string `json:"type"`
+	MaxSurge       string `json:"maxSurge,omitempty"`
+	MaxUnavailable string `json:"maxUnavailable,omitempty"`
+}
+
+type DeploymentInfo struct {
+	DeploymentId string         `json:"deploymentId"`
+	Spec         DeploymentSpec `json:"spec"`
+	Revision     int            `json:"revision"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+}
+
 type PodState struct {
 	Version      string                     `json:"version"`
 	Pods         map[string]PodInfo         `json:"pods"`
 	PodTemplates map[string]PodTemplateInfo `json:"podTemplates"`
 	ReplicaSets  map[string]ReplicaSetInfo  `json:"replicaSets"`
+	Deployments  map[string]DeploymentInfo  `json:"deployments"`
 }
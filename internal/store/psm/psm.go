@@ -88,6 +88,16 @@ func (m *PsmManager) AddContainerToPodTemplate(podId string, spec ContainerTempl
 		replaced := false
 		for i, c := range tpl.Spec.Containers {
 			if c.Name == spec.Name && spec.Name != "" {
+				// probes only come with the pod spec, containers created
+				// into the pod do not know about them
+				if spec.LivenessProbe == nil && spec.ReadinessProbe == nil && spec.StartupProbe == nil {
+					spec.LivenessProbe = c.LivenessProbe
+					spec.ReadinessProbe = c.ReadinessProbe
+					spec.StartupProbe = c.StartupProbe
+				}
+				if spec.Resources == nil {
+					spec.Resources = c.Resources
+				}
 				tpl.Spec.Containers[i] = spec
 				replaced = true
 				break
@@ -123,6 +133,19 @@ func (m *PsmManager) StoreReplicaSet(replicaSetId string, spec ReplicaSetSpec) e
 	})
 }
 
+// UpdateReplicaSet replaces the spec of an existing replica set.
+func (m *PsmManager) UpdateReplicaSet(replicaSetId string, spec ReplicaSetSpec) error {
+	return m.psmStore.withLock(func(st *PodState) error {
+		info, ok := st.ReplicaSets[replicaSetId]
+		if !ok {
+			return fmt.Errorf("replicaSetId=%s not found", replicaSetId)
+		}
+		info.Spec = spec
+		st.ReplicaSets[replicaSetId] = info
+		return nil
+	})
+}
+
 func (m *PsmManager) GetReplicaSet(replicaSetId string) (ReplicaSetInfo, error) {
 	var rs ReplicaSetInfo
 	err := m.psmStore.withRLock(func(st *PodState) error {
@@ -186,6 +209,78 @@ func (m *PsmManager) RemoveReplicaSet(replicaSetId string) error {
 	})
 }
 
+func (m *PsmManager) UpdateReplicaSetRevision(replicaSetId string, revision int) error {
+	return m.psmStore.withLock(func(st *PodState) error {
+		rs, ok := st.ReplicaSets[replicaSetId]
+		if !ok {
+			return fmt.Errorf("replicaSetId=%s not found", replicaSetId)
+		}
+		rs.Spec.Revision = revision
+		st.ReplicaSets[replicaSetId] = rs
+		return nil
+	})
+}
+
+func (m *PsmManager) StoreDeployment(deploymentId string, spec DeploymentSpec) error {
+	return m.psmStore.withLock(func(st *PodState) error {
+		now := time.Now()
+		d, ok := st.Deployments[deploymentId]
+		if !ok {
+			d = DeploymentInfo{DeploymentId: deploymentId, CreatedAt: now}
+		}
+		d.Spec = spec
+		d.UpdatedAt = now
+		st.Deployments[deploymentId] = d
+		return nil
+	})
+}
+
+func (m *PsmManager) GetDeployment(deploymentId string) (DeploymentInfo, error) {
+	var d DeploymentInfo
+	err := m.psmStore.withRLock(func(st *PodState) error {
+		info, ok := st.Deployments[deploymentId]
+		if !ok {
+			return fmt.Errorf("deploymentId=%s not found", deploymentId)
+		}
+		d = info
+		return nil
+	})
+	return d, err
+}
+
+func (m *PsmManager) GetDeploymentList() ([]DeploymentInfo, error) {
+	var list []DeploymentInfo
+	err := m.psmStore.withRLock(func(st *PodState) error {
+		for _, d := range st.Deployments {
+			list = append(list, d)
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (m *PsmManager) UpdateDeploymentRevision(deploymentId string, revision int) error {
+	return m.psmStore.withLock(func(st *PodState) error {
+		d, ok := st.Deployments[deploymentId]
+		if !ok {
+			return fmt.Errorf("deploymentId=%s not found", deploymentId)
+		}
+		d.Revision = revision
+		st.Deployments[deploymentId] = d
+		return nil
+	})
+}
+
+func (m *PsmManager) RemoveDeployment(deploymentId string) error {
+	return m.psmStore.withLock(func(st *PodState) error {
+		if _, ok := st.Deployments[deploymentId]; !ok {
+			return fmt.Errorf("deploymentId=%s not found", deploymentId)
+		}
+		delete(st.Deployments, deploymentId)
+		return nil
+	})
+}
+
 func (m *PsmManager) RemovePod(podId string) error {
 	return m.psmStore.withLock(func(st *PodState) error {
 		if _, ok := st.Pods[podId]; !ok {
@@ -204,6 +299,9 @@ func (m *PsmManager) UpdatePod(podId string, state string) error {
 		}
 
 		p.State = state
+		// the failure reason only describes the failed state
+		p.Reason = ""
+		p.Message = ""
 		switch state {
 		case "created":
 			p.CreatedAt = time.Now()
@@ -218,6 +316,109 @@ func (m *PsmManager) UpdatePod(podId string, state string) error {
 	})
 }
 
+func (m *PsmManager) UpdatePodFailed(podId string, reason string, message string) error {
+	return m.psmStore.withLock(func(st *PodState) error {
+		p, ok := st.Pods[podId]
+		if !ok {
+			return fmt.Errorf("podId=%s not found", podId)
+		}
+		p.State = "failed"
+		p.Reason = reason
+		p.Message = message
+		p.StoppedAt = time.Now()
+		st.Pods[podId] = p
+		return nil
+	})
+}
+
+func (m *PsmManager) UpdateInitContainerStatus(podId string, status InitContainerStatus) error {
+	return m.psmStore.withLock(func(st *PodState) error {
+		p, ok := st.Pods[podId]
+		if !ok {
+			return fmt.Errorf("podId=%s not found", podId)
+		}
+		replaced := false
+		for i, c := range p.InitContainers {
+			if c.Name == status.Name {
+				p.InitContainers[i] = status
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			p.InitContainers = append(p.InitContainers, status)
+		}
+		st.Pods[podId] = p
+		return nil
+	})
+}
+
+func (m *PsmManager) UpdatePodReadiness(podId string, ready bool, statuses []ContainerStatus) error {
+	return m.psmStore.withLock(func(st *PodState) error {
+		p, ok := st.Pods[podId]
+		if !ok {
+			return fmt.Errorf("podId=%s not found", podId)
+		}
+		// the restart bookkeeping belongs to the pod controller
+		prev := map[string]ContainerStatus{}
+		for _, c := range p.ContainerStatuses {
+			prev[c.Name] = c
+		}
+		merged := make([]ContainerStatus, 0, len(statuses))
+		for _, c := range statuses {
+			if old, ok := prev[c.Name]; ok {
+				c.RestartCount = old.RestartCount
+				c.LastTermination = old.LastTermination
+				c.Waiting = old.Waiting
+				c.BackoffSeconds = old.BackoffSeconds
+			}
+			merged = append(merged, c)
+		}
+		p.Ready = ready
+		p.ContainerStatuses = merged
+		st.Pods[podId] = p
+		return nil
+	})
+}
+
+func (m *PsmManager) RecordContainerRestart(podId, name string, termination ContainerTermination, backoffSeconds int) error {
+	return m.updateContainerStatus(podId, name, func(c *ContainerStatus) {
+		c.RestartCount++
+		c.LastTermination = &termination
+		c.Waiting = ""
+		c.BackoffSeconds = backoffSeconds
+	})
+}
+
+func (m *PsmManager) UpdateContainerTermination(podId, name string, termination ContainerTermination, waiting string) error {
+	return m.updateContainerStatus(podId, name, func(c *ContainerStatus) {
+		c.LastTermination = &termination
+		c.Waiting = waiting
+		c.Ready = false
+	})
+}
+
+func (m *PsmManager) updateContainerStatus(podId, name string, update func(c *ContainerStatus)) error {
+	return m.psmStore.withLock(func(st *PodState) error {
+		p, ok := st.Pods[podId]
+		if !ok {
+			return fmt.Errorf("podId=%s not found", podId)
+		}
+		for i := range p.ContainerStatuses {
+			if p.ContainerStatuses[i].Name == name {
+				update(&p.ContainerStatuses[i])
+				st.Pods[podId] = p
+				return nil
+			}
+		}
+		c := ContainerStatus{Name: name}
+		update(&c)
+		p.ContainerStatuses = append(p.ContainerStatuses, c)
+		st.Pods[podId] = p
+		return nil
+	})
+}
+
 func (m *PsmManager) UpdatePodStoppedByUser(podId string, stopped bool) error {
 	return m.psmStore.withLock(func(st *PodState) error {
 		p, ok := st.Pods[podId]
@@ -96,6 +96,7 @@ func (s *PsmStore) loadOrInit() (*PodState, error) {
 				Pods:         map[string]PodInfo{},
 				PodTemplates: map[string]PodTemplateInfo{},
 				ReplicaSets:  map[string]ReplicaSetInfo{},
+				Deployments:  map[string]DeploymentInfo{},
 			}, nil
 		}
 		return nil, err
@@ -114,6 +115,9 @@ func (s *PsmStore) loadOrInit() (*PodState, error) {
 	if st.ReplicaSets == nil {
 		st.ReplicaSets = map[string]ReplicaSetInfo{}
 	}
+	if st.Deployments == nil {
+		st.Deployments = map[string]DeploymentInfo{}
+	}
 	return &st, nil
 }
 
@@ -156,6 +160,9 @@ func (s *PsmStore) SetPodState() error {
 		if st.ReplicaSets == nil {
 			st.ReplicaSets = map[string]ReplicaSetInfo{}
 		}
+		if st.Deployments == nil {
+			st.Deployments = map[string]DeploymentInfo{}
+		}
 		return nil
 	})
 }
@@ -0,0 +1,14 @@
+package rsm
+
+type RsmHandler interface {
+	StoreConfigMap(configMapId string, info ConfigMapInfo) error
+	GetConfigMap(configMapId string) (ConfigMapInfo, error)
+	GetConfigMapByName(name, namespace string) (ConfigMapInfo, error)
+	GetConfigMapList() ([]ConfigMapInfo, error)
+	RemoveConfigMap(configMapId string) error
+	StoreSecret(secretId string, info SecretInfo) error
+	GetSecret(secretId string) (SecretInfo, error)
+	GetSecretByName(name, namespace string) (SecretInfo, error)
+	GetSecretList() ([]SecretInfo, error)
+	RemoveSecret(secretId string) error
+}
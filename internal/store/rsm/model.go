@@ -0,0 +1,33 @@
+package rsm
+
+import "time"
+
+type ConfigMapInfo struct {
+	ConfigMapId string            `json:"configMapId"`
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+	BinaryData  map[string][]byte `json:"binaryData,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+// SecretInfo keeps the data sealed, only the key names are in the clear.
+type SecretInfo struct {
+	SecretId      string            `json:"secretId"`
+	Name          string            `json:"name"`
+	Namespace     string            `json:"namespace"`
+	Type          string            `json:"type"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Keys          []string          `json:"keys"`
+	EncryptedData string            `json:"encryptedData"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	UpdatedAt     time.Time         `json:"updatedAt"`
+}
+
+type ResourceState struct {
+	Version    string                   `json:"version"`
+	ConfigMaps map[string]ConfigMapInfo `json:"configMaps"`
+	Secrets    map[string]SecretInfo    `json:"secrets"`
+}
@@ -0,0 +1,144 @@
+package rsm
+
+import (
+	"fmt"
+	"time"
+)
+
+func NewRsmManager(rsmStore *RsmStore) *RsmManager {
+	return &RsmManager{
+		rsmStore: rsmStore,
+	}
+}
+
+type RsmManager struct {
+	rsmStore *RsmStore
+}
+
+// StoreConfigMap creates or replaces the config map, the creation time of
+// a replaced one is kept.
+func (m *RsmManager) StoreConfigMap(configMapId string, info ConfigMapInfo) error {
+	return m.rsmStore.withLock(func(st *ResourceState) error {
+		now := time.Now()
+		info.ConfigMapId = configMapId
+		info.CreatedAt = now
+		if existing, ok := st.ConfigMaps[configMapId]; ok {
+			info.CreatedAt = existing.CreatedAt
+		}
+		info.UpdatedAt = now
+		st.ConfigMaps[configMapId] = info
+		return nil
+	})
+}
+
+func (m *RsmManager) GetConfigMap(configMapId string) (ConfigMapInfo, error) {
+	var info ConfigMapInfo
+	err := m.rsmStore.withRLock(func(st *ResourceState) error {
+		c, ok := st.ConfigMaps[configMapId]
+		if !ok {
+			return fmt.Errorf("configMapId=%s not found", configMapId)
+		}
+		info = c
+		return nil
+	})
+	return info, err
+}
+
+func (m *RsmManager) GetConfigMapByName(name, namespace string) (ConfigMapInfo, error) {
+	var info ConfigMapInfo
+	err := m.rsmStore.withRLock(func(st *ResourceState) error {
+		for _, c := range st.ConfigMaps {
+			if c.Name == name && c.Namespace == namespace {
+				info = c
+				return nil
+			}
+		}
+		return fmt.Errorf("configmap %s/%s not found", namespace, name)
+	})
+	return info, err
+}
+
+func (m *RsmManager) GetConfigMapList() ([]ConfigMapInfo, error) {
+	var list []ConfigMapInfo
+	err := m.rsmStore.withRLock(func(st *ResourceState) error {
+		for _, c := range st.ConfigMaps {
+			list = append(list, c)
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (m *RsmManager) RemoveConfigMap(configMapId string) error {
+	return m.rsmStore.withLock(func(st *ResourceState) error {
+		if _, ok := st.ConfigMaps[configMapId]; !ok {
+			return fmt.Errorf("configMapId=%s not found", configMapId)
+		}
+		delete(st.ConfigMaps, configMapId)
+		return nil
+	})
+}
+
+// StoreSecret creates or replaces the secret, the creation time of a
+// replaced one is kept.
+func (m *RsmManager) StoreSecret(secretId string, info SecretInfo) error {
+	return m.rsmStore.withLock(func(st *ResourceState) error {
+		now := time.Now()
+		info.SecretId = secretId
+		info.CreatedAt = now
+		if existing, ok := st.Secrets[secretId]; ok {
+			info.CreatedAt = existing.CreatedAt
+		}
+		info.UpdatedAt = now
+		st.Secrets[secretId] = info
+		return nil
+	})
+}
+
+func (m *RsmManager) GetSecret(secretId string) (SecretInfo, error) {
+	var info SecretInfo
+	err := m.rsmStore.withRLock(func(st *ResourceState) error {
+		s, ok := st.Secrets[secretId]
+		if !ok {
+			return fmt.Errorf("secretId=%s not found", secretId)
+		}
+		info = s
+		return nil
+	})
+	return info, err
+}
+
+func (m *RsmManager) GetSecretByName(name, namespace string) (SecretInfo, error) {
+	var info SecretInfo
+	err := m.rsmStore.withRLock(func(st *ResourceState) error {
+		for _, s := range st.Secrets {
+			if s.Name == name && s.Namespace == namespace {
+				info = s
+				return nil
+			}
+		}
+		return fmt.Errorf("secret %s/%s not found", namespace, name)
+	})
+	return info, err
+}
+
+func (m *RsmManager) GetSecretList() ([]SecretInfo, error) {
+	var list []SecretInfo
+	err := m.rsmStore.withRLock(func(st *ResourceState) error {
+		for _, s := range st.Secrets {
+			list = append(list, s)
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (m *RsmManager) RemoveSecret(secretId string) error {
+	return m.rsmStore.withLock(func(st *ResourceState) error {
+		if _, ok := st.Secrets[secretId]; !ok {
+			return fmt.Errorf("secretId=%s not found", secretId)
+		}
+		delete(st.Secrets, secretId)
+		return nil
+	})
+}
@@ -2,6 +2,7 @@ package ssm
 
 type SsmHandler interface {
 	StoreService(serviceId string, spec ServiceInfo) error
+	UpdateService(serviceId string, spec ServiceInfo) error
 	GetServiceList() ([]ServiceInfo, error)
 	GetServiceById(serviceId string) (ServiceInfo, error)
 	RemoveService(serviceId string) error
@@ -1,6 +1,9 @@
 package ssm
 
-import "time"
+import (
+	"condenser/internal/utils"
+	"time"
+)
 
 type ServicePort struct {
 	Port       int    `json:"port"`
@@ -15,6 +18,9 @@ type ServiceInfo struct {
 	Selector  map[string]string `json:"selector"`
 	Ports     []ServicePort     `json:"ports"`
 	CreatedAt time.Time         `json:"createdAt"`
+
+	MatchExpressions []utils.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+	Labels           map[string]string                `json:"labels,omitempty"`
 }
 
 type ServiceState struct {
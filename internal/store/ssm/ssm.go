@@ -24,6 +24,22 @@ func (m *SsmManager) StoreService(serviceId string, spec ServiceInfo) error {
 	})
 }
 
+// UpdateService replaces the labels, selector and ports of an existing service.
+func (m *SsmManager) UpdateService(serviceId string, spec ServiceInfo) error {
+	return m.ssmStore.withLock(func(st *ServiceState) error {
+		s, ok := st.Services[serviceId]
+		if !ok {
+			return fmt.Errorf("serviceId=%s not found", serviceId)
+		}
+		s.Selector = spec.Selector
+		s.MatchExpressions = spec.MatchExpressions
+		s.Labels = spec.Labels
+		s.Ports = spec.Ports
+		st.Services[serviceId] = s
+		return nil
+	})
+}
+
 func (m *SsmManager) GetServiceList() ([]ServiceInfo, error) {
 	var list []ServiceInfo
 	err := m.ssmStore.withRLock(func(st *ServiceState) error {
@@ -0,0 +1,15 @@
+package tsm
+
+type TsmStoreHandler interface {
+	SetTrustState() error
+}
+
+type TsmHandler interface {
+	StoreKey(keyId string, key PublicKeyInfo) error
+	RemoveKey(keyId string) error
+	GetKeyList() ([]PublicKeyInfo, error)
+	GetKeyById(keyId string) (PublicKeyInfo, error)
+	StorePolicy(policyId string, policy TrustPolicy) error
+	RemovePolicy(policyId string) error
+	GetPolicyList() ([]TrustPolicy, error)
+}
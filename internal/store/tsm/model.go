@@ -0,0 +1,26 @@
+package tsm
+
+import "time"
+
+type PublicKeyInfo struct {
+	KeyId       string    `json:"keyId"`
+	Name        string    `json:"name"`
+	Pem         string    `json:"pem"`
+	Algorithm   string    `json:"algorithm"`   // ecdsa | rsa | ed25519
+	Fingerprint string    `json:"fingerprint"` // sha256 of the DER encoded key
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type TrustPolicy struct {
+	PolicyId string `json:"policyId"`
+	// registry (docker.io), repository (docker.io/library/nginx) or prefix (ghcr.io/org/*)
+	Scope     string    `json:"scope"`
+	KeyIds    []string  `json:"keyIds"` // empty: any registered key
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type TrustState struct {
+	Version  string                   `json:"version"`
+	Keys     map[string]PublicKeyInfo `json:"keys"`
+	Policies map[string]TrustPolicy   `json:"policies"`
+}
@@ -0,0 +1,114 @@
+package tsm
+
+import (
+	"fmt"
+	"time"
+)
+
+func NewTsmManager(tsmStore *TsmStore) *TsmManager {
+	return &TsmManager{
+		tsmStore: tsmStore,
+	}
+}
+
+type TsmManager struct {
+	tsmStore *TsmStore
+}
+
+func (m *TsmManager) StoreKey(keyId string, key PublicKeyInfo) error {
+	return m.tsmStore.withLock(func(st *TrustState) error {
+		for _, k := range st.Keys {
+			if k.Name == key.Name {
+				return fmt.Errorf("key name already used: %s", key.Name)
+			}
+			if k.Fingerprint == key.Fingerprint {
+				return fmt.Errorf("key already registered: %s", k.KeyId)
+			}
+		}
+		key.KeyId = keyId
+		key.CreatedAt = time.Now()
+		st.Keys[keyId] = key
+		return nil
+	})
+}
+
+func (m *TsmManager) RemoveKey(keyId string) error {
+	return m.tsmStore.withLock(func(st *TrustState) error {
+		if _, ok := st.Keys[keyId]; !ok {
+			return fmt.Errorf("keyId=%s not found", keyId)
+		}
+		for _, p := range st.Policies {
+			for _, id := range p.KeyIds {
+				if id == keyId {
+					return fmt.Errorf("keyId=%s is referenced by trust policy %s", keyId, p.Scope)
+				}
+			}
+		}
+		delete(st.Keys, keyId)
+		return nil
+	})
+}
+
+func (m *TsmManager) GetKeyList() ([]PublicKeyInfo, error) {
+	var list []PublicKeyInfo
+	err := m.tsmStore.withRLock(func(st *TrustState) error {
+		for _, k := range st.Keys {
+			list = append(list, k)
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (m *TsmManager) GetKeyById(keyId string) (PublicKeyInfo, error) {
+	var info PublicKeyInfo
+	err := m.tsmStore.withRLock(func(st *TrustState) error {
+		k, ok := st.Keys[keyId]
+		if !ok {
+			return fmt.Errorf("keyId=%s not found", keyId)
+		}
+		info = k
+		return nil
+	})
+	return info, err
+}
+
+func (m *TsmManager) StorePolicy(policyId string, policy TrustPolicy) error {
+	return m.tsmStore.withLock(func(st *TrustState) error {
+		for _, p := range st.Policies {
+			if p.Scope == policy.Scope {
+				return fmt.Errorf("trust policy for %s already exists: %s", policy.Scope, p.PolicyId)
+			}
+		}
+		for _, id := range policy.KeyIds {
+			if _, ok := st.Keys[id]; !ok {
+				return fmt.Errorf("keyId=%s not found", id)
+			}
+		}
+		policy.PolicyId = policyId
+		policy.CreatedAt = time.Now()
+		st.Policies[policyId] = policy
+		return nil
+	})
+}
+
+func (m *TsmManager) RemovePolicy(policyId string) error {
+	return m.tsmStore.withLock(func(st *TrustState) error {
+		if _, ok := st.Policies[policyId]; !ok {
+			return fmt.Errorf("policyId=%s not found", policyId)
+		}
+		delete(st.Policies, policyId)
+		return nil
+	})
+}
+
+func (m *TsmManager) GetPolicyList() ([]TrustPolicy, error) {
+	var list []TrustPolicy
+	err := m.tsmStore.withRLock(func(st *TrustState) error {
+		for _, p := range st.Policies {
+			list = append(list, p)
+		}
+		return nil
+	})
+	return list, err
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// what a server-side apply did with a resource, as kubectl reports it
+const (
+	ApplyResultCreated    = "created"
+	ApplyResultConfigured = "configured"
+	ApplyResultUnchanged  = "unchanged"
+)
+
+// SpecChanged compares a stored and an applied spec in their JSON form,
+// so fields the store drops when empty compare equal to unset ones.
+func SpecChanged(stored, applied any) bool {
+	a, errA := json.Marshal(stored)
+	b, errB := json.Marshal(applied)
+	if errA != nil || errB != nil {
+		return true
+	}
+	return !bytes.Equal(a, b)
+}
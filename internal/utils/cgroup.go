@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	cgroupCPUPeriodUsec   = 100000
+	cgroupCPUMinQuotaUsec = 1000
+	cgroupMinShares       = 2
+	cgroupMaxShares       = 262144
+)
+
+// PodCgroupPath is the cgroup all members of a pod are nested under.
+func PodCgroupPath(podId string) string {
+	return filepath.Join(CgroupRuntimeDir, "pod-"+podId)
+}
+
+// ContainerCgroupPath is the cgroup the container process runs in. Pod
+// members sit below their pod, other containers directly under the root.
+func ContainerCgroupPath(containerId, podId string) string {
+	if podId == "" {
+		return filepath.Join(CgroupRuntimeDir, containerId)
+	}
+	return filepath.Join(PodCgroupPath(podId), containerId)
+}
+
+// ParseCPUQuantity parses kubernetes cpu quantities such as "500m", "1"
+// or "0.25" into millicores.
+func ParseCPUQuantity(s string) (int64, error) {
+	v := strings.TrimSpace(s)
+	if v == "" {
+		return 0, fmt.Errorf("invalid cpu: %q", s)
+	}
+	if milli, ok := strings.CutSuffix(v, "m"); ok {
+		n, err := strconv.ParseInt(milli, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid cpu: %q", s)
+		}
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid cpu: %q", s)
+	}
+	return int64(f * 1000), nil
+}
+
+// FormatCPUQuantity writes millicores back as a cpu quantity, whole cores
+// without a suffix.
+func FormatCPUQuantity(millis int64) string {
+	if millis%1000 == 0 {
+		return strconv.FormatInt(millis/1000, 10)
+	}
+	return strconv.FormatInt(millis, 10) + "m"
+}
+
+// CPUWeightFromMillis converts a cpu request to cpu.weight the way the
+// kubelet does, through cpu.shares of 1024 per core.
+func CPUWeightFromMillis(millis int64) uint64 {
+	shares := millis * 1024 / 1000
+	if shares < cgroupMinShares {
+		shares = cgroupMinShares
+	}
+	if shares > cgroupMaxShares {
+		shares = cgroupMaxShares
+	}
+	return uint64(1 + ((shares-cgroupMinShares)*9999)/(cgroupMaxShares-cgroupMinShares))
+}
+
+// CPUMaxFromMillis is the cpu.max line for a cpu limit.
+func CPUMaxFromMillis(millis int64) string {
+	quota := millis * cgroupCPUPeriodUsec / 1000
+	if quota < cgroupCPUMinQuotaUsec {
+		quota = cgroupCPUMinQuotaUsec
+	}
+	return fmt.Sprintf("%d %d", quota, cgroupCPUPeriodUsec)
+}
@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"context"
 	"io"
 	"os/exec"
+	"time"
 )
 
 func NewCommandFactory() *ExecCommandFactory {
@@ -16,6 +18,7 @@ func NewCommandFactory() *ExecCommandFactory {
 // the factory with a mock implementation.
 type CommandFactory interface {
 	Command(name string, args ...string) CommandExecutor
+	CommandContext(ctx context.Context, name string, args ...string) CommandExecutor
 }
 
 // execCommandFactory is the default implementation of commandFactory.
@@ -30,6 +33,15 @@ func (e *ExecCommandFactory) Command(name string, args ...string) CommandExecuto
 	return &ExecCmd{cmd: exec.Command(name, args...)}
 }
 
+// CommandContext returns a commandExecutor whose process is killed when
+// ctx is done. Wait returns shortly after even if children of the killed
+// process still hold its output pipes.
+func (e *ExecCommandFactory) CommandContext(ctx context.Context, name string, args ...string) CommandExecutor {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = time.Second
+	return &ExecCmd{cmd: cmd}
+}
+
 // commandExecutor represents a process that can be started.
 //
 // It provides a minimal surface over exec.Cmd so that command execution
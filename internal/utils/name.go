@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	dnsLabelPattern     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	dnsSubdomainPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// ValidateDNSLabel checks a name the way kubernetes does for volumes, it
+// has no '.' so it is always a single path element.
+func ValidateDNSLabel(name string) error {
+	if len(name) > 63 || !dnsLabelPattern.MatchString(name) {
+		return fmt.Errorf("invalid name %q: lowercase alphanumerics and '-' only", name)
+	}
+	return nil
+}
+
+// ValidateDNSSubdomain checks an object name the way kubernetes does for
+// config maps and secrets. The name becomes a host path element, so this
+// also keeps "..", "/" and friends out.
+func ValidateDNSSubdomain(name string) error {
+	if len(name) > 253 || !dnsSubdomainPattern.MatchString(name) {
+		return fmt.Errorf("invalid name %q: lowercase alphanumerics, '-' and '.' only", name)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ const (
 	ContainerRootDir = "/etc/raind/container"
 	ImageRootDir     = "/etc/raind/image"
 	LayerRootDir     = "/etc/raind/image/layers"
+	BuildCacheDir    = "/etc/raind/image/cache"
 
 	StoreDir      = "/etc/raind/store"
 	IpamStorePath = "/etc/raind/store/ipam.json"
@@ -15,6 +16,15 @@ const (
 	SsmStorePath  = "/etc/raind/store/ssm.json"
 	NpmStorePath  = "/etc/raind/store/npm.json"
 	BsmStorePath  = "/etc/raind/store/bsm.json"
+	TsmStorePath  = "/etc/raind/store/tsm.json"
+	BcmStorePath  = "/etc/raind/store/bcm.json"
+	RsmStorePath  = "/etc/raind/store/rsm.json"
+	NsmStorePath  = "/etc/raind/store/nsm.json"
+
+	// configMap and secret volumes, a tmpfs so secrets never reach the disk
+	ProjectedVolumeDir = "/etc/raind/projected"
+	// per pod state such as emptyDir volumes, removed with the pod
+	PodRootDir = "/etc/raind/pod"
 
 	CgroupRuntimeDir         = "/sys/fs/cgroup/raind"
 	CgroupSubtreeControlPath = "/sys/fs/cgroup/raind/cgroup.subtree_control"
@@ -28,6 +38,7 @@ const (
 	ClientKeyPath          = "/etc/raind/cert/raindClient.key"
 	HookClientCertPath     = "/etc/raind/cert/raindHookClient.crt"
 	HookClientKeyPath      = "/etc/raind/cert/raindHookClient.key"
+	SecretKeyPath          = "/etc/raind/cert/raindSecret.key"
 
 	UlogPath        = "/var/log/ulog/raind.jsonl"
 	AuditLogPath    = "/var/log/raind/raind_audit.jsonl"
@@ -37,4 +48,5 @@ const (
 
 	PodInfraImage               = "registry.k8s.io/pause:3.9"
 	PodInfraContainerNamePrefix = "condenser-pod-infra-"
+	PodInitContainerNamePrefix  = "condenser-pod-init-"
 )
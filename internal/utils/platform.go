@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"runtime"
+	"strings"
 )
 
 func HostOs() string {
@@ -18,3 +19,58 @@ func HostArch() (string, error) {
 		return "", fmt.Errorf("unsupported arch: %s", goarch)
 	}
 }
+
+// Platform is the os/arch[/variant] an image is built for.
+type Platform struct {
+	Os      string
+	Arch    string
+	Variant string
+}
+
+// ParsePlatform parses "os/arch[/variant]". Common arch aliases are normalized.
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q, expected os/arch[/variant]", s)
+	}
+	p := Platform{Os: parts[0], Arch: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	switch p.Arch {
+	case "x86_64", "x86-64":
+		p.Arch = "amd64"
+	case "aarch64":
+		p.Arch = "arm64"
+	}
+	return p, nil
+}
+
+func HostPlatform() (Platform, error) {
+	arch, err := HostArch()
+	if err != nil {
+		return Platform{}, err
+	}
+	return Platform{Os: HostOs(), Arch: arch}, nil
+}
+
+func (p Platform) String() string {
+	if p.Variant != "" {
+		return p.Os + "/" + p.Arch + "/" + p.Variant
+	}
+	return p.Os + "/" + p.Arch
+}
+
+// DirName is the platform as a path element, "linux-arm64-v8".
+func (p Platform) DirName() string {
+	return strings.ReplaceAll(p.String(), "/", "-")
+}
+
+// Matches reports whether an image for other runs where p is wanted. The
+// variant is only compared when both sides name one.
+func (p Platform) Matches(other Platform) bool {
+	if p.Os != other.Os || p.Arch != other.Arch {
+		return false
+	}
+	return p.Variant == "" || other.Variant == "" || p.Variant == other.Variant
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// project quotas go through the generic quotactl interface, which xfs and
+// ext4 (mounted with prjquota) both implement. x/sys/unix has no wrappers.
+const (
+	fsIocFsGetXattr     = 0x801c581f // _IOR('X', 31, struct fsxattr)
+	fsIocFsSetXattr     = 0x401c5820 // _IOW('X', 32, struct fsxattr)
+	fsXflagProjInherit  = 0x00000200
+	quotaSetQuota       = 0x800008
+	quotaPrjQuota       = 2
+	quotaSubCmdShift    = 8
+	quotaIfBlockLimits  = 1
+	quotaIfDqBlockBytes = 1024
+)
+
+// ErrQuotaUnsupported is returned when the filesystem below a directory
+// has no project quota enabled.
+var ErrQuotaUnsupported = errors.New("project quota is not enabled on this filesystem (xfs: mount with prjquota, ext4: enable the project feature and mount with prjquota)")
+
+type fsxattr struct {
+	Xflags     uint32
+	Extsize    uint32
+	Nextents   uint32
+	Projid     uint32
+	Cowextsize uint32
+	Pad        [8]byte
+}
+
+type ifDqblk struct {
+	BHardlimit uint64
+	BSoftlimit uint64
+	CurSpace   uint64
+	IHardlimit uint64
+	ISoftlimit uint64
+	CurInodes  uint64
+	BTime      uint64
+	ITime      uint64
+	Valid      uint32
+}
+
+// SetProjectQuota tags dir with projectId, so everything created below it
+// is charged to the project, and limits the project to limitBytes.
+func SetProjectQuota(dir string, projectId uint32, limitBytes int64) error {
+	if projectId == 0 {
+		return errors.New("project id 0 is reserved")
+	}
+	if limitBytes <= 0 {
+		return fmt.Errorf("invalid quota: %d bytes", limitBytes)
+	}
+	if err := setProjectId(dir, projectId); err != nil {
+		return err
+	}
+	// limits are in 1 KiB blocks, rounded up
+	blocks := uint64((limitBytes + quotaIfDqBlockBytes - 1) / quotaIfDqBlockBytes)
+	return setProjectLimit(dir, projectId, blocks)
+}
+
+// ClearProjectQuota lifts the limit of projectId again.
+func ClearProjectQuota(dir string, projectId uint32) error {
+	if projectId == 0 {
+		return nil
+	}
+	return setProjectLimit(dir, projectId, 0)
+}
+
+func setProjectId(dir string, projectId uint32) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var attr fsxattr
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), fsIocFsGetXattr, uintptr(unsafe.Pointer(&attr))); errno != 0 {
+		return quotaError(errno)
+	}
+	attr.Projid = projectId
+	attr.Xflags |= fsXflagProjInherit
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), fsIocFsSetXattr, uintptr(unsafe.Pointer(&attr))); errno != 0 {
+		return quotaError(errno)
+	}
+	return nil
+}
+
+func setProjectLimit(dir string, projectId uint32, blocks uint64) error {
+	device, err := blockDeviceOf(dir)
+	if err != nil {
+		return err
+	}
+	devicePtr, err := unix.BytePtrFromString(device)
+	if err != nil {
+		return err
+	}
+	dq := ifDqblk{BHardlimit: blocks, BSoftlimit: blocks, Valid: quotaIfBlockLimits}
+	cmd := quotaSetQuota<<quotaSubCmdShift | quotaPrjQuota
+	if _, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, uintptr(cmd), uintptr(unsafe.Pointer(devicePtr)),
+		uintptr(projectId), uintptr(unsafe.Pointer(&dq)), 0, 0); errno != 0 {
+		return quotaError(errno)
+	}
+	return nil
+}
+
+func quotaError(errno unix.Errno) error {
+	switch errno {
+	case unix.ENOTTY, unix.EOPNOTSUPP, unix.ESRCH, unix.ENOSYS, unix.EINVAL:
+		return fmt.Errorf("%w: %v", ErrQuotaUnsupported, errno)
+	}
+	return errno
+}
+
+// blockDeviceOf finds the device of the mount dir lives on in mountinfo.
+func blockDeviceOf(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var (
+		bestMount  string
+		bestDevice string
+	)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// id parent major:minor root mountpoint options [optional...] - fstype source superoptions
+		pre, post, ok := strings.Cut(sc.Text(), " - ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(pre)
+		postFields := strings.Fields(post)
+		if len(fields) < 5 || len(postFields) < 2 {
+			continue
+		}
+		mountPoint := unescapeMountPath(fields[4])
+		if !isPathWithin(abs, mountPoint) || len(mountPoint) < len(bestMount) {
+			continue
+		}
+		bestMount = mountPoint
+		bestDevice = postFields[1]
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(bestDevice, "/") {
+		return "", fmt.Errorf("%w: %s is not on a block device", ErrQuotaUnsupported, dir)
+	}
+	return bestDevice, nil
+}
+
+func isPathWithin(p string, dir string) bool {
+	if dir == "/" {
+		return true
+	}
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// unescapeMountPath undoes the octal escapes of mountinfo ("\040" is a space).
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			var c byte
+			valid := true
+			for _, d := range s[i+1 : i+4] {
+				if d < '0' || d > '7' {
+					valid = false
+					break
+				}
+				c = c*8 + byte(d-'0')
+			}
+			if valid {
+				b.WriteByte(c)
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// label selector operators, as in kubernetes matchExpressions
+const (
+	SelectorOpIn           = "In"
+	SelectorOpNotIn        = "NotIn"
+	SelectorOpExists       = "Exists"
+	SelectorOpDoesNotExist = "DoesNotExist"
+)
+
+// fields a fieldSelector can name, spelled as kubernetes does
+const (
+	FieldMetadataName      = "metadata.name"
+	FieldMetadataNamespace = "metadata.namespace"
+	FieldStatusPhase       = "status.phase"
+	FieldSpecNodeName      = "spec.nodeName"
+	FieldSpecPodId         = "spec.podId"
+)
+
+var (
+	labelKeyPattern   = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	labelValuePattern = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+	setBasedPattern   = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+type LabelSelectorRequirement struct {
+	Key      string   `json:"key" yaml:"key"`
+	Operator string   `json:"operator" yaml:"operator"`
+	Values   []string `json:"values,omitempty" yaml:"values"`
+}
+
+func (r LabelSelectorRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case SelectorOpIn:
+		return ok && slices.Contains(r.Values, value)
+	case SelectorOpNotIn:
+		return !ok || !slices.Contains(r.Values, value)
+	case SelectorOpExists:
+		return ok
+	case SelectorOpDoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+// LabelSelector holds requirements that must all match. An empty one
+// matches everything.
+type LabelSelector []LabelSelectorRequirement
+
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseLabelSelector parses the labelSelector query of kubectl:
+// "app=web,tier!=db", "env in (prod,staging)", "canary", "!canary".
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var result LabelSelector
+	for _, term := range splitSelector(selector) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("invalid label selector %q: empty requirement", selector)
+		}
+		r, err := parseLabelRequirement(term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %w", selector, err)
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func parseLabelRequirement(term string) (LabelSelectorRequirement, error) {
+	var r LabelSelectorRequirement
+	switch {
+	case strings.HasPrefix(term, "!"):
+		r = LabelSelectorRequirement{Key: strings.TrimSpace(term[1:]), Operator: SelectorOpDoesNotExist}
+	case strings.Contains(term, "!="):
+		key, value, _ := strings.Cut(term, "!=")
+		r = LabelSelectorRequirement{Key: strings.TrimSpace(key), Operator: SelectorOpNotIn, Values: []string{strings.TrimSpace(value)}}
+	case strings.Contains(term, "="):
+		key, value, _ := strings.Cut(term, "=")
+		value = strings.TrimPrefix(value, "=")
+		r = LabelSelectorRequirement{Key: strings.TrimSpace(key), Operator: SelectorOpIn, Values: []string{strings.TrimSpace(value)}}
+	default:
+		if m := setBasedPattern.FindStringSubmatch(term); m != nil {
+			r = LabelSelectorRequirement{Key: m[1], Operator: SelectorOpIn}
+			if m[2] == "notin" {
+				r.Operator = SelectorOpNotIn
+			}
+			for _, v := range strings.Split(m[3], ",") {
+				r.Values = append(r.Values, strings.TrimSpace(v))
+			}
+		} else {
+			r = LabelSelectorRequirement{Key: term, Operator: SelectorOpExists}
+		}
+	}
+	return r, ValidateLabelSelectorRequirement(r)
+}
+
+// splitSelector splits on the commas outside of the value sets.
+func splitSelector(selector string) []string {
+	if strings.TrimSpace(selector) == "" {
+		return nil
+	}
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+func ValidateLabelSelectorRequirement(r LabelSelectorRequirement) error {
+	if !labelKeyPattern.MatchString(r.Key) {
+		return fmt.Errorf("invalid label key %q", r.Key)
+	}
+	switch r.Operator {
+	case SelectorOpIn, SelectorOpNotIn:
+		if len(r.Values) == 0 {
+			return fmt.Errorf("%s: operator %s needs at least one value", r.Key, r.Operator)
+		}
+		for _, v := range r.Values {
+			if !labelValuePattern.MatchString(v) {
+				return fmt.Errorf("%s: invalid label value %q", r.Key, v)
+			}
+		}
+	case SelectorOpExists, SelectorOpDoesNotExist:
+		if len(r.Values) != 0 {
+			return fmt.Errorf("%s: operator %s takes no values", r.Key, r.Operator)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported operator %q", r.Key, r.Operator)
+	}
+	return nil
+}
+
+// MatchWorkloadSelector checks the matchLabels and matchExpressions of a
+// replica set or service. Unlike a query, an empty selector selects no pod.
+func MatchWorkloadSelector(matchLabels map[string]string, matchExpressions []LabelSelectorRequirement, labels map[string]string) bool {
+	if len(matchLabels) == 0 && len(matchExpressions) == 0 {
+		return false
+	}
+	for k, v := range matchLabels {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return LabelSelector(matchExpressions).Matches(labels)
+}
+
+type FieldRequirement struct {
+	Field string
+	Value string
+	Not   bool
+}
+
+// FieldSelector holds field requirements that must all match.
+type FieldSelector []FieldRequirement
+
+// ParseFieldSelector parses "status.phase=running,metadata.namespace!=kube",
+// only the given fields can be selected on.
+func ParseFieldSelector(selector string, fields []string) (FieldSelector, error) {
+	var result FieldSelector
+	for _, term := range splitSelector(selector) {
+		term = strings.TrimSpace(term)
+		var r FieldRequirement
+		if field, value, ok := strings.Cut(term, "!="); ok {
+			r = FieldRequirement{Field: field, Value: value, Not: true}
+		} else if field, value, ok := strings.Cut(term, "="); ok {
+			r = FieldRequirement{Field: field, Value: strings.TrimPrefix(value, "=")}
+		} else {
+			return nil, fmt.Errorf("invalid field selector %q: %q is not field=value", selector, term)
+		}
+		r.Field = strings.TrimSpace(r.Field)
+		r.Value = strings.TrimSpace(r.Value)
+		if !slices.Contains(fields, r.Field) {
+			return nil, fmt.Errorf("field label not supported: %s", r.Field)
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// Matches compares case-insensitively, so status.phase=Running finds the
+// pods stored as "running".
+func (s FieldSelector) Matches(fields map[string]string) bool {
+	for _, r := range s {
+		if strings.EqualFold(fields[r.Field], r.Value) == r.Not {
+			return false
+		}
+	}
+	return true
+}
+
+// ListOptions narrows a list call, as the selectors of kubectl get do.
+type ListOptions struct {
+	LabelSelector LabelSelector
+	FieldSelector FieldSelector
+}
+
+func ParseListOptions(labelSelector, fieldSelector string, fields []string) (ListOptions, error) {
+	labels, err := ParseLabelSelector(labelSelector)
+	if err != nil {
+		return ListOptions{}, err
+	}
+	fieldReqs, err := ParseFieldSelector(fieldSelector, fields)
+	if err != nil {
+		return ListOptions{}, err
+	}
+	return ListOptions{LabelSelector: labels, FieldSelector: fieldReqs}, nil
+}
+
+func (o ListOptions) Matches(labels, fields map[string]string) bool {
+	return o.LabelSelector.Matches(labels) && o.FieldSelector.Matches(fields)
+}
+
+// NodeName is the node every resource runs on, condenser manages one host.
+func NodeName() string {
+	name, _ := os.Hostname()
+	return name
+}